@@ -0,0 +1,58 @@
+package vfs
+
+import (
+	"os"
+	"time"
+)
+
+// Attributer is implemented by filesystems which can change the metadata of files.
+type Attributer interface {
+	// Chmod changes the mode of the named file to mode.
+	Chmod(name string, mode os.FileMode) error
+	// Chown changes the numeric uid and gid of the named file.
+	Chown(name string, uid, gid int) error
+	// Chtimes changes the access and modification times of the named file.
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// Owned is implemented by an os.FileInfo which knows the owner of the file.
+type Owned interface {
+	Owner() (uid, gid int)
+}
+
+// Chmod changes the mode of the named file on the given Filesystem.
+// If the Filesystem does not implement Attributer, a *os.PathError containing ErrUnsupported is returned.
+func Chmod(fs Filesystem, name string, mode os.FileMode) error {
+	if a, ok := fs.(Attributer); ok {
+		return a.Chmod(name, mode)
+	}
+	return &os.PathError{Op: "chmod", Path: name, Err: ErrUnsupported}
+}
+
+// Chown changes the numeric uid and gid of the named file on the given Filesystem.
+// If the Filesystem does not implement Attributer, a *os.PathError containing ErrUnsupported is returned.
+func Chown(fs Filesystem, name string, uid, gid int) error {
+	if a, ok := fs.(Attributer); ok {
+		return a.Chown(name, uid, gid)
+	}
+	return &os.PathError{Op: "chown", Path: name, Err: ErrUnsupported}
+}
+
+// Chtimes changes the access and modification times of the named file on the given Filesystem.
+// If the Filesystem does not implement Attributer, a *os.PathError containing ErrUnsupported is returned.
+func Chtimes(fs Filesystem, name string, atime time.Time, mtime time.Time) error {
+	if a, ok := fs.(Attributer); ok {
+		return a.Chtimes(name, atime, mtime)
+	}
+	return &os.PathError{Op: "chtimes", Path: name, Err: ErrUnsupported}
+}
+
+// FileOwner returns the numeric uid and gid of the file described by fi.
+// It supports FileInfos implementing Owned and those of the OS on unix systems.
+func FileOwner(fi os.FileInfo) (uid, gid int, ok bool) {
+	if o, ok := fi.(Owned); ok {
+		uid, gid := o.Owner()
+		return uid, gid, true
+	}
+	return sysOwner(fi)
+}
@@ -0,0 +1,15 @@
+//go:build windows || plan9
+
+package vfs
+
+import (
+	"os"
+)
+
+func sysOwner(fi os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+func sysNlink(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestAttributesUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	if err := vfs.Chmod(fs, "/file", 0600); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := vfs.Chown(fs, "/file", 1, 1); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := vfs.Chtimes(fs, "/file", time.Now(), time.Now()); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestAttributesReadOnly(t *testing.T) {
+	fs := vfs.ReadOnly(memfs.Create())
+	if err := vfs.Chmod(fs, "/", 0600); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := vfs.Chown(fs, "/", 1, 1); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := vfs.Chtimes(fs, "/", time.Now(), time.Now()); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestAttributesLogged(t *testing.T) {
+	var ops []string
+	fs := vfs.Logged(memfs.Create(), func(op string, args ...interface{}) {
+		ops = append(ops, op)
+	})
+	if err := vfs.Chmod(fs, "/", 0700); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if fi, _ := fs.Stat("/"); fi.Mode().Perm() != 0700 {
+		t.Errorf("Unexpected mode: %s", fi.Mode())
+	}
+	if len(ops) == 0 || ops[0] != "chmod" {
+		t.Errorf("Unexpected operations: %q", ops)
+	}
+}
+
+type ownedInfo struct {
+	os.FileInfo
+}
+
+func (ownedInfo) Owner() (int, int) {
+	return 1, 2
+}
+
+func TestFileOwner(t *testing.T) {
+	if uid, gid, ok := vfs.FileOwner(ownedInfo{}); !ok || uid != 1 || gid != 2 {
+		t.Errorf("Unexpected owner: %d:%d (%t)", uid, gid, ok)
+	}
+}
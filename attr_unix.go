@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+
+package vfs
+
+import (
+	"os"
+	"syscall"
+)
+
+func sysOwner(fi os.FileInfo) (int, int, bool) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid), true
+	}
+	return 0, 0, false
+}
+
+func sysNlink(fi os.FileInfo) (int, bool) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Nlink), true
+	}
+	return 0, false
+}
@@ -0,0 +1,558 @@
+package vfs
+
+import (
+	"container/list"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheOptions control the caching of Cached.
+// The zero value caches until invalidated without a size limit.
+type CacheOptions struct {
+	// TTL is the time cached results are served without asking the slow filesystem,
+	// zero never expires. Expired file contents are revalidated using OpenIfChanged.
+	TTL time.Duration
+	// MaxBytes limits the size of the file contents held in the cache,
+	// the least recently used contents are evicted first. Zero is unlimited.
+	MaxBytes int64
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// Cached creates a read-through wrapper around the filesystem slow, which caches
+// the results of Stat, Lstat and ReadDir in memory and the contents of files opened
+// for reading in the filesystem cache, e.g. a memfs. Contents are stored as files
+// in the root directory of cache. opts may be nil.
+//
+// Changes made through the wrapper, including files opened for writing, invalidate
+// the entries of the changed path and, if it is a symbolic link, of the files it points to.
+// Changes made to slow directly are only seen after the TTL expired, the path was
+// invalidated using Invalidate or an event of a watch started through the wrapper reported it.
+// Other aliases of a changed file are not tracked: results cached under the name of a
+// symbolic link or another hard link are refreshed like changes made to slow directly.
+// Extended attributes are not cached.
+// Only absolute paths are cached.
+func Cached(slow, cache Filesystem, opts *CacheOptions) *CacheFS {
+	fs := &CacheFS{
+		Filesystem: slow,
+		cache:      cache,
+		meta:       make(map[string]*cacheMeta),
+		contents:   make(map[string]*cacheContent),
+		lru:        list.New(),
+	}
+	if opts != nil {
+		fs.opts = *opts
+	}
+	if fs.opts.Now == nil {
+		fs.opts.Now = time.Now
+	}
+	return fs
+}
+
+// CacheFS represents a filesystem caching a slow filesystem
+// and works as a wrapper around existing filesystems.
+type CacheFS struct {
+	Filesystem
+	cache Filesystem
+	opts  CacheOptions
+
+	mutex sync.Mutex
+	// meta holds results keyed by operation and cleaned path, e.g. "stat:/a"
+	meta     map[string]*cacheMeta
+	contents map[string]*cacheContent
+	// lru holds the contents, the most recently used first
+	lru     *list.List
+	bytes   int64
+	ids     int
+	watches map[<-chan Event]cacheWatch
+}
+
+// cacheWatch is a watch on the slow filesystem, which invalidates the reported paths.
+type cacheWatch struct {
+	events <-chan Event
+	done   chan struct{}
+}
+
+// cacheMeta is a cached result of Stat, Lstat or ReadDir.
+type cacheMeta struct {
+	fi      os.FileInfo
+	fis     []os.FileInfo
+	err     error
+	expires time.Time
+}
+
+// cacheContent is the content of a file held in the cache.
+type cacheContent struct {
+	path       string
+	id         int
+	size       int64
+	validators Validators
+	expires    time.Time
+	elem       *list.Element
+}
+
+// expiry returns the expiration time of a result cached now.
+func (fs *CacheFS) expiry() time.Time {
+	if fs.opts.TTL == 0 {
+		return time.Time{}
+	}
+	return fs.opts.Now().Add(fs.opts.TTL)
+}
+
+// fresh reports whether a result with the expiration time expires may be served.
+func (fs *CacheFS) fresh(expires time.Time) bool {
+	return expires.IsZero() || fs.opts.Now().Before(expires)
+}
+
+// contentName returns the name of the file holding the content id in the cache.
+func (fs *CacheFS) contentName(id int) string {
+	return JoinPath(fs.cache, string(fs.cache.PathSeparator()), strconv.Itoa(id))
+}
+
+// Invalidate drops the cached results of the named file, everything below it
+// and the listing of its parent directory.
+func (fs *CacheFS) Invalidate(name string) {
+	p := cleanPath(fs, name)
+	if p == "" {
+		return
+	}
+	prefix := JoinPath(fs, p, "")
+	affected := func(path string) bool {
+		return path == p || strings.HasPrefix(path, prefix)
+	}
+	parent := Dir(fs, p)
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	for k := range fs.meta {
+		path := k[strings.Index(k, ":")+1:]
+		if affected(path) || path == parent {
+			delete(fs.meta, k)
+		}
+	}
+	for path, c := range fs.contents {
+		if affected(path) {
+			fs.removeContent(c)
+		}
+	}
+}
+
+// invalidateFile drops the cached results of the named file and the listing of its parent directory,
+// but not the results below it like Invalidate.
+func (fs *CacheFS) invalidateFile(name string) {
+	p := cleanPath(fs, name)
+	if p == "" {
+		return
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	for _, op := range []string{"stat", "lstat", "readdir"} {
+		delete(fs.meta, op+":"+p)
+	}
+	delete(fs.meta, "readdir:"+Dir(fs, p))
+	if c := fs.contents[p]; c != nil {
+		fs.removeContent(c)
+	}
+}
+
+// targets returns name and the targets of the symbolic links it leads to on the slow filesystem.
+// Only the last segments are resolved.
+func (fs *CacheFS) targets(name string) []string {
+	names := []string{name}
+	for i := 0; i < maxSymlinks; i++ {
+		fi, err := fs.Filesystem.Lstat(name)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			break
+		}
+		target, err := Readlink(fs.Filesystem, name)
+		if err != nil {
+			break
+		}
+		if !IsAbs(fs, target) {
+			target = Join(fs, Dir(fs, name), target)
+		}
+		name = target
+		names = append(names, name)
+	}
+	return names
+}
+
+// invalidateTargets invalidates name and the targets of the symbolic links it leads to.
+func (fs *CacheFS) invalidateTargets(name string) {
+	for _, n := range fs.targets(name) {
+		fs.Invalidate(n)
+	}
+}
+
+// removeContent drops a cached content.
+// The caller must hold the lock.
+func (fs *CacheFS) removeContent(c *cacheContent) {
+	delete(fs.contents, c.path)
+	fs.lru.Remove(c.elem)
+	fs.bytes -= c.size
+	fs.cache.Remove(fs.contentName(c.id))
+}
+
+// evict drops the least recently used contents until the size limit is met.
+// The caller must hold the lock.
+func (fs *CacheFS) evict() {
+	for fs.opts.MaxBytes > 0 && fs.bytes > fs.opts.MaxBytes && fs.lru.Len() > 0 {
+		fs.removeContent(fs.lru.Back().Value.(*cacheContent))
+	}
+}
+
+// cachedMeta returns the cached result with the key op and the cleaned path p,
+// or calls fn and caches its result. Errors other than not existing files are not cached.
+func (fs *CacheFS) cachedMeta(op, p string, fn func() *cacheMeta) *cacheMeta {
+	key := op + ":" + p
+	fs.mutex.Lock()
+	m, ok := fs.meta[key]
+	fs.mutex.Unlock()
+	if ok && fs.fresh(m.expires) {
+		return m
+	}
+	m = fn()
+	if m.err == nil || os.IsNotExist(m.err) {
+		m.expires = fs.expiry()
+		fs.mutex.Lock()
+		fs.meta[key] = m
+		fs.mutex.Unlock()
+	}
+	return m
+}
+
+// Stat returns the FileInfo of the named file, from the cache if possible.
+func (fs *CacheFS) Stat(name string) (os.FileInfo, error) {
+	p := cleanPath(fs, name)
+	if p == "" {
+		return fs.Filesystem.Stat(name)
+	}
+	m := fs.cachedMeta("stat", p, func() *cacheMeta {
+		fi, err := fs.Filesystem.Stat(name)
+		return &cacheMeta{fi: fi, err: err}
+	})
+	return m.fi, m.err
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links,
+// from the cache if possible.
+func (fs *CacheFS) Lstat(name string) (os.FileInfo, error) {
+	p := cleanPath(fs, name)
+	if p == "" {
+		return fs.Filesystem.Lstat(name)
+	}
+	m := fs.cachedMeta("lstat", p, func() *cacheMeta {
+		fi, err := fs.Filesystem.Lstat(name)
+		return &cacheMeta{fi: fi, err: err}
+	})
+	return m.fi, m.err
+}
+
+// ReadDir reads the named directory, from the cache if possible.
+// The entries are cached as results of Lstat as well.
+func (fs *CacheFS) ReadDir(path string) ([]os.FileInfo, error) {
+	p := cleanPath(fs, path)
+	if p == "" {
+		return fs.Filesystem.ReadDir(path)
+	}
+	m := fs.cachedMeta("readdir", p, func() *cacheMeta {
+		fis, err := fs.Filesystem.ReadDir(path)
+		if err == nil {
+			expires := fs.expiry()
+			fs.mutex.Lock()
+			for _, fi := range fis {
+				fs.meta["lstat:"+JoinPath(fs, p, fi.Name())] = &cacheMeta{fi: fi, expires: expires}
+			}
+			fs.mutex.Unlock()
+		}
+		return &cacheMeta{fis: fis, err: err}
+	})
+	if m.err != nil {
+		return nil, m.err
+	}
+	return append([]os.FileInfo(nil), m.fis...), nil
+}
+
+// OpenFile opens the named file. Regular files opened for reading are served from the cache,
+// files opened for writing invalidate the cached results of the file.
+func (fs *CacheFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	p := cleanPath(fs, name)
+	if p == "" {
+		return fs.Filesystem.OpenFile(name, flag, perm)
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		names := fs.targets(name)
+		for _, n := range names {
+			fs.Invalidate(n)
+		}
+		f, err := fs.Filesystem.OpenFile(name, flag, perm)
+		if err != nil {
+			return f, err
+		}
+		return &cacheWriteFile{File: f, fs: fs, names: names}, nil
+	}
+	fi, err := fs.Stat(name)
+	if err != nil {
+		if perr, ok := err.(*os.PathError); ok {
+			err = perr.Err
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if !fi.Mode().IsRegular() || (fs.opts.MaxBytes > 0 && fi.Size() > fs.opts.MaxBytes) {
+		return fs.Filesystem.OpenFile(name, flag, perm)
+	}
+	return fs.openContent(p, name)
+}
+
+// openContent opens the cached content of the cleaned path p,
+// fetching it from the slow filesystem if it is missing or changed.
+func (fs *CacheFS) openContent(p, name string) (File, error) {
+	fs.mutex.Lock()
+	c := fs.contents[p]
+	var since Validators
+	if c != nil {
+		if fs.fresh(c.expires) {
+			defer fs.mutex.Unlock()
+			return fs.openCached(c, name)
+		}
+		since = c.validators
+	}
+	fs.mutex.Unlock()
+
+	src, v, err := OpenIfChanged(fs.Filesystem, name, since)
+	if err == ErrNotModified {
+		fs.mutex.Lock()
+		defer fs.mutex.Unlock()
+		if fs.contents[p] == c {
+			c.expires = fs.expiry()
+			return fs.openCached(c, name)
+		}
+		// Evicted in the meantime
+		return fs.Filesystem.OpenFile(name, os.O_RDONLY, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	fs.mutex.Lock()
+	fs.ids++
+	id := fs.ids
+	fs.mutex.Unlock()
+	dst, err := fs.cache.OpenFile(fs.contentName(id), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	n, err := io.Copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		fs.cache.Remove(fs.contentName(id))
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if old := fs.contents[p]; old != nil {
+		fs.removeContent(old)
+	}
+	c = &cacheContent{path: p, id: id, size: n, validators: v, expires: fs.expiry()}
+	c.elem = fs.lru.PushFront(c)
+	fs.contents[p] = c
+	fs.bytes += n
+	fs.evict()
+	if fs.contents[p] != c {
+		// Grown beyond the size limit since Stat
+		return fs.Filesystem.OpenFile(name, os.O_RDONLY, 0)
+	}
+	return fs.openCached(c, name)
+}
+
+// openCached opens the cached content c for reading.
+// The caller must hold the lock.
+func (fs *CacheFS) openCached(c *cacheContent, name string) (File, error) {
+	fs.lru.MoveToFront(c.elem)
+	f, err := fs.cache.OpenFile(fs.contentName(c.id), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &cachedFile{File: f, fs: fs, name: name}, nil
+}
+
+// Remove removes the named file or directory and invalidates it.
+func (fs *CacheFS) Remove(name string) error {
+	err := fs.Filesystem.Remove(name)
+	fs.Invalidate(name)
+	return err
+}
+
+// Rename renames a file and invalidates both paths.
+func (fs *CacheFS) Rename(oldpath, newpath string) error {
+	err := fs.Filesystem.Rename(oldpath, newpath)
+	fs.Invalidate(oldpath)
+	fs.Invalidate(newpath)
+	return err
+}
+
+// Mkdir creates a directory and invalidates it.
+func (fs *CacheFS) Mkdir(name string, perm os.FileMode) error {
+	err := fs.Filesystem.Mkdir(name, perm)
+	fs.Invalidate(name)
+	return err
+}
+
+// Symlink creates newname as a symbolic link to oldname and invalidates newname.
+func (fs *CacheFS) Symlink(oldname, newname string) error {
+	err := Symlink(fs.Filesystem, oldname, newname)
+	fs.Invalidate(newname)
+	return err
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *CacheFS) Readlink(name string) (string, error) {
+	return Readlink(fs.Filesystem, name)
+}
+
+// Link creates newname as a hard link to oldname and invalidates both.
+func (fs *CacheFS) Link(oldname, newname string) error {
+	err := Link(fs.Filesystem, oldname, newname)
+	fs.Invalidate(oldname)
+	fs.Invalidate(newname)
+	return err
+}
+
+// Chmod changes the mode of the named file and invalidates it.
+func (fs *CacheFS) Chmod(name string, mode os.FileMode) error {
+	err := Chmod(fs.Filesystem, name, mode)
+	fs.invalidateTargets(name)
+	return err
+}
+
+// Chown changes the numeric uid and gid of the named file and invalidates it.
+func (fs *CacheFS) Chown(name string, uid, gid int) error {
+	err := Chown(fs.Filesystem, name, uid, gid)
+	fs.invalidateTargets(name)
+	return err
+}
+
+// Chtimes changes the access and modification times of the named file and invalidates it.
+func (fs *CacheFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	err := Chtimes(fs.Filesystem, name, atime, mtime)
+	fs.invalidateTargets(name)
+	return err
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *CacheFS) GetXattr(name, attr string) ([]byte, error) {
+	return GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *CacheFS) SetXattr(name, attr string, value []byte) error {
+	return SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *CacheFS) ListXattr(name string) ([]string, error) {
+	return ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *CacheFS) RemoveXattr(name, attr string) error {
+	return RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file on the slow filesystem.
+// Every event invalidates the cached results of its path before it is delivered.
+func (fs *CacheFS) Watch(name string) (<-chan Event, error) {
+	events, err := Watch(fs.Filesystem, name)
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan Event)
+	w := cacheWatch{events: events, done: make(chan struct{})}
+	go func() {
+		defer close(c)
+		for e := range events {
+			fs.Invalidate(e.Name)
+			select {
+			case c <- e:
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	fs.mutex.Lock()
+	if fs.watches == nil {
+		fs.watches = make(map[<-chan Event]cacheWatch)
+	}
+	fs.watches[c] = w
+	fs.mutex.Unlock()
+	return c, nil
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *CacheFS) Unwatch(events <-chan Event) error {
+	fs.mutex.Lock()
+	w, ok := fs.watches[events]
+	delete(fs.watches, events)
+	fs.mutex.Unlock()
+	if !ok {
+		return os.ErrInvalid
+	}
+	close(w.done)
+	return Unwatch(fs.Filesystem, w.events)
+}
+
+// cachedFile is a file served from the cache under its original name.
+type cachedFile struct {
+	File
+	fs   *CacheFS
+	name string
+}
+
+func (f *cachedFile) Name() string {
+	return f.name
+}
+
+func (f *cachedFile) Stat() (os.FileInfo, error) {
+	return f.fs.Stat(f.name)
+}
+
+// cacheWriteFile invalidates the file on every change.
+type cacheWriteFile struct {
+	File
+	fs *CacheFS
+	// names are the name of the file and the targets of the symbolic links it was opened through
+	names []string
+}
+
+// invalidate invalidates all names of the file.
+func (f *cacheWriteFile) invalidate() {
+	for _, name := range f.names {
+		f.fs.invalidateFile(name)
+	}
+}
+
+func (f *cacheWriteFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.invalidate()
+	return n, err
+}
+
+func (f *cacheWriteFile) Truncate(size int64) error {
+	err := f.File.Truncate(size)
+	f.invalidate()
+	return err
+}
+
+func (f *cacheWriteFile) Close() error {
+	err := f.File.Close()
+	f.invalidate()
+	return err
+}
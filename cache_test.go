@@ -0,0 +1,219 @@
+package vfs_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestCachedInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+	_ = vfs.Symlinker(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+	_ = vfs.Linker(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+	_ = vfs.Attributer(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+	_ = vfs.Xattrer(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+	_ = vfs.Watcher(vfs.Cached(vfs.OS(), memfs.Create(), nil))
+}
+
+func TestCachedConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return vfs.Cached(memfs.Create(), memfs.Create(), nil)
+	})
+}
+
+// countedFS returns a memfs counting the operations on it.
+func countedFS() (vfs.Filesystem, *vfs.LogFS, map[string]int) {
+	fs := memfs.Create()
+	counts := make(map[string]int)
+	return fs, vfs.Logged(fs, func(op string, args ...interface{}) {
+		counts[op]++
+	}), counts
+}
+
+func TestCachedMetadata(t *testing.T) {
+	backend, slow, counts := countedFS()
+	vfs.MkdirAll(backend, "/dir/sub", 0755)
+	vfs.WriteFile(backend, "/dir/file", []byte("content"), 0644)
+	fs := vfs.Cached(slow, memfs.Create(), nil)
+
+	for i := 0; i < 3; i++ {
+		if fi, err := fs.Stat("/dir/file"); err != nil || fi.Size() != 7 {
+			t.Fatalf("Unexpected stat: %v %v", fi, err)
+		}
+		if fis, err := fs.ReadDir("/dir/"); err != nil || len(fis) != 2 {
+			t.Fatalf("Unexpected entries: %v %v", fis, err)
+		}
+		if _, err := fs.Lstat("/dir/sub"); err != nil {
+			t.Fatalf("Lstat: %s", err)
+		}
+		if _, err := fs.Stat("/missing"); !os.IsNotExist(err) {
+			t.Fatalf("Expected not exist error, got %v", err)
+		}
+	}
+	if counts["stat"] != 2 || counts["readdir"] != 1 || counts["lstat"] != 0 {
+		t.Errorf("Unexpected operations on slow filesystem: %v", counts)
+	}
+
+	// Changes through the wrapper invalidate
+	if err := fs.Mkdir("/dir/new", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if fis, _ := fs.ReadDir("/dir"); len(fis) != 3 {
+		t.Errorf("Listing not invalidated: %v", fis)
+	}
+	if err := fs.Rename("/dir", "/moved"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if _, err := fs.Stat("/dir/file"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	// Changes on the slow filesystem need explicit invalidation
+	vfs.WriteFile(backend, "/missing", nil, 0644)
+	if _, err := fs.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected cached not exist error, got %v", err)
+	}
+	fs.Invalidate("/missing")
+	if _, err := fs.Stat("/missing"); err != nil {
+		t.Errorf("Stat after invalidate: %s", err)
+	}
+}
+
+func TestCachedContent(t *testing.T) {
+	backend, slow, counts := countedFS()
+	vfs.WriteFile(backend, "/file", []byte("v1"), 0644)
+	now := time.Now()
+	fs := vfs.Cached(slow, memfs.Create(), &vfs.CacheOptions{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+	})
+
+	read := func(expected string) {
+		t.Helper()
+		if b, err := vfs.ReadFile(fs, "/file"); err != nil || string(b) != expected {
+			t.Errorf("Unexpected content %q %v, expected %q", b, err, expected)
+		}
+	}
+	read("v1")
+	read("v1")
+	if counts["openfile"] != 1 {
+		t.Errorf("Expected 1 open on slow filesystem, got %d", counts["openfile"])
+	}
+	f, _ := fs.OpenFile("/file", os.O_RDONLY, 0)
+	if fi, err := f.Stat(); f.Name() != "/file" || err != nil || fi.Name() != "file" {
+		t.Errorf("Unexpected cached file: %s %v %v", f.Name(), fi, err)
+	}
+	f.Close()
+
+	// Unchanged content is revalidated after the TTL
+	now = now.Add(2 * time.Minute)
+	read("v1")
+	if counts["openfile"] != 1 {
+		t.Errorf("Expected revalidation without open, got %d opens", counts["openfile"])
+	}
+
+	// Changed content is fetched again after the TTL
+	vfs.WriteFile(backend, "/file", []byte("v2!"), 0644)
+	read("v1")
+	now = now.Add(2 * time.Minute)
+	read("v2!")
+
+	// Writes through the wrapper invalidate
+	if err := vfs.WriteFile(fs, "/file", []byte("v3"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	read("v3")
+}
+
+func TestCachedEviction(t *testing.T) {
+	_, slow, counts := countedFS()
+	cache := memfs.Create()
+	fs := vfs.Cached(slow, cache, &vfs.CacheOptions{MaxBytes: 10})
+	for _, name := range []string{"/a", "/b", "/large"} {
+		size := 6
+		if name == "/large" {
+			size = 11
+		}
+		vfs.WriteFile(fs, name, make([]byte, size), 0644)
+	}
+	opens := counts["openfile"]
+
+	vfs.ReadFile(fs, "/a")
+	vfs.ReadFile(fs, "/b")
+	vfs.ReadFile(fs, "/a")
+	vfs.ReadFile(fs, "/large")
+	if n := counts["openfile"] - opens; n != 4 {
+		t.Errorf("Expected 4 opens on slow filesystem, got %d", n)
+	}
+	if fis, _ := cache.ReadDir("/"); len(fis) != 1 {
+		t.Errorf("Expected a single cached content, got %d", len(fis))
+	}
+}
+
+func TestCachedWatch(t *testing.T) {
+	backend := memfs.Create()
+	vfs.WriteFile(backend, "/file", []byte("old"), 0644)
+	fs := vfs.Cached(backend, memfs.Create(), nil)
+	if b, err := vfs.ReadFile(fs, "/file"); err != nil || string(b) != "old" {
+		t.Fatalf("Unexpected content: %q %v", b, err)
+	}
+	if _, err := fs.Stat("/new"); !os.IsNotExist(err) {
+		t.Fatalf("Expected not exist error, got %v", err)
+	}
+
+	events, err := fs.Watch("/")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	// Changes on the slow filesystem are invalidated by their events
+	vfs.WriteFile(backend, "/new", nil, 0644)
+	if e := <-events; e.Name != "/new" {
+		t.Fatalf("Unexpected event %s", e)
+	}
+	if _, err := fs.Stat("/new"); err != nil {
+		t.Errorf("Stat after event: %s", err)
+	}
+	if err := fs.Unwatch(events); err != nil {
+		t.Errorf("Unwatch: %s", err)
+	}
+	for range events {
+	}
+	if err := fs.Unwatch(events); err != os.ErrInvalid {
+		t.Errorf("Expected invalid error, got %v", err)
+	}
+}
+
+func TestCachedSymlinkTarget(t *testing.T) {
+	backend := memfs.Create()
+	vfs.WriteFile(backend, "/t", []byte("old"), 0644)
+	backend.Symlink("/t", "/l")
+	backend.Symlink("l", "/rel")
+	fs := vfs.Cached(backend, memfs.Create(), nil)
+
+	for _, link := range []string{"/l", "/rel"} {
+		if b, err := vfs.ReadFile(fs, "/t"); err != nil || len(b) == 0 {
+			t.Fatalf("ReadFile: %q %v", b, err)
+		}
+		content := "written through " + link
+		if err := vfs.WriteFile(fs, link, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		if b, err := vfs.ReadFile(fs, "/t"); err != nil || string(b) != content {
+			t.Errorf("Target not invalidated by write through %s: %q %v", link, b, err)
+		}
+		if fi, err := fs.Stat("/t"); err != nil || fi.Size() != int64(len(content)) {
+			t.Errorf("Unexpected stat of target: %v %v", fi, err)
+		}
+	}
+
+	if err := fs.Chmod("/l", 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if fi, err := fs.Stat("/t"); err != nil || fi.Mode() != 0600 {
+		t.Errorf("Mode of target not invalidated: %v %v", fi, err)
+	}
+}
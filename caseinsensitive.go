@@ -0,0 +1,257 @@
+package vfs
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CaseInsensitive creates a wrapper around the given filesystem which looks up names
+// case-insensitively but preserves their case, like the default filesystems of macOS and Windows.
+//
+// Every segment of a path is replaced by the existing entry of its directory equal to it
+// under Unicode case folding, an exact match is preferred. New entries keep the case they
+// were created with. Creating an entry whose name differs only in case from an existing one
+// opens or reports the existing entry, renaming or linking onto it fails with os.ErrExist.
+// Renaming an entry to a different case of its own name is supported.
+//
+// The targets of symbolic links are resolved by the wrapped filesystem.
+func CaseInsensitive(fs Filesystem) *CaseInsensitiveFS {
+	return &CaseInsensitiveFS{Filesystem: fs}
+}
+
+// CaseInsensitiveFS represents a case-insensitive, case-preserving filesystem
+// and works as a wrapper around existing filesystems.
+type CaseInsensitiveFS struct {
+	Filesystem
+
+	// mutex serializes operations creating entries with the resolution of paths,
+	// so no two entries differing only in case are created.
+	mutex sync.RWMutex
+}
+
+// resolve returns the path on the wrapped filesystem of name.
+// Segments without a matching entry are kept as given, relative paths are returned unchanged.
+// The caller must hold the lock.
+func (fs *CaseInsensitiveFS) resolve(name string) string {
+	sep := string(fs.PathSeparator())
+	cleaned := cleanPath(fs, name)
+	segments := pathSegments(fs, cleaned)
+	if len(segments) == 0 {
+		return name
+	}
+	resolved := VolumeName(fs, cleaned)
+	for i, seg := range segments {
+		path := resolved + sep + seg
+		if _, err := fs.Filesystem.Lstat(path); err != nil {
+			match, ok := fs.match(resolved, seg)
+			if !ok {
+				// Nothing below a missing entry exists
+				return resolved + sep + strings.Join(segments[i:], sep)
+			}
+			path = resolved + sep + match
+		}
+		resolved = path
+	}
+	return resolved
+}
+
+// match returns the entry of dir equal to name under case folding.
+func (fs *CaseInsensitiveFS) match(dir, name string) (string, bool) {
+	if dir == VolumeName(fs, dir) {
+		dir += string(fs.PathSeparator())
+	}
+	fis, err := fs.Filesystem.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, fi := range fis {
+		if strings.EqualFold(fi.Name(), name) {
+			return fi.Name(), true
+		}
+	}
+	return "", false
+}
+
+// base returns the last segment of name.
+func (fs *CaseInsensitiveFS) base(name string) string {
+	sep := string(fs.PathSeparator())
+	if cleaned := cleanPath(fs, name); cleaned != "" {
+		name = cleaned
+	}
+	return name[strings.LastIndex(name, sep)+1:]
+}
+
+// collides reports whether host, the resolution of name, is an existing entry whose name differs in case.
+func (fs *CaseInsensitiveFS) collides(name, host string) bool {
+	return fs.base(name) != fs.base(host)
+}
+
+// OpenFile opens the named file, a created file keeps the case of name.
+func (fs *CaseInsensitiveFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&os.O_CREATE != 0 {
+		fs.mutex.Lock()
+		defer fs.mutex.Unlock()
+	} else {
+		fs.mutex.RLock()
+		defer fs.mutex.RUnlock()
+	}
+	return fs.Filesystem.OpenFile(fs.resolve(name), flag, perm)
+}
+
+// Remove removes the named file or directory.
+func (fs *CaseInsensitiveFS) Remove(name string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.Filesystem.Remove(fs.resolve(name))
+}
+
+// Rename renames a file.
+// It fails with os.ErrExist if newpath differs only in case from another existing entry.
+func (fs *CaseInsensitiveFS) Rename(oldpath, newpath string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	oldhost := fs.resolve(oldpath)
+	newhost := fs.resolve(newpath)
+	if newhost == oldhost {
+		// Change the case of the name
+		newhost = JoinPath(fs, Dir(fs, oldhost), fs.base(newpath))
+	} else if fs.collides(newpath, newhost) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+	}
+	return fs.Filesystem.Rename(oldhost, newhost)
+}
+
+// Mkdir creates a directory keeping the case of name.
+func (fs *CaseInsensitiveFS) Mkdir(name string, perm os.FileMode) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	host := fs.resolve(name)
+	if fs.collides(name, host) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	return fs.Filesystem.Mkdir(host, perm)
+}
+
+// Stat returns the FileInfo of the named file.
+func (fs *CaseInsensitiveFS) Stat(name string) (os.FileInfo, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return fs.Filesystem.Stat(fs.resolve(name))
+}
+
+// Lstat returns the FileInfo of the named file without following a symbolic link.
+func (fs *CaseInsensitiveFS) Lstat(name string) (os.FileInfo, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return fs.Filesystem.Lstat(fs.resolve(name))
+}
+
+// ReadDir reads the directory named by path, the entries keep their case.
+func (fs *CaseInsensitiveFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return fs.Filesystem.ReadDir(fs.resolve(path))
+}
+
+// OpenDir opens the named directory for iteration.
+// It implements DirOpener.
+func (fs *CaseInsensitiveFS) OpenDir(path string) (DirIterator, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return OpenDir(fs.Filesystem, fs.resolve(path))
+}
+
+// Symlink creates newname as a symbolic link to oldname, the target is stored unchanged.
+// It returns ErrUnsupported if the wrapped filesystem does not support symbolic links.
+func (fs *CaseInsensitiveFS) Symlink(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	host := fs.resolve(newname)
+	if fs.collides(newname, host) {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	return Symlink(fs.Filesystem, oldname, host)
+}
+
+// Link creates newname as a hard link to oldname.
+// It returns ErrUnsupported if the wrapped filesystem does not support hard links.
+func (fs *CaseInsensitiveFS) Link(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	host := fs.resolve(newname)
+	if fs.collides(newname, host) {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	return Link(fs.Filesystem, fs.resolve(oldname), host)
+}
+
+// Readlink returns the destination of the named symbolic link
+// if the wrapped filesystem supports symbolic links.
+func (fs *CaseInsensitiveFS) Readlink(name string) (string, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Readlink(fs.Filesystem, fs.resolve(name))
+}
+
+// Chmod changes the mode of the named file.
+func (fs *CaseInsensitiveFS) Chmod(name string, mode os.FileMode) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Chmod(fs.Filesystem, fs.resolve(name), mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *CaseInsensitiveFS) Chown(name string, uid, gid int) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Chown(fs.Filesystem, fs.resolve(name), uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *CaseInsensitiveFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Chtimes(fs.Filesystem, fs.resolve(name), atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *CaseInsensitiveFS) GetXattr(name, attr string) ([]byte, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return GetXattr(fs.Filesystem, fs.resolve(name), attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *CaseInsensitiveFS) SetXattr(name, attr string, value []byte) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return SetXattr(fs.Filesystem, fs.resolve(name), attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *CaseInsensitiveFS) ListXattr(name string) ([]string, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return ListXattr(fs.Filesystem, fs.resolve(name))
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *CaseInsensitiveFS) RemoveXattr(name, attr string) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return RemoveXattr(fs.Filesystem, fs.resolve(name), attr)
+}
+
+// Watch reports changes of the named file, the events carry the names of the wrapped filesystem.
+func (fs *CaseInsensitiveFS) Watch(name string) (<-chan Event, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Watch(fs.Filesystem, fs.resolve(name))
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *CaseInsensitiveFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
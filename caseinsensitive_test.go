@@ -0,0 +1,109 @@
+package vfs_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestCaseInsensitiveInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.CaseInsensitive(vfs.OS()))
+	_ = vfs.Symlinker(vfs.CaseInsensitive(vfs.OS()))
+	_ = vfs.Linker(vfs.CaseInsensitive(vfs.OS()))
+	_ = vfs.Attributer(vfs.CaseInsensitive(vfs.OS()))
+	_ = vfs.Watcher(vfs.CaseInsensitive(vfs.OS()))
+}
+
+func TestCaseInsensitiveConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return vfs.CaseInsensitive(memfs.Create())
+	})
+}
+
+// names returns the names of the entries of dir.
+func names(t *testing.T, fs vfs.Filesystem, dir string) []string {
+	t.Helper()
+	fis, err := fs.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir %s: %s", dir, err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names
+}
+
+func TestCaseInsensitiveLookup(t *testing.T) {
+	fs := vfs.CaseInsensitive(memfs.Create())
+	if err := vfs.MkdirAll(fs, "/Docs/Sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	vfs.WriteFile(fs, "/Docs/Sub/ReadMe.txt", []byte("content"), 0644)
+
+	for _, name := range []string{"/docs/sub/readme.txt", "/DOCS/SUB/README.TXT", "/docs/../Docs/./sub/README.txt"} {
+		b, err := vfs.ReadFile(fs, name)
+		if err != nil || string(b) != "content" {
+			t.Errorf("ReadFile %s: %q %v", name, b, err)
+		}
+	}
+	if got := names(t, fs, "/docs/sub"); len(got) != 1 || got[0] != "ReadMe.txt" {
+		t.Errorf("Case not preserved: %v", got)
+	}
+	if _, err := fs.Stat("/docs/missing/readme.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestCaseInsensitiveCollisions(t *testing.T) {
+	base := memfs.Create()
+	fs := vfs.CaseInsensitive(base)
+	vfs.WriteFile(fs, "/File.txt", []byte("old"), 0644)
+	vfs.WriteFile(fs, "/other", []byte("other"), 0644)
+
+	// Creating opens the existing file
+	if err := vfs.WriteFile(fs, "/FILE.TXT", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := vfs.ReadFile(base, "/File.txt"); err != nil || string(b) != "new" {
+		t.Errorf("Existing file not written: %q %v", b, err)
+	}
+	if _, err := fs.OpenFile("/file.txt", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	if err := fs.Mkdir("/FILE.txt", 0755); !os.IsExist(err) {
+		t.Errorf("Expected exist error for mkdir, got %v", err)
+	}
+	if err := fs.Rename("/other", "/file.TXT"); !os.IsExist(err) {
+		t.Errorf("Expected exist error for rename, got %v", err)
+	}
+	if err := fs.Symlink("/other", "/FILE.TXT"); !os.IsExist(err) {
+		t.Errorf("Expected exist error for symlink, got %v", err)
+	}
+	if got := names(t, base, "/"); len(got) != 2 {
+		t.Errorf("Unexpected entries: %v", got)
+	}
+}
+
+func TestCaseInsensitiveRenameCase(t *testing.T) {
+	base := memfs.Create()
+	fs := vfs.CaseInsensitive(base)
+	fs.Mkdir("/dir", 0755)
+	vfs.WriteFile(fs, "/dir/file", []byte("content"), 0644)
+
+	if err := fs.Rename("/DIR/file", "/dir/FILE"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if got := names(t, base, "/dir"); len(got) != 1 || got[0] != "FILE" {
+		t.Errorf("Unexpected entries: %v", got)
+	}
+	if err := fs.Rename("/dir", "/Dir"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if b, err := vfs.ReadFile(base, "/Dir/FILE"); err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+}
@@ -0,0 +1,393 @@
+package vfs
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed resolving a single path.
+const maxSymlinks = 255
+
+// Chroot creates a wrapper around the given filesystem which confines all operations
+// to the directory root, so it can be handed to untrusted code.
+//
+// Every path is interpreted relative to root, ".." never leaves it:
+// "../../etc/passwd" is the same file as "/etc/passwd" inside the chroot.
+// Symbolic links are resolved by the wrapper, absolute targets relative to root,
+// so links pointing outside of root can not be used to escape it.
+// Errors and the names of opened files contain the paths inside the chroot.
+//
+// Changes to the wrapped filesystem made outside of the wrapper, e.g. replacing
+// a directory by a symbolic link, may race with the resolution and are not prevented.
+func Chroot(fs Filesystem, root string) *ChrootFS {
+	sep := string(fs.PathSeparator())
+	return &ChrootFS{Filesystem: fs, root: strings.TrimSuffix(Clean(fs, root), sep)}
+}
+
+// ChrootFS represents a filesystem confined to a directory
+// and works as a wrapper around existing filesystems.
+type ChrootFS struct {
+	Filesystem
+	root string
+
+	// mutex serializes operations which may replace a path segment
+	// by a symbolic link with the resolution of paths.
+	mutex   sync.RWMutex
+	watches map[<-chan Event]chrootWatch
+}
+
+// chrootWatch forwards the events of the wrapped filesystem.
+type chrootWatch struct {
+	events <-chan Event
+	done   chan struct{}
+}
+
+// resolve returns the path on the wrapped filesystem of the path name inside the chroot.
+// Symbolic links are resolved, the last segment only if follow is set.
+// The caller must hold the lock.
+func (fs *ChrootFS) resolve(name string, follow bool) (string, error) {
+	var resolved []string
+	pending := pathSegments(fs, name)
+	links := 0
+	for len(pending) > 0 {
+		seg := pending[0]
+		pending = pending[1:]
+		switch seg {
+		case ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+		resolved = append(resolved, seg)
+		if len(pending) == 0 && !follow {
+			break
+		}
+		host := fs.host(resolved)
+		fi, err := fs.Filesystem.Lstat(host)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			// Missing entries are reported by the operation itself
+			continue
+		}
+		links++
+		if links > maxSymlinks {
+			return "", ErrTooManyLinks
+		}
+		target, err := Readlink(fs.Filesystem, host)
+		if err != nil {
+			return "", err
+		}
+		resolved = resolved[:len(resolved)-1]
+		if IsAbs(fs, target) {
+			resolved = resolved[:0]
+		}
+		pending = append(pathSegments(fs, target), pending...)
+	}
+	return fs.host(resolved), nil
+}
+
+// host returns the path of the segments on the wrapped filesystem.
+func (fs *ChrootFS) host(segments []string) string {
+	sep := string(fs.PathSeparator())
+	if len(segments) == 0 && fs.root == "" {
+		return sep
+	}
+	return fs.root + sep + strings.Join(segments, sep)
+}
+
+// inner returns the path inside the chroot of a path on the wrapped filesystem.
+func (fs *ChrootFS) inner(host string) string {
+	sep := string(fs.PathSeparator())
+	name := strings.TrimPrefix(host, fs.root)
+	if !strings.HasPrefix(name, sep) {
+		name = sep + name
+	}
+	return name
+}
+
+// pathError replaces the paths of the wrapped filesystem inside err by name.
+func pathError(err error, op, name string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.PathError{Op: e.Op, Path: name, Err: e.Err}
+	case *os.LinkError:
+		return &os.PathError{Op: e.Op, Path: name, Err: e.Err}
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// linkError replaces the paths of the wrapped filesystem inside err by oldname and newname.
+func linkError(err error, op, oldname, newname string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.LinkError{Op: op, Old: oldname, New: newname, Err: e.Err}
+	case *os.LinkError:
+		return &os.LinkError{Op: e.Op, Old: oldname, New: newname, Err: e.Err}
+	}
+	return &os.LinkError{Op: op, Old: oldname, New: newname, Err: err}
+}
+
+// do resolves name and calls fn with the path on the wrapped filesystem.
+func (fs *ChrootFS) do(op, name string, follow bool, fn func(host string) error) error {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	host, err := fs.resolve(name, follow)
+	if err == nil {
+		err = fn(host)
+	}
+	if err != nil {
+		return pathError(err, op, name)
+	}
+	return nil
+}
+
+// OpenFile opens the named file inside the chroot.
+// The returned file reports the name inside the chroot.
+func (fs *ChrootFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	var f File
+	err := fs.do("open", name, true, func(host string) (err error) {
+		f, err = fs.Filesystem.OpenFile(host, flag, perm)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &chrootFile{File: f, name: name}, nil
+}
+
+// Remove removes the named file or directory, symbolic links are not followed.
+func (fs *ChrootFS) Remove(name string) error {
+	return fs.do("remove", name, false, fs.Filesystem.Remove)
+}
+
+// RemoveAll removes path and any children it contains, symbolic links are not followed.
+func (fs *ChrootFS) RemoveAll(path string) error {
+	return fs.do("removeall", path, false, func(host string) error {
+		return RemoveAll(fs.Filesystem, host)
+	})
+}
+
+// Rename renames a file, symbolic links are not followed.
+func (fs *ChrootFS) Rename(oldpath, newpath string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	oldhost, err := fs.resolve(oldpath, false)
+	if err != nil {
+		return linkError(err, "rename", oldpath, newpath)
+	}
+	newhost, err := fs.resolve(newpath, false)
+	if err == nil {
+		err = fs.Filesystem.Rename(oldhost, newhost)
+	}
+	if err != nil {
+		return linkError(err, "rename", oldpath, newpath)
+	}
+	return nil
+}
+
+// Mkdir creates a directory inside the chroot.
+func (fs *ChrootFS) Mkdir(name string, perm os.FileMode) error {
+	return fs.do("mkdir", name, false, func(host string) error {
+		return fs.Filesystem.Mkdir(host, perm)
+	})
+}
+
+// MkdirAll creates a directory and all necessary parents inside the chroot.
+func (fs *ChrootFS) MkdirAll(path string, perm os.FileMode) error {
+	return fs.do("mkdir", path, true, func(host string) error {
+		return MkdirAll(fs.Filesystem, host, perm)
+	})
+}
+
+// Stat returns the FileInfo of the named file, following symbolic links.
+func (fs *ChrootFS) Stat(name string) (fi os.FileInfo, err error) {
+	err = fs.do("stat", name, true, func(host string) (err error) {
+		fi, err = fs.Filesystem.Stat(host)
+		return err
+	})
+	return fi, err
+}
+
+// Lstat returns the FileInfo of the named file without following a symbolic link.
+func (fs *ChrootFS) Lstat(name string) (fi os.FileInfo, err error) {
+	err = fs.do("lstat", name, false, func(host string) (err error) {
+		fi, err = fs.Filesystem.Lstat(host)
+		return err
+	})
+	return fi, err
+}
+
+// ReadDir reads the directory named by path inside the chroot.
+func (fs *ChrootFS) ReadDir(path string) (fis []os.FileInfo, err error) {
+	err = fs.do("readdirent", path, true, func(host string) (err error) {
+		fis, err = fs.Filesystem.ReadDir(host)
+		return err
+	})
+	return fis, err
+}
+
+// OpenDir opens the named directory for iteration.
+// It implements DirOpener.
+func (fs *ChrootFS) OpenDir(path string) (d DirIterator, err error) {
+	err = fs.do("opendir", path, true, func(host string) (err error) {
+		d, err = OpenDir(fs.Filesystem, host)
+		return err
+	})
+	return d, err
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+// The target is stored unchanged and resolved inside the chroot.
+// It returns ErrUnsupported if the wrapped filesystem does not support symbolic links.
+func (fs *ChrootFS) Symlink(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	host, err := fs.resolve(newname, false)
+	if err == nil {
+		err = Symlink(fs.Filesystem, oldname, host)
+	}
+	if err != nil {
+		return linkError(err, "symlink", oldname, newname)
+	}
+	return nil
+}
+
+// Link creates newname as a hard link to oldname, symbolic links are not followed.
+// It returns ErrUnsupported if the wrapped filesystem does not support hard links.
+func (fs *ChrootFS) Link(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	oldhost, err := fs.resolve(oldname, false)
+	if err != nil {
+		return linkError(err, "link", oldname, newname)
+	}
+	newhost, err := fs.resolve(newname, false)
+	if err == nil {
+		err = Link(fs.Filesystem, oldhost, newhost)
+	}
+	if err != nil {
+		return linkError(err, "link", oldname, newname)
+	}
+	return nil
+}
+
+// Readlink returns the destination of the named symbolic link
+// if the wrapped filesystem supports symbolic links.
+func (fs *ChrootFS) Readlink(name string) (target string, err error) {
+	err = fs.do("readlink", name, false, func(host string) (err error) {
+		target, err = Readlink(fs.Filesystem, host)
+		return err
+	})
+	return target, err
+}
+
+// Chmod changes the mode of the named file.
+func (fs *ChrootFS) Chmod(name string, mode os.FileMode) error {
+	return fs.do("chmod", name, true, func(host string) error {
+		return Chmod(fs.Filesystem, host, mode)
+	})
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *ChrootFS) Chown(name string, uid, gid int) error {
+	return fs.do("chown", name, true, func(host string) error {
+		return Chown(fs.Filesystem, host, uid, gid)
+	})
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *ChrootFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return fs.do("chtimes", name, true, func(host string) error {
+		return Chtimes(fs.Filesystem, host, atime, mtime)
+	})
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *ChrootFS) GetXattr(name, attr string) (value []byte, err error) {
+	err = fs.do("getxattr", name, true, func(host string) (err error) {
+		value, err = GetXattr(fs.Filesystem, host, attr)
+		return err
+	})
+	return value, err
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *ChrootFS) SetXattr(name, attr string, value []byte) error {
+	return fs.do("setxattr", name, true, func(host string) error {
+		return SetXattr(fs.Filesystem, host, attr, value)
+	})
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *ChrootFS) ListXattr(name string) (attrs []string, err error) {
+	err = fs.do("listxattr", name, true, func(host string) (err error) {
+		attrs, err = ListXattr(fs.Filesystem, host)
+		return err
+	})
+	return attrs, err
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *ChrootFS) RemoveXattr(name, attr string) error {
+	return fs.do("removexattr", name, true, func(host string) error {
+		return RemoveXattr(fs.Filesystem, host, attr)
+	})
+}
+
+// Watch reports changes of the named file, the names of the events are inside the chroot.
+func (fs *ChrootFS) Watch(name string) (<-chan Event, error) {
+	var events <-chan Event
+	err := fs.do("watch", name, true, func(host string) (err error) {
+		events, err = Watch(fs.Filesystem, host)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := make(chan Event)
+	w := chrootWatch{events: events, done: make(chan struct{})}
+	go func() {
+		defer close(c)
+		for e := range events {
+			select {
+			case c <- Event{Name: fs.inner(e.Name), Op: e.Op}:
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	fs.mutex.Lock()
+	if fs.watches == nil {
+		fs.watches = make(map[<-chan Event]chrootWatch)
+	}
+	fs.watches[c] = w
+	fs.mutex.Unlock()
+	return c, nil
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *ChrootFS) Unwatch(events <-chan Event) error {
+	fs.mutex.Lock()
+	w, ok := fs.watches[events]
+	delete(fs.watches, events)
+	fs.mutex.Unlock()
+	if !ok {
+		return os.ErrInvalid
+	}
+	close(w.done)
+	return Unwatch(fs.Filesystem, w.events)
+}
+
+// chrootFile reports the name inside the chroot.
+type chrootFile struct {
+	File
+	name string
+}
+
+func (f *chrootFile) Name() string {
+	return f.name
+}
@@ -0,0 +1,204 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestChrootInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.Chroot(vfs.OS(), "/"))
+	_ = vfs.Symlinker(vfs.Chroot(vfs.OS(), "/"))
+	_ = vfs.Watcher(vfs.Chroot(vfs.OS(), "/"))
+}
+
+func TestChrootConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		fs := memfs.Create()
+		fs.Mkdir("/jail", 0755)
+		return vfs.Chroot(fs, "/jail")
+	})
+}
+
+// chrootFS returns a memfs with a secret outside and a file inside the jail at /jail.
+func chrootFS(t *testing.T) (*vfs.ChrootFS, vfs.Filesystem) {
+	t.Helper()
+	fs := memfs.Create()
+	if err := vfs.MkdirAll(fs, "/jail/etc", 0755); err != nil {
+		t.Fatal(err)
+	}
+	vfs.MkdirAll(fs, "/etc", 0755)
+	vfs.WriteFile(fs, "/etc/passwd", []byte("secret"), 0644)
+	vfs.WriteFile(fs, "/jail/etc/passwd", []byte("jailed"), 0644)
+	return vfs.Chroot(fs, "/jail/"), fs
+}
+
+func TestChrootDotDot(t *testing.T) {
+	fs, _ := chrootFS(t)
+	for _, name := range []string{"/etc/passwd", "etc/passwd", "../../etc/passwd", "/etc/../../../etc/./passwd"} {
+		b, err := vfs.ReadFile(fs, name)
+		if err != nil || string(b) != "jailed" {
+			t.Errorf("ReadFile %s: %q %v", name, b, err)
+		}
+	}
+	fis, err := fs.ReadDir("/..")
+	if err != nil || len(fis) != 1 || fis[0].Name() != "etc" {
+		t.Errorf("ReadDir of parent of root: %v %v", fis, err)
+	}
+}
+
+func TestChrootBackslash(t *testing.T) {
+	fs := memfs.Create(memfs.WithPathSeparator('\\'))
+	vfs.MkdirAll(fs, `\jail\dir`, 0755)
+	vfs.WriteFile(fs, `\secret`, []byte("secret"), 0644)
+	vfs.WriteFile(fs, `\jail\secret`, []byte("jailed"), 0644)
+	fs.Symlink(`C:/secret`, `\jail\dir\link`)
+	c := vfs.Chroot(fs, `\jail\`)
+
+	for _, name := range []string{"../secret", "/../secret", `\..\secret`, `dir/..\../secret`, `C:\..\secret`, "dir/link"} {
+		b, err := vfs.ReadFile(c, name)
+		if err != nil || string(b) != "jailed" {
+			t.Errorf("ReadFile %s: %q %v", name, b, err)
+		}
+	}
+}
+
+func TestChrootSymlinks(t *testing.T) {
+	fs, inner := chrootFS(t)
+	links := map[string]string{
+		"/abs":   "/etc",
+		"/rel":   "../../../etc",
+		"/chain": "abs/passwd",
+	}
+	for link, target := range links {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatalf("Symlink: %s", err)
+		}
+	}
+	// Created on the wrapped filesystem pointing outside
+	if err := vfs.Symlink(inner, "/etc", "/jail/outside"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"/abs/passwd", "/rel/passwd", "/chain", "/outside/passwd"} {
+		b, err := vfs.ReadFile(fs, name)
+		if err != nil || string(b) != "jailed" {
+			t.Errorf("ReadFile %s: %q %v", name, b, err)
+		}
+	}
+	if target, err := fs.Readlink("/rel"); err != nil || target != "../../../etc" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+	if fi, err := fs.Lstat("/abs"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat of link: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/abs"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat of link: %v %v", fi, err)
+	}
+
+	// Writing through a link stays inside as well
+	if err := vfs.WriteFile(fs, "/outside/passwd", []byte("changed"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if b, _ := vfs.ReadFile(inner, "/etc/passwd"); string(b) != "secret" {
+		t.Errorf("File outside of chroot changed: %q", b)
+	}
+
+	// Removing the link keeps the target
+	if err := fs.Remove("/abs"); err != nil {
+		t.Errorf("Remove: %s", err)
+	}
+	if _, err := fs.Stat("/etc/passwd"); err != nil {
+		t.Errorf("Target removed: %s", err)
+	}
+}
+
+func TestChrootLoop(t *testing.T) {
+	fs, _ := chrootFS(t)
+	fs.Symlink("/b", "/a")
+	fs.Symlink("/a", "/b")
+	_, err := fs.Stat("/a")
+	if !errors.Is(err, vfs.ErrTooManyLinks) {
+		t.Fatalf("Expected ErrTooManyLinks, got %v", err)
+	}
+	if perr, ok := err.(*os.PathError); !ok || perr.Path != "/a" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestChrootErrorPaths(t *testing.T) {
+	fs, _ := chrootFS(t)
+	_, err := fs.OpenFile("/missing", os.O_RDONLY, 0)
+	if perr, ok := err.(*os.PathError); !ok || perr.Path != "/missing" || !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	err = fs.Rename("/missing", "/other")
+	if lerr, ok := err.(*os.LinkError); !ok || lerr.Old != "/missing" || lerr.New != "/other" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	f, err := fs.OpenFile("/etc/passwd", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer f.Close()
+	if f.Name() != "/etc/passwd" {
+		t.Errorf("Unexpected name: %s", f.Name())
+	}
+}
+
+func TestChrootWatch(t *testing.T) {
+	fs, _ := chrootFS(t)
+	events, err := fs.Watch("/etc")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	vfs.WriteFile(fs, "/etc/hosts", nil, 0644)
+	if ev := <-events; ev.Name != "/etc/hosts" || ev.Op != vfs.EventCreate {
+		t.Errorf("Unexpected event: %s", ev)
+	}
+	if err := fs.Unwatch(events); err != nil {
+		t.Errorf("Unwatch: %s", err)
+	}
+	for range events {
+	}
+	if err := fs.Unwatch(events); err == nil {
+		t.Errorf("Expected error unwatching twice")
+	}
+}
+
+func TestChrootOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-chroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outside := filepath.Join(dir, "secret")
+	jail := filepath.Join(dir, "jail")
+	ioutil.WriteFile(outside, []byte("secret"), 0644)
+	os.Mkdir(jail, 0755)
+	if err := os.Symlink(outside, filepath.Join(jail, "abs")); err != nil {
+		t.Skipf("Symlinks not supported: %s", err)
+	}
+	os.Symlink("../secret", filepath.Join(jail, "rel"))
+
+	fs := vfs.Chroot(vfs.OS(), jail)
+	for _, name := range []string{"/abs", "/rel", "/../secret"} {
+		if _, err := vfs.ReadFile(fs, name); !os.IsNotExist(err) {
+			t.Errorf("ReadFile %s: expected not exist error, got %v", name, err)
+		}
+	}
+	if err := vfs.WriteFile(fs, "/rel", []byte("changed"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if b, _ := ioutil.ReadFile(outside); string(b) != "secret" {
+		t.Errorf("File outside of chroot changed: %q", b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(jail, "secret")); string(b) != "changed" {
+		t.Errorf("File not created inside chroot: %q", b)
+	}
+}
@@ -0,0 +1,101 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrNotModified is returned by conditional operations if the file
+// did not change since the given validators were recorded.
+var ErrNotModified = errors.New("Not modified")
+
+// Validators identify a specific version of a file's content,
+// like the ETag, version ID and Last-Modified headers of HTTP and object stores.
+// Empty fields are unknown.
+type Validators struct {
+	ETag         string
+	VersionID    string
+	LastModified time.Time
+}
+
+// IsZero reports whether no validator is set.
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.VersionID == "" && v.LastModified.IsZero()
+}
+
+// Matches reports whether v and o identify the same version.
+// The strongest validator known to both sides is compared:
+// version ID, then ETag, then Last-Modified.
+func (v Validators) Matches(o Validators) bool {
+	switch {
+	case v.VersionID != "" && o.VersionID != "":
+		return v.VersionID == o.VersionID
+	case v.ETag != "" && o.ETag != "":
+		return v.ETag == o.ETag
+	case !v.LastModified.IsZero() && !o.LastModified.IsZero():
+		return v.LastModified.Equal(o.LastModified)
+	}
+	return false
+}
+
+// Validated is implemented by an os.FileInfo or File which
+// knows the validators of the underlying content.
+type Validated interface {
+	Validators() Validators
+}
+
+// ConditionalOpener is implemented by filesystems which can
+// open a file only if it changed, e.g. by a conditional GET request.
+type ConditionalOpener interface {
+	// OpenIfChanged opens the named file for reading if its current version
+	// does not match since. It returns the file together with its current validators,
+	// or ErrNotModified if the file did not change.
+	OpenIfChanged(name string, since Validators) (File, Validators, error)
+}
+
+// StatValidators returns the validators of the named file on the given Filesystem.
+// If the FileInfo does not implement Validated, a weak ETag is derived from
+// its size and modification time.
+func StatValidators(fs Filesystem, name string) (Validators, error) {
+	fi, err := fs.Stat(name)
+	if err != nil {
+		return Validators{}, err
+	}
+	return FileInfoValidators(fi), nil
+}
+
+// FileInfoValidators returns the validators exposed by fi, or
+// validators derived from its size and modification time.
+func FileInfoValidators(fi os.FileInfo) Validators {
+	if v, ok := fi.(Validated); ok {
+		return v.Validators()
+	}
+	return Validators{
+		ETag:         fmt.Sprintf("W/\"%x-%x\"", fi.Size(), fi.ModTime().UnixNano()),
+		LastModified: fi.ModTime(),
+	}
+}
+
+// OpenIfChanged opens the named file on the given Filesystem for reading if it changed
+// compared to since, otherwise ErrNotModified is returned.
+// It uses the native implementation if the Filesystem is a ConditionalOpener
+// and compares the validators of Stat otherwise.
+func OpenIfChanged(fs Filesystem, name string, since Validators) (File, Validators, error) {
+	if co, ok := fs.(ConditionalOpener); ok {
+		return co.OpenIfChanged(name, since)
+	}
+	v, err := StatValidators(fs, name)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	if v.Matches(since) {
+		return nil, v, ErrNotModified
+	}
+	f, err := Open(fs, name)
+	if err != nil {
+		return nil, Validators{}, err
+	}
+	return f, v, nil
+}
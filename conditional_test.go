@@ -0,0 +1,84 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestValidatorsMatches(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		a, b  vfs.Validators
+		match bool
+	}{
+		{vfs.Validators{}, vfs.Validators{}, false},
+		{vfs.Validators{ETag: "a"}, vfs.Validators{ETag: "a"}, true},
+		{vfs.Validators{ETag: "a"}, vfs.Validators{ETag: "b"}, false},
+		{vfs.Validators{ETag: "a", VersionID: "1"}, vfs.Validators{ETag: "b", VersionID: "1"}, true},
+		{vfs.Validators{ETag: "a", LastModified: now}, vfs.Validators{LastModified: now}, true},
+		{vfs.Validators{LastModified: now}, vfs.Validators{LastModified: now.Add(time.Second)}, false},
+	}
+	for i, test := range tests {
+		if m := test.a.Matches(test.b); m != test.match {
+			t.Errorf("Test %d: Expected match %t, got %t", i, test.match, m)
+		}
+	}
+}
+
+func TestOpenIfChanged(t *testing.T) {
+	fs := memfs.Create()
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	f, v, err := vfs.OpenIfChanged(fs, "/file", vfs.Validators{})
+	if err != nil {
+		t.Fatalf("OpenIfChanged: %s", err)
+	}
+	f.Close()
+	if v.IsZero() {
+		t.Fatalf("Expected validators")
+	}
+
+	if _, _, err := vfs.OpenIfChanged(fs, "/file", v); err != vfs.ErrNotModified {
+		t.Errorf("Expected ErrNotModified, got %v", err)
+	}
+
+	if err := vfs.WriteFile(fs, "/file", []byte("changed content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	f, _, err = vfs.OpenIfChanged(fs, "/file", v)
+	if err != nil {
+		t.Fatalf("Expected changed file, got %v", err)
+	}
+	f.Close()
+
+	if _, _, err := vfs.OpenIfChanged(fs, "/missing", v); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+type conditionalFS struct {
+	vfs.Filesystem
+	called bool
+}
+
+func (fs *conditionalFS) OpenIfChanged(name string, since vfs.Validators) (vfs.File, vfs.Validators, error) {
+	fs.called = true
+	return nil, since, vfs.ErrNotModified
+}
+
+func TestOpenIfChangedNative(t *testing.T) {
+	fs := &conditionalFS{Filesystem: vfs.Dummy(errors.New("Not implemented"))}
+	if _, _, err := vfs.OpenIfChanged(fs, "/file", vfs.Validators{ETag: "a"}); err != vfs.ErrNotModified {
+		t.Errorf("Expected ErrNotModified, got %v", err)
+	}
+	if !fs.called {
+		t.Errorf("Native OpenIfChanged not called")
+	}
+}
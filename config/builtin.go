@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/prefixfs"
+)
+
+func init() {
+	RegisterBackend("os", func(options json.RawMessage) (vfs.Filesystem, error) {
+		return vfs.OS(), nil
+	})
+	RegisterBackend("memfs", func(options json.RawMessage) (vfs.Filesystem, error) {
+		return memfs.Create(), nil
+	})
+	RegisterWrapper("readonly", func(fs vfs.Filesystem, options json.RawMessage) (vfs.Filesystem, error) {
+		var opts struct {
+			Writable []string `json:"writable"`
+		}
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		return vfs.ReadOnlyExcept(fs, opts.Writable...), nil
+	})
+	RegisterWrapper("prefix", func(fs vfs.Filesystem, options json.RawMessage) (vfs.Filesystem, error) {
+		var opts struct {
+			Prefix string `json:"prefix"`
+		}
+		if err := decodeOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		if opts.Prefix == "" {
+			return nil, errors.New("missing prefix")
+		}
+		return prefixfs.Create(fs, opts.Prefix), nil
+	})
+}
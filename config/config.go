@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/mountfs"
+)
+
+// Config describes a filesystem stack.
+// The backend is created first, the mounts are mounted into it
+// and the wrappers are applied in order on top of the result.
+//
+//	{
+//		"backend": {"type": "os"},
+//		"mounts": {
+//			"/memfs": {"backend": {"type": "memfs"}}
+//		},
+//		"wrappers": [
+//			{"type": "prefix", "options": {"prefix": "/srv"}},
+//			{"type": "readonly"}
+//		]
+//	}
+type Config struct {
+	Backend  Layer             `json:"backend"`
+	Mounts   map[string]Config `json:"mounts,omitempty"`
+	Wrappers []Layer           `json:"wrappers,omitempty"`
+}
+
+// Layer selects a registered backend or wrapper by type
+// and carries its type specific options.
+type Layer struct {
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// BackendFactory creates a backend filesystem from its options.
+type BackendFactory func(options json.RawMessage) (vfs.Filesystem, error)
+
+// WrapperFactory wraps a filesystem according to its options.
+type WrapperFactory func(fs vfs.Filesystem, options json.RawMessage) (vfs.Filesystem, error)
+
+var (
+	lock     sync.RWMutex
+	backends = make(map[string]BackendFactory)
+	wrappers = make(map[string]WrapperFactory)
+)
+
+// RegisterBackend makes a backend available under the given type.
+// Registering an existing type replaces it.
+func RegisterBackend(typ string, factory BackendFactory) {
+	lock.Lock()
+	backends[typ] = factory
+	lock.Unlock()
+}
+
+// RegisterWrapper makes a wrapper available under the given type.
+// Registering an existing type replaces it.
+func RegisterWrapper(typ string, factory WrapperFactory) {
+	lock.Lock()
+	wrappers[typ] = factory
+	lock.Unlock()
+}
+
+// Load decodes a JSON configuration from r and builds the filesystem stack.
+func Load(r io.Reader) (vfs.Filesystem, error) {
+	var c Config
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("config: %s", err)
+	}
+	return c.Build()
+}
+
+// Build creates the filesystem stack described by the configuration.
+func (c Config) Build() (vfs.Filesystem, error) {
+	lock.RLock()
+	backend, ok := backends[c.Backend.Type]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("config: unknown backend type %q", c.Backend.Type)
+	}
+	fs, err := backend(c.Backend.Options)
+	if err != nil {
+		return nil, fmt.Errorf("config: backend %q: %s", c.Backend.Type, err)
+	}
+
+	if len(c.Mounts) > 0 {
+		mfs := mountfs.Create(fs)
+		paths := make([]string, 0, len(c.Mounts))
+		for path := range c.Mounts {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			mount, err := c.Mounts[path].Build()
+			if err != nil {
+				return nil, err
+			}
+			if err := mfs.Mount(mount, path); err != nil {
+				return nil, fmt.Errorf("config: mount %q: %s", path, err)
+			}
+		}
+		fs = mfs
+	}
+
+	for _, l := range c.Wrappers {
+		lock.RLock()
+		wrapper, ok := wrappers[l.Type]
+		lock.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("config: unknown wrapper type %q", l.Type)
+		}
+		if fs, err = wrapper(fs, l.Options); err != nil {
+			return nil, fmt.Errorf("config: wrapper %q: %s", l.Type, err)
+		}
+	}
+	return fs, nil
+}
+
+// decodeOptions decodes options into v, missing options leave v untouched.
+func decodeOptions(options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	return json.Unmarshal(options, v)
+}
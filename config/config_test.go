@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/mountfs"
+	"github.com/blang/vfs/prefixfs"
+)
+
+func TestLoad(t *testing.T) {
+	fs, err := Load(strings.NewReader(`{
+		"backend": {"type": "memfs"},
+		"mounts": {"/mnt": {"backend": {"type": "memfs"}}},
+		"wrappers": [{"type": "readonly"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	ro, ok := fs.(*vfs.RoFS)
+	if !ok {
+		t.Fatalf("Expected readonly filesystem, got %T", fs)
+	}
+	if _, ok := ro.Filesystem.(*mountfs.MountFS); !ok {
+		t.Fatalf("Expected mountfs, got %T", ro.Filesystem)
+	}
+	if _, err := fs.Stat("/mnt"); err != nil {
+		t.Errorf("Mount not found: %s", err)
+	}
+	if err := fs.Mkdir("/mnt/dir", 0777); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestBuildReadOnlyWritable(t *testing.T) {
+	c := Config{
+		Backend:  Layer{Type: "memfs"},
+		Wrappers: []Layer{{Type: "readonly", Options: json.RawMessage(`{"writable": ["/tmp"]}`)}},
+	}
+	fs, err := c.Build()
+	if err != nil {
+		t.Fatalf("Build: %s", err)
+	}
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Errorf("Mkdir in writable path: %s", err)
+	}
+	if err := fs.Mkdir("/etc", 0777); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestBuildPrefix(t *testing.T) {
+	c := Config{
+		Backend:  Layer{Type: "memfs"},
+		Wrappers: []Layer{{Type: "prefix", Options: json.RawMessage(`{"prefix": "/srv"}`)}},
+	}
+	fs, err := c.Build()
+	if err != nil {
+		t.Fatalf("Build: %s", err)
+	}
+	if pfs, ok := fs.(*prefixfs.FS); !ok || pfs.Prefix != "/srv" {
+		t.Errorf("Expected prefixfs, got %#v", fs)
+	}
+
+	c.Wrappers[0].Options = nil
+	if _, err := c.Build(); err == nil {
+		t.Errorf("Expected error for missing prefix")
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := (Config{Backend: Layer{Type: "unknown"}}).Build(); err == nil {
+		t.Errorf("Expected error for unknown backend")
+	}
+	if _, err := (Config{Backend: Layer{Type: "memfs"}, Wrappers: []Layer{{Type: "unknown"}}}).Build(); err == nil {
+		t.Errorf("Expected error for unknown wrapper")
+	}
+	if _, err := Load(strings.NewReader(`{`)); err == nil {
+		t.Errorf("Expected decoding error")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	errBackend := errors.New("backend")
+	RegisterBackend("failing", func(options json.RawMessage) (vfs.Filesystem, error) {
+		return nil, errBackend
+	})
+	var wrapped vfs.Filesystem
+	RegisterWrapper("recording", func(fs vfs.Filesystem, options json.RawMessage) (vfs.Filesystem, error) {
+		wrapped = fs
+		return fs, nil
+	})
+
+	if _, err := (Config{Backend: Layer{Type: "failing"}}).Build(); err == nil {
+		t.Errorf("Expected backend error")
+	}
+	fs, err := Config{Backend: Layer{Type: "memfs"}, Wrappers: []Layer{{Type: "recording"}}}.Build()
+	if err != nil {
+		t.Fatalf("Build: %s", err)
+	}
+	if _, ok := wrapped.(*memfs.MemFS); !ok || wrapped != fs {
+		t.Errorf("Wrapper not applied: %T", wrapped)
+	}
+}
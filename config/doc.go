@@ -0,0 +1,12 @@
+// Package config builds filesystem stacks from a declarative JSON configuration.
+//
+// The following types are registered by default:
+//
+// 	backend "os":       vfs.OS()
+// 	backend "memfs":    memfs.Create()
+// 	wrapper "readonly": vfs.ReadOnlyExcept(fs, writable...), options: {"writable": ["/tmp"]}
+// 	wrapper "prefix":   prefixfs.Create(fs, prefix), options: {"prefix": "/path"}
+//
+// Further backends and wrappers can be made available using RegisterBackend and RegisterWrapper.
+// Configurations kept in other formats like YAML can be converted to JSON or decoded into Config directly.
+package config
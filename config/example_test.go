@@ -0,0 +1,25 @@
+package config_test
+
+import (
+	"strings"
+
+	"github.com/blang/vfs/config"
+)
+
+func ExampleLoad() {
+	// An in-memory filesystem with a read-only view of the OS filesystem mounted on /os
+	fs, err := config.Load(strings.NewReader(`{
+		"backend": {"type": "memfs"},
+		"mounts": {
+			"/os": {
+				"backend": {"type": "os"},
+				"wrappers": [{"type": "readonly"}]
+			}
+		}
+	}`))
+	if err != nil {
+		return
+	}
+	fs.Mkdir("/scratch", 0777)
+	fs.Stat("/os/etc/hosts")
+}
@@ -0,0 +1,261 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Copier is implemented by filesystems which can copy a file
+// without streaming its content through the caller, e.g. a server-side copy.
+type Copier interface {
+	// CopyFile copies the file src to dst, both paths are inside the Filesystem.
+	// If dst exists, it is replaced.
+	CopyFile(dst, src string) error
+}
+
+// CopyFile copies the file srcPath on the src Filesystem to dstPath on the dst Filesystem.
+// The destination is created with the permissions of the source or truncated if it already exists.
+// If dst implements Attributer, the mode of the source is applied regardless of the umask.
+// If src and dst are the same Filesystem and it implements Copier, the native copy is used.
+func CopyFile(dst, src Filesystem, dstPath, srcPath string) error {
+	if c, ok := dst.(Copier); ok && sameFilesystem(dst, src) {
+		return c.CopyFile(dstPath, srcPath)
+	}
+
+	fi, err := src.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.PathError{Op: "copy", Path: srcPath, Err: ErrIsDirectory}
+	}
+	in, err := Open(src, srcPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := dst.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	return chmodSupported(dst, dstPath, fi.Mode())
+}
+
+// chmodSupported changes the mode of the named file if the Filesystem supports it.
+func chmodSupported(fs Filesystem, name string, mode os.FileMode) error {
+	if err := Chmod(fs, name, mode); err != nil && !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// CopyOptions control how CopyTree copies a tree.
+// The zero value copies all entries, fails on existing files and stops at the first error.
+type CopyOptions struct {
+	// Filter is called with the source path of every entry, returning false skips it.
+	// Skipping a directory skips all its entries.
+	Filter func(path string, fi os.FileInfo) bool
+	// Overwrite replaces existing files, otherwise copying onto an existing file fails.
+	// Existing directories are always merged.
+	Overwrite bool
+	// PreserveTimes copies the modification times, if the destination supports Chtimes.
+	PreserveTimes bool
+	// PreserveXattrs copies the extended attributes of files and directories, if both filesystems support them.
+	PreserveXattrs bool
+	// OnError is called with the source path of an entry which could not be copied.
+	// Returning nil skips the entry and continues, returning an error stops CopyTree with it.
+	// If OnError is nil, the first error stops CopyTree.
+	OnError func(path string, err error) error
+}
+
+// CopyTree copies the file or directory srcRoot on the src Filesystem with all its entries to dstRoot on the dst Filesystem.
+// Missing parents of dstRoot are created. Modes of files and directories are preserved,
+// symbolic links are recreated if dst supports them. Other file types like devices can not be copied.
+// Paths are converted between the path separators of both filesystems.
+// The options may be nil.
+func CopyTree(dst, src Filesystem, dstRoot, srcRoot string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	srcRoot, dstRoot = Clean(src, srcRoot), Clean(dst, dstRoot)
+	srcSep := string(src.PathSeparator())
+	if sameFilesystem(dst, src) && (dstRoot == srcRoot || strings.HasPrefix(dstRoot, strings.TrimSuffix(srcRoot, srcSep)+srcSep)) {
+		return &os.LinkError{Op: "copy", Old: srcRoot, New: dstRoot, Err: os.ErrInvalid}
+	}
+
+	fail := func(path string, err error) error {
+		if opts.OnError != nil {
+			return opts.OnError(path, err)
+		}
+		return err
+	}
+	// Directories get their mode and times after all entries are copied
+	type copiedDir struct {
+		path    string
+		srcPath string
+		fi      os.FileInfo
+	}
+	var dirs []copiedDir
+
+	err := Walk(src, srcRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return fail(path, err)
+		}
+		if opts.Filter != nil && !opts.Filter(path, fi) {
+			if fi.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		dstPath := dstRoot
+		for _, seg := range strings.Split(strings.TrimPrefix(path, srcRoot), srcSep) {
+			if seg != "" {
+				dstPath = JoinPath(dst, dstPath, seg)
+			}
+		}
+		if err := copyEntry(dst, src, dstPath, path, fi, opts); err != nil {
+			if err := fail(path, err); err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		if fi.IsDir() {
+			dirs = append(dirs, copiedDir{dstPath, path, fi})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		err := chmodSupported(dst, d.path, d.fi.Mode())
+		if err == nil && opts.PreserveXattrs {
+			err = xattrsSupported(dst, src, d.path, d.srcPath)
+		}
+		if err == nil && opts.PreserveTimes {
+			err = chtimesSupported(dst, d.path, d.fi)
+		}
+		if err != nil {
+			if err := fail(d.path, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyEntry copies a single file, directory or symbolic link, the entries of a directory are not copied.
+func copyEntry(dst, src Filesystem, dstPath, srcPath string, fi os.FileInfo, opts *CopyOptions) error {
+	mode := fi.Mode()
+	switch {
+	case mode.IsDir():
+		// The directory stays writable until its entries are copied
+		return MkdirAll(dst, dstPath, mode.Perm()|0700)
+	case mode&os.ModeSymlink != 0:
+		target, err := Readlink(src, srcPath)
+		if err != nil {
+			return err
+		}
+		if err := copyTarget(dst, dstPath, true, opts); err != nil {
+			return err
+		}
+		return Symlink(dst, target, dstPath)
+	case mode.IsRegular():
+		if err := copyTarget(dst, dstPath, false, opts); err != nil {
+			return err
+		}
+		if err := CopyFile(dst, src, dstPath, srcPath); err != nil {
+			return err
+		}
+		if opts.PreserveXattrs {
+			if err := xattrsSupported(dst, src, dstPath, srcPath); err != nil {
+				return err
+			}
+		}
+		if opts.PreserveTimes {
+			return chtimesSupported(dst, dstPath, fi)
+		}
+		return nil
+	}
+	return &os.PathError{Op: "copy", Path: srcPath, Err: ErrUnsupported}
+}
+
+// copyTarget prepares dstPath to be replaced by a file depending on the options,
+// an existing file is removed if remove is set. Missing parents are created.
+func copyTarget(dst Filesystem, dstPath string, remove bool, opts *CopyOptions) error {
+	fi, err := dst.Lstat(dstPath)
+	if os.IsNotExist(err) {
+		if i := strings.LastIndex(dstPath, string(dst.PathSeparator())); i > 0 {
+			return MkdirAll(dst, dstPath[:i], 0755)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !opts.Overwrite || fi.IsDir() {
+		return &os.PathError{Op: "copy", Path: dstPath, Err: os.ErrExist}
+	}
+	// Symbolic links are replaced instead of written through
+	if remove || fi.Mode()&os.ModeSymlink != 0 {
+		return dst.Remove(dstPath)
+	}
+	return nil
+}
+
+// chtimesSupported copies the modification time of fi to the named file if the Filesystem supports it.
+func chtimesSupported(fs Filesystem, name string, fi os.FileInfo) error {
+	if err := Chtimes(fs, name, fi.ModTime(), fi.ModTime()); err != nil && !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// xattrsSupported copies the extended attributes of srcPath if both Filesystems support them.
+func xattrsSupported(dst, src Filesystem, dstPath, srcPath string) error {
+	if err := CopyXattrs(dst, src, dstPath, srcPath); err != nil && !errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	return nil
+}
+
+// Move moves the file srcPath on the src Filesystem to dstPath on the dst Filesystem.
+// If src and dst are the same Filesystem, the file is renamed,
+// otherwise or if the rename fails with ErrCrossDevice, it is copied and removed from src afterwards.
+func Move(dst, src Filesystem, dstPath, srcPath string) error {
+	if sameFilesystem(dst, src) {
+		err := src.Rename(srcPath, dstPath)
+		if !errors.Is(err, ErrCrossDevice) {
+			return err
+		}
+	}
+	if err := CopyFile(dst, src, dstPath, srcPath); err != nil {
+		return err
+	}
+	return src.Remove(srcPath)
+}
+
+// sameFilesystem reports whether a and b are the same Filesystem instance.
+// Filesystems of non-comparable types are never considered the same.
+func sameFilesystem(a, b Filesystem) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || ta == nil || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
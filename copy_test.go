@@ -0,0 +1,281 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/mountfs"
+)
+
+func TestCopyFile(t *testing.T) {
+	src := memfs.Create()
+	dst := memfs.Create()
+	if err := vfs.WriteFile(src, "/file", []byte("content"), 0640); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	if err := vfs.CopyFile(dst, src, "/copy", "/file"); err != nil {
+		t.Fatalf("CopyFile: %s", err)
+	}
+	if b, err := vfs.ReadFile(dst, "/copy"); err != nil || string(b) != "content" {
+		t.Errorf("Invalid copy: %q %v", b, err)
+	}
+	if fi, err := dst.Stat("/copy"); err != nil || fi.Mode() != 0640 {
+		t.Errorf("Invalid mode: %v", fi)
+	}
+
+	if err := src.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := vfs.CopyFile(dst, src, "/dir", "/dir"); err == nil {
+		t.Errorf("Expected error copying directory")
+	}
+	if err := vfs.CopyFile(dst, src, "/copy", "/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+type copierFS struct {
+	vfs.Filesystem
+	copied [2]string
+}
+
+func (fs *copierFS) CopyFile(dst, src string) error {
+	fs.copied = [2]string{dst, src}
+	return nil
+}
+
+func TestCopyFileCopier(t *testing.T) {
+	fs := &copierFS{Filesystem: vfs.Dummy(errors.New("Not implemented"))}
+	if err := vfs.CopyFile(fs, fs, "/dst", "/src"); err != nil {
+		t.Fatalf("CopyFile: %s", err)
+	}
+	if fs.copied != [2]string{"/dst", "/src"} {
+		t.Errorf("Native copy not used: %q", fs.copied)
+	}
+
+	// Different filesystems do not use the native copy
+	other := &copierFS{Filesystem: vfs.Dummy(errors.New("Not implemented"))}
+	if err := vfs.CopyFile(other, fs, "/dst", "/src"); err == nil {
+		t.Errorf("Expected error")
+	}
+	if other.copied != [2]string{} {
+		t.Errorf("Native copy used across filesystems")
+	}
+}
+
+func TestMove(t *testing.T) {
+	src := memfs.Create()
+	dst := memfs.Create()
+	if err := vfs.WriteFile(src, "/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	// Same filesystem renames
+	if err := vfs.Move(src, src, "/moved", "/file"); err != nil {
+		t.Fatalf("Move: %s", err)
+	}
+	if _, err := src.Stat("/file"); !os.IsNotExist(err) {
+		t.Errorf("Source still exists")
+	}
+
+	// Across filesystems
+	if err := vfs.Move(dst, src, "/file", "/moved"); err != nil {
+		t.Fatalf("Move: %s", err)
+	}
+	if _, err := src.Stat("/moved"); !os.IsNotExist(err) {
+		t.Errorf("Source still exists")
+	}
+	if b, err := vfs.ReadFile(dst, "/file"); err != nil || string(b) != "content" {
+		t.Errorf("Invalid move: %q %v", b, err)
+	}
+}
+
+func TestMoveCrossDevice(t *testing.T) {
+	fs := mountfs.Create(memfs.Create())
+	fs.Mount(memfs.Create(), "/mnt")
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	if err := fs.Rename("/file", "/mnt/file"); !errors.Is(err, vfs.ErrCrossDevice) {
+		t.Fatalf("Expected ErrCrossDevice, got %v", err)
+	}
+	if err := vfs.Move(fs, fs, "/mnt/file", "/file"); err != nil {
+		t.Fatalf("Move: %s", err)
+	}
+	if _, err := fs.Stat("/file"); !os.IsNotExist(err) {
+		t.Errorf("Source still exists")
+	}
+	if b, err := vfs.ReadFile(fs, "/mnt/file"); err != nil || string(b) != "content" {
+		t.Errorf("Invalid move: %q %v", b, err)
+	}
+}
+
+func copyTreeTestFS(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	if err := vfs.MkdirAll(fs, "/src/sub/empty", 0750); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	for name, content := range map[string]string{"/src/a": "a", "/src/sub/b": "b", "/src/skip": "skip"} {
+		if err := vfs.WriteFile(fs, name, []byte(content), 0640); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	if err := fs.Chmod("/src/sub", 0555); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if err := fs.Symlink("sub/b", "/src/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	return fs
+}
+
+func TestCopyTree(t *testing.T) {
+	src := copyTreeTestFS(t)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	src.(vfs.Attributer).Chtimes("/src/a", mtime, mtime)
+	src.(vfs.Attributer).Chtimes("/src/sub", mtime, mtime)
+
+	dst := memfs.Create()
+	opts := &vfs.CopyOptions{
+		Filter: func(path string, fi os.FileInfo) bool {
+			return fi.Name() != "skip"
+		},
+		PreserveTimes: true,
+	}
+	if err := vfs.CopyTree(dst, src, "/dst/copy", "/src", opts); err != nil {
+		t.Fatalf("CopyTree: %s", err)
+	}
+
+	for name, content := range map[string]string{"/dst/copy/a": "a", "/dst/copy/sub/b": "b", "/dst/copy/link": "b"} {
+		if b, err := vfs.ReadFile(dst, name); err != nil || string(b) != content {
+			t.Errorf("Invalid copy of %s: %q %v", name, b, err)
+		}
+	}
+	if _, err := dst.Stat("/dst/copy/skip"); !os.IsNotExist(err) {
+		t.Errorf("Filtered file copied: %v", err)
+	}
+	if target, err := dst.Readlink("/dst/copy/link"); err != nil || target != "sub/b" {
+		t.Errorf("Invalid link: %q %v", target, err)
+	}
+	if fi, err := dst.Stat("/dst/copy/sub"); err != nil || fi.Mode().Perm() != 0555 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("Invalid directory: %v %v", fi, err)
+	}
+	if fi, err := dst.Stat("/dst/copy/sub/empty"); err != nil || fi.Mode().Perm() != 0750 {
+		t.Errorf("Invalid empty directory: %v %v", fi, err)
+	}
+	if fi, err := dst.Stat("/dst/copy/a"); err != nil || fi.Mode() != 0640 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("Invalid file: %v %v", fi, err)
+	}
+}
+
+func TestCopyTreeRoots(t *testing.T) {
+	src := memfs.Create()
+	vfs.MkdirAll(src, "/d", 0755)
+	vfs.WriteFile(src, "/a", []byte("a"), 0644)
+	vfs.WriteFile(src, "/d/b", []byte("b"), 0644)
+
+	for _, c := range []struct {
+		dst              vfs.Filesystem
+		dstRoot, srcRoot string
+		files            map[string]string
+	}{
+		{memfs.Create(), "/dst", "/", map[string]string{"/dst/a": "a", "/dst/d/b": "b"}},
+		{memfs.Create(), "/dst/", "/d/", map[string]string{"/dst/b": "b"}},
+		{memfs.Create(), "/", "/d", map[string]string{"/b": "b"}},
+		{memfs.Create(memfs.WithPathSeparator('\\')), `C:\dst\`, "/", map[string]string{`\dst\a`: "a", `\dst\d\b`: "b"}},
+	} {
+		if err := vfs.CopyTree(c.dst, src, c.dstRoot, c.srcRoot, nil); err != nil {
+			t.Fatalf("CopyTree %s to %s: %s", c.srcRoot, c.dstRoot, err)
+		}
+		for name, content := range c.files {
+			if b, err := vfs.ReadFile(c.dst, name); err != nil || string(b) != content {
+				t.Errorf("Invalid copy of %s to %s: %s: %q %v", c.srcRoot, c.dstRoot, name, b, err)
+			}
+		}
+	}
+}
+
+func TestCopyTreeExisting(t *testing.T) {
+	src := copyTreeTestFS(t)
+	dst := memfs.Create()
+	dst.Mkdir("/dst", 0755)
+	vfs.WriteFile(dst, "/dst/a", []byte("old"), 0644)
+	vfs.WriteFile(dst, "/dst/link", []byte("old"), 0644)
+
+	if err := vfs.CopyTree(dst, src, "/dst", "/src", nil); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+
+	// Errors of single entries can be skipped
+	var failed []string
+	opts := &vfs.CopyOptions{OnError: func(path string, err error) error {
+		failed = append(failed, path)
+		return nil
+	}}
+	if err := vfs.CopyTree(dst, src, "/dst", "/src", opts); err != nil {
+		t.Fatalf("CopyTree: %s", err)
+	}
+	if !reflect.DeepEqual(failed, []string{"/src/a", "/src/link"}) {
+		t.Errorf("Unexpected failed entries: %q", failed)
+	}
+	if b, err := vfs.ReadFile(dst, "/dst/sub/b"); err != nil || string(b) != "b" {
+		t.Errorf("Invalid copy: %q %v", b, err)
+	}
+
+	opts = &vfs.CopyOptions{Overwrite: true}
+	if err := vfs.CopyTree(dst, src, "/dst", "/src", opts); err != nil {
+		t.Fatalf("CopyTree: %s", err)
+	}
+	if b, err := vfs.ReadFile(dst, "/dst/a"); err != nil || string(b) != "a" {
+		t.Errorf("File not replaced: %q %v", b, err)
+	}
+	if target, err := dst.Readlink("/dst/link"); err != nil || target != "sub/b" {
+		t.Errorf("Link not replaced: %q %v", target, err)
+	}
+}
+
+func TestCopyTreeIntoItself(t *testing.T) {
+	fs := copyTreeTestFS(t)
+	if err := vfs.CopyTree(fs, fs, "/src/sub/copy", "/src", nil); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	if err := vfs.CopyTree(fs, fs, "/src2", "/src", nil); err != nil {
+		t.Errorf("CopyTree: %s", err)
+	}
+}
+
+func TestCopyTreeOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-copytree")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Stage into the OS filesystem and back into memory
+	src := copyTreeTestFS(t)
+	root := filepath.Join(dir, "tree")
+	if err := vfs.CopyTree(vfs.OS(), src, root, "/src", nil); err != nil {
+		t.Fatalf("CopyTree to OS: %s", err)
+	}
+	// The read-only directory would prevent the cleanup
+	defer os.Chmod(filepath.Join(root, "sub"), 0755)
+	dst := memfs.Create()
+	if err := vfs.CopyTree(dst, vfs.OS(), "/tree", root, nil); err != nil {
+		t.Fatalf("CopyTree from OS: %s", err)
+	}
+	if b, err := vfs.ReadFile(dst, "/tree/link"); err != nil || string(b) != "b" {
+		t.Errorf("Invalid copy: %q %v", b, err)
+	}
+	if fi, err := dst.Stat("/tree/skip"); err != nil || fi.Mode() != 0640 {
+		t.Errorf("Invalid mode: %v %v", fi, err)
+	}
+}
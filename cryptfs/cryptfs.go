@@ -0,0 +1,369 @@
+package cryptfs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+var (
+	// ErrInvalidKey is returned by Create if the master key is shorter than 16 bytes.
+	ErrInvalidKey = errors.New("Master key too short")
+	// ErrCorrupt is returned if encrypted content or names fail authentication,
+	// e.g. because they were modified or encrypted using a different key.
+	ErrCorrupt = errors.New("Encrypted data corrupt")
+)
+
+// Options configure the encryption of a FS.
+type Options struct {
+	// ObfuscateNames encrypts the names of all files, directories and symbolic links
+	// and the targets of symbolic links.
+	// Entries of the wrapped filesystem which can not be decrypted are not listed by ReadDir.
+	ObfuscateNames bool
+}
+
+// FS is a filesystem which encrypts the content of files on the wrapped filesystem.
+type FS struct {
+	vfs.Filesystem
+	content cipher.AEAD
+	// names and nameIV are only set if names are obfuscated
+	names  cipher.AEAD
+	nameIV []byte
+
+	mutex   sync.Mutex
+	watches map[<-chan vfs.Event]cryptWatch
+}
+
+// cryptWatch forwards the events of the wrapped filesystem.
+type cryptWatch struct {
+	events <-chan vfs.Event
+	done   chan struct{}
+}
+
+// Create returns a wrapper of fs encrypting with keys derived from masterKey.
+// The options may be nil.
+func Create(fs vfs.Filesystem, masterKey []byte, opts *Options) (*FS, error) {
+	if len(masterKey) < 16 {
+		return nil, ErrInvalidKey
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	content, err := newAEAD(deriveKey(masterKey, "content"))
+	if err != nil {
+		return nil, err
+	}
+	c := &FS{Filesystem: fs, content: content}
+	if opts.ObfuscateNames {
+		if c.names, err = newAEAD(deriveKey(masterKey, "names")); err != nil {
+			return nil, err
+		}
+		c.nameIV = deriveKey(masterKey, "name iv")
+	}
+	return c, nil
+}
+
+// deriveKey returns a 256 bit key for the given purpose.
+func deriveKey(masterKey []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte("vfs/cryptfs " + purpose))
+	return mac.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptName encrypts a single segment, the nonce is derived from the name.
+func (fs *FS) encryptName(name string) string {
+	mac := hmac.New(sha256.New, fs.nameIV)
+	mac.Write([]byte(name))
+	nonce := mac.Sum(nil)[:fs.names.NonceSize()]
+	return base64.RawURLEncoding.EncodeToString(fs.names.Seal(nonce, nonce, []byte(name), nil))
+}
+
+// decryptName decrypts a single segment encrypted by encryptName.
+func (fs *FS) decryptName(name string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil || len(b) < fs.names.NonceSize() {
+		return "", ErrCorrupt
+	}
+	n := fs.names.NonceSize()
+	plain, err := fs.names.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return "", ErrCorrupt
+	}
+	return string(plain), nil
+}
+
+// hostPath returns the path on the wrapped filesystem.
+func (fs *FS) hostPath(name string) string {
+	if fs.names == nil {
+		return name
+	}
+	segments := strings.Split(name, string(fs.PathSeparator()))
+	for i, seg := range segments {
+		if seg != "" && seg != "." && seg != ".." {
+			segments[i] = fs.encryptName(seg)
+		}
+	}
+	return strings.Join(segments, string(fs.PathSeparator()))
+}
+
+// plainPath returns the path of a path on the wrapped filesystem.
+func (fs *FS) plainPath(host string) (string, error) {
+	if fs.names == nil {
+		return host, nil
+	}
+	segments := strings.Split(host, string(fs.PathSeparator()))
+	for i, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." {
+			continue
+		}
+		name, err := fs.decryptName(seg)
+		if err != nil {
+			return "", err
+		}
+		segments[i] = name
+	}
+	return strings.Join(segments, string(fs.PathSeparator())), nil
+}
+
+// fileInfo reports the decrypted name and size.
+type fileInfo struct {
+	os.FileInfo
+	name string
+	size int64
+}
+
+func (fi fileInfo) Name() string { return fi.name }
+func (fi fileInfo) Size() int64  { return fi.size }
+
+// info returns the FileInfo of an entry of the wrapped filesystem.
+func (fs *FS) info(fi os.FileInfo) (os.FileInfo, error) {
+	size := fi.Size()
+	if fi.Mode().IsRegular() {
+		size = plainSize(size)
+	}
+	name := fi.Name()
+	if fs.names != nil {
+		var err error
+		if name, err = fs.decryptName(name); err != nil {
+			return nil, err
+		}
+	}
+	return fileInfo{FileInfo: fi, name: name, size: size}, nil
+}
+
+// stat returns the FileInfo of the named entry, the name is kept if it is not encrypted like the root.
+func (fs *FS) stat(fi os.FileInfo, err error, name string) (os.FileInfo, error) {
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	if info, err := fs.info(fi); err == nil {
+		return info, nil
+	}
+	return fileInfo{FileInfo: fi, name: fi.Name(), size: fi.Size()}, nil
+}
+
+// infos returns the FileInfos of entries of the wrapped filesystem, skipping foreign entries.
+func (fs *FS) infos(fis []os.FileInfo) []os.FileInfo {
+	infos := make([]os.FileInfo, 0, len(fis))
+	for _, fi := range fis {
+		if info, err := fs.info(fi); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// pathError replaces the encrypted paths inside err by name.
+func pathError(err error, name string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.PathError{Op: e.Op, Path: name, Err: e.Err}
+	case *os.LinkError:
+		return &os.PathError{Op: e.Op, Path: name, Err: e.Err}
+	}
+	return err
+}
+
+// linkError replaces the encrypted paths inside err by oldname and newname.
+func linkError(err error, oldname, newname string) error {
+	switch e := err.(type) {
+	case *os.PathError:
+		return &os.LinkError{Op: e.Op, Old: oldname, New: newname, Err: e.Err}
+	case *os.LinkError:
+		return &os.LinkError{Op: e.Op, Old: oldname, New: newname, Err: e.Err}
+	}
+	return err
+}
+
+// OpenFile opens the named file, the returned file encrypts and decrypts its content.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	// Writing needs to read the surrounding chunk, appending is done by the wrapper
+	hflag := flag &^ os.O_APPEND
+	if flag&os.O_WRONLY != 0 {
+		hflag = hflag&^os.O_WRONLY | os.O_RDWR
+	}
+	f, err := fs.Filesystem.OpenFile(fs.hostPath(name), hflag, perm)
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	cf := &file{fs: fs, f: f, name: name, flag: flag}
+	fi, err := f.Stat()
+	if err == nil && fi.Mode().IsRegular() {
+		err = cf.init(fi.Size())
+	}
+	if err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return cf, nil
+}
+
+// Remove removes the named file or directory.
+func (fs *FS) Remove(name string) error {
+	return pathError(fs.Filesystem.Remove(fs.hostPath(name)), name)
+}
+
+// RemoveAll removes path and any children it contains.
+func (fs *FS) RemoveAll(path string) error {
+	return pathError(vfs.RemoveAll(fs.Filesystem, fs.hostPath(path)), path)
+}
+
+// Rename renames a file, the content does not need to be encrypted again.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	return linkError(fs.Filesystem.Rename(fs.hostPath(oldpath), fs.hostPath(newpath)), oldpath, newpath)
+}
+
+// Mkdir creates a directory.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	return pathError(fs.Filesystem.Mkdir(fs.hostPath(name), perm), name)
+}
+
+// MkdirAll creates a directory and all necessary parents.
+func (fs *FS) MkdirAll(path string, perm os.FileMode) error {
+	return pathError(vfs.MkdirAll(fs.Filesystem, fs.hostPath(path), perm), path)
+}
+
+// Stat returns the FileInfo of the named file with the size of the decrypted content.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(fs.hostPath(name))
+	return fs.stat(fi, err, name)
+}
+
+// Lstat returns the FileInfo of the named file with the size of the decrypted content,
+// without following a symbolic link.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(fs.hostPath(name))
+	return fs.stat(fi, err, name)
+}
+
+// ReadDir reads the directory named by path and returns a list of sorted directory entries.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(fs.hostPath(path))
+	if err != nil {
+		return nil, pathError(err, path)
+	}
+	infos := fs.infos(fis)
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	return infos, nil
+}
+
+// Symlink creates newname as a symbolic link to oldname, the target is encrypted if names are obfuscated.
+func (fs *FS) Symlink(oldname, newname string) error {
+	return linkError(vfs.Symlink(fs.Filesystem, fs.hostPath(oldname), fs.hostPath(newname)), oldname, newname)
+}
+
+// Link creates newname as a hard link to oldname, the content does not need to be encrypted again.
+func (fs *FS) Link(oldname, newname string) error {
+	return linkError(vfs.Link(fs.Filesystem, fs.hostPath(oldname), fs.hostPath(newname)), oldname, newname)
+}
+
+// Readlink returns the decrypted destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	target, err := vfs.Readlink(fs.Filesystem, fs.hostPath(name))
+	if err != nil {
+		return "", pathError(err, name)
+	}
+	target, err = fs.plainPath(target)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return target, nil
+}
+
+// Chmod changes the mode of the named file.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return pathError(vfs.Chmod(fs.Filesystem, fs.hostPath(name), mode), name)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	return pathError(vfs.Chown(fs.Filesystem, fs.hostPath(name), uid, gid), name)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return pathError(vfs.Chtimes(fs.Filesystem, fs.hostPath(name), atime, mtime), name)
+}
+
+// Watch reports changes of the named file with decrypted names.
+func (fs *FS) Watch(name string) (<-chan vfs.Event, error) {
+	events, err := vfs.Watch(fs.Filesystem, fs.hostPath(name))
+	if err != nil {
+		return nil, pathError(err, name)
+	}
+	c := make(chan vfs.Event)
+	w := cryptWatch{events: events, done: make(chan struct{})}
+	go func() {
+		defer close(c)
+		for e := range events {
+			if plain, err := fs.plainPath(e.Name); err == nil {
+				e.Name = plain
+			}
+			select {
+			case c <- e:
+			case <-w.done:
+				return
+			}
+		}
+	}()
+	fs.mutex.Lock()
+	if fs.watches == nil {
+		fs.watches = make(map[<-chan vfs.Event]cryptWatch)
+	}
+	fs.watches[c] = w
+	fs.mutex.Unlock()
+	return c, nil
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *FS) Unwatch(events <-chan vfs.Event) error {
+	fs.mutex.Lock()
+	w, ok := fs.watches[events]
+	delete(fs.watches, events)
+	fs.mutex.Unlock()
+	if !ok {
+		return os.ErrInvalid
+	}
+	close(w.done)
+	return vfs.Unwatch(fs.Filesystem, w.events)
+}
@@ -0,0 +1,168 @@
+package cryptfs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func create(t *testing.T, fs vfs.Filesystem, obfuscate bool) *FS {
+	t.Helper()
+	c, err := Create(fs, testKey, &Options{ObfuscateNames: obfuscate})
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	return c
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(&FS{})
+	_ = vfs.Symlinker(&FS{})
+	_ = vfs.Watcher(&FS{})
+}
+
+func TestConformance(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		vfstest.TestFilesystem(t, func() vfs.Filesystem {
+			return create(t, memfs.Create(), false)
+		})
+	})
+	t.Run("ObfuscateNames", func(t *testing.T) {
+		vfstest.TestFilesystem(t, func() vfs.Filesystem {
+			return create(t, memfs.Create(), true)
+		})
+	})
+}
+
+func TestInvalidKey(t *testing.T) {
+	if _, err := Create(memfs.Create(), []byte("short"), nil); err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	inner := memfs.Create()
+	fs := create(t, inner, false)
+	secret := []byte(strings.Repeat("secret", 1000))
+	if err := vfs.WriteFile(fs, "/file", secret, 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	raw, err := vfs.ReadFile(inner, "/file")
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Errorf("Content stored in plain text")
+	}
+	if plainSize(int64(len(raw))) != int64(len(secret)) {
+		t.Errorf("Unexpected size %d of encrypted file", len(raw))
+	}
+	if fi, err := fs.Stat("/file"); err != nil || fi.Size() != int64(len(secret)) || fi.Name() != "file" {
+		t.Errorf("Unexpected FileInfo: %v %v", fi, err)
+	}
+
+	// Another key can not decrypt the content
+	other, _ := Create(inner, []byte("fedcba9876543210fedcba9876543210"), nil)
+	if _, err := vfs.ReadFile(other, "/file"); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestTampering(t *testing.T) {
+	inner := memfs.Create()
+	fs := create(t, inner, false)
+	content := bytes.Repeat([]byte{'x'}, 3*chunkSize)
+	vfs.WriteFile(fs, "/file", content, 0600)
+	raw, _ := vfs.ReadFile(inner, "/file")
+
+	tests := map[string][]byte{
+		"flipped bit": append(append([]byte{}, raw[:100]...), append([]byte{raw[100] ^ 1}, raw[101:]...)...),
+		// Removing the last chunk leaves a valid sequence of full chunks
+		"truncated": raw[:headerSize+2*encChunkSize],
+		"header":    []byte("vfs"),
+		"swapped": append(append(append([]byte{}, raw[:headerSize]...),
+			raw[headerSize+encChunkSize:headerSize+2*encChunkSize]...),
+			append(append([]byte{}, raw[headerSize:headerSize+encChunkSize]...), raw[headerSize+2*encChunkSize:]...)...),
+	}
+	for name, data := range tests {
+		vfs.WriteFile(inner, "/file", data, 0600)
+		if _, err := vfs.ReadFile(fs, "/file"); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: expected ErrCorrupt, got %v", name, err)
+		}
+	}
+}
+
+func TestObfuscateNames(t *testing.T) {
+	inner := memfs.Create()
+	fs := create(t, inner, true)
+	if err := vfs.MkdirAll(fs, "/secret/dir", 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	vfs.WriteFile(fs, "/secret/dir/file.txt", []byte("content"), 0644)
+	if err := fs.Symlink("../secret/dir/file.txt", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	vfs.WriteFile(inner, "/foreign", nil, 0644)
+
+	err := vfs.Walk(inner, "/", func(path string, fi os.FileInfo, err error) error {
+		if strings.Contains(path, "secret") || strings.Contains(path, "file") {
+			t.Errorf("Name stored in plain text: %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	if target, _ := vfs.Readlink(inner, fs.hostPath("/link")); strings.Contains(target, "secret") {
+		t.Errorf("Link target stored in plain text: %s", target)
+	}
+
+	fis, err := fs.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir: %s", err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	if strings.Join(names, ",") != "link,secret" {
+		t.Errorf("Unexpected entries: %q", names)
+	}
+	if target, err := fs.Readlink("/link"); err != nil || target != "../secret/dir/file.txt" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+	if b, err := vfs.ReadFile(fs, "/link"); err != nil || string(b) != "content" {
+		t.Errorf("ReadFile through link: %q %v", b, err)
+	}
+	_, err = fs.Stat("/secret/missing")
+	if perr, ok := err.(*os.PathError); !ok || perr.Path != "/secret/missing" || !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fs := create(t, memfs.Create(), true)
+	fs.Mkdir("/dir", 0755)
+	events, err := fs.Watch("/dir")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	vfs.WriteFile(fs, "/dir/file", nil, 0644)
+	if ev := <-events; ev.Name != "/dir/file" || ev.Op != vfs.EventCreate {
+		t.Errorf("Unexpected event: %s", ev)
+	}
+	if err := fs.Unwatch(events); err != nil {
+		t.Errorf("Unwatch: %s", err)
+	}
+	if err := fs.Unwatch(events); err == nil {
+		t.Errorf("Expected error unwatching twice")
+	}
+}
@@ -0,0 +1,21 @@
+// Package cryptfs defines a filesystem wrapper which transparently encrypts
+// the content of files and optionally their names.
+//
+// Content is encrypted with AES-256-GCM in chunks of 4 KiB, so files can be
+// read and written at any offset without processing the whole file.
+// Every chunk is authenticated together with a random file ID, its position
+// and whether it is the last chunk, so modified, reordered or truncated content is detected.
+// With Options.ObfuscateNames every segment of file names and symbolic link targets
+// is encrypted deterministically and encoded using unpadded URL-safe base64.
+//
+// The keys are derived from a master key, which must be random, e.g. generated using crypto/rand.
+// Passwords have to be stretched using a key derivation function like scrypt first:
+//
+//	key := make([]byte, 32)
+//	rand.Read(key)
+//	fs, err := cryptfs.Create(vfs.OS(), key, &cryptfs.Options{ObfuscateNames: true})
+//
+// Directory structure, file sizes rounded to chunks, modes and times are not hidden.
+// Entries with equal names have equal encrypted names and whole files may be swapped
+// or rolled back by someone with write access to the wrapped filesystem.
+package cryptfs
@@ -0,0 +1,33 @@
+package cryptfs_test
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/cryptfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleFS() {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	storage := memfs.Create()
+	fs, err := cryptfs.Create(storage, key, &cryptfs.Options{ObfuscateNames: true})
+	if err != nil {
+		panic(err)
+	}
+
+	vfs.WriteFile(fs, "/token", []byte("secret"), 0600)
+	b, _ := vfs.ReadFile(fs, "/token")
+	fmt.Println(string(b))
+
+	// The storage only contains encrypted names and content
+	fis, _ := storage.ReadDir("/")
+	fmt.Println(fis[0].Name() == "token")
+	// Output:
+	// secret
+	// false
+}
@@ -0,0 +1,385 @@
+package cryptfs
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/blang/vfs"
+)
+
+// Layout of encrypted files: a header of magic and file ID followed by the chunks,
+// each chunk consists of nonce, ciphertext and tag.
+// Every file has at least one chunk, only the last chunk may be shorter.
+const (
+	magic        = "vfsc"
+	idSize       = 16
+	headerSize   = len(magic) + idSize
+	chunkSize    = 4096
+	nonceSize    = 12
+	overhead     = nonceSize + 16
+	encChunkSize = chunkSize + overhead
+)
+
+// plainSize returns the size of the decrypted content of an encrypted file of the given size.
+func plainSize(size int64) int64 {
+	body := size - int64(headerSize)
+	if body <= 0 {
+		return 0
+	}
+	full, rem := body/encChunkSize, body%encChunkSize
+	if rem > overhead {
+		return full*chunkSize + rem - overhead
+	}
+	return full * chunkSize
+}
+
+// lastChunk returns the index of the last chunk of content of the given size.
+func lastChunk(size int64) int64 {
+	if size == 0 {
+		return 0
+	}
+	return (size - 1) / chunkSize
+}
+
+// file encrypts and decrypts the content of a file of the wrapped filesystem.
+// Directories have no ID.
+type file struct {
+	fs   *FS
+	f    vfs.File
+	name string
+	flag int
+
+	mutex sync.Mutex
+	id    []byte
+	off   int64
+}
+
+// init reads the header of a regular file or writes it to an empty file.
+func (f *file) init(size int64) error {
+	if size > 0 {
+		header := make([]byte, headerSize)
+		if _, err := f.f.ReadAt(header, 0); err != nil || string(header[:len(magic)]) != magic {
+			return ErrCorrupt
+		}
+		f.id = header[len(magic):]
+		return nil
+	}
+	f.id = make([]byte, idSize)
+	if _, err := rand.Read(f.id); err != nil {
+		return err
+	}
+	if !f.writable() {
+		// Empty files stay empty until they are written
+		return nil
+	}
+	if err := f.writeHost(0, append([]byte(magic), f.id...)); err != nil {
+		return err
+	}
+	return f.writeChunk(0, nil, true)
+}
+
+func (f *file) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+// size returns the size of the decrypted content.
+func (f *file) size() (int64, error) {
+	fi, err := f.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return plainSize(fi.Size()), nil
+}
+
+// ad returns the additional data authenticated with chunk i.
+func (f *file) ad(i int64, last bool) []byte {
+	ad := make([]byte, idSize+9)
+	copy(ad, f.id)
+	binary.BigEndian.PutUint64(ad[idSize:], uint64(i))
+	if last {
+		ad[idSize+8] = 1
+	}
+	return ad
+}
+
+// readChunk returns the decrypted chunk i of content of the given size.
+func (f *file) readChunk(i, size int64) ([]byte, error) {
+	buf := make([]byte, encChunkSize)
+	n, err := f.f.ReadAt(buf, int64(headerSize)+i*encChunkSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n < overhead {
+		return nil, ErrCorrupt
+	}
+	last := i == lastChunk(size)
+	plain, err := f.fs.content.Open(nil, buf[:nonceSize], buf[nonceSize:n], f.ad(i, last))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if (last && int64(len(plain)) != size-i*chunkSize) || (!last && len(plain) != chunkSize) {
+		return nil, ErrCorrupt
+	}
+	return plain, nil
+}
+
+// writeChunk encrypts and writes chunk i.
+func (f *file) writeChunk(i int64, plain []byte, last bool) error {
+	nonce := make([]byte, nonceSize, encChunkSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return f.writeHost(int64(headerSize)+i*encChunkSize, f.fs.content.Seal(nonce, nonce, plain, f.ad(i, last)))
+}
+
+// writeHost writes p at offset off of the wrapped file.
+func (f *file) writeHost(off int64, p []byte) error {
+	if _, err := f.f.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := f.f.Write(p)
+	return err
+}
+
+func (f *file) readAt(p []byte, off int64) (int, error) {
+	size, err := f.size()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for n < len(p) && off < size {
+		i := off / chunkSize
+		plain, err := f.readChunk(i, size)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], plain[off-i*chunkSize:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *file) writeAt(p []byte, off int64) (int, error) {
+	size, err := f.size()
+	if err != nil {
+		return 0, err
+	}
+	if off > size {
+		if err := f.truncate(off); err != nil {
+			return 0, err
+		}
+		size = off
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	end := off + int64(len(p))
+	newSize := size
+	if end > newSize {
+		newSize = end
+	}
+	first, lastWritten := off/chunkSize, (end-1)/chunkSize
+	oldLast, newLast := lastChunk(size), lastChunk(newSize)
+	if oldLast < first {
+		// The full last chunk is followed by new chunks
+		plain, err := f.readChunk(oldLast, size)
+		if err != nil {
+			return 0, err
+		}
+		if err := f.writeChunk(oldLast, plain, false); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for i := first; i <= lastWritten; i++ {
+		start := i * chunkSize
+		var plain []byte
+		if start < size {
+			if plain, err = f.readChunk(i, size); err != nil {
+				return n, err
+			}
+		}
+		chunkEnd := start + chunkSize
+		if chunkEnd > newSize {
+			chunkEnd = newSize
+		}
+		buf := make([]byte, chunkEnd-start)
+		copy(buf, plain)
+		c := copy(buf[off+int64(n)-start:], p[n:])
+		if err := f.writeChunk(i, buf, i == newLast); err != nil {
+			return n, err
+		}
+		n += c
+	}
+	return n, nil
+}
+
+func (f *file) truncate(size int64) error {
+	old, err := f.size()
+	if err != nil {
+		return err
+	}
+	if size >= old {
+		zeros := make([]byte, chunkSize)
+		for old < size {
+			// Fill up to the next chunk boundary
+			c := chunkSize - old%chunkSize
+			if c > size-old {
+				c = size - old
+			}
+			if _, err := f.writeAt(zeros[:c], old); err != nil {
+				return err
+			}
+			old += c
+		}
+		return nil
+	}
+	last := lastChunk(size)
+	plain, err := f.readChunk(last, old)
+	if err != nil {
+		return err
+	}
+	keep := size - last*chunkSize
+	if err := f.writeChunk(last, plain[:keep], true); err != nil {
+		return err
+	}
+	return f.f.Truncate(int64(headerSize) + last*encChunkSize + overhead + keep)
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Sync() error {
+	return f.f.Sync()
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	fi, err := f.f.Stat()
+	return f.fs.stat(fi, err, f.name)
+}
+
+func (f *file) Readdir(n int) ([]os.FileInfo, error) {
+	for {
+		fis, err := f.f.Readdir(n)
+		if err != nil {
+			err = pathError(err, f.name)
+		}
+		infos := f.fs.infos(fis)
+		// Only foreign entries were read, continue to not signal the end
+		if len(infos) > 0 || err != nil || n <= 0 {
+			return infos, err
+		}
+	}
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.id == nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: vfs.ErrIsDirectory}
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrPermission}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	n, err := f.readAt(p, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, f.err("read", err)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if f.id == nil {
+		return 0, &os.PathError{Op: "readat", Path: f.name, Err: vfs.ErrIsDirectory}
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrPermission}
+	}
+	if off < 0 {
+		return 0, &os.PathError{Op: "readat", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	n, err := f.readAt(p, off)
+	return n, f.err("read", err)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if f.id == nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: vfs.ErrIsDirectory}
+	}
+	if !f.writable() {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: os.ErrPermission}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.flag&os.O_APPEND != 0 {
+		size, err := f.size()
+		if err != nil {
+			return 0, f.err("write", err)
+		}
+		f.off = size
+	}
+	n, err := f.writeAt(p, f.off)
+	f.off += int64(n)
+	return n, f.err("write", err)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		size, err := f.size()
+		if err != nil {
+			return 0, f.err("seek", err)
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *file) Truncate(size int64) error {
+	if f.id == nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: vfs.ErrIsDirectory}
+	}
+	if !f.writable() {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrPermission}
+	}
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.err("truncate", f.truncate(size))
+}
+
+func (f *file) Close() error {
+	return pathError(f.f.Close(), f.name)
+}
+
+// err returns err as *os.PathError containing the name of the file, io.EOF is returned unchanged.
+func (f *file) err(op string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*os.PathError); ok {
+		return pathError(err, f.name)
+	}
+	return &os.PathError{Op: op, Path: f.name, Err: err}
+}
@@ -0,0 +1,126 @@
+package cryptfs
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestPlainSize(t *testing.T) {
+	for _, size := range []int64{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize, 3*chunkSize + 17} {
+		chunks := lastChunk(size) + 1
+		encrypted := int64(headerSize) + chunks*overhead + size
+		if s := plainSize(encrypted); s != size {
+			t.Errorf("plainSize(%d) = %d, expected %d", encrypted, s, size)
+		}
+	}
+}
+
+// TestRandomAccess compares random writes, reads and truncates with a plain buffer.
+func TestRandomAccess(t *testing.T) {
+	fs := create(t, memfs.Create(), false)
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+
+	rnd := rand.New(rand.NewSource(1))
+	var expected []byte
+	for i := 0; i < 300; i++ {
+		switch rnd.Intn(3) {
+		case 0:
+			off := rnd.Int63n(4 * chunkSize)
+			data := make([]byte, rnd.Intn(2*chunkSize))
+			rnd.Read(data)
+			if _, err := f.Seek(off, io.SeekStart); err != nil {
+				t.Fatalf("Seek: %s", err)
+			}
+			if n, err := f.Write(data); err != nil || n != len(data) {
+				t.Fatalf("Write: %d %v", n, err)
+			}
+			if end := int(off) + len(data); end > len(expected) {
+				expected = append(expected, make([]byte, end-len(expected))...)
+			}
+			copy(expected[off:], data)
+		case 1:
+			size := rnd.Int63n(5 * chunkSize)
+			if err := f.Truncate(size); err != nil {
+				t.Fatalf("Truncate: %s", err)
+			}
+			if int(size) > len(expected) {
+				expected = append(expected, make([]byte, int(size)-len(expected))...)
+			}
+			expected = expected[:size]
+		case 2:
+			off := rnd.Int63n(5 * chunkSize)
+			p := make([]byte, rnd.Intn(2*chunkSize)+1)
+			n, err := f.ReadAt(p, off)
+			var want []byte
+			if int(off) < len(expected) {
+				want = expected[off:]
+			}
+			if len(want) > len(p) {
+				want = want[:len(p)]
+			}
+			if !bytes.Equal(p[:n], want) || (n < len(p) && err != io.EOF) || (n == len(p) && err != nil) {
+				t.Fatalf("ReadAt(%d, %d): %d %v, expected %d bytes", len(p), off, n, err, len(want))
+			}
+		}
+		if fi, err := f.Stat(); err != nil || fi.Size() != int64(len(expected)) {
+			t.Fatalf("Unexpected size: %v %v, expected %d", fi, err, len(expected))
+		}
+	}
+
+	b, err := vfs.ReadFile(fs, "/file")
+	if err != nil || !bytes.Equal(b, expected) {
+		t.Errorf("Unexpected content: %d bytes %v, expected %d bytes", len(b), err, len(expected))
+	}
+}
+
+func TestAppend(t *testing.T) {
+	fs := create(t, memfs.Create(), false)
+	for i := 0; i < 3; i++ {
+		f, err := fs.OpenFile("/log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatalf("OpenFile: %s", err)
+		}
+		f.Write(bytes.Repeat([]byte{byte('a' + i)}, chunkSize))
+		f.Close()
+	}
+	b, err := vfs.ReadFile(fs, "/log")
+	if err != nil || len(b) != 3*chunkSize || b[0] != 'a' || b[chunkSize] != 'b' || b[3*chunkSize-1] != 'c' {
+		t.Errorf("Unexpected content: %d bytes %v", len(b), err)
+	}
+}
+
+func TestFileFlags(t *testing.T) {
+	fs := create(t, memfs.Create(), false)
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+	f, err := fs.OpenFile("/file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("x")); !os.IsPermission(err) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if err := f.Truncate(0); !os.IsPermission(err) {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+
+	fs.Mkdir("/dir", 0755)
+	d, err := fs.OpenFile("/dir", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer d.Close()
+	if _, err := d.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read of directory succeeded")
+	}
+}
@@ -0,0 +1,142 @@
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// DirIterator reads the entries of a directory in batches,
+// so huge directories are never materialized at once.
+type DirIterator interface {
+	// Next returns up to n entries like File.Readdir, at the end of the directory io.EOF is returned.
+	// If n <= 0, all remaining entries are returned.
+	Next(n int) ([]os.FileInfo, error)
+	// Close releases the iterator.
+	Close() error
+}
+
+// DirOpener is implemented by filesystems which iterate directories without reading them at once.
+type DirOpener interface {
+	// OpenDir opens the named directory for iteration.
+	OpenDir(path string) (DirIterator, error)
+}
+
+// OpenDir opens the named directory of the given Filesystem for iteration.
+// If the Filesystem does not implement DirOpener, the entries are read at once using ReadDir.
+func OpenDir(fs Filesystem, path string) (DirIterator, error) {
+	if d, ok := fs.(DirOpener); ok {
+		return d.OpenDir(path)
+	}
+	fis, err := fs.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	return FileDirIterator(&dirFile{fs: fs, name: path, entries: fis, read: true}), nil
+}
+
+// FileDirIterator returns a DirIterator reading the entries of the open directory f using Readdir.
+// Closing the iterator closes f.
+func FileDirIterator(f File) DirIterator {
+	return fileDirIterator{f}
+}
+
+type fileDirIterator struct {
+	File
+}
+
+func (d fileDirIterator) Next(n int) ([]os.FileInfo, error) {
+	return d.Readdir(n)
+}
+
+// DirFile opens the named directory of the given Filesystem for listing.
+// The entries are read using fs.ReadDir on the first call to Readdir,
+// reading and writing return ErrIsDirectory.
+// It can be used by Filesystems without native directory handles.
+func DirFile(fs Filesystem, name string) (File, error) {
+	fi, err := fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrNotDirectory}
+	}
+	return &dirFile{fs: fs, name: name}, nil
+}
+
+// dirFile represents an open directory, which can only be listed.
+type dirFile struct {
+	fs      Filesystem
+	name    string
+	entries []os.FileInfo
+	read    bool
+}
+
+func (d *dirFile) Name() string {
+	return d.name
+}
+
+func (d *dirFile) Sync() error {
+	return nil
+}
+
+func (d *dirFile) Stat() (os.FileInfo, error) {
+	return d.fs.Stat(d.name)
+}
+
+// Readdir reads the directory on the first call and
+// returns the entries in chunks of n afterwards.
+func (d *dirFile) Readdir(n int) ([]os.FileInfo, error) {
+	if !d.read {
+		fis, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = fis
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dirFile) Truncate(int64) error {
+	return &os.PathError{Op: "truncate", Path: d.name, Err: ErrIsDirectory}
+}
+
+func (d *dirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: ErrIsDirectory}
+}
+
+func (d *dirFile) ReadAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.name, Err: ErrIsDirectory}
+}
+
+func (d *dirFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.name, Err: ErrIsDirectory}
+}
+
+// Seek to the start of the directory restarts the listing,
+// other offsets are not supported.
+func (d *dirFile) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, &os.PathError{Op: "seek", Path: d.name, Err: ErrIsDirectory}
+	}
+	d.entries = nil
+	d.read = false
+	return 0, nil
+}
+
+func (d *dirFile) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package vfs_test
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestDirFile(t *testing.T) {
+	fs := memfs.Create()
+	fs.Mkdir("/dir", 0777)
+	for _, name := range []string{"/dir/a", "/dir/b", "/dir/c"} {
+		vfs.WriteFile(fs, name, nil, 0666)
+	}
+
+	if _, err := vfs.DirFile(fs, "/dir/a"); err == nil {
+		t.Errorf("Expected error opening a file")
+	}
+	if _, err := vfs.DirFile(fs, "/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	d, err := vfs.DirFile(fs, "/dir")
+	if err != nil {
+		t.Fatalf("DirFile: %s", err)
+	}
+	if fi, err := d.Stat(); err != nil || !fi.IsDir() || fi.Name() != "dir" {
+		t.Errorf("Stat: %v %v", fi, err)
+	}
+	if fis, err := d.Readdir(2); err != nil || len(fis) != 2 || fis[0].Name() != "a" || fis[1].Name() != "b" {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if fis, err := d.Readdir(2); err != nil || len(fis) != 1 || fis[0].Name() != "c" {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if fis, err := d.Readdir(2); err != io.EOF || len(fis) != 0 {
+		t.Errorf("Expected EOF, got %v %v", fis, err)
+	}
+
+	// Seek to the start restarts the listing
+	if _, err := d.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %s", err)
+	}
+	if fis, err := d.Readdir(-1); err != nil || len(fis) != 3 {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if _, err := d.Seek(1, io.SeekStart); err == nil {
+		t.Errorf("Expected seek error")
+	}
+	if _, err := d.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Expected read error")
+	}
+	if _, err := d.Write([]byte("x")); err == nil {
+		t.Errorf("Expected write error")
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	// The embedded interface hides memfs.MemFS.OpenDir
+	fs := struct{ vfs.Filesystem }{memfs.Create()}
+	fs.Mkdir("/dir", 0777)
+	for _, name := range []string{"/dir/a", "/dir/b", "/dir/c"} {
+		vfs.WriteFile(fs, name, nil, 0666)
+	}
+	if _, err := vfs.OpenDir(fs, "/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	d, err := vfs.OpenDir(fs, "/dir")
+	if err != nil {
+		t.Fatalf("OpenDir: %s", err)
+	}
+	if fis, err := d.Next(2); err != nil || len(fis) != 2 || fis[0].Name() != "a" || fis[1].Name() != "b" {
+		t.Errorf("Next: %v %v", fis, err)
+	}
+	if fis, err := d.Next(-1); err != nil || len(fis) != 1 || fis[0].Name() != "c" {
+		t.Errorf("Next: %v %v", fis, err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+}
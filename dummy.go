@@ -86,6 +86,16 @@ func (f DumFile) Sync() error {
 	return f.err
 }
 
+// Stat returns dummy error
+func (f DumFile) Stat() (os.FileInfo, error) {
+	return nil, f.err
+}
+
+// Readdir returns dummy error
+func (f DumFile) Readdir(n int) ([]os.FileInfo, error) {
+	return nil, f.err
+}
+
 // Truncate returns dummy error
 func (f DumFile) Truncate(size int64) error {
 	return f.err
@@ -60,4 +60,10 @@ func TestDummyFile(t *testing.T) {
 	if err := f.Sync(); err != errDum {
 		t.Errorf("Sync DummyError expected: %s", err)
 	}
+	if _, err := f.Stat(); err != errDum {
+		t.Errorf("Stat DummyError expected: %s", err)
+	}
+	if _, err := f.Readdir(0); err != errDum {
+		t.Errorf("Readdir DummyError expected: %s", err)
+	}
 }
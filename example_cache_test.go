@@ -0,0 +1,27 @@
+package vfs_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleCached() {
+	slow := memfs.Create() // e.g. an sftpfs or webdavfs
+	vfs.WriteFile(slow, "/data", []byte("v1"), 0644)
+	fs := vfs.Cached(slow, memfs.Create(), &vfs.CacheOptions{TTL: time.Minute, MaxBytes: 64 << 20})
+
+	b, _ := vfs.ReadFile(fs, "/data")
+	fmt.Println(string(b))
+
+	// Changes made behind the back of the cache need invalidation
+	vfs.WriteFile(slow, "/data", []byte("v2"), 0644)
+	fs.Invalidate("/data")
+	b, _ = vfs.ReadFile(fs, "/data")
+	fmt.Println(string(b))
+	// Output:
+	// v1
+	// v2
+}
@@ -0,0 +1,23 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleCaseInsensitive() {
+	// Behave like the filesystems of macOS and Windows in tests
+	fs := vfs.CaseInsensitive(memfs.Create())
+	vfs.WriteFile(fs, "/ReadMe.txt", []byte("hello"), 0644)
+
+	b, _ := vfs.ReadFile(fs, "/README.TXT")
+	fmt.Println(string(b))
+
+	fis, _ := fs.ReadDir("/")
+	fmt.Println(fis[0].Name())
+	// Output:
+	// hello
+	// ReadMe.txt
+}
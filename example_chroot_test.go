@@ -0,0 +1,26 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleChroot() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/srv/upload", 0755)
+	vfs.WriteFile(fs, "/secret", []byte("secret"), 0600)
+
+	// Untrusted code only sees the content of /srv/upload
+	upload := vfs.Chroot(fs, "/srv/upload")
+	upload.Symlink("/secret", "/link")
+
+	_, err := vfs.ReadFile(upload, "../../secret")
+	fmt.Println(err)
+	_, err = vfs.ReadFile(upload, "/link")
+	fmt.Println(err)
+	// Output:
+	// open ../../secret: file does not exist
+	// open /link: file does not exist
+}
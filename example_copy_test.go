@@ -0,0 +1,24 @@
+package vfs_test
+
+import (
+	"os"
+	"strings"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleCopyTree() {
+	// Stage a source tree of the OS into memory, without version control data
+	fs := memfs.Create()
+	opts := &vfs.CopyOptions{
+		Filter: func(path string, fi os.FileInfo) bool {
+			return !strings.HasPrefix(fi.Name(), ".git")
+		},
+		PreserveTimes: true,
+	}
+	vfs.CopyTree(fs, vfs.OS(), "/src", "/home/user/project", opts)
+
+	// ... and copy the results back
+	vfs.CopyTree(vfs.OS(), fs, "/home/user/project/out", "/src/out", &vfs.CopyOptions{Overwrite: true})
+}
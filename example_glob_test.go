@@ -0,0 +1,41 @@
+package vfs_test
+
+import (
+	"fmt"
+	iofs "io/fs"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleGlob() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/src/cmd", 0755)
+	vfs.WriteFile(fs, "/src/main.go", nil, 0644)
+	vfs.WriteFile(fs, "/src/cmd/tool.go", nil, 0644)
+	vfs.WriteFile(fs, "/src/README", nil, 0644)
+
+	matches, _ := vfs.Glob(fs, "/src/**/*.go")
+	fmt.Println(matches)
+	// Output: [/src/cmd/tool.go /src/main.go]
+}
+
+func ExampleWalkDir() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/src/cmd", 0755)
+	vfs.WriteFile(fs, "/src/main.go", nil, 0644)
+	vfs.WriteFile(fs, "/src/cmd/tool.go", nil, 0644)
+
+	vfs.WalkDir(fs, "/src", func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Println(path, d.IsDir())
+		return nil
+	})
+	// Output:
+	// /src true
+	// /src/cmd true
+	// /src/cmd/tool.go false
+	// /src/main.go false
+}
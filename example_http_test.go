@@ -0,0 +1,18 @@
+package vfs_test
+
+import (
+	"net/http"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleHTTPDir() {
+	fs := memfs.Create()
+	fs.Mkdir("/public", 0755)
+	vfs.WriteFile(fs, "/public/index.html", []byte("<h1>Hello World</h1>"), 0644)
+
+	// Serve the directory /public of the in-memory filesystem
+	http.Handle("/", http.FileServer(vfs.HTTPDir(vfs.ReadOnly(fs), "/public")))
+	http.ListenAndServe(":8080", nil)
+}
@@ -0,0 +1,17 @@
+package vfs_test
+
+import (
+	"log"
+
+	"github.com/blang/vfs"
+)
+
+func ExampleLogged() {
+	// Report every operation on the OS filesystem
+	fs := vfs.Logged(vfs.OS(), func(op string, args ...interface{}) {
+		log.Println(append([]interface{}{op}, args...)...)
+	})
+
+	// Logs "mkdir /tmp/vfs_example -rwxrwxrwx <nil>"
+	fs.Mkdir("/tmp/vfs_example", 0777)
+}
@@ -0,0 +1,21 @@
+package vfs_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleEnforcePermissions() {
+	fs := memfs.Create(memfs.WithOwner(1000, 1000))
+	vfs.WriteFile(fs, "/secret", []byte("secret"), 0600)
+
+	// Access as another user
+	other := vfs.EnforcePermissions(fs, 2000, 2000)
+	_, err := vfs.ReadFile(other, "/secret")
+	fmt.Println(os.IsPermission(err))
+	// Output:
+	// true
+}
@@ -0,0 +1,18 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleQuota() {
+	// Limit a sandbox to 1 MiB in at most 100 files and directories
+	fs := vfs.Quota(memfs.Create(), 1<<20, 100)
+
+	if err := vfs.WriteFile(fs, "/output.txt", make([]byte, 2<<20), 0666); err != nil {
+		fmt.Println(err)
+	}
+	// Output: write /output.txt: Quota exceeded
+}
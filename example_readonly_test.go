@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
 )
 
 // Every vfs.Filesystem could be easily wrapped
@@ -37,3 +38,17 @@ func ExampleRoFS() {
 		return
 	}
 }
+
+func ExampleReadOnlyExcept() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/etc", 0755)
+	vfs.MkdirAll(fs, "/tmp", 0755)
+
+	// Freeze everything but the scratch directory
+	frozen := vfs.ReadOnlyExcept(fs, "/tmp")
+	fmt.Println(vfs.WriteFile(frozen, "/etc/app.conf", nil, 0644))
+	fmt.Println(vfs.WriteFile(frozen, "/tmp/output", nil, 0644))
+	// Output:
+	// Filesystem is read-only
+	// <nil>
+}
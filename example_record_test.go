@@ -0,0 +1,27 @@
+package vfs_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleRecord() {
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/config", []byte("debug=true"), 0644)
+
+	// Record the operations of a run
+	var trace bytes.Buffer
+	b, _ := vfs.ReadFile(vfs.Record(fs, &trace), "/config")
+	fmt.Println(string(b))
+
+	// Serve a later run from the trace only
+	replay, _ := vfs.Replay(&trace)
+	b, _ = vfs.ReadFile(replay, "/config")
+	fmt.Println(string(b))
+	// Output:
+	// debug=true
+	// debug=true
+}
@@ -0,0 +1,17 @@
+package vfs_test
+
+import (
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleThrottle() {
+	// A slow disk transferring 1 MB/s with a latency of 5-7ms per operation
+	fs := vfs.Throttle(memfs.Create(), 1<<20, 5*time.Millisecond)
+	fs.Jitter = 2 * time.Millisecond
+
+	// Takes about 100ms
+	vfs.WriteFile(fs, "/file", make([]byte, 100<<10), 0644)
+}
@@ -0,0 +1,40 @@
+package vfs_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleTree() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/etc/app", 0755)
+	vfs.WriteFile(fs, "/etc/app/config.json", []byte("{}"), 0644)
+	vfs.WriteFile(fs, "/etc/hosts", []byte("127.0.0.1 localhost\n"), 0644)
+
+	vfs.Tree(fs, "/", os.Stdout)
+	// Output:
+	// drwxr-xr-x        - /
+	// drwxr-xr-x        - └── etc
+	// drwxr-xr-x        -     ├── app
+	// -rw-r--r--        2     │   └── config.json
+	// -rw-r--r--       20     └── hosts
+}
+
+func ExampleEqual() {
+	expected := memfs.Create()
+	vfs.WriteFile(expected, "/out.txt", []byte("done"), 0644)
+
+	// The filesystem modified by the code under test
+	actual := memfs.Create()
+	vfs.WriteFile(actual, "/out.txt", []byte("failed"), 0644)
+	vfs.WriteFile(actual, "/tmp.lock", nil, 0600)
+
+	mismatches, _ := vfs.Equal(expected, actual, "/")
+	fmt.Println(mismatches)
+	// Output:
+	// out.txt: content differs
+	// tmp.lock: only in b
+}
@@ -0,0 +1,30 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleBegin() {
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/config", []byte("v1"), 0644)
+
+	tx, _ := vfs.Begin(fs)
+	vfs.WriteFile(tx, "/config.new", []byte("v2"), 0644)
+	tx.Rename("/config.new", "/config")
+
+	// Changes are only visible inside the transaction until Commit
+	b, _ := vfs.ReadFile(fs, "/config")
+	fmt.Println(string(b))
+
+	if err := tx.Commit(); err != nil {
+		fmt.Println(err)
+	}
+	b, _ = vfs.ReadFile(fs, "/config")
+	fmt.Println(string(b))
+	// Output:
+	// v1
+	// v2
+}
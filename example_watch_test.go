@@ -0,0 +1,25 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleWatch() {
+	// Code watching a config file runs unchanged against vfs.OS()
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/app.conf", []byte("debug=false"), 0644)
+
+	events, err := vfs.Watch(fs, "/app.conf")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer vfs.Unwatch(fs, events)
+
+	vfs.WriteFile(fs, "/app.conf", []byte("debug=true"), 0644)
+	fmt.Println(<-events)
+	// Output: WRITE /app.conf
+}
@@ -0,0 +1,26 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleWorkdir() {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/srv/app/logs", 0755)
+
+	// Each component resolves relative paths against its own working directory
+	app := vfs.Workdir(fs, "/srv/app")
+	app.Chdir("logs")
+	vfs.WriteFile(app, "app.log", []byte("started\n"), 0644)
+
+	wd, _ := app.Getwd()
+	fmt.Println(wd)
+	_, err := fs.Stat("/srv/app/logs/app.log")
+	fmt.Println(err)
+	// Output:
+	// /srv/app/logs
+	// <nil>
+}
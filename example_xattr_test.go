@@ -0,0 +1,21 @@
+package vfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleSetXattr() {
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/download.iso", []byte("content"), 0644)
+
+	// Store the origin alongside the contents, syncfs and CopyTree with PreserveXattrs carry it along
+	vfs.SetXattr(fs, "/download.iso", "user.xdg.origin.url", []byte("https://example.com/download.iso"))
+
+	origin, _ := vfs.GetXattr(fs, "/download.iso", "user.xdg.origin.url")
+	fmt.Println(string(origin))
+	// Output:
+	// https://example.com/download.iso
+}
@@ -0,0 +1,10 @@
+// Package faultfs defines a filesystem wrapper which injects errors and latency
+// into selected operations, to test how code handles partial failures.
+//
+// Faults are programmed per operation and per path pattern:
+//
+//	fs := faultfs.Create(memfs.Create())
+//	fs.On("write", "*.log").Nth(3).Return(syscall.EIO)
+//	fs.On("rename", "").Return(syscall.EXDEV)
+//	fs.On("", "/slow/*").Delay(100 * time.Millisecond)
+package faultfs
@@ -0,0 +1,35 @@
+package faultfs_test
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/blang/vfs/faultfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleFS() {
+	fs := faultfs.Create(memfs.Create())
+
+	// The second write to any log file fails
+	fs.On("write", "*.log").Nth(2).Return(syscall.EIO)
+
+	// Renames always fail, like across devices
+	fs.On("rename", "").Return(syscall.EXDEV)
+
+	// Reading the directory /slow takes a while
+	fs.On("readdir", "/slow").Delay(10 * time.Millisecond)
+
+	f, _ := fs.OpenFile("/app.log", os.O_CREATE|os.O_WRONLY, 0644)
+	f.Write([]byte("first\n"))
+	_, err := f.Write([]byte("second\n"))
+	fmt.Println(err)
+
+	err = fs.Rename("/app.log", "/app.log.1")
+	fmt.Println(err)
+	// Output:
+	// write /app.log: input/output error
+	// rename /app.log /app.log.1: invalid cross-device link
+}
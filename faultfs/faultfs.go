@@ -0,0 +1,352 @@
+package faultfs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// FS is a filesystem which injects faults into the operations of the wrapped filesystem.
+type FS struct {
+	vfs.Filesystem
+
+	mutex sync.Mutex
+	rules []*Rule
+}
+
+// Create returns a wrapper of fs without any faults.
+func Create(fs vfs.Filesystem) *FS {
+	return &FS{Filesystem: fs}
+}
+
+// Rule describes a fault injected into matching operations.
+type Rule struct {
+	fs      *FS
+	op      string
+	pattern string
+	nth     int
+	err     error
+	delay   time.Duration
+	calls   int
+}
+
+// On adds a rule for the operation op on paths matching pattern and returns it.
+// Operations are named like the methods in lower case, e.g. "openfile", "write" or "readdir".
+// Operations on files are named "read", "readat", "write", "seek", "truncate",
+// "sync", "stat", "readdir" and "close".
+// The pattern is matched using path.Match against the full path,
+// a pattern without a slash is matched against the base name as well.
+// An empty op or pattern matches everything.
+// The rule has no effect until Return or Delay are set.
+func (fs *FS) On(op, pattern string) *Rule {
+	r := &Rule{fs: fs, op: op, pattern: pattern}
+	fs.mutex.Lock()
+	fs.rules = append(fs.rules, r)
+	fs.mutex.Unlock()
+	return r
+}
+
+// Reset removes all rules.
+func (fs *FS) Reset() {
+	fs.mutex.Lock()
+	fs.rules = nil
+	fs.mutex.Unlock()
+}
+
+// Return makes the matching operations fail with err.
+func (r *Rule) Return(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// Delay delays the matching operations by d.
+func (r *Rule) Delay(d time.Duration) *Rule {
+	r.delay = d
+	return r
+}
+
+// Nth restricts the rule to the nth matching operation, counting from 1.
+func (r *Rule) Nth(n int) *Rule {
+	r.nth = n
+	return r
+}
+
+// Calls returns the number of operations matched by the rule.
+func (r *Rule) Calls() int {
+	r.fs.mutex.Lock()
+	defer r.fs.mutex.Unlock()
+	return r.calls
+}
+
+func (r *Rule) match(op string, names []string) bool {
+	if r.op != "" && r.op != op {
+		return false
+	}
+	if r.pattern == "" {
+		return true
+	}
+	for _, name := range names {
+		if ok, _ := path.Match(r.pattern, name); ok {
+			return true
+		}
+		if !strings.Contains(r.pattern, "/") {
+			if ok, _ := path.Match(r.pattern, path.Base(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fault applies the rules matching the operation on the named files
+// and returns the error to inject, if any.
+func (fs *FS) fault(op string, names ...string) error {
+	var err error
+	var delay time.Duration
+	fs.mutex.Lock()
+	for _, r := range fs.rules {
+		if !r.match(op, names) {
+			continue
+		}
+		r.calls++
+		if r.nth != 0 && r.calls != r.nth {
+			continue
+		}
+		delay += r.delay
+		if err == nil {
+			err = r.err
+		}
+	}
+	fs.mutex.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return err
+}
+
+// OpenFile opens the named file, the returned file injects faults as well.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if err := fs.fault("openfile", name); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return f, err
+	}
+	return &file{File: f, fs: fs, name: name}, nil
+}
+
+// Remove removes the named file or directory.
+func (fs *FS) Remove(name string) error {
+	if err := fs.fault("remove", name); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return fs.Filesystem.Remove(name)
+}
+
+// Rename renames a file, rules match either path.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	if err := fs.fault("rename", oldpath, newpath); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return fs.Filesystem.Rename(oldpath, newpath)
+}
+
+// Mkdir creates a directory.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	if err := fs.fault("mkdir", name); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return fs.Filesystem.Mkdir(name, perm)
+}
+
+// Stat returns the FileInfo of the named file.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	if err := fs.fault("stat", name); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fs.Filesystem.Stat(name)
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	if err := fs.fault("lstat", name); err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return fs.Filesystem.Lstat(name)
+}
+
+// ReadDir reads the named directory.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	if err := fs.fault("readdir", path); err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	return fs.Filesystem.ReadDir(path)
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (fs *FS) Symlink(oldname, newname string) error {
+	if err := fs.fault("symlink", newname); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	return vfs.Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Link creates newname as a hard link to oldname.
+func (fs *FS) Link(oldname, newname string) error {
+	if err := fs.fault("link", oldname, newname); err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	return vfs.Link(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	if err := fs.fault("readlink", name); err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	if err := fs.fault("chmod", name); err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	return vfs.Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	if err := fs.fault("chown", name); err != nil {
+		return &os.PathError{Op: "chown", Path: name, Err: err}
+	}
+	return vfs.Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := fs.fault("chtimes", name); err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *FS) GetXattr(name, attr string) ([]byte, error) {
+	if err := fs.fault("getxattr", name); err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: name, Err: err}
+	}
+	return vfs.GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *FS) SetXattr(name, attr string, value []byte) error {
+	if err := fs.fault("setxattr", name); err != nil {
+		return &os.PathError{Op: "setxattr", Path: name, Err: err}
+	}
+	return vfs.SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *FS) ListXattr(name string) ([]string, error) {
+	if err := fs.fault("listxattr", name); err != nil {
+		return nil, &os.PathError{Op: "listxattr", Path: name, Err: err}
+	}
+	return vfs.ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *FS) RemoveXattr(name, attr string) error {
+	if err := fs.fault("removexattr", name); err != nil {
+		return &os.PathError{Op: "removexattr", Path: name, Err: err}
+	}
+	return vfs.RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file.
+func (fs *FS) Watch(name string) (<-chan vfs.Event, error) {
+	if err := fs.fault("watch", name); err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	return vfs.Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *FS) Unwatch(events <-chan vfs.Event) error {
+	return vfs.Unwatch(fs.Filesystem, events)
+}
+
+// file injects faults into the operations on an open file.
+type file struct {
+	vfs.File
+	fs   *FS
+	name string
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if err := f.fs.fault("read", f.name); err != nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	return f.File.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.fs.fault("readat", f.name); err != nil {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	return f.File.ReadAt(p, off)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if err := f.fs.fault("write", f.name); err != nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: err}
+	}
+	return f.File.Write(p)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.fs.fault("seek", f.name); err != nil {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: err}
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.fs.fault("truncate", f.name); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+	}
+	return f.File.Truncate(size)
+}
+
+func (f *file) Sync() error {
+	if err := f.fs.fault("sync", f.name); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	return f.File.Sync()
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	if err := f.fs.fault("stat", f.name); err != nil {
+		return nil, &os.PathError{Op: "stat", Path: f.name, Err: err}
+	}
+	return f.File.Stat()
+}
+
+func (f *file) Readdir(n int) ([]os.FileInfo, error) {
+	if err := f.fs.fault("readdir", f.name); err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: err}
+	}
+	return f.File.Readdir(n)
+}
+
+func (f *file) Close() error {
+	if err := f.fs.fault("close", f.name); err != nil {
+		return &os.PathError{Op: "close", Path: f.name, Err: err}
+	}
+	return f.File.Close()
+}
@@ -0,0 +1,135 @@
+package faultfs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestNthWrite(t *testing.T) {
+	fs := Create(memfs.Create())
+	r := fs.On("write", "*.log").Nth(3).Return(syscall.EIO)
+
+	f, err := fs.OpenFile("/app.log", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	for i := 1; i <= 4; i++ {
+		_, err := f.Write([]byte("line\n"))
+		if i == 3 {
+			if perr, ok := err.(*os.PathError); !ok || perr.Err != syscall.EIO {
+				t.Errorf("Write %d: expected EIO, got %v", i, err)
+			}
+		} else if err != nil {
+			t.Errorf("Write %d: unexpected error %s", i, err)
+		}
+	}
+	if n := r.Calls(); n != 4 {
+		t.Errorf("Expected 4 calls, got %d", n)
+	}
+
+	// Other files are unaffected
+	if err := vfs.WriteFile(fs, "/app.txt", []byte("data"), 0644); err != nil {
+		t.Errorf("WriteFile: %s", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	fs := Create(memfs.Create())
+	fs.On("rename", "").Return(syscall.EXDEV)
+	vfs.WriteFile(fs, "/a", nil, 0644)
+
+	err := fs.Rename("/a", "/b")
+	if lerr, ok := err.(*os.LinkError); !ok || lerr.Err != syscall.EXDEV {
+		t.Errorf("Expected EXDEV, got %v", err)
+	}
+	if _, err := fs.Stat("/a"); err != nil {
+		t.Errorf("File must not be renamed: %s", err)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	fs := Create(memfs.Create())
+	errFault := errors.New("fault")
+	fs.On("mkdir", "/dir/*").Return(errFault)
+	fs.On("stat", "/dir").Return(errFault)
+
+	if err := fs.Mkdir("/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := fs.Mkdir("/dir/sub", 0755); err == nil {
+		t.Errorf("Expected fault on /dir/sub")
+	}
+	if err := vfs.MkdirAll(fs, "/other/sub", 0755); err != nil {
+		t.Errorf("MkdirAll: %s", err)
+	}
+	if _, err := fs.Stat("/dir"); err == nil {
+		t.Errorf("Expected fault on stat /dir")
+	}
+	if _, err := fs.Lstat("/dir"); err != nil {
+		t.Errorf("Lstat: %s", err)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	fs := Create(memfs.Create())
+	errFault := errors.New("fault")
+	fs.On("setxattr", "/file").Return(errFault)
+	vfs.WriteFile(fs, "/file", nil, 0644)
+
+	if err := vfs.SetXattr(fs, "/file", "user.a", nil); !errors.Is(err, errFault) {
+		t.Errorf("Expected fault, got %v", err)
+	}
+	if err := vfs.SetXattr(fs, "/other", "user.a", nil); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestFileOps(t *testing.T) {
+	fs := Create(memfs.Create())
+	vfs.WriteFile(fs, "/file", []byte("data"), 0644)
+	fs.On("read", "").Return(syscall.EIO)
+	fs.On("close", "file").Return(syscall.EIO)
+
+	f, err := fs.OpenFile("/file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	if _, err := f.Read(make([]byte, 4)); err == nil {
+		t.Errorf("Expected fault on read")
+	}
+	if _, err := f.ReadAt(make([]byte, 4), 0); err != nil {
+		t.Errorf("ReadAt: %s", err)
+	}
+	if err := f.Close(); err == nil {
+		t.Errorf("Expected fault on close")
+	}
+
+	fs.Reset()
+	if b, err := vfs.ReadFile(fs, "/file"); err != nil || string(b) != "data" {
+		t.Errorf("Unexpected read after reset: %q %v", b, err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	fs := Create(memfs.Create())
+	r := fs.On("", "/slow").Delay(20 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := fs.Stat("/slow"); !os.IsNotExist(err) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("Expected delay, took %s", d)
+	}
+	fs.Stat("/fast")
+	if n := r.Calls(); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+}
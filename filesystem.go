@@ -12,17 +12,52 @@ var (
 	ErrIsDirectory = errors.New("Is directory")
 	// ErrNotDirectory is returned if a file is not a directory
 	ErrNotDirectory = errors.New("Is not a directory")
+	// ErrNotEmpty is returned if a directory to be removed is not empty
+	ErrNotEmpty = errors.New("Directory not empty")
+	// ErrUnsupported is returned if an operation is not supported by the filesystem
+	ErrUnsupported = errors.New("Operation not supported")
+	// ErrQuotaExceeded is returned if an operation would exceed a size or file limit
+	ErrQuotaExceeded = errors.New("Quota exceeded")
+	// ErrRemoteTimeout is returned if a remote backend like httpfs or sftpfs did not respond in time
+	ErrRemoteTimeout = errors.New("Remote operation timed out")
+	// ErrCrossDevice is returned if an operation can not act across filesystem boundaries
+	ErrCrossDevice = errors.New("Crossing filesystem boundary")
+	// ErrTooManyLinks is returned if too many symbolic links were encountered resolving a path
+	ErrTooManyLinks = errors.New("Too many levels of symbolic links")
 )
 
+// WrapError returns an error classifying cause as kind, one of the errors above.
+// It reports the message of cause and matches both kind and cause in errors.Is and errors.As,
+// so callers checking the error of the underlying system keep working.
+func WrapError(kind, cause error) error {
+	return &kindError{kind: kind, cause: cause}
+}
+
+// kindError is an error of the underlying system classified as one of the errors of this package.
+type kindError struct {
+	kind  error
+	cause error
+}
+
+func (e *kindError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *kindError) Unwrap() error {
+	return e.cause
+}
+
 // Filesystem represents an abstract filesystem
 type Filesystem interface {
 	PathSeparator() uint8
 	OpenFile(name string, flag int, perm os.FileMode) (File, error)
 	Remove(name string) error
-	// RemoveAll(path string) error
 	Rename(oldpath, newpath string) error
 	Mkdir(name string, perm os.FileMode) error
-	// Symlink(oldname, newname string) error
 	// TempDir() string
 	// Chmod(name string, mode FileMode) error
 	// Chown(name string, uid, gid int) error
@@ -32,11 +67,15 @@ type Filesystem interface {
 }
 
 // File represents a File with common operations.
-// It differs from os.File so e.g. Stat() needs to be called from the Filesystem instead.
-//   osfile.Stat() -> filesystem.Stat(file.Name())
+// It is a subset of os.File, so *os.File satisfies the interface.
 type File interface {
 	Name() string
 	Sync() error
+	// Stat returns the FileInfo describing the file.
+	Stat() (os.FileInfo, error)
+	// Readdir reads the contents of the directory associated with the file
+	// and returns a slice of up to n FileInfo values, like os.File.Readdir.
+	Readdir(n int) ([]os.FileInfo, error)
 	// Truncate shrinks or extends the size of the File to the specified size.
 	Truncate(int64) error
 	io.Reader
@@ -62,6 +101,16 @@ func Open(fs Filesystem, name string) (File, error) {
 	return fs.OpenFile(name, os.O_RDONLY, 0)
 }
 
+// RemoveAller is implemented by filesystems with a native RemoveAll operation.
+type RemoveAller interface {
+	RemoveAll(path string) error
+}
+
+// MkdirAller is implemented by filesystems with a native MkdirAll operation.
+type MkdirAller interface {
+	MkdirAll(path string, perm os.FileMode) error
+}
+
 // MkdirAll creates a directory named path on the given Filesystem,
 // along with any necessary parents, and returns nil,
 // or else returns an error.
@@ -69,7 +118,15 @@ func Open(fs Filesystem, name string) (File, error) {
 // directories that MkdirAll creates.
 // If path is already a directory, MkdirAll does nothing
 // and returns nil.
+// If the Filesystem implements MkdirAller, its native implementation is used.
 func MkdirAll(fs Filesystem, path string, perm os.FileMode) error {
+	if m, ok := fs.(MkdirAller); ok {
+		return m.MkdirAll(path, perm)
+	}
+	return mkdirAll(fs, path, perm)
+}
+
+func mkdirAll(fs Filesystem, path string, perm os.FileMode) error {
 	if dir, err := fs.Stat(path); err == nil {
 		if dir.IsDir() {
 			return nil
@@ -80,7 +137,7 @@ func MkdirAll(fs Filesystem, path string, perm os.FileMode) error {
 	parts := SplitPath(path, string(fs.PathSeparator()))
 	if len(parts) > 1 {
 		// Create parent
-		err := MkdirAll(fs, strings.Join(parts[0:len(parts)-1], string(fs.PathSeparator())), perm)
+		err := mkdirAll(fs, strings.Join(parts[0:len(parts)-1], string(fs.PathSeparator())), perm)
 		if err != nil {
 			return err
 		}
@@ -104,7 +161,15 @@ func MkdirAll(fs Filesystem, path string, perm os.FileMode) error {
 // It removes everything it can but returns the first error
 // it encounters.  If the path does not exist, RemoveAll
 // returns nil.
+// If the Filesystem implements RemoveAller, its native implementation is used.
 func RemoveAll(fs Filesystem, path string) error {
+	if r, ok := fs.(RemoveAller); ok {
+		return r.RemoveAll(path)
+	}
+	return removeAll(fs, path)
+}
+
+func removeAll(fs Filesystem, path string) error {
 	if err := fs.Remove(path); err == nil || os.IsNotExist(err) {
 		return nil
 	}
@@ -121,7 +186,7 @@ func RemoveAll(fs Filesystem, path string) error {
 	// Remove contents & return first error.
 	err = nil
 	for _, fi := range fis {
-		err1 := RemoveAll(fs, path+string(fs.PathSeparator())+fi.Name())
+		err1 := removeAll(fs, path+string(fs.PathSeparator())+fi.Name())
 		if err == nil {
 			err = err1
 		}
@@ -279,3 +279,57 @@ func TestRemoveAll(t *testing.T) {
 	}
 
 }
+
+type removeAllFS struct {
+	Filesystem
+	removed string
+}
+
+func (fs *removeAllFS) RemoveAll(path string) error {
+	fs.removed = path
+	return nil
+}
+
+func (fs *removeAllFS) MkdirAll(path string, perm os.FileMode) error {
+	fs.removed = "mkdir " + path
+	return nil
+}
+
+func TestNativeRemoveAllMkdirAll(t *testing.T) {
+	fs := &removeAllFS{Filesystem: Dummy(errors.New("Not implemented"))}
+	if err := RemoveAll(fs, "/tmp"); err != nil || fs.removed != "/tmp" {
+		t.Errorf("Native RemoveAll not used: %q %v", fs.removed, err)
+	}
+	if err := MkdirAll(fs, "/tmp", 0777); err != nil || fs.removed != "mkdir /tmp" {
+		t.Errorf("Native MkdirAll not used: %q %v", fs.removed, err)
+	}
+
+	ro := ReadOnly(fs)
+	if err := RemoveAll(ro, "/tmp"); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := MkdirAll(ro, "/tmp", 0777); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	cause := &os.SyscallError{Syscall: "rename", Err: errors.New("cross-device link")}
+	err := &os.LinkError{Op: "rename", Old: "/a", New: "/b", Err: WrapError(ErrCrossDevice, cause)}
+	if !errors.Is(err, ErrCrossDevice) {
+		t.Errorf("Expected ErrCrossDevice, got %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Expected the cause, got %v", err)
+	}
+	var serr *os.SyscallError
+	if !errors.As(err, &serr) || serr != cause {
+		t.Errorf("Expected the cause as *os.SyscallError, got %v", err)
+	}
+	if errors.Is(err, ErrRemoteTimeout) {
+		t.Errorf("Unexpected match of ErrRemoteTimeout")
+	}
+	if msg := err.Error(); msg != "rename /a /b: "+cause.Error() {
+		t.Errorf("Unexpected message %q", msg)
+	}
+}
@@ -0,0 +1,5 @@
+// Package fusefs serves a vfs.Filesystem through FUSE, making it visible
+// to other processes under a mount point of the operating system.
+//
+// It is available on Linux, macOS and FreeBSD and uses github.com/hanwen/go-fuse.
+package fusefs
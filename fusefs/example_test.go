@@ -0,0 +1,22 @@
+//go:build linux || darwin || freebsd
+
+package fusefs_test
+
+import (
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/fusefs"
+	"github.com/blang/vfs/memfs"
+)
+
+func ExampleMount() {
+	// Serve an in-memory filesystem under /mnt/memfs
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/hello.txt", []byte("Hello World"), 0644)
+
+	server, err := fusefs.Mount(fs, "/mnt/memfs", nil)
+	if err != nil {
+		return
+	}
+	// Other processes can access /mnt/memfs/hello.txt until it is unmounted
+	server.Wait()
+}
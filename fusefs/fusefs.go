@@ -0,0 +1,355 @@
+//go:build linux || darwin || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+// Mount serves the given filesystem on mountpoint.
+// The returned server is already serving requests, call Unmount to stop it
+// or Wait to block until the filesystem is unmounted externally.
+// If opts is nil, the defaults of go-fuse are used.
+func Mount(fs vfs.Filesystem, mountpoint string, opts *gofs.Options) (*fuse.Server, error) {
+	return gofs.Mount(mountpoint, Root(fs), opts)
+}
+
+// Root returns the root node serving the given filesystem,
+// which can be passed to go-fuse directly for custom setups.
+func Root(fs vfs.Filesystem) gofs.InodeEmbedder {
+	return &node{fs: fs}
+}
+
+// node represents a file or directory of the served filesystem.
+// Its path is derived from its position in the inode tree, so it stays valid across renames.
+type node struct {
+	gofs.Inode
+	fs vfs.Filesystem
+}
+
+var (
+	_ gofs.NodeGetattrer  = (*node)(nil)
+	_ gofs.NodeSetattrer  = (*node)(nil)
+	_ gofs.NodeLookuper   = (*node)(nil)
+	_ gofs.NodeReaddirer  = (*node)(nil)
+	_ gofs.NodeOpener     = (*node)(nil)
+	_ gofs.NodeCreater    = (*node)(nil)
+	_ gofs.NodeMkdirer    = (*node)(nil)
+	_ gofs.NodeUnlinker   = (*node)(nil)
+	_ gofs.NodeRmdirer    = (*node)(nil)
+	_ gofs.NodeRenamer    = (*node)(nil)
+	_ gofs.NodeReadlinker = (*node)(nil)
+	_ gofs.NodeSymlinker  = (*node)(nil)
+)
+
+// path returns the path of the node inside the served filesystem,
+// optionally joined with the name of a child.
+func (n *node) path(name ...string) string {
+	sep := string(n.fs.PathSeparator())
+	segments := append([]string{n.Path(nil)}, name...)
+	p := strings.Trim(strings.Join(segments, "/"), "/")
+	return sep + strings.Replace(p, "/", sep, -1)
+}
+
+func (n *node) Getattr(ctx context.Context, f gofs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	fi, err := n.fs.Lstat(n.path())
+	if err != nil {
+		return toErrno(err)
+	}
+	fillAttr(fi, &out.Attr)
+	return 0
+}
+
+// Setattr supports changing the size, mode, owner and times of a file.
+// Changing the mode, owner and times requires a filesystem implementing vfs.Attributer.
+func (n *node) Setattr(ctx context.Context, f gofs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if errno := n.setattr(in); errno != 0 {
+		return errno
+	}
+	if size, ok := in.GetSize(); ok {
+		if h, ok := f.(*handle); ok {
+			h.lock.Lock()
+			err := h.f.Truncate(int64(size))
+			h.lock.Unlock()
+			if err != nil {
+				return toErrno(err)
+			}
+		} else {
+			file, err := n.fs.OpenFile(n.path(), os.O_WRONLY, 0)
+			if err != nil {
+				return toErrno(err)
+			}
+			err = file.Truncate(int64(size))
+			if err1 := file.Close(); err == nil {
+				err = err1
+			}
+			if err != nil {
+				return toErrno(err)
+			}
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+// setattr applies the mode, owner and times of a Setattr request.
+func (n *node) setattr(in *fuse.SetAttrIn) syscall.Errno {
+	p := n.path()
+	if mode, ok := in.GetMode(); ok {
+		if err := vfs.Chmod(n.fs, p, os.FileMode(mode).Perm()); err != nil {
+			return toErrno(err)
+		}
+	}
+	uid, uidOk := in.GetUID()
+	gid, gidOk := in.GetGID()
+	if uidOk || gidOk {
+		fi, err := n.fs.Stat(p)
+		if err != nil {
+			return toErrno(err)
+		}
+		// Keep the current id if only one of them changes
+		curUID, curGID, _ := vfs.FileOwner(fi)
+		if !uidOk {
+			uid = uint32(curUID)
+		}
+		if !gidOk {
+			gid = uint32(curGID)
+		}
+		if err := vfs.Chown(n.fs, p, int(uid), int(gid)); err != nil {
+			return toErrno(err)
+		}
+	}
+	atime, atimeOk := in.GetATime()
+	mtime, mtimeOk := in.GetMTime()
+	if atimeOk || mtimeOk {
+		if !mtimeOk {
+			fi, err := n.fs.Stat(p)
+			if err != nil {
+				return toErrno(err)
+			}
+			mtime = fi.ModTime()
+		}
+		if !atimeOk {
+			atime = mtime
+		}
+		if err := vfs.Chtimes(n.fs, p, atime, mtime); err != nil {
+			return toErrno(err)
+		}
+	}
+	return 0
+}
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	return n.child(ctx, name, out)
+}
+
+// child creates the inode of an existing child.
+func (n *node) child(ctx context.Context, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	fi, err := n.fs.Lstat(n.path(name))
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	fillAttr(fi, &out.Attr)
+	return n.NewInode(ctx, &node{fs: n.fs}, gofs.StableAttr{Mode: fileType(fi.Mode())}), 0
+}
+
+func (n *node) Readdir(ctx context.Context) (gofs.DirStream, syscall.Errno) {
+	fis, err := n.fs.ReadDir(n.path())
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(fis))
+	for _, fi := range fis {
+		entries = append(entries, fuse.DirEntry{Name: fi.Name(), Mode: fileType(fi.Mode())})
+	}
+	return gofs.NewListDirStream(entries), 0
+}
+
+// openFlags are the open flags passed on to the served filesystem.
+const openFlags = os.O_RDONLY | os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_EXCL | os.O_TRUNC
+
+func (n *node) Open(ctx context.Context, flags uint32) (gofs.FileHandle, uint32, syscall.Errno) {
+	f, err := n.fs.OpenFile(n.path(), int(flags)&openFlags, 0)
+	if err != nil {
+		return nil, 0, toErrno(err)
+	}
+	return &handle{f: f}, 0, 0
+}
+
+func (n *node) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*gofs.Inode, gofs.FileHandle, uint32, syscall.Errno) {
+	f, err := n.fs.OpenFile(n.path(name), int(flags)&openFlags|os.O_CREATE, os.FileMode(mode).Perm())
+	if err != nil {
+		return nil, nil, 0, toErrno(err)
+	}
+	child, errno := n.child(ctx, name, out)
+	if errno != 0 {
+		f.Close()
+		return nil, nil, 0, errno
+	}
+	return child, &handle{f: f}, 0, 0
+}
+
+func (n *node) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	if err := n.fs.Mkdir(n.path(name), os.FileMode(mode).Perm()); err != nil {
+		return nil, toErrno(err)
+	}
+	return n.child(ctx, name, out)
+}
+
+func (n *node) Unlink(ctx context.Context, name string) syscall.Errno {
+	return toErrno(n.fs.Remove(n.path(name)))
+}
+
+func (n *node) Rmdir(ctx context.Context, name string) syscall.Errno {
+	return toErrno(n.fs.Remove(n.path(name)))
+}
+
+// Rename moves a child to a new parent, flags like RENAME_EXCHANGE are not supported.
+func (n *node) Rename(ctx context.Context, name string, newParent gofs.InodeEmbedder, newName string, flags uint32) syscall.Errno {
+	if flags != 0 {
+		return syscall.ENOTSUP
+	}
+	np, ok := newParent.(*node)
+	if !ok {
+		return syscall.EXDEV
+	}
+	return toErrno(n.fs.Rename(n.path(name), np.path(newName)))
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := vfs.Readlink(n.fs, n.path())
+	if err != nil {
+		return nil, toErrno(err)
+	}
+	return []byte(target), 0
+}
+
+func (n *node) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*gofs.Inode, syscall.Errno) {
+	if err := vfs.Symlink(n.fs, target, n.path(name)); err != nil {
+		return nil, toErrno(err)
+	}
+	return n.child(ctx, name, out)
+}
+
+// handle serves I/O on an open vfs.File.
+// FUSE requests carry explicit offsets, writes seek before writing.
+type handle struct {
+	lock sync.Mutex
+	f    vfs.File
+}
+
+var (
+	_ gofs.FileReader   = (*handle)(nil)
+	_ gofs.FileWriter   = (*handle)(nil)
+	_ gofs.FileFsyncer  = (*handle)(nil)
+	_ gofs.FileReleaser = (*handle)(nil)
+)
+
+func (h *handle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	n, err := h.f.ReadAt(dest, off)
+	if err != nil && err != io.EOF {
+		return nil, toErrno(err)
+	}
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (h *handle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, err := h.f.Seek(off, io.SeekStart); err != nil {
+		return 0, toErrno(err)
+	}
+	n, err := h.f.Write(data)
+	if err != nil {
+		return uint32(n), toErrno(err)
+	}
+	return uint32(n), 0
+}
+
+func (h *handle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return toErrno(h.f.Sync())
+}
+
+func (h *handle) Release(ctx context.Context) syscall.Errno {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return toErrno(h.f.Close())
+}
+
+// fileType returns the file type bits of a mode in unix notation.
+func fileType(mode os.FileMode) uint32 {
+	switch {
+	case mode.IsDir():
+		return syscall.S_IFDIR
+	case mode&os.ModeSymlink != 0:
+		return syscall.S_IFLNK
+	}
+	return syscall.S_IFREG
+}
+
+func fillAttr(fi os.FileInfo, attr *fuse.Attr) {
+	attr.Mode = fileType(fi.Mode()) | uint32(fi.Mode().Perm())
+	attr.Size = uint64(fi.Size())
+	attr.Blocks = (attr.Size + 511) / 512
+	attr.Nlink = 1
+	if uid, gid, ok := vfs.FileOwner(fi); ok {
+		attr.Uid, attr.Gid = uint32(uid), uint32(gid)
+	}
+	mtime := fi.ModTime()
+	attr.SetTimes(&mtime, &mtime, &mtime)
+}
+
+// toErrno maps errors of the served filesystem to errno values.
+func toErrno(err error) syscall.Errno {
+	if err == nil {
+		return 0
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return syscall.ENOENT
+	case errors.Is(err, os.ErrExist):
+		return syscall.EEXIST
+	case errors.Is(err, os.ErrPermission):
+		return syscall.EACCES
+	case errors.Is(err, os.ErrInvalid):
+		return syscall.EINVAL
+	case errors.Is(err, vfs.ErrReadOnly):
+		return syscall.EROFS
+	case errors.Is(err, vfs.ErrIsDirectory):
+		return syscall.EISDIR
+	case errors.Is(err, vfs.ErrNotDirectory):
+		return syscall.ENOTDIR
+	case errors.Is(err, vfs.ErrNotEmpty):
+		return syscall.ENOTEMPTY
+	case errors.Is(err, vfs.ErrUnsupported):
+		return syscall.ENOTSUP
+	case errors.Is(err, vfs.ErrCrossDevice):
+		return syscall.EXDEV
+	case errors.Is(err, vfs.ErrQuotaExceeded):
+		return syscall.ENOSPC
+	case errors.Is(err, vfs.ErrRemoteTimeout):
+		return syscall.ETIMEDOUT
+	case errors.Is(err, memfs.ErrReadOnly), errors.Is(err, memfs.ErrWriteOnly):
+		return syscall.EBADF
+	}
+	return syscall.EIO
+}
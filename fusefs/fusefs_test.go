@@ -0,0 +1,259 @@
+//go:build linux || darwin || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	gofs "github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+// rootNode returns the initialized root node serving fs without mounting it.
+func rootNode(fs vfs.Filesystem) *node {
+	root := Root(fs)
+	gofs.NewNodeFS(root, &gofs.Options{})
+	return root.(*node)
+}
+
+// attach adds a child returned by a node method to the inode tree,
+// which the FUSE bridge does when serving requests.
+func attach(parent *node, name string, child *gofs.Inode) *node {
+	parent.AddChild(name, child, true)
+	return child.Operations().(*node)
+}
+
+func TestLookupReaddir(t *testing.T) {
+	fs := memfs.Create()
+	fs.Mkdir("/dir", 0755)
+	vfs.WriteFile(fs, "/dir/file", []byte("content"), 0644)
+	root := rootNode(fs)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, errno := root.Lookup(ctx, "dir", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup: %s", errno)
+	}
+	dir := attach(root, "dir", inode)
+	if out.Mode&syscall.S_IFDIR == 0 {
+		t.Errorf("Expected directory mode, got %o", out.Mode)
+	}
+	inode, errno = dir.Lookup(ctx, "file", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup: %s", errno)
+	}
+	file := attach(dir, "file", inode)
+	if out.Size != 7 || out.Mode != syscall.S_IFREG|0644 {
+		t.Errorf("Unexpected attributes: size %d, mode %o", out.Size, out.Mode)
+	}
+	if p := file.path(); p != "/dir/file" {
+		t.Errorf("Unexpected path: %s", p)
+	}
+	if _, errno := root.Lookup(ctx, "missing", &out); errno != syscall.ENOENT {
+		t.Errorf("Expected ENOENT, got %s", errno)
+	}
+
+	stream, errno := root.Readdir(ctx)
+	if errno != 0 {
+		t.Fatalf("Readdir: %s", errno)
+	}
+	e, errno := stream.Next()
+	if errno != 0 || e.Name != "dir" || e.Mode != syscall.S_IFDIR {
+		t.Errorf("Unexpected entry: %v (%s)", e, errno)
+	}
+	if stream.HasNext() {
+		t.Errorf("Expected a single entry")
+	}
+}
+
+func TestCreateReadWrite(t *testing.T) {
+	fs := memfs.Create()
+	root := rootNode(fs)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, fh, _, errno := root.Create(ctx, "file", uint32(os.O_RDWR), 0644, &out)
+	if errno != 0 {
+		t.Fatalf("Create: %s", errno)
+	}
+	child := attach(root, "file", inode)
+	h := fh.(*handle)
+	if n, errno := h.Write(ctx, []byte("hello world"), 0); errno != 0 || n != 11 {
+		t.Fatalf("Write: %d, %s", n, errno)
+	}
+	if _, errno := h.Write(ctx, []byte("W"), 6); errno != 0 {
+		t.Fatalf("Write: %s", errno)
+	}
+	buf := make([]byte, 32)
+	res, errno := h.Read(ctx, buf, 0)
+	if errno != 0 {
+		t.Fatalf("Read: %s", errno)
+	}
+	b, _ := res.Bytes(nil)
+	if string(b) != "hello World" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+	if errno := h.Release(ctx); errno != 0 {
+		t.Fatalf("Release: %s", errno)
+	}
+
+	var attr fuse.AttrOut
+	in := &fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_SIZE
+	in.Size = 5
+	if errno := child.Setattr(ctx, nil, in, &attr); errno != 0 {
+		t.Fatalf("Setattr: %s", errno)
+	}
+	if attr.Size != 5 {
+		t.Errorf("Expected size 5, got %d", attr.Size)
+	}
+	if b, _ := vfs.ReadFile(fs, "/file"); string(b) != "hello" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+}
+
+func TestMkdirRenameRemove(t *testing.T) {
+	fs := memfs.Create()
+	root := rootNode(fs)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, errno := root.Mkdir(ctx, "dir", 0755, &out)
+	if errno != 0 {
+		t.Fatalf("Mkdir: %s", errno)
+	}
+	dir := attach(root, "dir", inode)
+	if _, _, _, errno := root.Create(ctx, "file", uint32(os.O_WRONLY), 0644, &out); errno != 0 {
+		t.Fatalf("Create: %s", errno)
+	}
+	if errno := root.Rename(ctx, "file", dir, "moved", 0); errno != 0 {
+		t.Fatalf("Rename: %s", errno)
+	}
+	if _, err := fs.Stat("/dir/moved"); err != nil {
+		t.Errorf("Expected renamed file: %s", err)
+	}
+	if errno := root.Rmdir(ctx, "dir"); errno != syscall.ENOTEMPTY {
+		t.Errorf("Expected ENOTEMPTY, got %s", errno)
+	}
+	if errno := dir.Unlink(ctx, "moved"); errno != 0 {
+		t.Fatalf("Unlink: %s", errno)
+	}
+	if errno := root.Rmdir(ctx, "dir"); errno != 0 {
+		t.Fatalf("Rmdir: %s", errno)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	fs := memfs.Create()
+	root := rootNode(fs)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, errno := root.Symlink(ctx, "/target", "link", &out)
+	if errno != 0 {
+		t.Fatalf("Symlink: %s", errno)
+	}
+	link := attach(root, "link", inode)
+	if out.Mode&syscall.S_IFMT != syscall.S_IFLNK {
+		t.Errorf("Expected symlink mode, got %o", out.Mode)
+	}
+	target, errno := link.Readlink(ctx)
+	if errno != 0 || string(target) != "/target" {
+		t.Errorf("Readlink: %q, %s", target, errno)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	root := rootNode(vfs.ReadOnly(memfs.Create()))
+	var out fuse.EntryOut
+	if _, errno := root.Mkdir(context.Background(), "dir", 0755, &out); errno != syscall.EROFS {
+		t.Errorf("Expected EROFS, got %s", errno)
+	}
+}
+
+func TestToErrno(t *testing.T) {
+	tests := []struct {
+		err   error
+		errno syscall.Errno
+	}{
+		{nil, 0},
+		{&os.PathError{Op: "open", Path: "/file", Err: os.ErrNotExist}, syscall.ENOENT},
+		{&os.PathError{Op: "open", Path: "/file", Err: syscall.EACCES}, syscall.EACCES},
+		{vfs.ErrReadOnly, syscall.EROFS},
+		{vfs.ErrUnsupported, syscall.ENOTSUP},
+		{vfs.ErrCrossDevice, syscall.EXDEV},
+		{errors.New("other"), syscall.EIO},
+	}
+	for i, test := range tests {
+		if errno := toErrno(test.err); errno != test.errno {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.errno, errno)
+		}
+	}
+}
+
+func TestMount(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping mount in short mode")
+	}
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+
+	dir, err := ioutil.TempDir("", "fusefs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+	server, err := Mount(fs, dir, &gofs.Options{MountOptions: fuse.MountOptions{DirectMount: true}})
+	if err != nil {
+		t.Skipf("FUSE not available: %s", err)
+	}
+	defer server.Unmount()
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "file"))
+	if err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q, %v", b, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "new"), []byte("data"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if b, _ := vfs.ReadFile(fs, "/new"); string(b) != "data" {
+		t.Errorf("Unexpected content: %q", b)
+	}
+}
+
+func TestSetattrAttributes(t *testing.T) {
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	root := rootNode(fs)
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	inode, errno := root.Lookup(ctx, "file", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup: %s", errno)
+	}
+	file := attach(root, "file", inode)
+
+	in := &fuse.SetAttrIn{}
+	in.Valid = fuse.FATTR_MODE | fuse.FATTR_UID | fuse.FATTR_MTIME
+	in.Mode = syscall.S_IFREG | 0600
+	in.Uid = 1000
+	in.Mtime = 1577934245
+	var attr fuse.AttrOut
+	if errno := file.Setattr(ctx, nil, in, &attr); errno != 0 {
+		t.Fatalf("Setattr: %s", errno)
+	}
+	if attr.Mode != syscall.S_IFREG|0600 || attr.Uid != 1000 || attr.Gid != 0 || attr.Mtime != 1577934245 {
+		t.Errorf("Unexpected attributes: mode %o, owner %d:%d, mtime %d", attr.Mode, attr.Uid, attr.Gid, attr.Mtime)
+	}
+}
@@ -0,0 +1,130 @@
+package vfs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// globStar is a pattern segment matching any number of directories.
+const globStar = "**"
+
+// Match reports whether name matches the shell pattern.
+// The pattern syntax is the one of path.Match, in addition a "**" segment
+// matches zero or more directories, e.g. "src/**/*.go" matches "src/main.go" and "src/a/b/c.go".
+// A trailing "**" matches all files and directories below, e.g. "src/**" matches "src/a/b/c.go".
+// Segments are separated by '/'. The only possible returned error is path.ErrBadPattern.
+func Match(pattern, name string) (bool, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return false, err
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/")), nil
+}
+
+// matchSegments matches the segments of a valid pattern against the segments of a name.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == globStar {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Glob returns the names of all files on the given Filesystem matching pattern or nil if there is no matching file.
+// The syntax of patterns is the same as in Match, using the path separator of the Filesystem.
+// Glob ignores I/O errors such as unreadable directories and does not follow
+// symbolic links while expanding "**". The returned names are sorted.
+// The only possible returned error is path.ErrBadPattern, when pattern is malformed.
+func Glob(fs Filesystem, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	sep := string(fs.PathSeparator())
+	segments := strings.Split(pattern, sep)
+	dir := ""
+	if strings.HasPrefix(pattern, sep) {
+		dir, segments = sep, segments[1:]
+	}
+
+	found := make(map[string]bool)
+	glob(fs, dir, segments, found)
+	if len(found) == 0 {
+		return nil, nil
+	}
+	matches := make([]string, 0, len(found))
+	for name := range found {
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// glob adds the files below dir matching the pattern segments to found.
+// An empty dir is the current directory of the filesystem.
+func glob(fs Filesystem, dir string, segments []string, found map[string]bool) {
+	for len(segments) > 0 && segments[0] == "" {
+		// Repeated or trailing separator
+		segments = segments[1:]
+	}
+	if len(segments) == 0 {
+		if dir != "" {
+			found[dir] = true
+		}
+		return
+	}
+
+	seg := segments[0]
+	if !hasMeta(seg) {
+		name := JoinPath(fs, dir, seg)
+		if _, err := fs.Lstat(name); err == nil {
+			glob(fs, name, segments[1:], found)
+		}
+		return
+	}
+
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	fis, err := fs.ReadDir(readDir)
+	if err != nil {
+		return
+	}
+	if seg == globStar {
+		rest := segments[1:]
+		glob(fs, dir, rest, found)
+		for _, fi := range fis {
+			name := JoinPath(fs, dir, fi.Name())
+			if fi.IsDir() {
+				glob(fs, name, segments, found)
+			} else if len(rest) == 0 {
+				// A trailing ** also matches the files below dir, like Match
+				found[name] = true
+			}
+		}
+		return
+	}
+	for _, fi := range fis {
+		if ok, _ := path.Match(seg, fi.Name()); ok {
+			glob(fs, JoinPath(fs, dir, fi.Name()), segments[1:], found)
+		}
+	}
+}
+
+// hasMeta reports whether the pattern segment contains any of the magic characters recognized by path.Match.
+func hasMeta(seg string) bool {
+	return strings.ContainsAny(seg, `*?[\`)
+}
@@ -0,0 +1,78 @@
+package vfs_test
+
+import (
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "main.go", false},
+		{"**", "a/b/c", true},
+		{"src/pkg/**", "src/pkg/sub/d.go", true},
+		{"**/c", "c", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b", "a/x/c", false},
+		{"/**/*.txt", "/docs/readme.txt", true},
+	}
+	for _, test := range tests {
+		if ok, err := vfs.Match(test.pattern, test.name); err != nil || ok != test.match {
+			t.Errorf("Match(%q, %q) = %t %v, expected %t", test.pattern, test.name, ok, err, test.match)
+		}
+	}
+	if _, err := vfs.Match("[", "a"); err != path.ErrBadPattern {
+		t.Errorf("Expected ErrBadPattern, got %v", err)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/src/pkg/sub", 0755)
+	vfs.MkdirAll(fs, "/docs", 0755)
+	for _, name := range []string{"/main.go", "/src/a.go", "/src/b.txt", "/src/pkg/c.go", "/src/pkg/sub/d.go", "/docs/e.go"} {
+		vfs.WriteFile(fs, name, nil, 0644)
+	}
+	fs.Symlink("/src", "/link")
+
+	tests := []struct {
+		pattern string
+		matches []string
+	}{
+		{"/*.go", []string{"/main.go"}},
+		{"/src/*", []string{"/src/a.go", "/src/b.txt", "/src/pkg"}},
+		{"/*/*.go", []string{"/docs/e.go", "/link/a.go", "/src/a.go"}},
+		{"/src/pkg/sub/d.go", []string{"/src/pkg/sub/d.go"}},
+		{"/src/**/*.go", []string{"/src/a.go", "/src/pkg/c.go", "/src/pkg/sub/d.go"}},
+		// Symbolic links are not followed expanding **
+		{"/**/c.go", []string{"/src/pkg/c.go"}},
+		{"/link/*.go", []string{"/link/a.go"}},
+		{"/src/**/", []string{"/src", "/src/pkg", "/src/pkg/sub"}},
+		{"/src/pkg/**", []string{"/src/pkg", "/src/pkg/c.go", "/src/pkg/sub", "/src/pkg/sub/d.go"}},
+		{"/missing/*", nil},
+		{"/src/a.go/*", nil},
+	}
+	for _, test := range tests {
+		matches, err := vfs.Glob(fs, test.pattern)
+		if err != nil {
+			t.Errorf("Glob(%q): %s", test.pattern, err)
+		}
+		if !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("Glob(%q) = %q, expected %q", test.pattern, matches, test.matches)
+		}
+	}
+
+	if _, err := vfs.Glob(fs, "/src/[a"); err != path.ErrBadPattern {
+		t.Errorf("Expected ErrBadPattern, got %v", err)
+	}
+}
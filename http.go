@@ -0,0 +1,92 @@
+package vfs
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// HTTPDir returns an http.FileSystem serving the directory root of the given Filesystem,
+// which can be passed to http.FileServer.
+// Names are slash-separated and can not escape root.
+func HTTPDir(fs Filesystem, root string) http.FileSystem {
+	return httpDir{fs: fs, root: root}
+}
+
+type httpDir struct {
+	fs   Filesystem
+	root string
+}
+
+func (d httpDir) Open(name string) (http.File, error) {
+	if strings.Contains(name, "\x00") {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+	sep := string(d.fs.PathSeparator())
+	p := strings.TrimSuffix(d.root, sep) + strings.Replace(path.Clean("/"+name), "/", sep, -1)
+	fi, err := d.fs.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return &httpDirFile{fs: d.fs, path: p, info: ioFileInfo(fi)}, nil
+	}
+	return Open(d.fs, p)
+}
+
+// httpDirFile represents an open directory, which can only be listed.
+type httpDirFile struct {
+	fs      Filesystem
+	path    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	read    bool
+}
+
+func (d *httpDirFile) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *httpDirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.path, Err: ErrIsDirectory}
+}
+
+func (d *httpDirFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+func (d *httpDirFile) Close() error {
+	return nil
+}
+
+// Readdir reads the directory on the first call and
+// returns the entries in chunks of count afterwards.
+func (d *httpDirFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		fis, err := d.fs.ReadDir(d.path)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = make([]os.FileInfo, len(fis))
+		for i, fi := range fis {
+			d.entries[i] = ioFileInfo(fi)
+		}
+		d.read = true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
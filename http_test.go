@@ -0,0 +1,87 @@
+package vfs_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func httpTestFS(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/www/dir", 0755)
+	vfs.WriteFile(fs, "/secret", []byte("secret"), 0644)
+	vfs.WriteFile(fs, "/www/hello.txt", []byte("Hello World"), 0644)
+	vfs.WriteFile(fs, "/www/dir/a.txt", []byte("a"), 0644)
+	vfs.WriteFile(fs, "/www/dir/b.txt", []byte("b"), 0644)
+	return vfs.ReadOnly(fs)
+}
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Get %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestHTTPDirFileServer(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(vfs.HTTPDir(httpTestFS(t), "/www")))
+	defer server.Close()
+
+	if code, body := get(t, server.URL+"/hello.txt"); code != http.StatusOK || body != "Hello World" {
+		t.Errorf("Unexpected response: %d %q", code, body)
+	}
+	if code, body := get(t, server.URL+"/dir/"); code != http.StatusOK || !strings.Contains(body, "a.txt") || !strings.Contains(body, "b.txt") {
+		t.Errorf("Unexpected listing: %d %q", code, body)
+	}
+	if code, _ := get(t, server.URL+"/missing"); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", code)
+	}
+	if code, body := get(t, server.URL+"/../secret"); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d %q", code, body)
+	}
+}
+
+func TestHTTPDirReaddir(t *testing.T) {
+	d := vfs.HTTPDir(httpTestFS(t), "/www/")
+	f, err := d.Open("/dir")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || !fi.IsDir() || fi.Mode()&os.ModeDir == 0 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	fis, err := f.Readdir(1)
+	if err != nil || len(fis) != 1 || fis[0].Name() != "a.txt" {
+		t.Fatalf("Readdir: %v, %v", fis, err)
+	}
+	fis, err = f.Readdir(5)
+	if err != nil || len(fis) != 1 || fis[0].Name() != "b.txt" {
+		t.Fatalf("Readdir: %v, %v", fis, err)
+	}
+	if _, err := f.Readdir(1); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	f, err = d.Open("hello.txt")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || fi.Size() != 11 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	if _, err := f.Readdir(-1); err == nil {
+		t.Errorf("Expected error listing a file")
+	}
+}
@@ -0,0 +1,7 @@
+// Package httpfs defines a read-only filesystem backed by an HTTP or HTTPS server,
+// e.g. an artifact repository or a static file server.
+//
+// Paths are mapped to URLs below a base URL. Files are read with GET requests,
+// random access uses Range requests if the server supports them. Stat uses HEAD requests.
+// Directories are listed from an optional index file in JSON format, see WriteIndex.
+package httpfs
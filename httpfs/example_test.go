@@ -0,0 +1,23 @@
+package httpfs_test
+
+import (
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/httpfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/mountfs"
+)
+
+func ExampleFS() {
+	// Access the artifacts of a repository, which are listed by .index.json files
+	artifacts, err := httpfs.Create(nil, "https://artifacts.example.com/releases")
+	if err != nil {
+		return
+	}
+	artifacts.Index = ".index.json"
+
+	// Mount the repository read-only into a larger virtual tree
+	fs := mountfs.Create(memfs.Create())
+	fs.Mount(artifacts, "/releases")
+
+	vfs.ReadFile(fs, "/releases/v1.0.0/checksums.txt")
+}
@@ -0,0 +1,403 @@
+package httpfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// ErrReadOnly is returned by every operation modifying the filesystem.
+var ErrReadOnly = vfs.ErrReadOnly
+
+// FS represents a read-only filesystem served over HTTP.
+type FS struct {
+	client *http.Client
+	base   *url.URL
+
+	// Index is the name of the index file listing the entries of a directory, e.g. ".index.json".
+	// It is requested relative to the URL of the directory. If empty, ReadDir returns ErrUnsupported.
+	Index string
+}
+
+// Create returns a filesystem serving the files below the base URL using the given client.
+// If client is nil, http.DefaultClient is used.
+// Requests timing out, e.g. after the Timeout of the client, fail with errors matching vfs.ErrRemoteTimeout.
+func Create(client *http.Client, base string) (*FS, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	return &FS{client: client, base: u}, nil
+}
+
+// url returns the URL of the named file.
+func (fs *FS) url(name string) string {
+	u := *fs.base
+	u.Path += clean(name)
+	return u.String()
+}
+
+func clean(name string) string {
+	return path.Clean("/" + name)
+}
+
+// IndexEntry describes an entry of a directory index.
+type IndexEntry struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mtime"`
+}
+
+// WriteIndex writes the index of a directory with the given entries to w,
+// e.g. to serve it next to the files:
+//
+//	fis, _ := fs.ReadDir("/artifacts")
+//	f, _ := fs.OpenFile("/artifacts/.index.json", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+//	httpfs.WriteIndex(f, fis)
+func WriteIndex(w io.Writer, fis []os.FileInfo) error {
+	entries := make([]IndexEntry, 0, len(fis))
+	for _, fi := range fis {
+		entries = append(entries, IndexEntry{Name: fi.Name(), Size: fi.Size(), Mode: fi.Mode(), ModTime: fi.ModTime()})
+	}
+	return json.NewEncoder(w).Encode(entries)
+}
+
+// statusError returns the error of an unsuccessful response.
+func statusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return os.ErrNotExist
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return os.ErrPermission
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Read beyond the end of a file of unknown size
+		return io.EOF
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return vfs.ErrRemoteTimeout
+	}
+	return fmt.Errorf("unexpected response: %s", resp.Status)
+}
+
+// timeoutError classifies network timeouts of err as vfs.ErrRemoteTimeout.
+func timeoutError(err error) error {
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return vfs.WrapError(vfs.ErrRemoteTimeout, err)
+	}
+	return err
+}
+
+// do sends a request for url and returns the successful response.
+// The body of the response must be closed by the caller.
+func (fs *FS) do(method, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := fs.client.Do(req)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
+
+// PathSeparator returns the path separator
+func (fs *FS) PathSeparator() uint8 {
+	return '/'
+}
+
+// OpenFile opens the named file for reading, the content is requested on the first read.
+// Flags requesting write access return ErrReadOnly.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	fi, err := fs.stat(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if fi.IsDir() {
+		return vfs.DirFile(fs, name)
+	}
+	size := fi.Size()
+	if h, ok := fi.Sys().(http.Header); ok && h.Get("Content-Length") == "" {
+		size = -1
+	}
+	return &file{fs: fs, name: name, url: fs.url(name), info: fi, size: size}, nil
+}
+
+// Remove is disabled and returns ErrReadOnly
+func (fs *FS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// Rename is disabled and returns ErrReadOnly
+func (fs *FS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrReadOnly}
+}
+
+// Mkdir is disabled and returns ErrReadOnly
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// Stat returns the FileInfo of the named file from the headers of a HEAD request.
+// Directories are recognized by a redirect to a URL ending in a slash, like http.FileServer
+// responds, or by their index file. The root is always a directory.
+// The FileInfo's Sys() returns the http.Header of the response.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.stat(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+// Lstat returns the FileInfo of the named file, there are no symbolic links.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.stat(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+func (fs *FS) stat(name string) (os.FileInfo, error) {
+	p := clean(name)
+	dir := vfs.DumFileInfo{IName: path.Base(p), IMode: os.ModeDir | 0555, IDir: true}
+	if p == "/" {
+		return dir, nil
+	}
+	resp, err := fs.do(http.MethodHead, fs.url(p), nil)
+	if os.IsNotExist(err) && fs.Index != "" {
+		if resp, ierr := fs.do(http.MethodHead, fs.url(path.Join(p, fs.Index)), nil); ierr == nil {
+			resp.Body.Close()
+			dir.ISys = resp.Header
+			return dir, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if strings.HasSuffix(resp.Request.URL.Path, "/") {
+		dir.ISys = resp.Header
+		return dir, nil
+	}
+	fi := vfs.DumFileInfo{IName: path.Base(p), IMode: 0444, ISys: resp.Header}
+	if resp.ContentLength > 0 {
+		fi.ISize = resp.ContentLength
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		fi.IModTime = t
+	}
+	return fi, nil
+}
+
+// ReadDir returns the entries of the named directory from its index file sorted by name.
+// It returns ErrUnsupported if Index is not set.
+func (fs *FS) ReadDir(dir string) ([]os.FileInfo, error) {
+	fis, err := fs.readDir(dir)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: err}
+	}
+	return fis, nil
+}
+
+func (fs *FS) readDir(dir string) ([]os.FileInfo, error) {
+	if fs.Index == "" {
+		return nil, vfs.ErrUnsupported
+	}
+	resp, err := fs.do(http.MethodGet, fs.url(path.Join(clean(dir), fs.Index)), nil)
+	if os.IsNotExist(err) {
+		if fi, serr := fs.stat(dir); serr == nil && !fi.IsDir() {
+			return nil, vfs.ErrNotDirectory
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var entries []IndexEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fis = append(fis, vfs.DumFileInfo{IName: e.Name, ISize: e.Size, IMode: e.Mode, IModTime: e.ModTime, IDir: e.Mode.IsDir()})
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, nil
+}
+
+// file is an open remote file.
+// Read streams the content from the offset, ReadAt requests the range to read.
+type file struct {
+	fs   *FS
+	name string
+	url  string
+	info os.FileInfo
+	// size is the size of the content, -1 if the server did not report it
+	size int64
+
+	mutex  sync.Mutex
+	offset int64
+	body   io.ReadCloser
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+// get requests the content from off up to end, exclusive, or the end of the file if end is negative.
+// Servers ignoring the Range header are handled by skipping the content before off.
+func (f *file) get(off, end int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	if end >= 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, end-1))
+	} else if off > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+	}
+	resp, err := f.fs.do(http.MethodGet, f.url, header)
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	if resp.StatusCode == http.StatusOK && off > 0 {
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, off); err != nil {
+			resp.Body.Close()
+			return nil, &os.PathError{Op: "read", Path: f.name, Err: timeoutError(err)}
+		}
+	}
+	return resp.Body, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.size >= 0 && f.offset >= f.size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		body, err := f.get(f.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	if err == io.EOF && f.offset < f.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, timeoutError(err)
+}
+
+// ReadAt requests the range of p, it is safe for concurrent use.
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: errors.New("negative offset")}
+	}
+	if f.size >= 0 && off >= f.size {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if f.size >= 0 && end > f.size {
+		end = f.size
+	}
+	body, err := f.get(off, end)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:end-off])
+	if err == io.ErrUnexpectedEOF && f.size < 0 || err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, timeoutError(err)
+}
+
+// Seek sets the offset of the next Read, a new request is sent on the next Read.
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		if f.size < 0 {
+			return 0, &os.PathError{Op: "seek", Path: f.name, Err: vfs.ErrUnsupported}
+		}
+		offset += f.size
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	if offset != f.offset {
+		f.close()
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *file) close() {
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+}
+
+func (f *file) Readdir(int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: vfs.ErrNotDirectory}
+}
+
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Truncate(int64) error {
+	return ErrReadOnly
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (f *file) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.close()
+	return nil
+}
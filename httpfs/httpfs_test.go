@@ -0,0 +1,275 @@
+package httpfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+var modTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// serve returns a server for the files of a memfs below /repo,
+// which serves indexes of directories as .index.json.
+func serve(t *testing.T, ranges bool) (*httptest.Server, *int32) {
+	t.Helper()
+	mem := memfs.Create(memfs.WithClock(func() time.Time { return modTime }))
+	vfs.MkdirAll(mem, "/dir/sub dir", 0755)
+	vfs.WriteFile(mem, "/file.txt", []byte("0123456789"), 0644)
+	vfs.WriteFile(mem, "/dir/a", []byte("a"), 0644)
+	vfs.WriteFile(mem, "/dir/sub dir/b", []byte("b"), 0644)
+
+	var requests int32
+	files := http.StripPrefix("/repo", http.FileServer(vfs.HTTPDir(mem, "/")))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		name := strings.TrimPrefix(r.URL.Path, "/repo")
+		switch {
+		case path.Base(name) == ".index.json":
+			fis, err := mem.ReadDir(path.Dir(name))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			WriteIndex(w, fis)
+		case !ranges && r.Method == http.MethodGet:
+			b, err := vfs.ReadFile(mem, name)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(b)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func create(t *testing.T, srv *httptest.Server, index string) *FS {
+	t.Helper()
+	fs, err := Create(srv.Client(), srv.URL+"/repo/")
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	fs.Index = index
+	return fs
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(&FS{})
+}
+
+func TestStat(t *testing.T) {
+	srv, _ := serve(t, true)
+	fs := create(t, srv, "")
+
+	fi, err := fs.Stat("/file.txt")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "file.txt" || fi.Size() != 10 || fi.IsDir() || !fi.ModTime().Equal(modTime) || fi.Mode() != 0444 {
+		t.Errorf("Unexpected FileInfo: %s %d %v %s %s", fi.Name(), fi.Size(), fi.IsDir(), fi.ModTime(), fi.Mode())
+	}
+	if _, ok := fi.Sys().(http.Header); !ok {
+		t.Errorf("Expected http.Header as Sys, got %T", fi.Sys())
+	}
+	for _, name := range []string{"/", "/dir", "/dir/sub dir"} {
+		if fi, err := fs.Stat(name); err != nil || !fi.IsDir() {
+			t.Errorf("Stat %s: expected directory, got %v %v", name, fi, err)
+		}
+	}
+	if _, err := fs.Lstat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	srv, requests := serve(t, true)
+	fs := create(t, srv, "")
+
+	if b, err := vfs.ReadFile(fs, "/dir/sub dir/b"); err != nil || string(b) != "b" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+
+	f, err := fs.OpenFile("/file.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatalf("Seek: %s", err)
+	}
+	if b, err := ioutil.ReadAll(f); err != nil || string(b) != "6789" {
+		t.Errorf("Read after seek: %q %v", b, err)
+	}
+
+	// Concurrent reads request their range only
+	atomic.StoreInt32(requests, 0)
+	var wg sync.WaitGroup
+	for off := 0; off < 10; off += 3 {
+		wg.Add(1)
+		go func(off int) {
+			defer wg.Done()
+			p := make([]byte, 3)
+			n, err := f.ReadAt(p, int64(off))
+			expected := "0123456789"[off:]
+			if len(expected) > 3 {
+				expected = expected[:3]
+			}
+			if string(p[:n]) != expected || (err != nil) != (n < 3) {
+				t.Errorf("ReadAt %d: %q %v", off, p[:n], err)
+			}
+		}(off)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(requests); n != 4 {
+		t.Errorf("Expected 4 requests, got %d", n)
+	}
+	if n, err := f.ReadAt(make([]byte, 1), 10); n != 0 || err != io.EOF {
+		t.Errorf("Expected EOF reading at the end, got %d %v", n, err)
+	}
+}
+
+func TestReadWithoutRanges(t *testing.T) {
+	srv, _ := serve(t, false)
+	fs := create(t, srv, "")
+
+	f, err := fs.OpenFile("/file.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	p := make([]byte, 4)
+	if n, err := f.ReadAt(p, 5); err != nil || string(p[:n]) != "5678" {
+		t.Errorf("ReadAt: %q %v", p[:n], err)
+	}
+	f.Seek(8, io.SeekStart)
+	if b, err := ioutil.ReadAll(f); err != nil || string(b) != "89" {
+		t.Errorf("Read after seek: %q %v", b, err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	srv, _ := serve(t, true)
+	if _, err := create(t, srv, "").ReadDir("/dir"); !strings.Contains(err.Error(), vfs.ErrUnsupported.Error()) {
+		t.Errorf("Expected unsupported error without index, got %v", err)
+	}
+
+	fs := create(t, srv, ".index.json")
+	fis, err := fs.ReadDir("/dir")
+	if err != nil || len(fis) != 2 {
+		t.Fatalf("ReadDir: %v %v", fis, err)
+	}
+	if fis[0].Name() != "a" || fis[0].Size() != 1 || fis[0].IsDir() || fis[1].Name() != "sub dir" || !fis[1].IsDir() {
+		t.Errorf("Unexpected entries: %v", fis)
+	}
+	if _, err := fs.ReadDir("/file.txt"); err == nil || !strings.Contains(err.Error(), vfs.ErrNotDirectory.Error()) {
+		t.Errorf("Expected not a directory error, got %v", err)
+	}
+	if _, err := fs.ReadDir("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	var walked []string
+	vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		walked = append(walked, path)
+		return err
+	})
+	if s := strings.Join(walked, ","); s != "/,/dir,/dir/a,/dir/sub dir,/dir/sub dir/b,/file.txt" {
+		t.Errorf("Unexpected walk: %s", s)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	srv, _ := serve(t, true)
+	fs := create(t, srv, "")
+	if _, err := fs.OpenFile("/file.txt", os.O_RDWR, 0); err == nil || !strings.Contains(err.Error(), ErrReadOnly.Error()) {
+		t.Errorf("Expected read-only error, got %v", err)
+	}
+	if err := fs.Remove("/file.txt"); err == nil {
+		t.Errorf("Remove succeeded")
+	}
+	if err := fs.Mkdir("/new", 0755); err == nil {
+		t.Errorf("Mkdir succeeded")
+	}
+	if err := vfs.WriteFile(fs, "/new", []byte("new"), 0644); err == nil {
+		t.Errorf("WriteFile succeeded")
+	}
+	if err := fs.Rename("/file.txt", "/other"); err == nil {
+		t.Errorf("Rename succeeded")
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	var buf bytes.Buffer
+	fis := []os.FileInfo{vfs.DumFileInfo{IName: "a", ISize: 3, IMode: 0644, IModTime: modTime}}
+	if err := WriteIndex(&buf, fis); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"name":"a","size":3,"mode":420,"mtime":"2020-01-02T03:04:05Z"}]` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected index %s", buf.String())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repo/gateway":
+			w.WriteHeader(http.StatusGatewayTimeout)
+		case "/repo/body":
+			w.Header().Set("Content-Length", "10")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("01234"))
+			w.(http.Flusher).Flush()
+			fallthrough
+		default:
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	client := srv.Client()
+	client.Timeout = 100 * time.Millisecond
+	fs, err := Create(client, srv.URL+"/repo/")
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+
+	for _, name := range []string{"/slow", "/gateway"} {
+		if _, err := fs.Stat(name); !errors.Is(err, vfs.ErrRemoteTimeout) {
+			t.Errorf("Stat %s: expected ErrRemoteTimeout, got %v", name, err)
+		}
+	}
+	if _, err := fs.Stat("/slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stat: expected the timeout of the client, got %v", err)
+	}
+
+	f, err := fs.OpenFile("/body", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if _, err := ioutil.ReadAll(f); !errors.Is(err, vfs.ErrRemoteTimeout) {
+		t.Errorf("Read: expected ErrRemoteTimeout, got %v", err)
+	}
+}
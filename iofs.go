@@ -0,0 +1,318 @@
+package vfs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ToIOFS returns an io/fs.FS view of the given Filesystem,
+// so it can be passed to stdlib APIs like fs.WalkDir, template.ParseFS or http.FS.
+// Names are resolved relative to the root of the Filesystem.
+// The returned FS also implements fs.StatFS, fs.ReadDirFS and fs.ReadFileFS.
+func ToIOFS(fs Filesystem) iofs.FS {
+	return ioFS{fs}
+}
+
+type ioFS struct {
+	fs Filesystem
+}
+
+// path converts a valid io/fs name to a path of the Filesystem.
+func (f ioFS) path(op, name string) (string, error) {
+	if !iofs.ValidPath(name) {
+		return "", &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+	sep := string(f.fs.PathSeparator())
+	if name == "." {
+		return sep, nil
+	}
+	return sep + strings.Replace(name, "/", sep, -1), nil
+}
+
+// ioError replaces the path of a *os.PathError with the io/fs name.
+func ioError(op, name string, err error) error {
+	if perr, ok := err.(*os.PathError); ok {
+		return &iofs.PathError{Op: perr.Op, Path: name, Err: perr.Err}
+	}
+	return &iofs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (f ioFS) Open(name string) (iofs.File, error) {
+	p, err := f.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.fs.Stat(p)
+	if err != nil {
+		return nil, ioError("open", name, err)
+	}
+	if fi.IsDir() {
+		return &ioDir{fs: f.fs, path: p, name: name, info: ioFileInfo(fi)}, nil
+	}
+	file, err := Open(f.fs, p)
+	if err != nil {
+		return nil, ioError("open", name, err)
+	}
+	return &ioFile{File: file, name: name}, nil
+}
+
+func (f ioFS) Stat(name string) (iofs.FileInfo, error) {
+	p, err := f.path("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.fs.Stat(p)
+	if err != nil {
+		return nil, ioError("stat", name, err)
+	}
+	return ioFileInfo(fi), nil
+}
+
+func (f ioFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	p, err := f.path("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := f.fs.ReadDir(p)
+	if err != nil {
+		return nil, ioError("readdir", name, err)
+	}
+	return dirEntries(fis), nil
+}
+
+func (f ioFS) ReadFile(name string) ([]byte, error) {
+	p, err := f.path("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ReadFile(f.fs, p)
+	if err != nil {
+		return nil, ioError("readfile", name, err)
+	}
+	return b, nil
+}
+
+func dirEntries(fis []os.FileInfo) []iofs.DirEntry {
+	entries := make([]iofs.DirEntry, len(fis))
+	for i, fi := range fis {
+		entries[i] = iofs.FileInfoToDirEntry(ioFileInfo(fi))
+	}
+	return entries
+}
+
+// ioFileInfo makes sure the mode of a directory has os.ModeDir set,
+// which io/fs relies on but some Filesystems omit.
+func ioFileInfo(fi os.FileInfo) iofs.FileInfo {
+	if fi.IsDir() && !fi.Mode().IsDir() {
+		return dirInfo{fi}
+	}
+	return fi
+}
+
+type dirInfo struct {
+	os.FileInfo
+}
+
+func (fi dirInfo) Mode() os.FileMode {
+	return fi.FileInfo.Mode() | os.ModeDir
+}
+
+// ioFile adapts a File to io/fs.File.
+type ioFile struct {
+	File
+	name string
+}
+
+func (f *ioFile) Stat() (iofs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, ioError("stat", f.name, err)
+	}
+	return ioFileInfo(fi), nil
+}
+
+// ioDir adapts a directory of a Filesystem to io/fs.ReadDirFile.
+type ioDir struct {
+	fs      Filesystem
+	path    string
+	name    string
+	info    os.FileInfo
+	entries []iofs.DirEntry
+	read    bool
+}
+
+func (d *ioDir) Stat() (iofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *ioDir) Read(p []byte) (int, error) {
+	return 0, &iofs.PathError{Op: "read", Path: d.name, Err: ErrIsDirectory}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+// ReadDir reads the directory on the first call and
+// returns the entries in chunks of n afterwards.
+func (d *ioDir) ReadDir(n int) ([]iofs.DirEntry, error) {
+	if !d.read {
+		fis, err := d.fs.ReadDir(d.path)
+		if err != nil {
+			return nil, ioError("readdir", d.name, err)
+		}
+		d.entries = dirEntries(fis)
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// FromIOFS returns a read-only Filesystem backed by the given io/fs.FS.
+// Paths are slash-separated and resolved relative to the root of fsys,
+// write operations return ErrReadOnly.
+func FromIOFS(fsys iofs.FS) Filesystem {
+	return &IOFS{FS: fsys}
+}
+
+// IOFS represents a read-only Filesystem backed by an io/fs.FS.
+type IOFS struct {
+	FS iofs.FS
+}
+
+// name converts a Filesystem path to an io/fs name.
+func (fs *IOFS) name(p string) string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// PathSeparator returns the path separator
+func (fs *IOFS) PathSeparator() uint8 {
+	return '/'
+}
+
+// OpenFile opens the named file for reading.
+// Flags requesting write access return ErrReadOnly.
+func (fs *IOFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, ErrReadOnly
+	}
+	f, err := fs.FS.Open(fs.name(name))
+	if err != nil {
+		return nil, err
+	}
+	return &fromIOFile{File: f, name: name}, nil
+}
+
+// Remove is disabled and returns ErrReadOnly
+func (fs *IOFS) Remove(name string) error {
+	return ErrReadOnly
+}
+
+// Rename is disabled and returns ErrReadOnly
+func (fs *IOFS) Rename(oldpath, newpath string) error {
+	return ErrReadOnly
+}
+
+// Mkdir is disabled and returns ErrReadOnly
+func (fs *IOFS) Mkdir(name string, perm os.FileMode) error {
+	return ErrReadOnly
+}
+
+// Stat wraps fs.Stat
+func (fs *IOFS) Stat(name string) (os.FileInfo, error) {
+	return iofs.Stat(fs.FS, fs.name(name))
+}
+
+// Lstat wraps fs.Stat, io/fs does not expose symbolic links.
+func (fs *IOFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.Stat(name)
+}
+
+// ReadDir wraps fs.ReadDir and returns the sorted FileInfos of the entries.
+func (fs *IOFS) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := iofs.ReadDir(fs.FS, fs.name(path))
+	if err != nil {
+		return nil, err
+	}
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		fis = append(fis, fi)
+	}
+	return fis, nil
+}
+
+// fromIOFile adapts an io/fs.File to File.
+type fromIOFile struct {
+	iofs.File
+	name string
+}
+
+func (f *fromIOFile) Name() string {
+	return f.name
+}
+
+func (f *fromIOFile) Sync() error {
+	return nil
+}
+
+func (f *fromIOFile) Truncate(int64) error {
+	return ErrReadOnly
+}
+
+func (f *fromIOFile) Write(p []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (f *fromIOFile) ReadAt(p []byte, off int64) (int, error) {
+	if r, ok := f.File.(io.ReaderAt); ok {
+		return r.ReadAt(p, off)
+	}
+	return 0, ErrUnsupported
+}
+
+func (f *fromIOFile) Readdir(n int) ([]os.FileInfo, error) {
+	d, ok := f.File.(iofs.ReadDirFile)
+	if !ok {
+		return nil, &os.PathError{Op: "readdir", Path: f.name, Err: ErrNotDirectory}
+	}
+	entries, err := d.ReadDir(n)
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		fi, ierr := e.Info()
+		if ierr != nil {
+			return fis, ierr
+		}
+		fis = append(fis, fi)
+	}
+	return fis, err
+}
+
+func (f *fromIOFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.File.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,92 @@
+package vfs_test
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestToIOFS(t *testing.T) {
+	mfs := memfs.Create()
+	if err := vfs.MkdirAll(mfs, "/a/b", 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	for _, name := range []string{"/a/b/file1", "/a/file2", "/file3"} {
+		if err := vfs.WriteFile(mfs, name, []byte(name), 0666); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+
+	fsys := vfs.ToIOFS(mfs)
+	if err := fstest.TestFS(fsys, "a/b/file1", "a/file2", "file3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Stat(fsys, "missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := fsys.Open("/a"); err == nil {
+		t.Errorf("Expected invalid path error")
+	}
+
+	var walked []string
+	fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		walked = append(walked, path)
+		return err
+	})
+	if len(walked) != 6 {
+		t.Errorf("Unexpected walk: %q", walked)
+	}
+}
+
+func TestFromIOFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/file1": &fstest.MapFile{Data: []byte("file1"), Mode: 0644},
+		"file2":   &fstest.MapFile{Data: []byte("file2"), Mode: 0600},
+	}
+	vfsys := vfs.FromIOFS(fsys)
+
+	if b, err := vfs.ReadFile(vfsys, "/a/file1"); err != nil || string(b) != "file1" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+	if fi, err := vfsys.Stat("/file2"); err != nil || fi.Size() != 5 || fi.Mode() != 0600 {
+		t.Errorf("Stat: %v %v", fi, err)
+	}
+	fis, err := vfsys.ReadDir("/")
+	if err != nil {
+		t.Fatalf("ReadDir: %s", err)
+	}
+	if len(fis) != 2 || fis[0].Name() != "a" || !fis[0].IsDir() || fis[1].Name() != "file2" {
+		t.Errorf("Unexpected entries: %v", fis)
+	}
+
+	f, err := vfs.Open(vfsys, "/file2")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if _, err := f.Seek(2, os.SEEK_SET); err != nil {
+		t.Errorf("Seek: %s", err)
+	}
+	p := make([]byte, 3)
+	if n, err := f.Read(p); err != nil || string(p[:n]) != "le2" {
+		t.Errorf("Read: %q %v", p[:n], err)
+	}
+	if _, err := f.Write(p); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	f.Close()
+
+	if _, err := vfs.Create(vfsys, "/new"); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := vfsys.Mkdir("/dir", 0777); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := vfsys.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
@@ -2,8 +2,13 @@ package vfs
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // WriteFile writes data to a file named by filename on the given Filesystem. If
@@ -42,7 +47,7 @@ func ReadFile(fs Filesystem, filename string) ([]byte, error) {
 	// It's a good but not certain bet that FileInfo will tell us exactly how
 	// much to read, so let's try it but be prepared for the answer to be wrong.
 	var n int64
-	if fi, err := fs.Stat(filename); err == nil {
+	if fi, err := f.Stat(); err == nil {
 		if size := fi.Size(); size < 1e9 {
 			n = size
 		}
@@ -57,6 +62,110 @@ func ReadFile(fs Filesystem, filename string) ([]byte, error) {
 	return readAll(f, n+bytes.MinRead)
 }
 
+// ReadAll reads from r until an error or EOF and returns the data it read.
+// A successful call returns err == nil, not err == EOF.
+//
+// This is a port of the stdlib ioutil.ReadAll function.
+func ReadAll(r io.Reader) ([]byte, error) {
+	return readAll(r, bytes.MinRead)
+}
+
+// Random number state for the names of temporary files.
+var (
+	rand   uint32
+	randmu sync.Mutex
+)
+
+func reseed() uint32 {
+	return uint32(time.Now().UnixNano() + int64(os.Getpid()))
+}
+
+func nextRandom() string {
+	randmu.Lock()
+	r := rand
+	if r == 0 {
+		r = reseed()
+	}
+	r = r*1664525 + 1013904223 // constants from Numerical Recipes
+	rand = r
+	randmu.Unlock()
+	return strconv.Itoa(int(1e9 + r%1e9))[1:]
+}
+
+var errPatternHasSeparator = errors.New("pattern contains path separator")
+
+// prefixAndSuffix splits pattern by the last wildcard "*", if applicable,
+// returning prefix as the part before "*" and suffix as the part after "*".
+func prefixAndSuffix(fs Filesystem, pattern string) (prefix, suffix string, err error) {
+	if strings.IndexByte(pattern, fs.PathSeparator()) != -1 {
+		err = errPatternHasSeparator
+		return
+	}
+	if pos := strings.LastIndex(pattern, "*"); pos != -1 {
+		prefix, suffix = pattern[:pos], pattern[pos+1:]
+	} else {
+		prefix = pattern
+	}
+	return
+}
+
+// tempName tries names generated from pattern inside dir until create
+// does not report that the name already exists.
+func tempName(fs Filesystem, op, dir, pattern string, create func(name string) error) (string, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	prefix, suffix, err := prefixAndSuffix(fs, pattern)
+	if err != nil {
+		return "", &os.PathError{Op: op, Path: pattern, Err: err}
+	}
+	nconflict := 0
+	for i := 0; i < 10000; i++ {
+		name := JoinPath(fs, dir, prefix+nextRandom()+suffix)
+		err = create(name)
+		if os.IsExist(err) {
+			if nconflict++; nconflict > 10 {
+				randmu.Lock()
+				rand = reseed()
+				randmu.Unlock()
+			}
+			continue
+		}
+		return name, err
+	}
+	return "", err
+}
+
+// TempFile creates a new temporary file in the directory dir on the given Filesystem,
+// opens the file for reading and writing, and returns the resulting File.
+// The filename is generated by taking pattern and adding a random string to the end.
+// If pattern includes a "*", the random string replaces the last "*".
+// If dir is the empty string, TempFile uses os.TempDir().
+// Multiple calls to TempFile will not choose the same file, as long as
+// the Filesystem supports os.O_EXCL. It is the caller's responsibility
+// to remove the file when no longer needed.
+//
+// This is a port of the stdlib ioutil.TempFile function.
+func TempFile(fs Filesystem, dir, pattern string) (f File, err error) {
+	_, err = tempName(fs, "createtemp", dir, pattern, func(name string) error {
+		f, err = fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		return err
+	})
+	return f, err
+}
+
+// TempDir creates a new temporary directory in the directory dir on the given Filesystem
+// and returns the path of the new directory. The directory name is generated from
+// pattern like by TempFile. If dir is the empty string, TempDir uses os.TempDir().
+// It is the caller's responsibility to remove the directory when no longer needed.
+//
+// This is a port of the stdlib ioutil.TempDir function.
+func TempDir(fs Filesystem, dir, pattern string) (string, error) {
+	return tempName(fs, "mkdirtemp", dir, pattern, func(name string) error {
+		return fs.Mkdir(name, 0700)
+	})
+}
+
 // readAll reads from r until an error or EOF and returns the data it read from
 // the internal buffer allocated with a specified capacity.
 //
@@ -3,6 +3,7 @@ package vfs_test
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/blang/vfs"
@@ -52,3 +53,64 @@ func TestReadFile(t *testing.T) {
 		t.Fatalf("ReadFile failed: expected error")
 	}
 }
+
+func TestReadAll(t *testing.T) {
+	data, err := vfs.ReadAll(bytes.NewReader(testdata))
+	if err != nil {
+		t.Fatalf("ReadAll failed: %s", err)
+	}
+	if !bytes.Equal(data, testdata) {
+		t.Fatalf("Bad data: %q", data)
+	}
+}
+
+func TestTempFile(t *testing.T) {
+	fs := memfs.Create()
+	fs.Mkdir("/tmp", 0777)
+
+	names := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		f, err := vfs.TempFile(fs, "/tmp", "prefix-*.txt")
+		if err != nil {
+			t.Fatalf("TempFile failed: %s", err)
+		}
+		name := f.Name()
+		f.Close()
+		if names[name] {
+			t.Fatalf("Name %q chosen twice", name)
+		}
+		names[name] = true
+		if !strings.HasPrefix(name, "/tmp/prefix-") || !strings.HasSuffix(name, ".txt") {
+			t.Errorf("Unexpected name %q", name)
+		}
+		if info, err := fs.Stat(name); err != nil || info.Mode() != 0600 {
+			t.Errorf("Unexpected file %q: %v %v", name, info, err)
+		}
+	}
+
+	if _, err := vfs.TempFile(fs, "/tmp", "a/b*"); err == nil {
+		t.Errorf("Expected error for pattern with separator")
+	}
+	if _, err := vfs.TempFile(fs, "/nonexisting", "file"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	fs := memfs.Create()
+
+	name, err := vfs.TempDir(fs, "/", "dir")
+	if err != nil {
+		t.Fatalf("TempDir failed: %s", err)
+	}
+	if !strings.HasPrefix(name, "/dir") {
+		t.Errorf("Unexpected name %q", name)
+	}
+	if info, err := fs.Stat(name); err != nil || !info.IsDir() || info.Mode().Perm() != 0700 {
+		t.Errorf("Unexpected directory %q: %v %v", name, info, err)
+	}
+	other, err := vfs.TempDir(fs, "/", "dir")
+	if err != nil || other == name {
+		t.Errorf("Unexpected second directory %q: %v", other, err)
+	}
+}
@@ -0,0 +1,34 @@
+package vfs
+
+import (
+	"os"
+)
+
+// Linker is implemented by filesystems supporting hard links.
+type Linker interface {
+	// Link creates newname as a hard link to the file oldname.
+	Link(oldname, newname string) error
+}
+
+// Linked is implemented by an os.FileInfo which knows the number of hard links to the file.
+type Linked interface {
+	Nlink() int
+}
+
+// Link creates newname as a hard link to the file oldname on the given Filesystem.
+// If the Filesystem does not implement Linker, a *os.LinkError containing ErrUnsupported is returned.
+func Link(fs Filesystem, oldname, newname string) error {
+	if l, ok := fs.(Linker); ok {
+		return l.Link(oldname, newname)
+	}
+	return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: ErrUnsupported}
+}
+
+// FileLinks returns the number of hard links to the file described by fi.
+// It supports FileInfos implementing Linked and those of the OS on unix systems.
+func FileLinks(fi os.FileInfo) (n int, ok bool) {
+	if l, ok := fi.(Linked); ok {
+		return l.Nlink(), true
+	}
+	return sysNlink(fi)
+}
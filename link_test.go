@@ -0,0 +1,81 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestLinkUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	err := vfs.Link(fs, "/a", "/b")
+	if !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if _, ok := err.(*os.LinkError); !ok {
+		t.Errorf("Expected *os.LinkError, got %T", err)
+	}
+}
+
+func TestLinkWrappers(t *testing.T) {
+	fs := memfs.Create()
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+	wrappers := map[string]vfs.Filesystem{
+		"ReadOnlyExcept": vfs.ReadOnlyExcept(fs, "/"),
+		"Logged":         vfs.Logged(fs, func(op string, args ...interface{}) {}),
+		"Chroot":         vfs.Chroot(fs, "/"),
+	}
+	for name, w := range wrappers {
+		link := "/" + name
+		if err := vfs.Link(w, "/file", link); err != nil {
+			t.Errorf("%s: Link: %s", name, err)
+		}
+		if b, err := vfs.ReadFile(fs, link); err != nil || string(b) != "content" {
+			t.Errorf("%s: unexpected content %q %v", name, b, err)
+		}
+	}
+	if err := vfs.Link(vfs.ReadOnly(fs), "/file", "/ro"); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestLinkQuota(t *testing.T) {
+	fs := vfs.Quota(memfs.Create(), 10, 0)
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+	if err := fs.Link("/file", "/link"); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if bytes, files := fs.Usage(); bytes != 7 || files != 1 {
+		t.Errorf("Unexpected usage: %d bytes, %d files", bytes, files)
+	}
+}
+
+func TestLinkOS(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("Link counts are not supported")
+	}
+	dir, err := ioutil.TempDir("", "vfs-link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := vfs.OS()
+	file, link := filepath.Join(dir, "file"), filepath.Join(dir, "link")
+	vfs.WriteFile(fs, file, []byte("content"), 0644)
+	if err := vfs.Link(fs, file, link); err != nil {
+		t.Fatalf("Link: %s", err)
+	}
+	fi, err := fs.Stat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := vfs.FileLinks(fi); !ok || n != 2 {
+		t.Errorf("FileLinks: %d %t", n, ok)
+	}
+}
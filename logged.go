@@ -0,0 +1,245 @@
+package vfs
+
+import (
+	"os"
+	"time"
+)
+
+// Logged creates a wrapper around the given filesystem which reports every operation to logger.
+// The logger is called after the operation was delegated with the name of the operation,
+// its arguments and the resulting error, which is nil on success.
+// Operations on files opened through the wrapper are reported as well,
+// Read and Write include the number of bytes transferred.
+//
+//	fs := vfs.Logged(vfs.OS(), func(op string, args ...interface{}) {
+//		log.Println(append([]interface{}{op}, args...)...)
+//	})
+func Logged(fs Filesystem, logger func(op string, args ...interface{})) *LogFS {
+	return &LogFS{Filesystem: fs, Logger: logger}
+}
+
+// LogFS represents a filesystem which reports every operation
+// and works as a wrapper around existing filesystems.
+type LogFS struct {
+	Filesystem
+	Logger func(op string, args ...interface{})
+}
+
+// OpenFile opens the file and reports the operation.
+// The returned file reports its operations as well.
+func (fs *LogFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	fs.Logger("openfile", name, flag, perm, err)
+	if err != nil {
+		return f, err
+	}
+	return &logFile{File: f, logger: fs.Logger}, nil
+}
+
+// Remove removes the named file or directory and reports the operation.
+func (fs *LogFS) Remove(name string) error {
+	err := fs.Filesystem.Remove(name)
+	fs.Logger("remove", name, err)
+	return err
+}
+
+// RemoveAll removes path and any children it contains and reports the operation.
+func (fs *LogFS) RemoveAll(path string) error {
+	err := RemoveAll(fs.Filesystem, path)
+	fs.Logger("removeall", path, err)
+	return err
+}
+
+// Rename renames a file and reports the operation.
+func (fs *LogFS) Rename(oldpath, newpath string) error {
+	err := fs.Filesystem.Rename(oldpath, newpath)
+	fs.Logger("rename", oldpath, newpath, err)
+	return err
+}
+
+// Mkdir creates a directory and reports the operation.
+func (fs *LogFS) Mkdir(name string, perm os.FileMode) error {
+	err := fs.Filesystem.Mkdir(name, perm)
+	fs.Logger("mkdir", name, perm, err)
+	return err
+}
+
+// MkdirAll creates a directory and all missing parents and reports the operation.
+func (fs *LogFS) MkdirAll(path string, perm os.FileMode) error {
+	err := MkdirAll(fs.Filesystem, path, perm)
+	fs.Logger("mkdirall", path, perm, err)
+	return err
+}
+
+// Symlink creates a symbolic link and reports the operation.
+func (fs *LogFS) Symlink(oldname, newname string) error {
+	err := Symlink(fs.Filesystem, oldname, newname)
+	fs.Logger("symlink", oldname, newname, err)
+	return err
+}
+
+// Link creates a hard link and reports the operation.
+func (fs *LogFS) Link(oldname, newname string) error {
+	err := Link(fs.Filesystem, oldname, newname)
+	fs.Logger("link", oldname, newname, err)
+	return err
+}
+
+// Readlink returns the destination of the named symbolic link and reports the operation.
+func (fs *LogFS) Readlink(name string) (string, error) {
+	target, err := Readlink(fs.Filesystem, name)
+	fs.Logger("readlink", name, err)
+	return target, err
+}
+
+// Chmod changes the mode of the named file and reports the operation.
+func (fs *LogFS) Chmod(name string, mode os.FileMode) error {
+	err := Chmod(fs.Filesystem, name, mode)
+	fs.Logger("chmod", name, mode, err)
+	return err
+}
+
+// Chown changes the numeric uid and gid of the named file and reports the operation.
+func (fs *LogFS) Chown(name string, uid, gid int) error {
+	err := Chown(fs.Filesystem, name, uid, gid)
+	fs.Logger("chown", name, uid, gid, err)
+	return err
+}
+
+// Chtimes changes the access and modification times of the named file and reports the operation.
+func (fs *LogFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	err := Chtimes(fs.Filesystem, name, atime, mtime)
+	fs.Logger("chtimes", name, atime, mtime, err)
+	return err
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file and reports the operation.
+func (fs *LogFS) GetXattr(name, attr string) ([]byte, error) {
+	value, err := GetXattr(fs.Filesystem, name, attr)
+	fs.Logger("getxattr", name, attr, err)
+	return value, err
+}
+
+// SetXattr sets the extended attribute attr of the named file and reports the operation.
+func (fs *LogFS) SetXattr(name, attr string, value []byte) error {
+	err := SetXattr(fs.Filesystem, name, attr, value)
+	fs.Logger("setxattr", name, attr, len(value), err)
+	return err
+}
+
+// ListXattr returns the names of the extended attributes of the named file and reports the operation.
+func (fs *LogFS) ListXattr(name string) ([]string, error) {
+	attrs, err := ListXattr(fs.Filesystem, name)
+	fs.Logger("listxattr", name, err)
+	return attrs, err
+}
+
+// RemoveXattr removes the extended attribute attr of the named file and reports the operation.
+func (fs *LogFS) RemoveXattr(name, attr string) error {
+	err := RemoveXattr(fs.Filesystem, name, attr)
+	fs.Logger("removexattr", name, attr, err)
+	return err
+}
+
+// Watch reports changes of the named file and reports the operation.
+func (fs *LogFS) Watch(name string) (<-chan Event, error) {
+	events, err := Watch(fs.Filesystem, name)
+	fs.Logger("watch", name, err)
+	return events, err
+}
+
+// Unwatch stops a watch started by Watch and reports the operation.
+func (fs *LogFS) Unwatch(events <-chan Event) error {
+	err := Unwatch(fs.Filesystem, events)
+	fs.Logger("unwatch", err)
+	return err
+}
+
+// Stat returns the FileInfo of the named file and reports the operation.
+func (fs *LogFS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(name)
+	fs.Logger("stat", name, err)
+	return fi, err
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links and reports the operation.
+func (fs *LogFS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(name)
+	fs.Logger("lstat", name, err)
+	return fi, err
+}
+
+// ReadDir reads the named directory and reports the operation.
+func (fs *LogFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	fs.Logger("readdir", path, err)
+	return fis, err
+}
+
+// OpenDir opens the named directory for iteration and reports the operation.
+// It implements DirOpener.
+func (fs *LogFS) OpenDir(path string) (DirIterator, error) {
+	d, err := OpenDir(fs.Filesystem, path)
+	fs.Logger("opendir", path, err)
+	return d, err
+}
+
+// logFile reports the operations on a File.
+type logFile struct {
+	File
+	logger func(op string, args ...interface{})
+}
+
+func (f *logFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.logger("read", f.Name(), n, err)
+	return n, err
+}
+
+func (f *logFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	f.logger("readat", f.Name(), off, n, err)
+	return n, err
+}
+
+func (f *logFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.logger("write", f.Name(), n, err)
+	return n, err
+}
+
+func (f *logFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.File.Seek(offset, whence)
+	f.logger("seek", f.Name(), offset, whence, err)
+	return n, err
+}
+
+func (f *logFile) Truncate(size int64) error {
+	err := f.File.Truncate(size)
+	f.logger("truncate", f.Name(), size, err)
+	return err
+}
+
+func (f *logFile) Sync() error {
+	err := f.File.Sync()
+	f.logger("sync", f.Name(), err)
+	return err
+}
+
+func (f *logFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	f.logger("stat", f.Name(), err)
+	return fi, err
+}
+
+func (f *logFile) Readdir(n int) ([]os.FileInfo, error) {
+	fis, err := f.File.Readdir(n)
+	f.logger("readdir", f.Name(), n, err)
+	return fis, err
+}
+
+func (f *logFile) Close() error {
+	err := f.File.Close()
+	f.logger("close", f.Name(), err)
+	return err
+}
@@ -0,0 +1,82 @@
+package vfs_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestLogged(t *testing.T) {
+	var ops []string
+	fs := vfs.Logged(memfs.Create(), func(op string, args ...interface{}) {
+		ops = append(ops, strings.TrimSpace(fmt.Sprintln(append([]interface{}{op}, args...)...)))
+	})
+
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Rename("/dir/file", "/file"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if err := fs.Remove("/missing"); err == nil {
+		t.Fatalf("Expected error")
+	}
+
+	expected := []string{
+		"mkdir /dir -rwxrwxrwx <nil>",
+		fmt.Sprintf("openfile /dir/file %d -rw-rw-rw- <nil>", os.O_WRONLY|os.O_CREATE|os.O_TRUNC),
+		"write /dir/file 7 <nil>",
+		"close /dir/file <nil>",
+		"rename /dir/file /file <nil>",
+	}
+	if len(ops) != len(expected)+1 {
+		t.Fatalf("Unexpected operations: %q", ops)
+	}
+	if !reflect.DeepEqual(ops[:len(expected)], expected) {
+		t.Errorf("Unexpected operations: %q", ops)
+	}
+	if last := ops[len(ops)-1]; !strings.HasPrefix(last, "remove /missing ") || strings.HasSuffix(last, "<nil>") {
+		t.Errorf("Expected failed remove, got %q", last)
+	}
+}
+
+func TestLoggedFile(t *testing.T) {
+	var ops []string
+	fs := vfs.Logged(memfs.Create(), func(op string, args ...interface{}) {
+		ops = append(ops, op)
+	})
+	vfs.WriteFile(fs, "/file", []byte("content"), 0666)
+	ops = nil
+
+	b, err := vfs.ReadFile(fs, "/file")
+	if err != nil || string(b) != "content" {
+		t.Fatalf("ReadFile: %q, %v", b, err)
+	}
+	if ops[0] != "openfile" || ops[len(ops)-1] != "close" || !strings.Contains(strings.Join(ops, " "), "read") {
+		t.Errorf("Unexpected operations: %q", ops)
+	}
+}
+
+func TestLoggedSymlink(t *testing.T) {
+	var ops []string
+	fs := vfs.Logged(memfs.Create(), func(op string, args ...interface{}) {
+		ops = append(ops, op)
+	})
+	if err := vfs.Symlink(fs, "/target", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	if err := vfs.MkdirAll(fs, "/a/b", 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if !reflect.DeepEqual(ops, []string{"symlink", "mkdirall"}) {
+		t.Errorf("Unexpected operations: %q", ops)
+	}
+}
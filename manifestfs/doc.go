@@ -0,0 +1,3 @@
+// Package manifestfs defines a manifest of a file tree and a filesystem
+// wrapper serving metadata operations from such a manifest.
+package manifestfs
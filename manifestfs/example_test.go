@@ -0,0 +1,26 @@
+package manifestfs_test
+
+import (
+	"os"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/manifestfs"
+)
+
+func ExampleFS() {
+	// Generate a manifest of a tree once, e.g. while publishing it
+	m, err := manifestfs.Generate(vfs.OS(), "/tmp", false)
+	if err != nil {
+		return
+	}
+	f, err := os.Create("manifest.json")
+	if err != nil {
+		return
+	}
+	m.Write(f)
+	f.Close()
+
+	// Later, serve Stat and ReadDir from the manifest instead of a slow backend
+	fs := manifestfs.Create(vfs.OS(), m)
+	fs.ReadDir("/tmp")
+}
@@ -0,0 +1,104 @@
+package manifestfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	filepath "path"
+	"strings"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// Entry describes a single file or directory of a Manifest.
+type Entry struct {
+	// Path relative to the manifest root, "." is the root itself.
+	Path    string      `json:"path"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"mtime"`
+	// Hash is the hex encoded SHA-256 sum of the file content, if generated.
+	Hash string `json:"sha256,omitempty"`
+}
+
+// Manifest is a listing index of a file tree.
+type Manifest struct {
+	// Root is the path the manifest was generated from.
+	Root    string  `json:"root"`
+	Entries []Entry `json:"entries"`
+}
+
+// Generate walks the tree rooted at root and records every file and directory.
+// If hash is true, the content of every regular file is read to compute its SHA-256 sum.
+func Generate(fs vfs.Filesystem, root string, hash bool) (*Manifest, error) {
+	root = filepath.Clean(root)
+	m := &Manifest{Root: root}
+	err := vfs.Walk(fs, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		e := Entry{
+			Path:    relPath(root, path),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		}
+		if info.IsDir() {
+			e.Size = 0
+		} else if hash && info.Mode().IsRegular() {
+			sum, err := hashFile(fs, path)
+			if err != nil {
+				return err
+			}
+			e.Hash = sum
+		}
+		m.Entries = append(m.Entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func hashFile(fs vfs.Filesystem, path string) (string, error) {
+	f, err := vfs.Open(fs, path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// relPath returns path relative to root, both paths must be clean.
+func relPath(root, path string) string {
+	if path == root {
+		return "."
+	}
+	if root == "/" {
+		return strings.TrimPrefix(path, "/")
+	}
+	return strings.TrimPrefix(path, root+"/")
+}
+
+// Write encodes the manifest as JSON to w.
+func (m *Manifest) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// Read decodes a JSON encoded manifest from r.
+func Read(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	m.Root = filepath.Clean(m.Root)
+	return m, nil
+}
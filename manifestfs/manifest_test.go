@@ -0,0 +1,72 @@
+package manifestfs
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func testFS(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	if err := vfs.MkdirAll(fs, "/data/sub", 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/data/file.txt", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/data/sub/other.txt", []byte("world!"), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	return fs
+}
+
+func TestGenerate(t *testing.T) {
+	fs := testFS(t)
+	m, err := Generate(fs, "/data", true)
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	var paths []string
+	for _, e := range m.Entries {
+		paths = append(paths, e.Path)
+	}
+	if expected := []string{".", "file.txt", "sub", "sub/other.txt"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Paths %q, expected %q", paths, expected)
+	}
+	if e := m.Entries[1]; e.Size != 5 || e.Mode != 0644 {
+		t.Errorf("Unexpected entry: %+v", e)
+	}
+	if h := m.Entries[1].Hash; h != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected hash: %s", h)
+	}
+	if h := m.Entries[2].Hash; h != "" {
+		t.Errorf("Directory must not be hashed: %s", h)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	fs := testFS(t)
+	m, err := Generate(fs, "/data", false)
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	m2, err := Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if m2.Root != m.Root || len(m2.Entries) != len(m.Entries) {
+		t.Fatalf("Manifest mismatch: %+v", m2)
+	}
+	for i := range m.Entries {
+		if !m.Entries[i].ModTime.Equal(m2.Entries[i].ModTime) || m.Entries[i].Path != m2.Entries[i].Path {
+			t.Errorf("Entry mismatch: %+v != %+v", m.Entries[i], m2.Entries[i])
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package manifestfs
+
+import (
+	"os"
+	filepath "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// Create returns a filesystem that serves Stat, Lstat and ReadDir for paths
+// inside the manifest root from the given manifest instead of the underlying filesystem.
+// All other operations are forwarded to fs. Mutating operations invalidate the
+// affected manifest entries, so later metadata requests on them reach fs again.
+func Create(fs vfs.Filesystem, m *Manifest) *FS {
+	mfs := &FS{
+		Filesystem: fs,
+		root:       filepath.Clean(m.Root),
+		entries:    make(map[string]Entry, len(m.Entries)),
+		childs:     make(map[string][]string),
+		stale:      make(map[string]bool),
+	}
+	for _, e := range m.Entries {
+		p := filepath.Clean(e.Path)
+		e.Path = p
+		mfs.entries[p] = e
+		if p != "." {
+			dir := filepath.Dir(p)
+			mfs.childs[dir] = append(mfs.childs[dir], filepath.Base(p))
+		}
+	}
+	for _, names := range mfs.childs {
+		sort.Strings(names)
+	}
+	return mfs
+}
+
+// FS is a filesystem serving metadata from a Manifest.
+type FS struct {
+	vfs.Filesystem
+	root    string
+	lock    sync.RWMutex
+	entries map[string]Entry
+	childs  map[string][]string
+	// stale marks manifest paths which are no longer authoritative.
+	stale map[string]bool
+}
+
+// lookup returns the manifest relative path of name and
+// whether the manifest is authoritative for it.
+func (fs *FS) lookup(name string) (string, bool) {
+	name = filepath.Clean(name)
+	if name != fs.root && !strings.HasPrefix(name, strings.TrimSuffix(fs.root, "/")+"/") {
+		return "", false
+	}
+	rel := relPath(fs.root, name)
+	for p := rel; ; p = filepath.Dir(p) {
+		if fs.stale[p] {
+			return "", false
+		}
+		if p == "." {
+			break
+		}
+	}
+	return rel, true
+}
+
+// invalidate marks name and the listing of its parent as stale.
+func (fs *FS) invalidate(name string) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	name = filepath.Clean(name)
+	if rel, ok := fs.lookup(name); ok {
+		fs.stale[rel] = true
+	}
+	if rel, ok := fs.lookup(filepath.Dir(name)); ok {
+		fs.stale[rel] = true
+	}
+}
+
+// Stat returns the FileInfo recorded in the manifest.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fs.lock.RLock()
+	rel, ok := fs.lookup(name)
+	e, found := fs.entries[rel]
+	fs.lock.RUnlock()
+	if !ok {
+		return fs.Filesystem.Stat(name)
+	}
+	if !found {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return fs.info(e), nil
+}
+
+// Lstat returns the FileInfo recorded in the manifest.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fs.lock.RLock()
+	rel, ok := fs.lookup(name)
+	e, found := fs.entries[rel]
+	fs.lock.RUnlock()
+	if !ok {
+		return fs.Filesystem.Lstat(name)
+	}
+	if !found {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return fs.info(e), nil
+}
+
+// ReadDir returns the sorted directory entries recorded in the manifest.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	rel, ok := fs.lookup(path)
+	if !ok {
+		return fs.Filesystem.ReadDir(path)
+	}
+	e, found := fs.entries[rel]
+	if !found {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: os.ErrNotExist}
+	}
+	if !e.Mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: vfs.ErrNotDirectory}
+	}
+	names := fs.childs[rel]
+	fis := make([]os.FileInfo, 0, len(names))
+	for _, n := range names {
+		fis = append(fis, fs.info(fs.entries[filepath.Join(rel, n)]))
+	}
+	return fis, nil
+}
+
+// OpenFile forwards to the underlying filesystem and
+// invalidates the manifest entry if the file is opened for writing.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		fs.invalidate(name)
+	}
+	return fs.Filesystem.OpenFile(name, flag, perm)
+}
+
+// Remove forwards to the underlying filesystem and invalidates the manifest entry.
+func (fs *FS) Remove(name string) error {
+	fs.invalidate(name)
+	return fs.Filesystem.Remove(name)
+}
+
+// Rename forwards to the underlying filesystem and invalidates both manifest entries.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	fs.invalidate(oldpath)
+	fs.invalidate(newpath)
+	return fs.Filesystem.Rename(oldpath, newpath)
+}
+
+// Mkdir forwards to the underlying filesystem and invalidates the manifest entry.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	fs.invalidate(name)
+	return fs.Filesystem.Mkdir(name, perm)
+}
+
+// OpenDir opens the named directory for iteration, the entries recorded in the manifest are served from it.
+// It implements vfs.DirOpener.
+func (fs *FS) OpenDir(path string) (vfs.DirIterator, error) {
+	fs.lock.RLock()
+	_, ok := fs.lookup(path)
+	fs.lock.RUnlock()
+	if !ok {
+		return vfs.OpenDir(fs.Filesystem, path)
+	}
+	f, err := vfs.DirFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	return vfs.FileDirIterator(f), nil
+}
+
+// Symlink forwards to the underlying filesystem and invalidates the manifest entry of newname.
+// It returns vfs.ErrUnsupported if the underlying filesystem does not support symbolic links.
+func (fs *FS) Symlink(oldname, newname string) error {
+	fs.invalidate(newname)
+	return vfs.Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Link forwards to the underlying filesystem and invalidates the manifest entries of both names,
+// as the link count of oldname changes.
+// It returns vfs.ErrUnsupported if the underlying filesystem does not support hard links.
+func (fs *FS) Link(oldname, newname string) error {
+	fs.invalidate(oldname)
+	fs.invalidate(newname)
+	return vfs.Link(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link, which is not recorded in the manifest.
+func (fs *FS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// Chmod forwards to the underlying filesystem and invalidates the manifest entry.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	fs.invalidate(name)
+	return vfs.Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown forwards to the underlying filesystem and invalidates the manifest entry.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	fs.invalidate(name)
+	return vfs.Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes forwards to the underlying filesystem and invalidates the manifest entry.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs.invalidate(name)
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+// Extended attributes are not recorded in the manifest.
+func (fs *FS) GetXattr(name, attr string) ([]byte, error) {
+	return vfs.GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *FS) SetXattr(name, attr string, value []byte) error {
+	return vfs.SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *FS) ListXattr(name string) ([]string, error) {
+	return vfs.ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *FS) RemoveXattr(name, attr string) error {
+	return vfs.RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file on the underlying filesystem.
+// Events do not invalidate the manifest, only changes made through FS do.
+func (fs *FS) Watch(name string) (<-chan vfs.Event, error) {
+	return vfs.Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *FS) Unwatch(events <-chan vfs.Event) error {
+	return vfs.Unwatch(fs.Filesystem, events)
+}
+
+func (fs *FS) info(e Entry) entryInfo {
+	if e.Path == "." {
+		return entryInfo{name: filepath.Base(fs.root), e: e}
+	}
+	return entryInfo{name: filepath.Base(e.Path), e: e}
+}
+
+// entryInfo implements os.FileInfo for a manifest Entry.
+type entryInfo struct {
+	name string
+	e    Entry
+}
+
+func (fi entryInfo) Name() string       { return fi.name }
+func (fi entryInfo) Size() int64        { return fi.e.Size }
+func (fi entryInfo) Mode() os.FileMode  { return fi.e.Mode }
+func (fi entryInfo) ModTime() time.Time { return fi.e.ModTime }
+func (fi entryInfo) IsDir() bool        { return fi.e.Mode.IsDir() }
+
+// Sys returns the manifest Entry
+func (fi entryInfo) Sys() interface{} { return fi.e }
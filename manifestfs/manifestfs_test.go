@@ -0,0 +1,167 @@
+package manifestfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+var errBackend = errors.New("backend accessed")
+
+func testManifest() *Manifest {
+	now := time.Now()
+	return &Manifest{
+		Root: "/data",
+		Entries: []Entry{
+			{Path: ".", Mode: os.ModeDir | 0755, ModTime: now},
+			{Path: "sub", Mode: os.ModeDir | 0755, ModTime: now},
+			{Path: "file.txt", Size: 5, Mode: 0644, ModTime: now},
+			{Path: "sub/other.txt", Size: 6, Mode: 0600, ModTime: now},
+		},
+	}
+}
+
+func TestInterface(t *testing.T) {
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+	_ = vfs.Filesystem(fs)
+	_ = vfs.DirOpener(fs)
+	_ = vfs.Symlinker(fs)
+	_ = vfs.Linker(fs)
+	_ = vfs.Attributer(fs)
+	_ = vfs.Xattrer(fs)
+	_ = vfs.Watcher(fs)
+}
+
+func TestStat(t *testing.T) {
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+
+	fi, err := fs.Stat("/data/sub/other.txt")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "other.txt" || fi.Size() != 6 || fi.Mode() != 0600 || fi.IsDir() {
+		t.Errorf("Unexpected fileinfo: %+v", fi)
+	}
+
+	fi, err = fs.Lstat("/data")
+	if err != nil {
+		t.Fatalf("Lstat: %s", err)
+	}
+	if fi.Name() != "data" || !fi.IsDir() {
+		t.Errorf("Unexpected fileinfo: %+v", fi)
+	}
+
+	if _, err := fs.Stat("/data/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	// Outside of manifest root
+	if _, err := fs.Stat("/other"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+	if _, err := fs.Stat("/database"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+
+	fis, err := fs.ReadDir("/data")
+	if err != nil {
+		t.Fatalf("ReadDir: %s", err)
+	}
+	if len(fis) != 2 || fis[0].Name() != "file.txt" || fis[1].Name() != "sub" {
+		t.Errorf("Unexpected entries: %v", fis)
+	}
+
+	if _, err := fs.ReadDir("/data/file.txt"); err == nil {
+		t.Errorf("Expected error readdir(file)")
+	}
+	if _, err := fs.ReadDir("/data/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+
+	if err := fs.Remove("/data/sub/other.txt"); err != errBackend {
+		t.Fatalf("Expected backend error, got %v", err)
+	}
+	if _, err := fs.Stat("/data/sub/other.txt"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+	if _, err := fs.ReadDir("/data/sub"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+	// Unrelated entries are still served from the manifest
+	if _, err := fs.Stat("/data/file.txt"); err != nil {
+		t.Errorf("Stat: %s", err)
+	}
+
+	// Read-only open does not invalidate
+	fs.OpenFile("/data/file.txt", os.O_RDONLY, 0)
+	if _, err := fs.Stat("/data/file.txt"); err != nil {
+		t.Errorf("Stat: %s", err)
+	}
+	fs.OpenFile("/data/file.txt", os.O_RDWR, 0)
+	if _, err := fs.Stat("/data/file.txt"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+
+	d, err := fs.OpenDir("/data")
+	if err != nil {
+		t.Fatalf("OpenDir: %s", err)
+	}
+	defer d.Close()
+	fis, err := d.Next(1)
+	if err != nil || len(fis) != 1 || fis[0].Name() != "file.txt" {
+		t.Errorf("Unexpected entries: %v %v", fis, err)
+	}
+	fis, err = d.Next(0)
+	if err != nil || len(fis) != 1 || fis[0].Name() != "sub" {
+		t.Errorf("Unexpected entries: %v %v", fis, err)
+	}
+
+	if _, err := fs.OpenDir("/data/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := fs.OpenDir("/other"); err != errBackend {
+		t.Errorf("Expected backend error, got %v", err)
+	}
+}
+
+func TestInvalidateAttributes(t *testing.T) {
+	changes := []struct {
+		name   string
+		change func(fs *FS, name string) error
+	}{
+		{"/data/file.txt", func(fs *FS, name string) error { return fs.Chmod(name, 0600) }},
+		{"/data/sub", func(fs *FS, name string) error { return fs.Chtimes(name, time.Now(), time.Now()) }},
+		{"/data/sub/link", func(fs *FS, name string) error { return fs.Symlink("other.txt", name) }},
+	}
+	for _, c := range changes {
+		fs := Create(vfs.Dummy(errBackend), testManifest())
+		if err := c.change(fs, c.name); !errors.Is(err, vfs.ErrUnsupported) {
+			t.Errorf("Expected unsupported error, got %v", err)
+		}
+		if _, err := fs.Lstat(c.name); err != errBackend {
+			t.Errorf("Expected %s to be invalidated, got %v", c.name, err)
+		}
+	}
+
+	// Extended attributes are not recorded, changing them keeps the manifest
+	fs := Create(vfs.Dummy(errBackend), testManifest())
+	fs.SetXattr("/data/file.txt", "user.test", nil)
+	if _, err := fs.Stat("/data/file.txt"); err != nil {
+		t.Errorf("Expected /data/file.txt to be served from the manifest, got %v", err)
+	}
+}
@@ -11,6 +11,7 @@ type Buffer interface {
 	io.Reader
 	io.ReaderAt
 	io.Writer
+	io.WriterAt
 	io.Seeker
 	io.Closer
 	// Truncate shrinks or extends the size of the Buffer to the specified size.
@@ -24,6 +25,7 @@ const MinBufferSize = 512
 var ErrTooLarge = errors.New("Volume too large")
 
 // Buf is a Buffer working on a slice of bytes.
+// The slice is copied when it grows, see ChunkBuf for large files.
 type Buf struct {
 	buf *[]byte
 	ptr int64
@@ -107,6 +109,9 @@ func (v *Buf) Read(p []byte) (n int, err error) {
 // ReadAt always returns a non-nil error when n < len(b).
 // At end of file, that error is io.EOF.
 func (v *Buf) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("ReadAt: negative offset")
+	}
 	if len(p) == 0 {
 		return 0, nil
 	}
@@ -121,6 +126,22 @@ func (v *Buf) ReadAt(p []byte, off int64) (n int, err error) {
 	return
 }
 
+// WriteAt writes len(p) bytes to the Buffer starting at byte offset off.
+// It returns the number of bytes written and an error if any.
+// The Buffer is extended if necessary, a gap between the previous end
+// and off reads as zero bytes. The current offset is not changed.
+func (v *Buf) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("WriteAt: negative offset")
+	}
+	if writeEnd := int(off) + len(p) - len(*v.buf); writeEnd > 0 {
+		if err := v.grow(writeEnd); err != nil {
+			return 0, err
+		}
+	}
+	return copy((*v.buf)[off:], p), nil
+}
+
 // Truncate truncates the Buffer to a given size.
 // It returns an error if the given size is negative.
 // If the Buffer is larger than the specified size, the extra data is lost.
@@ -158,6 +179,10 @@ func (v *Buf) grow(n int) error {
 		*v.buf = buf
 	}
 	*v.buf = (*v.buf)[0 : m+n]
+	// The capacity might contain data of a previous truncate
+	for i := m; i < m+n; i++ {
+		(*v.buf)[i] = 0
+	}
 	return nil
 }
 
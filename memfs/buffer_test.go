@@ -270,3 +270,54 @@ func TestBufferGrowWriteAndSeek(t *testing.T) {
 		t.Fatalf("Invalid buffer cap: %d", c)
 	}
 }
+
+func TestWriteAt(t *testing.T) {
+	buf := make([]byte, 0, len(dots))
+	v := NewBuffer(&buf)
+
+	// Write behind the end, the gap is filled with zeros
+	if n, err := v.WriteAt([]byte(abc[:4]), 4); err != nil || n != 4 {
+		t.Fatalf("Unexpected write error: %d %s", n, err)
+	}
+	if s := string(buf); s != "\x00\x00\x00\x00abcd" {
+		t.Errorf("Unexpected buffer content: %q", s)
+	}
+
+	// Overwrite inside the buffer
+	if n, err := v.WriteAt([]byte("xy"), 2); err != nil || n != 2 {
+		t.Fatalf("Unexpected write error: %d %s", n, err)
+	}
+	if s := string(buf); s != "\x00\x00xyabcd" {
+		t.Errorf("Unexpected buffer content: %q", s)
+	}
+
+	// Offset is not changed by WriteAt
+	if n, err := v.Seek(0, os.SEEK_CUR); err != nil || n != 0 {
+		t.Errorf("Unexpected offset: %d %s", n, err)
+	}
+
+	if _, err := v.WriteAt([]byte("x"), -1); err == nil {
+		t.Errorf("Expected error on negative offset")
+	}
+	if _, err := v.ReadAt(make([]byte, 1), -1); err == nil {
+		t.Errorf("Expected error on negative offset")
+	}
+}
+
+// TestWriteAtAfterTruncate tests if growing the buffer into
+// previously truncated capacity exposes old data
+func TestWriteAtAfterTruncate(t *testing.T) {
+	buf := make([]byte, len(dots))
+	copy(buf, dots)
+	v := NewBuffer(&buf)
+
+	if err := v.Truncate(2); err != nil {
+		t.Fatalf("Unexpected truncate error: %s", err)
+	}
+	if _, err := v.WriteAt([]byte("x"), 5); err != nil {
+		t.Fatalf("Unexpected write error: %s", err)
+	}
+	if s := string(buf); s != "1.\x00\x00\x00x" {
+		t.Errorf("Unexpected buffer content: %q", s)
+	}
+}
@@ -0,0 +1,264 @@
+package memfs
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ChunkSize is the size of the extents of Chunks.
+const ChunkSize = 64 * 1024
+
+// Chunks stores the content of a file in extents of ChunkSize bytes,
+// so growing a file never copies its existing content.
+// Extents are allocated on write and may be shorter than ChunkSize,
+// unallocated parts like holes of sparse files read as zero bytes.
+// Chunks is not safe for concurrent use.
+type Chunks struct {
+	size   int64
+	chunks [][]byte
+	// shared marks extents referenced by a copy, which are copied before they are modified
+	shared []bool
+}
+
+// Size returns the size of the content.
+func (c *Chunks) Size() int64 {
+	return c.size
+}
+
+// readAt copies the content at off to p and returns the number of bytes copied.
+func (c *Chunks) readAt(p []byte, off int64) int {
+	if off >= c.size {
+		return 0
+	}
+	if rest := c.size - off; int64(len(p)) > rest {
+		p = p[:rest]
+	}
+	for n := 0; n < len(p); {
+		i, o := int((off+int64(n))/ChunkSize), int((off+int64(n))%ChunkSize)
+		dst := p[n:]
+		if len(dst) > ChunkSize-o {
+			dst = dst[:ChunkSize-o]
+		}
+		copied := 0
+		if i < len(c.chunks) && o < len(c.chunks[i]) {
+			copied = copy(dst, c.chunks[i][o:])
+		}
+		for j := copied; j < len(dst); j++ {
+			dst[j] = 0
+		}
+		n += len(dst)
+	}
+	return len(p)
+}
+
+// writeAt writes p at off, the content is extended if necessary.
+func (c *Chunks) writeAt(p []byte, off int64) {
+	for n := 0; n < len(p); {
+		i, o := int((off+int64(n))/ChunkSize), int((off+int64(n))%ChunkSize)
+		m := len(p) - n
+		if m > ChunkSize-o {
+			m = ChunkSize - o
+		}
+		copy(c.chunk(i, o+m)[o:], p[n:n+m])
+		n += m
+	}
+	if end := off + int64(len(p)); end > c.size {
+		c.size = end
+	}
+}
+
+// chunk returns extent i for modification with a length of at least n bytes.
+func (c *Chunks) chunk(i, n int) []byte {
+	for len(c.chunks) <= i {
+		c.chunks = append(c.chunks, nil)
+		c.shared = append(c.shared, false)
+	}
+	chunk := c.chunks[i]
+	if c.shared[i] || n > cap(chunk) {
+		size := cap(chunk)
+		if n > size && i > 0 {
+			// Files spanning several extents are large, avoid growing them stepwise
+			size = ChunkSize
+		} else if n > size {
+			size = 2*size + MinBufferSize
+			if size < n {
+				size = n
+			}
+			if size > ChunkSize {
+				size = ChunkSize
+			}
+		}
+		grown := make([]byte, len(chunk), size)
+		copy(grown, chunk)
+		chunk = grown
+		c.shared[i] = false
+	}
+	if l := len(chunk); n > l {
+		chunk = chunk[:n]
+		// The capacity might contain data of a previous truncate
+		for j := l; j < n; j++ {
+			chunk[j] = 0
+		}
+	}
+	c.chunks[i] = chunk
+	return chunk
+}
+
+// truncate changes the size of the content, extending it does not allocate extents.
+func (c *Chunks) truncate(size int64) {
+	if size < c.size {
+		n := int((size + ChunkSize - 1) / ChunkSize)
+		if n < len(c.chunks) {
+			for i := n; i < len(c.chunks); i++ {
+				c.chunks[i] = nil
+			}
+			c.chunks = c.chunks[:n]
+			c.shared = c.shared[:n]
+		}
+		if o := int(size % ChunkSize); o > 0 && n <= len(c.chunks) && len(c.chunks[n-1]) > o {
+			c.chunks[n-1] = c.chunks[n-1][:o]
+		}
+	}
+	c.size = size
+}
+
+// share returns a copy of c, the extents are shared by both
+// and copied before either side modifies them.
+func (c *Chunks) share() *Chunks {
+	for i := range c.shared {
+		c.shared[i] = true
+	}
+	return &Chunks{
+		size:   c.size,
+		chunks: append([][]byte(nil), c.chunks...),
+		shared: append([]bool(nil), c.shared...),
+	}
+}
+
+// writeTo writes the content to w.
+func (c *Chunks) writeTo(w io.Writer) error {
+	buf := make([]byte, ChunkSize)
+	for off := int64(0); off < c.size; {
+		n := c.readAt(buf, off)
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		off += int64(n)
+	}
+	return nil
+}
+
+// readFrom appends the content read from r until EOF.
+func (c *Chunks) readFrom(r io.Reader) error {
+	buf := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		c.writeAt(buf[:n], c.size)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ChunkBuf is a Buffer working on Chunks.
+// Seeking beyond the end is allowed, a following write leaves a hole which reads as zero bytes.
+type ChunkBuf struct {
+	c   *Chunks
+	ptr int64
+}
+
+// NewChunkBuffer creates a new data volume based on chunks
+func NewChunkBuffer(c *Chunks) *ChunkBuf {
+	return &ChunkBuf{
+		c: c,
+	}
+}
+
+// Seek sets the offset for the next Read or Write on the buffer to offset,
+// interpreted according to whence:
+// 	0 (os.SEEK_SET) means relative to the origin of the file
+// 	1 (os.SEEK_CUR) means relative to the current offset
+// 	2 (os.SEEK_END) means relative to the end of the file
+// It returns the new offset and an error, if any.
+func (v *ChunkBuf) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case os.SEEK_SET:
+		abs = offset
+	case os.SEEK_CUR:
+		abs = v.ptr + offset
+	case os.SEEK_END:
+		abs = v.c.size + offset
+	default:
+		return 0, errors.New("Seek: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("Seek: negative position")
+	}
+	v.ptr = abs
+	return abs, nil
+}
+
+// Write writes len(p) byte to the Buffer at the current offset.
+func (v *ChunkBuf) Write(p []byte) (int, error) {
+	v.c.writeAt(p, v.ptr)
+	v.ptr += int64(len(p))
+	return len(p), nil
+}
+
+// Close the buffer. Currently no effect.
+func (v *ChunkBuf) Close() error {
+	return nil
+}
+
+// Read reads len(p) byte from the Buffer starting at the current offset.
+// Returns io.EOF error if pointer is at the end of the Buffer.
+func (v *ChunkBuf) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if v.ptr >= v.c.size {
+		return 0, io.EOF
+	}
+	n := v.c.readAt(p, v.ptr)
+	v.ptr += int64(n)
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes from the Buffer starting at byte offset off.
+// ReadAt always returns a non-nil error when n < len(p).
+// At end of file, that error is io.EOF.
+func (v *ChunkBuf) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ReadAt: negative offset")
+	}
+	n := v.c.readAt(p, off)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt writes len(p) bytes to the Buffer starting at byte offset off.
+// The Buffer is extended if necessary, a gap between the previous end
+// and off reads as zero bytes. The current offset is not changed.
+func (v *ChunkBuf) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("WriteAt: negative offset")
+	}
+	v.c.writeAt(p, off)
+	return len(p), nil
+}
+
+// Truncate changes the size of the Buffer, the extended part reads as zero bytes.
+func (v *ChunkBuf) Truncate(size int64) error {
+	if size < 0 {
+		return errors.New("Truncate: size must be non-negative")
+	}
+	v.c.truncate(size)
+	return nil
+}
@@ -0,0 +1,146 @@
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+func TestChunkBufInterface(t *testing.T) {
+	_ = Buffer(NewChunkBuffer(&Chunks{}))
+}
+
+// TestChunksRandomAccess compares random writes, reads and truncates with a plain buffer.
+func TestChunksRandomAccess(t *testing.T) {
+	c := &Chunks{}
+	v := NewChunkBuffer(c)
+	rnd := rand.New(rand.NewSource(1))
+	var expected []byte
+	for i := 0; i < 500; i++ {
+		switch rnd.Intn(3) {
+		case 0:
+			off := rnd.Int63n(4 * ChunkSize)
+			data := make([]byte, rnd.Intn(2*ChunkSize))
+			rnd.Read(data)
+			if n, err := v.WriteAt(data, off); err != nil || n != len(data) {
+				t.Fatalf("WriteAt: %d %v", n, err)
+			}
+			if end := int(off) + len(data); len(data) > 0 && end > len(expected) {
+				expected = append(expected, make([]byte, end-len(expected))...)
+			}
+			copy(expected[off:], data)
+		case 1:
+			size := rnd.Int63n(5 * ChunkSize)
+			if err := v.Truncate(size); err != nil {
+				t.Fatalf("Truncate: %s", err)
+			}
+			if int(size) > len(expected) {
+				expected = append(expected, make([]byte, int(size)-len(expected))...)
+			}
+			expected = expected[:size]
+		case 2:
+			off := rnd.Int63n(5 * ChunkSize)
+			p := make([]byte, rnd.Intn(2*ChunkSize)+1)
+			n, err := v.ReadAt(p, off)
+			var want []byte
+			if int(off) < len(expected) {
+				want = expected[off:]
+			}
+			if len(want) > len(p) {
+				want = want[:len(p)]
+			}
+			if !bytes.Equal(p[:n], want) || (n < len(p) && err != io.EOF) || (n == len(p) && err != nil) {
+				t.Fatalf("ReadAt(%d, %d): %d %v, expected %d bytes", len(p), off, n, err, len(want))
+			}
+		}
+		if c.Size() != int64(len(expected)) {
+			t.Fatalf("Unexpected size %d, expected %d", c.Size(), len(expected))
+		}
+	}
+}
+
+func TestChunksSparse(t *testing.T) {
+	c := &Chunks{}
+	v := NewChunkBuffer(c)
+	if n, err := v.Seek(10*ChunkSize, os.SEEK_SET); err != nil || n != 10*ChunkSize {
+		t.Fatalf("Seek beyond end: %d %v", n, err)
+	}
+	if _, err := v.Write([]byte(abc)); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	if err := v.Truncate(100 * ChunkSize); err != nil {
+		t.Fatalf("Truncate: %s", err)
+	}
+	allocated := 0
+	for _, chunk := range c.chunks {
+		allocated += cap(chunk)
+	}
+	if allocated > ChunkSize {
+		t.Errorf("Holes allocated: %d bytes", allocated)
+	}
+
+	p := make([]byte, 2*ChunkSize)
+	if n, err := v.ReadAt(p, 9*ChunkSize); err != nil || n != len(p) {
+		t.Fatalf("ReadAt: %d %v", n, err)
+	}
+	if !bytes.Equal(p[ChunkSize:ChunkSize+len(abc)], []byte(abc)) {
+		t.Errorf("Unexpected content: %q", p[ChunkSize:ChunkSize+len(abc)])
+	}
+	p[ChunkSize], p[ChunkSize+len(abc)-1] = 0, 0
+	if !bytes.Equal(p[:ChunkSize+1], make([]byte, ChunkSize+1)) || !bytes.Equal(p[ChunkSize+len(abc)-1:], make([]byte, ChunkSize-len(abc)+1)) {
+		t.Errorf("Holes do not read as zero bytes")
+	}
+}
+
+// TestChunksTruncateExtend checks that data cut off by a truncate reads as zero bytes after extending.
+func TestChunksTruncateExtend(t *testing.T) {
+	c := &Chunks{}
+	c.writeAt([]byte(dots), 0)
+	c.truncate(2)
+	c.writeAt([]byte("x"), 5)
+	p := make([]byte, 6)
+	if n := c.readAt(p, 0); n != 6 || string(p) != "1.\x00\x00\x00x" {
+		t.Errorf("Unexpected content: %q", p[:n])
+	}
+}
+
+func TestChunksShare(t *testing.T) {
+	c := &Chunks{}
+	c.writeAt(bytes.Repeat([]byte{'a'}, 3*ChunkSize), 0)
+	s := c.share()
+	c.writeAt([]byte("b"), ChunkSize)
+	s.truncate(ChunkSize + 1)
+
+	p := make([]byte, 3*ChunkSize)
+	if n := c.readAt(p, 0); n != 3*ChunkSize || p[ChunkSize] != 'b' || p[ChunkSize+1] != 'a' {
+		t.Errorf("Unexpected content of original: %d bytes", n)
+	}
+	if n := s.readAt(p, 0); n != ChunkSize+1 || p[ChunkSize] != 'a' {
+		t.Errorf("Unexpected content of copy: %d bytes", n)
+	}
+	// Only the modified chunk is copied
+	if &c.chunks[0][0] != &s.chunks[0][0] || &c.chunks[1][0] == &s.chunks[1][0] {
+		t.Errorf("Unexpected sharing of chunks")
+	}
+}
+
+func BenchmarkChunkBufWrite(b *testing.B) {
+	data := make([]byte, 32*1024)
+	b.SetBytes(int64(len(data)))
+	v := NewChunkBuffer(&Chunks{})
+	for i := 0; i < b.N; i++ {
+		v.Write(data)
+	}
+}
+
+func BenchmarkBufWrite(b *testing.B) {
+	data := make([]byte, 32*1024)
+	b.SetBytes(int64(len(data)))
+	var buf []byte
+	v := NewBuffer(&buf)
+	for i := 0; i < b.N; i++ {
+		v.Write(data)
+	}
+}
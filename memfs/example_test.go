@@ -1,6 +1,11 @@
 package memfs_test
 
 import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blang/vfs"
 	"github.com/blang/vfs/memfs"
 )
 
@@ -13,3 +18,41 @@ func ExampleMemFS() {
 	// The memory fs is completely empty, permissions are supported (e.g. Stat()) but have no effect.
 	fs.Mkdir("/tmp", 0777)
 }
+
+func ExampleMemFS_Snapshot() {
+	fs := memfs.Create()
+	fs.Mkdir("/fixtures", 0777)
+	snap := fs.Snapshot()
+
+	// Modify the filesystem, e.g. during a test case
+	fs.Mkdir("/tmp", 0777)
+
+	// Reset it to the seeded state
+	fs.Restore(snap)
+}
+
+func ExampleWithClock() {
+	// A fixed clock and owner make the metadata reproducible, e.g. for golden files
+	clock := func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	fs := memfs.Create(memfs.WithClock(clock), memfs.WithOwner(1000, 1000))
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+
+	fi, _ := fs.Stat("/file")
+	uid, gid, _ := vfs.FileOwner(fi)
+	fmt.Println(fi.ModTime(), uid, gid)
+	// Output:
+	// 2020-01-01 00:00:00 +0000 UTC 1000 1000
+}
+
+func ExampleWithPathSeparator() {
+	// Windows-style paths, e.g. to test path handling on Linux
+	fs := memfs.Create(memfs.WithPathSeparator('\\'))
+	vfs.MkdirAll(fs, `C:\Users\gopher`, 0755)
+	vfs.WriteFile(fs, vfs.Join(fs, `C:\Users`, "gopher", "notes.txt"), []byte("content"), 0644)
+
+	f, _ := fs.OpenFile("C:/Users/gopher/notes.txt", os.O_RDONLY, 0)
+	defer f.Close()
+	fmt.Println(f.Name())
+	// Output:
+	// \Users\gopher\notes.txt
+}
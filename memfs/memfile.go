@@ -1,7 +1,13 @@
 package memfs
 
 import (
+	"errors"
+	"os"
+	filepath "path"
 	"sync"
+	"time"
+
+	"github.com/blang/vfs"
 )
 
 // MemFile represents a file backed by a Buffer which is secured from concurrent access.
@@ -9,21 +15,62 @@ type MemFile struct {
 	Buffer
 	mutex *sync.RWMutex
 	name  string
+	info  os.FileInfo
+	// appendMode moves the offset to the end of the file before each write
+	appendMode bool
 }
 
+// errWriteAtInAppendMode is returned by WriteAt if the file is opened with os.O_APPEND.
+var errWriteAtInAppendMode = errors.New("WriteAt in append mode")
+
 // NewMemFile creates a Buffer which byte slice is safe from concurrent access,
 // the file itself is not thread-safe.
 //
 // This means multiple files can work safely on the same byte slice,
 // but multiple go routines working on the same file may corrupt the internal pointer structure.
+// Files of a MemFS store their content in Chunks instead.
 func NewMemFile(name string, rwMutex *sync.RWMutex, buf *[]byte) *MemFile {
 	return &MemFile{
 		Buffer: NewBuffer(buf),
 		mutex:  rwMutex,
 		name:   name,
+		info:   &bufInfo{name: filepath.Base(name), buf: buf, mutex: rwMutex},
 	}
 }
 
+// bufInfo describes a MemFile created by NewMemFile.
+type bufInfo struct {
+	name  string
+	buf   *[]byte
+	mutex *sync.RWMutex
+}
+
+func (fi bufInfo) Name() string {
+	return fi.name
+}
+
+func (fi bufInfo) Size() int64 {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	return int64(len(*fi.buf))
+}
+
+func (fi bufInfo) Mode() os.FileMode {
+	return 0
+}
+
+func (fi bufInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (fi bufInfo) IsDir() bool {
+	return false
+}
+
+func (fi bufInfo) Sys() interface{} {
+	return nil
+}
+
 // Name of the file
 func (b MemFile) Name() string {
 	return b.name
@@ -34,11 +81,34 @@ func (b MemFile) Sync() error {
 	return nil
 }
 
+// emit reports a change of the file to the watches of its filesystem.
+func (b MemFile) emit(op vfs.EventOp) {
+	if fi, ok := b.info.(*fileInfo); ok {
+		if fs, ok := fi.fs.(*MemFS); ok {
+			fs.emitFile(op, fi)
+		}
+	}
+}
+
+// Stat returns the FileInfo of the file.
+// The size of the file is reported at the time of the call.
+func (b MemFile) Stat() (os.FileInfo, error) {
+	return b.info, nil
+}
+
+// Readdir returns an error, a MemFile is never a directory.
+func (b MemFile) Readdir(n int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{"readdir", b.name, vfs.ErrNotDirectory}
+}
+
 // Truncate changes the size of the file
 func (b MemFile) Truncate(size int64) (err error) {
 	b.mutex.Lock()
 	err = b.Buffer.Truncate(size)
 	b.mutex.Unlock()
+	if err == nil {
+		b.emit(vfs.EventWrite)
+	}
 	return
 }
 
@@ -68,10 +138,36 @@ func (b *MemFile) ReadAt(p []byte, off int64) (n int, err error) {
 // Write writes len(p) byte to the Buffer.
 // It returns the number of bytes written and an error if any.
 // Write returns non-nil error when n!=len(p).
+// In append mode the data is always written to the end of the file.
 func (b *MemFile) Write(p []byte) (n int, err error) {
 	b.mutex.Lock()
-	n, err = b.Buffer.Write(p)
+	if b.appendMode {
+		_, err = b.Buffer.Seek(0, os.SEEK_END)
+	}
+	if err == nil {
+		n, err = b.Buffer.Write(p)
+	}
+	b.mutex.Unlock()
+	if n > 0 {
+		b.emit(vfs.EventWrite)
+	}
+	return
+}
+
+// WriteAt writes len(p) bytes to the Buffer starting at byte offset off.
+// It does not use or change the offset of the file and is safe for concurrent use.
+// WriteAt is not allowed in append mode.
+// See Buf.WriteAt()
+func (b *MemFile) WriteAt(p []byte, off int64) (n int, err error) {
+	if b.appendMode {
+		return 0, &os.PathError{"writeat", b.name, errWriteAtInAppendMode}
+	}
+	b.mutex.Lock()
+	n, err = b.Buffer.WriteAt(p, off)
 	b.mutex.Unlock()
+	if n > 0 {
+		b.emit(vfs.EventWrite)
+	}
 	return
 }
 
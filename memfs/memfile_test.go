@@ -1,10 +1,63 @@
 package memfs
 
 import (
-	"github.com/blang/vfs"
+	"io"
+	"sync"
 	"testing"
+
+	"github.com/blang/vfs"
 )
 
 func TestFileInterface(t *testing.T) {
 	_ = vfs.File(NewMemFile("", nil, nil))
+	_ = io.WriterAt(NewMemFile("", nil, nil))
+}
+
+func TestMemFileStat(t *testing.T) {
+	buf := []byte(dots)
+	f := NewMemFile("/dir/file", &sync.RWMutex{}, &buf)
+	fi, err := f.Stat()
+	if err != nil || fi.Name() != "file" || fi.Size() != int64(len(dots)) {
+		t.Errorf("Stat: %v %v", fi, err)
+	}
+}
+
+func TestConcurrentReadWriteAt(t *testing.T) {
+	buf := make([]byte, 0)
+	f := NewMemFile("", &sync.RWMutex{}, &buf)
+
+	const chunk = 16
+	const chunks = 32
+	var wg sync.WaitGroup
+	for i := 0; i < chunks; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			p := make([]byte, chunk)
+			for j := range p {
+				p[j] = byte(i)
+			}
+			if _, err := f.WriteAt(p, int64(i*chunk)); err != nil {
+				t.Errorf("Unexpected write error: %s", err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			f.ReadAt(make([]byte, chunk), int64(i*chunk))
+		}(i)
+	}
+	wg.Wait()
+
+	if n, err := f.Seek(0, io.SeekCurrent); err != nil || n != 0 {
+		t.Errorf("Unexpected offset: %d %s", n, err)
+	}
+	p := make([]byte, chunk*chunks)
+	if n, err := f.ReadAt(p, 0); err != nil || n != len(p) {
+		t.Fatalf("Unexpected read error: %d %s", n, err)
+	}
+	for i, b := range p {
+		if b != byte(i/chunk) {
+			t.Fatalf("Unexpected byte at %d: %d", i, b)
+		}
+	}
 }
@@ -3,10 +3,11 @@ package memfs
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	filepath "path"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/blang/vfs"
@@ -18,57 +19,237 @@ var (
 	// ErrWriteOnly is returned if the file is write-only and read operations are disabled.
 	ErrWriteOnly = errors.New("File is write-only")
 	// ErrIsDirectory is returned if the file under operation is not a regular file but a directory.
-	ErrIsDirectory = errors.New("Is directory")
+	ErrIsDirectory = vfs.ErrIsDirectory
+	// ErrNotEmpty is returned if a directory to be removed is not empty.
+	ErrNotEmpty = vfs.ErrNotEmpty
+	// ErrTooManyLinks is returned if too many symbolic links were encountered resolving a path.
+	ErrTooManyLinks = vfs.ErrTooManyLinks
 )
 
-// PathSeparator used to separate path segments
+// PathSeparator used to separate path segments by default
 const PathSeparator = "/"
 
 // MemFS is a in-memory filesystem
+//
+// Operations changing a single directory hold the read lock of the filesystem
+// and lock the mutex of the directory, so unrelated directories are modified in parallel.
+// Operations moving or removing subtrees and walking the whole tree hold the write lock.
+// Mutexes are locked from parent to child and never while waiting for the filesystem lock.
 type MemFS struct {
-	root *fileInfo
-	wd   *fileInfo
-	lock *sync.RWMutex
+	root    *fileInfo
+	wd      *fileInfo
+	lock    *sync.RWMutex
+	watches watches
+	// inodes is the last assigned inode number
+	inodes uint64
+	now    func() time.Time
+	uid    int
+	gid    int
+	sep    uint8
 }
 
-// Create a new MemFS filesystem which entirely resides in memory
-func Create() *MemFS {
-	root := &fileInfo{
-		name: "/",
-		dir:  true,
+// Option configures a MemFS on creation.
+type Option func(fs *MemFS)
+
+// WithClock sets the clock used for modification times, defaults to time.Now.
+// A fixed clock makes modification times reproducible, e.g. for golden files in tests.
+func WithClock(now func() time.Time) Option {
+	return func(fs *MemFS) {
+		fs.now = now
+	}
+}
+
+// WithOwner sets the uid and gid of new files, including the root directory.
+// Defaults to 0.
+func WithOwner(uid, gid int) Option {
+	return func(fs *MemFS) {
+		fs.uid, fs.gid = uid, gid
 	}
-	return &MemFS{
-		root: root,
-		wd:   root,
+}
+
+// WithPathSeparator sets the path separator, defaults to '/'.
+// With a backslash, paths look like the ones of Windows: slashes are accepted as separators as well
+// and a leading drive letter like "C:" is ignored, all drives share the same tree.
+func WithPathSeparator(sep uint8) Option {
+	return func(fs *MemFS) {
+		fs.sep = sep
+	}
+}
+
+// Create a new MemFS filesystem which entirely resides in memory.
+// The root directory has the mode 0755.
+func Create(opts ...Option) *MemFS {
+	fs := &MemFS{
 		lock: &sync.RWMutex{},
+		now:  time.Now,
+		sep:  PathSeparator[0],
+	}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	fs.root = &fileInfo{
+		name:   string(fs.sep),
+		dir:    true,
+		fs:     fs,
+		childs: make(map[string]*fileInfo),
+		inode:  fs.newInode(0755),
 	}
+	fs.wd = fs.root
+	return fs
 }
 
+// Reset removes all files and directories, leaving an empty filesystem.
+// The root directory is kept and reused with the mode, times, owner and extended attributes
+// of a new filesystem, the working directory is reset to root. All watches are stopped.
+// Open file handles stay usable but are detached from the filesystem.
+func (fs *MemFS) Reset() {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.root.mutex.Lock()
+	for name := range fs.root.childs {
+		delete(fs.root.childs, name)
+	}
+	fs.root.mode = 0755
+	fs.root.modTime = fs.now()
+	fs.root.uid, fs.root.gid = fs.uid, fs.gid
+	fs.root.nlink = 1
+	fs.root.xattrs = nil
+	fs.root.mutex.Unlock()
+	fs.wd = fs.root
+	fs.unwatchAll()
+}
+
+// fileInfo is an entry of a directory.
+// Hard links are entries sharing the same inode.
 type fileInfo struct {
-	name    string
-	dir     bool
+	name   string
+	dir    bool
+	parent *fileInfo
+	fs     vfs.Filesystem
+	childs map[string]*fileInfo
+	// link is the target of a symbolic link
+	link string
+	*inode
+}
+
+// inode holds the content and attributes of a file, which are shared by all its hard links.
+type inode struct {
+	ino     uint64
 	mode    os.FileMode
-	parent  *fileInfo
-	size    int64
 	modTime time.Time
-	fs      vfs.Filesystem
-	childs  map[string]*fileInfo
-	buf     *[]byte
-	mutex   *sync.RWMutex
+	// data is the content of regular files
+	data *Chunks
+	// mutex guards the attributes, the content of files and the entries of directories
+	mutex *sync.RWMutex
+	uid   int
+	gid   int
+	// nlink is the number of entries of a file, directories count their subdirectories instead.
+	// A removed directory has no links.
+	nlink int
+	// xattrs are the extended attributes
+	xattrs map[string][]byte
+}
+
+// newInode returns the inode of a new file with a single link.
+func (fs *MemFS) newInode(mode os.FileMode) *inode {
+	return &inode{
+		ino:     atomic.AddUint64(&fs.inodes, 1),
+		mode:    mode,
+		modTime: fs.now(),
+		mutex:   &sync.RWMutex{},
+		uid:     fs.uid,
+		gid:     fs.gid,
+		nlink:   1,
+	}
+}
+
+// child returns the entry of the directory fi with the given name.
+func (fi *fileInfo) child(name string) (*fileInfo, bool) {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	c, ok := fi.childs[name]
+	return c, ok
+}
+
+// add inserts the entry c into the directory fi.
+// If an entry with the same name exists, it is returned instead.
+// Entries can not be added to removed directories.
+func (fi *fileInfo) add(c *fileInfo) (*fileInfo, error) {
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	if fi.nlink == 0 {
+		return nil, os.ErrNotExist
+	}
+	if existing, ok := fi.childs[c.name]; ok {
+		return existing, nil
+	}
+	fi.childs[c.name] = c
+	return nil, nil
+}
+
+// remove deletes the entry c from the directory fi, directories must be empty.
+// It reports false if c is no longer an entry of fi.
+func (fi *fileInfo) remove(c *fileInfo) (bool, error) {
+	fi.mutex.Lock()
+	defer fi.mutex.Unlock()
+	if fi.childs[c.name] != c {
+		return false, nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.dir && len(c.childs) > 0 {
+		return true, ErrNotEmpty
+	}
+	delete(fi.childs, c.name)
+	c.nlink--
+	return true, nil
+}
+
+// SysInfo is returned by the Sys method of the FileInfos of a MemFS.
+type SysInfo struct {
+	// Filesystem the file belongs to
+	Filesystem vfs.Filesystem
+	// Ino is the inode number, which is equal for hard links of the same file
+	Ino uint64
+	// Nlink is the number of hard links to the file
+	Nlink int
+	Uid   int
+	Gid   int
 }
 
 func (fi fileInfo) Sys() interface{} {
-	return fi.fs
+	uid, gid := fi.Owner()
+	return SysInfo{Filesystem: fi.fs, Ino: fi.ino, Nlink: fi.Nlink(), Uid: uid, Gid: gid}
+}
+
+// Nlink returns the number of hard links to the file.
+// Directories are linked by their parent, themselves and their subdirectories.
+// It implements vfs.Linked.
+func (fi fileInfo) Nlink() int {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	if !fi.dir {
+		return fi.nlink
+	}
+	n := 2
+	for _, child := range fi.childs {
+		if child.dir {
+			n++
+		}
+	}
+	return n
 }
 
 func (fi fileInfo) Size() int64 {
 	if fi.dir {
 		return 0
 	}
+	if fi.link != "" {
+		return int64(len(fi.link))
+	}
 	fi.mutex.RLock()
-	l := len(*(fi.buf))
-	fi.mutex.RUnlock()
-	return int64(l)
+	defer fi.mutex.RUnlock()
+	return fi.data.Size()
 }
 
 func (fi fileInfo) IsDir() bool {
@@ -81,10 +262,17 @@ func (fi fileInfo) IsDir() bool {
 // 	- Rename
 // 	- Open (except with O_RDONLY)
 func (fi fileInfo) ModTime() time.Time {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
 	return fi.modTime
 }
 
 func (fi fileInfo) Mode() os.FileMode {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	if fi.dir {
+		return fi.mode | os.ModeDir
+	}
 	return fi.mode
 }
 
@@ -92,24 +280,73 @@ func (fi fileInfo) Name() string {
 	return fi.name
 }
 
+// Owner returns the numeric uid and gid of the file.
+// It implements vfs.Owned.
+func (fi fileInfo) Owner() (int, int) {
+	fi.mutex.RLock()
+	defer fi.mutex.RUnlock()
+	return fi.uid, fi.gid
+}
+
+// linkPath returns the absolute path of the symbolic link target.
+func (fi fileInfo) linkPath() string {
+	if vfs.IsAbs(fi.fs, fi.link) {
+		return fi.link
+	}
+	return vfs.Join(fi.fs, fi.parent.AbsPath(), fi.link)
+}
+
 func (fi fileInfo) AbsPath() string {
-	if fi.parent != nil {
-		return filepath.Join(fi.parent.AbsPath(), fi.name)
+	if fi.parent == nil {
+		return fi.name
+	}
+	sep := string(fi.fs.PathSeparator())
+	if fi.parent.parent == nil {
+		return sep + fi.name
 	}
-	return "/"
+	return fi.parent.AbsPath() + sep + fi.name
 }
 
 // PathSeparator returns the path separator
 func (fs *MemFS) PathSeparator() uint8 {
-	return '/'
+	return fs.sep
 }
 
-// Mkdir creates a new directory with given permissions
-func (fs *MemFS) Mkdir(name string, perm os.FileMode) error {
+// Chdir changes the working directory to the named directory, following symbolic links.
+// Relative paths are resolved against the path of the working directory, which follows
+// renames of the directory. It implements vfs.Chdirer.
+func (fs *MemFS) Chdir(dir string) error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
-	name = filepath.Clean(name)
-	base := filepath.Base(name)
+
+	_, fi, err := fs.fileInfoFollow(dir)
+	if err == nil && fi == nil {
+		err = os.ErrNotExist
+	}
+	if err == nil && !fi.dir {
+		err = vfs.ErrNotDirectory
+	}
+	if err != nil {
+		return &os.PathError{Op: "chdir", Path: dir, Err: err}
+	}
+	fs.wd = fi
+	return nil
+}
+
+// Getwd returns the absolute path of the working directory.
+// It implements vfs.Chdirer.
+func (fs *MemFS) Getwd() (string, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	return fs.wd.AbsPath(), nil
+}
+
+// Mkdir creates a new directory with given permissions
+func (fs *MemFS) Mkdir(name string, perm os.FileMode) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	name = vfs.Clean(fs, name)
+	base := vfs.Base(fs, name)
 	parent, fi, err := fs.fileInfo(name)
 	if err != nil {
 		return &os.PathError{"mkdir", name, err}
@@ -119,14 +356,21 @@ func (fs *MemFS) Mkdir(name string, perm os.FileMode) error {
 	}
 
 	fi = &fileInfo{
-		name:    base,
-		dir:     true,
-		mode:    perm,
-		parent:  parent,
-		modTime: time.Now(),
-		fs:      fs,
-	}
-	parent.childs[base] = fi
+		name:   base,
+		dir:    true,
+		parent: parent,
+		fs:     fs,
+		childs: make(map[string]*fileInfo),
+		inode:  fs.newInode(perm),
+	}
+	existing, err := parent.add(fi)
+	if err != nil {
+		return &os.PathError{"mkdir", name, err}
+	}
+	if existing != nil {
+		return &os.PathError{"mkdir", name, fmt.Errorf("Directory %q already exists", name)}
+	}
+	fs.emit(vfs.EventCreate, fi.AbsPath())
 	return nil
 }
 
@@ -147,67 +391,166 @@ func (fs *MemFS) ReadDir(path string) ([]os.FileInfo, error) {
 	fs.lock.RLock()
 	defer fs.lock.RUnlock()
 
-	path = filepath.Clean(path)
-	_, fi, err := fs.fileInfo(path)
+	path = vfs.Clean(fs, path)
+	_, fi, err := fs.fileInfoFollow(path)
 	if err != nil {
 		return nil, &os.PathError{"readdir", path, err}
 	}
-	if fi == nil || !fi.dir {
+	if fi == nil {
+		return nil, &os.PathError{"readdir", path, os.ErrNotExist}
+	}
+	if !fi.dir {
 		return nil, &os.PathError{"readdir", path, vfs.ErrNotDirectory}
 	}
 
+	fi.mutex.RLock()
 	fis := make([]os.FileInfo, 0, len(fi.childs))
 	for _, e := range fi.childs {
 		fis = append(fis, e)
 	}
+	fi.mutex.RUnlock()
 	sort.Sort(byName(fis))
 	return fis, nil
 }
 
-func (fs *MemFS) fileInfo(path string) (parent *fileInfo, node *fileInfo, err error) {
-	path = filepath.Clean(path)
-	segments := vfs.SplitPath(path, PathSeparator)
+// OpenDir opens the named directory for iteration in lexical order.
+// The names of the entries are read on open, their FileInfos are looked up in batches by Next,
+// entries removed in the meantime are skipped.
+// It implements vfs.DirOpener.
+func (fs *MemFS) OpenDir(path string) (vfs.DirIterator, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
 
-	// Shortcut for working directory and root
-	if len(segments) == 1 {
-		if segments[0] == "" {
-			return nil, fs.root, nil
-		} else if segments[0] == "." {
-			return fs.wd.parent, fs.wd, nil
+	path = vfs.Clean(fs, path)
+	_, fi, err := fs.fileInfoFollow(path)
+	if err == nil && fi == nil {
+		err = os.ErrNotExist
+	}
+	if err == nil && !fi.dir {
+		err = vfs.ErrNotDirectory
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "opendir", Path: path, Err: err}
+	}
+
+	fi.mutex.RLock()
+	names := make([]string, 0, len(fi.childs))
+	for name := range fi.childs {
+		names = append(names, name)
+	}
+	fi.mutex.RUnlock()
+	sort.Strings(names)
+	return &dirIterator{node: fi, names: names}, nil
+}
+
+// dirIterator iterates the entries of a directory.
+type dirIterator struct {
+	node  *fileInfo
+	names []string
+}
+
+// Next returns the FileInfos of up to n remaining entries.
+func (d *dirIterator) Next(n int) ([]os.FileInfo, error) {
+	if n <= 0 {
+		n = len(d.names)
+	} else if len(d.names) == 0 {
+		return nil, io.EOF
+	}
+	fis := make([]os.FileInfo, 0, n)
+	d.node.mutex.RLock()
+	for len(fis) < n && len(d.names) > 0 {
+		if fi, ok := d.node.childs[d.names[0]]; ok {
+			fis = append(fis, fi)
 		}
+		d.names = d.names[1:]
+	}
+	d.node.mutex.RUnlock()
+	if len(fis) == 0 && n > 0 {
+		return nil, io.EOF
 	}
+	return fis, nil
+}
 
-	// Determine root to traverse
-	parent = fs.root
-	if segments[0] == "." {
-		parent = fs.wd
+// Close releases the remaining names.
+func (d *dirIterator) Close() error {
+	d.names = nil
+	return nil
+}
+
+// maxLinkDepth limits the number of symbolic links followed during path resolution.
+const maxLinkDepth = 40
+
+// fileInfo resolves the given path without following a symbolic link in the last segment.
+// Symbolic links in the directory part of the path are followed.
+// If the last segment does not exist, the parent directory and a nil node are returned.
+func (fs *MemFS) fileInfo(path string) (parent *fileInfo, node *fileInfo, err error) {
+	return fs.resolve(path, false, 0)
+}
+
+// fileInfoFollow resolves the given path like fileInfo but also follows
+// a symbolic link in the last segment.
+func (fs *MemFS) fileInfoFollow(path string) (parent *fileInfo, node *fileInfo, err error) {
+	return fs.resolve(path, true, 0)
+}
+
+// abs returns the cleaned absolute path, relative paths are resolved against the working directory.
+// The caller must hold the lock.
+func (fs *MemFS) abs(path string) string {
+	if vfs.IsAbs(fs, path) {
+		return vfs.Clean(fs, path)
 	}
+	return vfs.Join(fs, fs.wd.AbsPath(), path[len(vfs.VolumeName(fs, path)):])
+}
+
+// split returns the segments of the absolute path without its volume name.
+func (fs *MemFS) split(path string) []string {
+	path = fs.abs(path)
+	return vfs.SplitPath(path[len(vfs.VolumeName(fs, path)):], string(fs.sep))
+}
+
+func (fs *MemFS) resolve(path string, follow bool, depth int) (parent *fileInfo, node *fileInfo, err error) {
+	if depth > maxLinkDepth {
+		return nil, nil, ErrTooManyLinks
+	}
+	segments := fs.split(path)
+
+	// Shortcut for root
+	if len(segments) == 1 {
+		return nil, fs.root, nil
+	}
+	parent = fs.root
 	segments = segments[1:]
 
 	// Further directories
 	if len(segments) > 1 {
 		for _, seg := range segments[:len(segments)-1] {
-
-			if parent.childs == nil {
+			entry, ok := parent.child(seg)
+			if !ok {
 				return nil, nil, os.ErrNotExist
 			}
-			if entry, ok := parent.childs[seg]; ok && entry.dir {
-				parent = entry
-			} else {
+			if entry.link != "" {
+				_, entry, err = fs.resolve(entry.linkPath(), true, depth+1)
+				if err != nil {
+					return nil, nil, err
+				}
+				if entry == nil {
+					return nil, nil, os.ErrNotExist
+				}
+			}
+			if !entry.dir {
 				return nil, nil, os.ErrNotExist
 			}
+			parent = entry
 		}
 	}
 
 	lastSeg := segments[len(segments)-1]
-	if parent.childs != nil {
-		if node, ok := parent.childs[lastSeg]; ok {
-			return parent, node, nil
+	if node, ok := parent.child(lastSeg); ok {
+		if follow && node.link != "" {
+			return fs.resolve(node.linkPath(), true, depth+1)
 		}
-	} else {
-		parent.childs = make(map[string]*fileInfo)
+		return parent, node, nil
 	}
-
 	return parent, nil, nil
 }
 
@@ -218,54 +561,98 @@ func hasFlag(flag int, flags int) bool {
 // OpenFile opens a file handle with a specified flag (os.O_RDONLY etc.) and perm (e.g. 0666).
 // If success the returned File can be used for I/O. Otherwise an error is returned, which
 // is a *os.PathError and can be extracted for further information.
+// Directories can only be opened for reading and listed using Readdir.
 func (fs *MemFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
-	fs.lock.Lock()
-	defer fs.lock.Unlock()
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
 
-	name = filepath.Clean(name)
-	base := filepath.Base(name)
-	fiParent, fiNode, err := fs.fileInfo(name)
+	name = vfs.Clean(fs, name)
+	fiNode, created, err := fs.openNode(name, flag, perm)
 	if err != nil {
 		return nil, &os.PathError{"open", name, err}
 	}
-
-	if fiNode == nil {
-		if !hasFlag(os.O_CREATE, flag) {
-			return nil, &os.PathError{"open", name, os.ErrNotExist}
-		}
-		fiNode = &fileInfo{
-			name:    base,
-			dir:     false,
-			mode:    perm,
-			parent:  fiParent,
-			modTime: time.Now(),
-			fs:      fs,
-		}
-		fiParent.childs[base] = fiNode
+	if created {
+		fs.emit(vfs.EventCreate, fiNode.AbsPath())
 	} else { // file exists
 		if hasFlag(os.O_CREATE|os.O_EXCL, flag) {
 			return nil, &os.PathError{"open", name, os.ErrExist}
 		}
 		if fiNode.dir {
-			return nil, &os.PathError{"open", name, ErrIsDirectory}
+			if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+				return nil, &os.PathError{"open", name, ErrIsDirectory}
+			}
+			return &dirFile{fs: fs, node: fiNode, name: fiNode.AbsPath()}, nil
+		}
+		if hasFlag(os.O_TRUNC, flag) {
+			fs.emit(vfs.EventWrite, fiNode.AbsPath())
 		}
 	}
 
-	if !hasFlag(os.O_RDONLY, flag) {
-		fiNode.modTime = time.Now()
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		fiNode.mutex.Lock()
+		fiNode.modTime = fs.now()
+		fiNode.mutex.Unlock()
 	}
 	return fiNode.file(flag)
 }
 
+// openNode resolves name following symbolic links.
+// If os.O_CREATE is set, a missing file or the missing target of a dangling link is created,
+// it reports whether the file was created.
+func (fs *MemFS) openNode(name string, flag int, perm os.FileMode) (*fileInfo, bool, error) {
+	for {
+		target := name
+		fiParent, fiNode, err := fs.fileInfo(target)
+		for depth := 0; err == nil && fiNode != nil && fiNode.link != ""; depth++ {
+			if depth == maxLinkDepth {
+				err = ErrTooManyLinks
+				break
+			}
+			target = fiNode.linkPath()
+			fiParent, fiNode, err = fs.fileInfo(target)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if fiNode != nil {
+			return fiNode, false, nil
+		}
+		if !hasFlag(os.O_CREATE, flag) {
+			return nil, false, os.ErrNotExist
+		}
+
+		fiNode = &fileInfo{
+			name:   vfs.Base(fs, target),
+			parent: fiParent,
+			fs:     fs,
+			inode:  fs.newInode(perm),
+		}
+		fiNode.data = &Chunks{}
+		existing, err := fiParent.add(fiNode)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return fiNode, true, nil
+		}
+		// Created concurrently, resolve the new entry
+	}
+}
+
+// file returns a new handle of the file with its own offset.
+// Truncation is visible to all open handles of the file.
 func (fi *fileInfo) file(flag int) (vfs.File, error) {
-	if fi.buf == nil || hasFlag(os.O_TRUNC, flag) {
-		buf := make([]byte, 0, MinBufferSize)
-		fi.buf = &buf
-		fi.mutex = &sync.RWMutex{}
+	if hasFlag(os.O_TRUNC, flag) {
+		fi.mutex.Lock()
+		fi.data.truncate(0)
+		fi.mutex.Unlock()
 	}
-	var f vfs.File = NewMemFile(fi.AbsPath(), fi.mutex, fi.buf)
-	if hasFlag(os.O_APPEND, flag) {
-		f.Seek(0, os.SEEK_END)
+	var f vfs.File = &MemFile{
+		Buffer:     NewChunkBuffer(fi.data),
+		mutex:      fi.mutex,
+		name:       fi.AbsPath(),
+		info:       fi,
+		appendMode: hasFlag(os.O_APPEND, flag),
 	}
 	if hasFlag(os.O_RDWR, flag) {
 		return f, nil
@@ -278,6 +665,98 @@ func (fi *fileInfo) file(flag int) (vfs.File, error) {
 	return f, nil
 }
 
+// dirFile is an open directory, which can only be listed.
+type dirFile struct {
+	fs      *MemFS
+	node    *fileInfo
+	name    string
+	entries []os.FileInfo
+	read    bool
+}
+
+// Name returns the absolute path of the directory
+func (d *dirFile) Name() string {
+	return d.name
+}
+
+// Sync has no effect
+func (d *dirFile) Sync() error {
+	return nil
+}
+
+// Stat returns the FileInfo of the directory
+func (d *dirFile) Stat() (os.FileInfo, error) {
+	return d.node, nil
+}
+
+// Readdir reads the sorted entries of the directory on the first call
+// and returns them in chunks of n afterwards. If n <= 0 all remaining entries are returned.
+// Entries created or removed after the first call are not reflected.
+func (d *dirFile) Readdir(n int) ([]os.FileInfo, error) {
+	if !d.read {
+		d.fs.lock.RLock()
+		d.node.mutex.RLock()
+		d.entries = make([]os.FileInfo, 0, len(d.node.childs))
+		for _, e := range d.node.childs {
+			d.entries = append(d.entries, e)
+		}
+		d.node.mutex.RUnlock()
+		d.fs.lock.RUnlock()
+		sort.Sort(byName(d.entries))
+		d.read = true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// Truncate is disabled and returns ErrIsDirectory
+func (d *dirFile) Truncate(size int64) error {
+	return &os.PathError{"truncate", d.name, ErrIsDirectory}
+}
+
+// Read is disabled and returns ErrIsDirectory
+func (d *dirFile) Read(p []byte) (int, error) {
+	return 0, &os.PathError{"read", d.name, ErrIsDirectory}
+}
+
+// ReadAt is disabled and returns ErrIsDirectory
+func (d *dirFile) ReadAt(p []byte, off int64) (int, error) {
+	return 0, &os.PathError{"read", d.name, ErrIsDirectory}
+}
+
+// Write is disabled and returns ErrIsDirectory
+func (d *dirFile) Write(p []byte) (int, error) {
+	return 0, &os.PathError{"write", d.name, ErrIsDirectory}
+}
+
+// Seek to the start of the directory restarts the listing,
+// other offsets return ErrIsDirectory.
+func (d *dirFile) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != os.SEEK_SET {
+		return 0, &os.PathError{"seek", d.name, ErrIsDirectory}
+	}
+	d.entries = nil
+	d.read = false
+	return 0, nil
+}
+
+// Close has no effect
+func (d *dirFile) Close() error {
+	return nil
+}
+
 // roFile wraps the given file and disables Write(..) operation.
 type roFile struct {
 	vfs.File
@@ -288,6 +767,11 @@ func (f *roFile) Write(p []byte) (n int, err error) {
 	return 0, ErrReadOnly
 }
 
+// WriteAt is disabled and returns ErrorReadOnly
+func (f *roFile) WriteAt(p []byte, off int64) (n int, err error) {
+	return 0, ErrReadOnly
+}
+
 // woFile wraps the given file and disables Read(..) operation.
 type woFile struct {
 	vfs.File
@@ -298,33 +782,144 @@ func (f *woFile) Read(p []byte) (n int, err error) {
 	return 0, ErrWriteOnly
 }
 
+// ReadAt is disabled and returns ErrorWroteOnly
+func (f *woFile) ReadAt(p []byte, off int64) (n int, err error) {
+	return 0, ErrWriteOnly
+}
+
+// WriteAt writes len(p) bytes starting at byte offset off, see MemFile.WriteAt
+func (f *woFile) WriteAt(p []byte, off int64) (n int, err error) {
+	return f.File.(io.WriterAt).WriteAt(p, off)
+}
+
 // Remove removes the named file or directory.
 // If there is an error, it will be of type *PathError.
 func (fs *MemFS) Remove(name string) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = vfs.Clean(fs, name)
+	for {
+		fiParent, fiNode, err := fs.fileInfo(name)
+		if err != nil {
+			return &os.PathError{"remove", name, err}
+		}
+		if fiNode == nil {
+			return &os.PathError{"remove", name, os.ErrNotExist}
+		}
+		if fiParent == nil {
+			return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+		}
+		removed, err := fiParent.remove(fiNode)
+		if err != nil {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+		if removed {
+			fs.emit(vfs.EventRemove, fiNode.AbsPath())
+			return nil
+		}
+		// Replaced concurrently, resolve the new entry
+	}
+}
+
+// RemoveAll removes path and any children it contains.
+// If the path does not exist, RemoveAll returns nil.
+// It implements vfs.RemoveAller.
+func (fs *MemFS) RemoveAll(path string) error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
 
-	name = filepath.Clean(name)
-	fiParent, fiNode, err := fs.fileInfo(name)
-	if err != nil {
-		return &os.PathError{"remove", name, err}
+	path = vfs.Clean(fs, path)
+	fiParent, fiNode, err := fs.fileInfo(path)
+	if err != nil || fiNode == nil {
+		// A missing parent means path does not exist either
+		return nil
 	}
-	if fiNode == nil {
-		return &os.PathError{"remove", name, os.ErrNotExist}
+	if fiParent == nil {
+		return &os.PathError{Op: "removeall", Path: path, Err: os.ErrPermission}
 	}
-
+	if fs.watched() {
+		fs.emitTree(vfs.EventRemove, fiNode, fiNode.AbsPath())
+	}
+	fiParent.mutex.Lock()
 	delete(fiParent.childs, fiNode.name)
+	fiParent.mutex.Unlock()
+	fiNode.unlinkTree()
+	return nil
+}
+
+// unlinkTree decrements the link counts of the files of the removed tree rooted at fi.
+// The caller must hold the write lock of the filesystem.
+func (fi *fileInfo) unlinkTree() {
+	fi.mutex.Lock()
+	fi.nlink--
+	fi.mutex.Unlock()
+	for _, child := range fi.childs {
+		child.unlinkTree()
+	}
+}
+
+// MkdirAll creates a directory named path, along with any necessary parents.
+// The permission bits perm are used for all directories that MkdirAll creates.
+// If path is already a directory, MkdirAll does nothing and returns nil.
+// It implements vfs.MkdirAller.
+func (fs *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	path = vfs.Clean(fs, path)
+	segments := fs.split(path)
+	parent := fs.root
+	current := segments[0]
+	for i := 1; i < len(segments); i++ {
+		next := current + string(fs.sep) + segments[i]
+		_, fi, err := fs.fileInfo(next)
+		if err == nil && fi != nil && fi.link != "" {
+			// Dangling links are not replaced by directories
+			if _, fi, err = fs.fileInfoFollow(next); err == nil && fi == nil {
+				err = os.ErrNotExist
+			}
+		}
+		if err != nil {
+			return &os.PathError{Op: "mkdir", Path: path, Err: err}
+		}
+		if fi == nil {
+			fi = &fileInfo{
+				name:   segments[i],
+				dir:    true,
+				parent: parent,
+				fs:     fs,
+				childs: make(map[string]*fileInfo),
+				inode:  fs.newInode(perm),
+			}
+			existing, err := parent.add(fi)
+			if err != nil {
+				return &os.PathError{Op: "mkdir", Path: path, Err: err}
+			}
+			if existing != nil {
+				// Created concurrently, resolve the segment again
+				i--
+				continue
+			}
+			fs.emit(vfs.EventCreate, fi.AbsPath())
+		} else if !fi.dir {
+			return &os.PathError{Op: "mkdir", Path: next, Err: vfs.ErrNotDirectory}
+		}
+		current = next
+		parent = fi
+	}
 	return nil
 }
 
 // Rename renames (moves) a file.
+// An existing file at newpath is replaced, an existing directory only if oldpath is a directory and newpath is empty.
 // Handles to the oldpath persist but might return oldpath if Name() is called.
 func (fs *MemFS) Rename(oldpath, newpath string) error {
 	fs.lock.Lock()
 	defer fs.lock.Unlock()
 
 	// OldPath
-	oldpath = filepath.Clean(oldpath)
+	oldpath = vfs.Clean(fs, oldpath)
 	fiOldParent, fiOld, err := fs.fileInfo(oldpath)
 	if err != nil {
 		return &os.PathError{"rename", oldpath, err}
@@ -333,24 +928,106 @@ func (fs *MemFS) Rename(oldpath, newpath string) error {
 		return &os.PathError{"rename", oldpath, os.ErrNotExist}
 	}
 
-	newpath = filepath.Clean(newpath)
+	newpath = vfs.Clean(fs, newpath)
 	fiNewParent, fiNew, err := fs.fileInfo(newpath)
 	if err != nil {
 		return &os.PathError{"rename", newpath, err}
 	}
 
+	if fiNew != nil && fiNew.inode == fiOld.inode {
+		// Renaming a file onto itself or one of its hard links does nothing
+		return nil
+	}
+	if fiOldParent == nil || fiNewParent == nil {
+		// The root can neither be moved nor replaced
+		return &os.PathError{Op: "rename", Path: newpath, Err: os.ErrInvalid}
+	}
+	// A directory can not be moved into itself
+	for p := fiNewParent; p != nil; p = p.parent {
+		if p == fiOld {
+			return &os.PathError{"rename", newpath, os.ErrInvalid}
+		}
+	}
 	if fiNew != nil {
-		return &os.PathError{"rename", newpath, os.ErrExist}
+		switch {
+		case fiOld.dir && !fiNew.dir:
+			return &os.PathError{Op: "rename", Path: newpath, Err: vfs.ErrNotDirectory}
+		case !fiOld.dir && fiNew.dir:
+			return &os.PathError{Op: "rename", Path: newpath, Err: ErrIsDirectory}
+		}
+		if _, err := fiNewParent.remove(fiNew); err != nil {
+			return &os.PathError{Op: "rename", Path: newpath, Err: err}
+		}
+		fs.emit(vfs.EventRemove, fiNew.AbsPath())
 	}
 
-	newBase := filepath.Base(newpath)
+	newBase := vfs.Base(fs, newpath)
 
 	// Relink
+	fs.emit(vfs.EventRename, fiOld.AbsPath())
+	fiOldParent.mutex.Lock()
 	delete(fiOldParent.childs, fiOld.name)
+	fiOldParent.mutex.Unlock()
+	fiOld.mutex.Lock()
 	fiOld.parent = fiNewParent
 	fiOld.name = newBase
-	fiOld.modTime = time.Now()
+	fiOld.modTime = fs.now()
+	fiOld.mutex.Unlock()
+	fiNewParent.mutex.Lock()
 	fiNewParent.childs[fiOld.name] = fiOld
+	fiNewParent.mutex.Unlock()
+	fs.emit(vfs.EventCreate, fiOld.AbsPath())
+	return nil
+}
+
+// CopyFile copies the regular file src to dst without streaming its content
+// through a File handle, the content is shared until either file is modified.
+// Like opening dst with os.O_TRUNC, symbolic links are followed and an existing file
+// keeps its inode, so hard links and open handles see the new content.
+// The mode of src is applied to dst.
+// It implements vfs.Copier.
+func (fs *MemFS) CopyFile(dst, src string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	src = vfs.Clean(fs, src)
+	_, fiSrc, err := fs.fileInfoFollow(src)
+	if err != nil {
+		return &os.PathError{Op: "copy", Path: src, Err: err}
+	}
+	if fiSrc == nil {
+		return &os.PathError{Op: "copy", Path: src, Err: os.ErrNotExist}
+	}
+	if fiSrc.dir {
+		return &os.PathError{Op: "copy", Path: src, Err: ErrIsDirectory}
+	}
+	fiSrc.mutex.Lock()
+	mode := fiSrc.mode & chmodBits
+	data := fiSrc.data.share()
+	fiSrc.mutex.Unlock()
+
+	dst = vfs.Clean(fs, dst)
+	fiDst, created, err := fs.openNode(dst, os.O_CREATE, mode)
+	if err != nil {
+		return &os.PathError{Op: "copy", Path: dst, Err: err}
+	}
+	if fiDst.dir {
+		return &os.PathError{Op: "copy", Path: dst, Err: ErrIsDirectory}
+	}
+	if fiDst.inode == fiSrc.inode {
+		return nil
+	}
+
+	fiDst.mutex.Lock()
+	*fiDst.data = *data
+	fiDst.mode = fiDst.mode&^chmodBits | mode
+	fiDst.modTime = fs.now()
+	fiDst.mutex.Unlock()
+	if created {
+		fs.emit(vfs.EventCreate, fiDst.AbsPath())
+	} else {
+		fs.emit(vfs.EventWrite, fiDst.AbsPath())
+	}
 	return nil
 }
 
@@ -360,21 +1037,201 @@ func (fs *MemFS) Stat(name string) (os.FileInfo, error) {
 	fs.lock.RLock()
 	defer fs.lock.RUnlock()
 
-	name = filepath.Clean(name)
-	// dir, base := filepath.Split(name)
-	_, fi, err := fs.fileInfo(name)
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfoFollow(name)
 	if err != nil {
 		return nil, &os.PathError{"stat", name, err}
 	}
 	if fi == nil {
 		return nil, &os.PathError{"stat", name, os.ErrNotExist}
 	}
+	// A followed symbolic link keeps its own name
+	if base := vfs.Base(fs, name); fi.parent != nil && base != fi.name && base != "." {
+		return &linkedInfo{fileInfo: fi, name: base}, nil
+	}
 	return fi, nil
 }
 
+// linkedInfo describes the target of a symbolic link under the name of the link.
+type linkedInfo struct {
+	*fileInfo
+	name string
+}
+
+func (fi linkedInfo) Name() string {
+	return fi.name
+}
+
 // Lstat returns a FileInfo describing the named file.
-// MemFS does not support symbolic links.
-// Alias for fs.Stat(name)
+// If the file is a symbolic link, the returned FileInfo describes the link itself.
 func (fs *MemFS) Lstat(name string) (os.FileInfo, error) {
-	return fs.Stat(name)
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfo(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	if fi == nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return fi, nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+// Relative targets are resolved relative to the directory of the link.
+// It implements vfs.Symlinker.
+func (fs *MemFS) Symlink(oldname, newname string) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	newname = vfs.Clean(fs, newname)
+	parent, fi, err := fs.fileInfo(newname)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	if fi != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	if oldname == "" {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrInvalid}
+	}
+
+	fi = &fileInfo{
+		name:   vfs.Base(fs, newname),
+		parent: parent,
+		fs:     fs,
+		link:   oldname,
+		inode:  fs.newInode(os.ModeSymlink | 0777),
+	}
+	existing, err := parent.add(fi)
+	if err == nil && existing != nil {
+		err = os.ErrExist
+	}
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	fs.emit(vfs.EventCreate, fi.AbsPath())
+	return nil
+}
+
+// Readlink returns the destination of the named symbolic link.
+// It implements vfs.Symlinker.
+func (fs *MemFS) Readlink(name string) (string, error) {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfo(name)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if fi == nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrNotExist}
+	}
+	if fi.link == "" {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return fi.link, nil
+}
+
+// Link creates newname as a hard link to the file oldname.
+// Both names share the content and attributes of the file,
+// a symbolic link oldname is not followed. Directories can not be linked.
+// It implements vfs.Linker.
+func (fs *MemFS) Link(oldname, newname string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	oldname = vfs.Clean(fs, oldname)
+	_, fiOld, err := fs.fileInfo(oldname)
+	if err == nil && fiOld == nil {
+		err = os.ErrNotExist
+	}
+	if err == nil && fiOld.dir {
+		err = ErrIsDirectory
+	}
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+
+	newname = vfs.Clean(fs, newname)
+	parent, fi, err := fs.fileInfo(newname)
+	if err == nil && fi != nil {
+		err = os.ErrExist
+	}
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+
+	fiOld.mutex.Lock()
+	fiOld.nlink++
+	fiOld.mutex.Unlock()
+	fi = &fileInfo{
+		name:   vfs.Base(fs, newname),
+		parent: parent,
+		fs:     fs,
+		link:   fiOld.link,
+		inode:  fiOld.inode,
+	}
+	parent.mutex.Lock()
+	parent.childs[fi.name] = fi
+	parent.mutex.Unlock()
+	fs.emit(vfs.EventCreate, fi.AbsPath())
+	return nil
+}
+
+// chmodBits are the mode bits changed by Chmod.
+const chmodBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Chmod changes the mode of the named file, following symbolic links.
+// It implements vfs.Attributer.
+func (fs *MemFS) Chmod(name string, mode os.FileMode) error {
+	return fs.setAttr("chmod", name, func(fi *fileInfo) {
+		fi.mode = fi.mode&^chmodBits | mode&chmodBits
+	})
+}
+
+// Chown changes the numeric uid and gid of the named file, following symbolic links.
+// A uid or gid of -1 keeps the current value.
+// It implements vfs.Attributer.
+func (fs *MemFS) Chown(name string, uid, gid int) error {
+	return fs.setAttr("chown", name, func(fi *fileInfo) {
+		if uid != -1 {
+			fi.uid = uid
+		}
+		if gid != -1 {
+			fi.gid = gid
+		}
+	})
+}
+
+// Chtimes changes the modification time of the named file, following symbolic links.
+// The access time is not recorded.
+// It implements vfs.Attributer.
+func (fs *MemFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return fs.setAttr("chtimes", name, func(fi *fileInfo) {
+		fi.modTime = mtime
+	})
+}
+
+// setAttr applies set to the named file.
+func (fs *MemFS) setAttr(op, name string, set func(fi *fileInfo)) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfoFollow(name)
+	if err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if fi == nil {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	fi.mutex.Lock()
+	set(fi)
+	fi.mutex.Unlock()
+	fs.emit(vfs.EventChmod, fi.AbsPath())
+	return nil
 }
@@ -1,8 +1,13 @@
 package memfs
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +16,7 @@ import (
 
 func TestInterface(t *testing.T) {
 	_ = vfs.Filesystem(Create())
+	_ = vfs.DirOpener(Create())
 }
 
 func TestCreate(t *testing.T) {
@@ -173,7 +179,7 @@ func TestReadDir(t *testing.T) {
 	}
 
 	// Readdir non existing directory
-	if _, err := fs.ReadDir("/usr"); err == nil {
+	if _, err := fs.ReadDir("/usr"); !os.IsNotExist(err) {
 		t.Errorf("Expected error readdir(nofound)")
 	}
 
@@ -276,6 +282,56 @@ func TestReadWrite(t *testing.T) {
 	}
 }
 
+func TestReadWriteAt(t *testing.T) {
+	fs := Create()
+	f, err := fs.OpenFile("/readme.txt", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	w, ok := f.(io.WriterAt)
+	if !ok {
+		t.Fatalf("File does not implement io.WriterAt")
+	}
+	if n, err := w.WriteAt([]byte(abc), int64(len(dots))); err != nil || n != len(abc) {
+		t.Fatalf("Unexpected write error: %d %s", n, err)
+	}
+	if n, err := w.WriteAt([]byte(dots), 0); err != nil || n != len(dots) {
+		t.Fatalf("Unexpected write error: %d %s", n, err)
+	}
+
+	// Shared offset is untouched
+	if n, err := f.Seek(0, os.SEEK_CUR); err != nil || n != 0 {
+		t.Errorf("Unexpected offset: %d %s", n, err)
+	}
+
+	p := make([]byte, len(abc))
+	if n, err := f.ReadAt(p, int64(len(dots))); err != nil || string(p[:n]) != abc {
+		t.Errorf("Invalid read: %d %s %s", n, err, string(p[:n]))
+	}
+	f.Close()
+
+	ro, err := fs.OpenFile("/readme.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	if _, err := ro.(io.WriterAt).WriteAt([]byte(abc), 0); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got: %v", err)
+	}
+	ro.Close()
+
+	wo, err := fs.OpenFile("/readme.txt", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	if _, err := wo.ReadAt(p, 0); err != ErrWriteOnly {
+		t.Errorf("Expected ErrWriteOnly, got: %v", err)
+	}
+	if _, err := wo.(io.WriterAt).WriteAt([]byte("x"), 0); err != nil {
+		t.Errorf("Unexpected write error: %s", err)
+	}
+	wo.Close()
+}
+
 func TestOpenRO(t *testing.T) {
 	fs := Create()
 	f, err := fs.OpenFile("/readme.txt", os.O_CREATE|os.O_RDONLY, 0666)
@@ -360,6 +416,110 @@ func TestOpenAppend(t *testing.T) {
 	f.Close()
 }
 
+func TestAppendIgnoresSeek(t *testing.T) {
+	fs := Create()
+	if _, err := writeFile(fs, "/readme.txt", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fs.OpenFile("/readme.txt", os.O_APPEND|os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	defer f.Close()
+
+	// Reading starts at the beginning of the file
+	p := make([]byte, 1)
+	if _, err := f.Read(p); err != nil || p[0] != dots[0] {
+		t.Errorf("Unexpected read: %q %v", p, err)
+	}
+
+	// A second handle extends the file
+	other, err := fs.OpenFile("/readme.txt", os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	other.Write([]byte("x"))
+	other.Close()
+
+	f.Seek(0, os.SEEK_SET)
+	if _, err := f.Write([]byte(abc)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/readme.txt"); err != nil || string(b) != dots+"x"+abc {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	if _, err := f.(io.WriterAt).WriteAt([]byte(abc), 0); err == nil {
+		t.Errorf("Expected WriteAt error in append mode")
+	}
+}
+
+func TestOpenExisting(t *testing.T) {
+	fs := Create()
+	if _, err := writeFile(fs, "/readme.txt", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// O_CREATE without O_EXCL opens the existing file
+	f, err := fs.OpenFile("/readme.txt", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	f.Close()
+	if b, err := vfs.ReadFile(fs, "/readme.txt"); err != nil || string(b) != dots {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+
+	if _, err := fs.OpenFile("/readme.txt", os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	if _, err := fs.OpenFile("/new.txt", os.O_CREATE|os.O_EXCL|os.O_RDWR, 0666); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestHandleOffsets(t *testing.T) {
+	fs := Create()
+	if _, err := writeFile(fs, "/readme.txt", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f1, _ := fs.OpenFile("/readme.txt", os.O_RDWR, 0)
+	f2, _ := fs.OpenFile("/readme.txt", os.O_RDONLY, 0)
+	defer f1.Close()
+	defer f2.Close()
+
+	if _, err := f1.Seek(4, os.SEEK_SET); err != nil {
+		t.Fatalf("Seek error: %s", err)
+	}
+	p := make([]byte, 2)
+	if _, err := f2.Read(p); err != nil || string(p) != dots[:2] {
+		t.Errorf("Unexpected read: %q %v", p, err)
+	}
+	if _, err := f1.Read(p); err != nil || string(p) != dots[4:6] {
+		t.Errorf("Unexpected read: %q %v", p, err)
+	}
+
+	// Truncation through a new handle is visible to open handles
+	f3, err := fs.OpenFile("/readme.txt", os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	f3.Close()
+	if _, err := f2.Read(p); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if fi, err := f1.Stat(); err != nil || fi.Size() != 0 {
+		t.Errorf("Unexpected size: %v %v", fi, err)
+	}
+
+	// Writing behind the end fills the gap with zeros
+	if _, err := f1.Write([]byte("x")); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/readme.txt"); err != nil || string(b) != "\x00\x00\x00\x00\x00\x00x" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+}
+
 func TestTruncateToLength(t *testing.T) {
 	var params = []struct {
 		size int64
@@ -551,8 +711,51 @@ func TestRename(t *testing.T) {
 	}
 
 	// Overwrite existing file
-	if err := fs.Rename("/newdirectory/README.txt", "/README.txt"); err == nil {
-		t.Errorf("Expected error renaming file")
+	if err := fs.Rename("/newdirectory/README.txt", "/README.txt"); err != nil {
+		t.Errorf("Error replacing file: %s", err)
+	}
+	if _, err := fs.Stat("/newdirectory/README.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected renamed file to be moved: %v", err)
+	}
+
+	// Replace a directory by a file or a file by a directory
+	if err := fs.Rename("/README.txt", "/newdirectory"); err == nil {
+		t.Errorf("Expected error replacing directory by file")
+	}
+	if err := fs.Mkdir("/emptydirectory", 0777); err != nil {
+		t.Errorf("Error creating directory: %s", err)
+	}
+	if err := fs.Rename("/emptydirectory", "/README.txt"); err == nil {
+		t.Errorf("Expected error replacing file by directory")
+	}
+	// Empty directories are replaced
+	if err := fs.Rename("/emptydirectory", "/newdirectory/empty"); err != nil {
+		t.Errorf("Error moving directory: %s", err)
+	}
+	if err := fs.Mkdir("/emptydirectory", 0777); err != nil {
+		t.Errorf("Error creating directory: %s", err)
+	}
+	if err := fs.Rename("/newdirectory/empty", "/emptydirectory"); err != nil {
+		t.Errorf("Error replacing empty directory: %s", err)
+	}
+
+	// Move directory into itself
+	if err := fs.Mkdir("/newdirectory/sub", 0777); err != nil {
+		t.Errorf("Error creating directory: %s", err)
+	}
+	if err := fs.Rename("/newdirectory", "/newdirectory/sub/moved"); err == nil {
+		t.Errorf("Expected error moving directory into itself")
+	}
+
+	// The root can not be replaced
+	if err := fs.Rename("/newdirectory", "/"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid error replacing root, got %v", err)
+	}
+	if err := fs.Rename("/README.txt", "/"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid error replacing root, got %v", err)
+	}
+	if _, err := fs.Stat("/newdirectory/sub"); err != nil {
+		t.Errorf("Directory changed by failed rename: %s", err)
 	}
 
 }
@@ -579,4 +782,809 @@ func TestModTime(t *testing.T) {
 	if tAfterRead != mtimeAfterWrite {
 		t.Error("Open with O_RDONLY should not modify mtime")
 	}
+
+	f, err = fs.OpenFile("/readme.txt", os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("Could not open file: %s", err)
+	}
+	f.Close()
+	if fi, _ := fs.Stat("/readme.txt"); !fi.ModTime().After(mtimeAfterWrite) {
+		t.Error("Open with O_WRONLY should modify mtime")
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	fs := Create()
+	_ = vfs.Copier(fs)
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	if _, err := writeFile(fs, "/readme.txt", os.O_CREATE|os.O_RDWR, 0640, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+
+	if err := fs.CopyFile("/tmp/copy.txt", "/readme.txt"); err != nil {
+		t.Fatalf("Copy error: %s", err)
+	}
+	if b, err := readFile(fs, "/tmp/copy.txt"); err != nil || string(b) != dots {
+		t.Errorf("Invalid copy: %q %s", b, err)
+	}
+	if fi, err := fs.Stat("/tmp/copy.txt"); err != nil || fi.Mode() != 0640 {
+		t.Errorf("Invalid copy mode: %v %s", fi, err)
+	}
+
+	// Copy is independent of source
+	if _, err := writeFile(fs, "/readme.txt", os.O_RDWR|os.O_TRUNC, 0640, []byte(abc)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if b, err := readFile(fs, "/tmp/copy.txt"); err != nil || string(b) != dots {
+		t.Errorf("Copy changed with source: %q %s", b, err)
+	}
+
+	// Replace existing destination
+	if err := fs.CopyFile("/tmp/copy.txt", "/readme.txt"); err != nil {
+		t.Fatalf("Copy error: %s", err)
+	}
+	if b, err := readFile(fs, "/tmp/copy.txt"); err != nil || string(b) != abc {
+		t.Errorf("Invalid copy: %q %s", b, err)
+	}
+
+	if err := fs.CopyFile("/tmp/dir.txt", "/tmp"); err == nil {
+		t.Errorf("Expected error copying directory")
+	}
+	if err := fs.CopyFile("/tmp", "/readme.txt"); err == nil {
+		t.Errorf("Expected error copying onto directory")
+	}
+	if err := fs.CopyFile("/tmp/copy2.txt", "/nonexisting.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := fs.CopyFile("/nonexisting/copy.txt", "/readme.txt"); err == nil {
+		t.Errorf("Expected error copying to non existing directory")
+	}
+}
+
+func TestCopyFileLinks(t *testing.T) {
+	fs := Create()
+	if _, err := writeFile(fs, "/src.txt", os.O_CREATE|os.O_RDWR, 0600, []byte(abc)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if _, err := writeFile(fs, "/dst.txt", os.O_CREATE|os.O_RDWR, 0644, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.Link("/dst.txt", "/hardlink.txt"); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+	if err := fs.Symlink("/dst.txt", "/symlink.txt"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	f, err := fs.OpenFile("/dst.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open error: %s", err)
+	}
+	defer f.Close()
+
+	// The content is written through the symbolic link into the existing file
+	if err := fs.CopyFile("/symlink.txt", "/src.txt"); err != nil {
+		t.Fatalf("Copy error: %s", err)
+	}
+	if fi, err := fs.Lstat("/symlink.txt"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected symbolic link to be kept: %v %v", fi, err)
+	}
+	for _, name := range []string{"/dst.txt", "/hardlink.txt"} {
+		if b, err := readFile(fs, name); err != nil || string(b) != abc {
+			t.Errorf("Invalid content of %s: %q %v", name, b, err)
+		}
+	}
+	if b, err := ioutil.ReadAll(f); err != nil || string(b) != abc {
+		t.Errorf("Open handle does not see the copy: %q %v", b, err)
+	}
+	if fi, err := fs.Stat("/hardlink.txt"); err != nil || fi.Mode() != 0600 || fi.Sys().(SysInfo).Nlink != 2 {
+		t.Errorf("Unexpected destination: %v %v", fi, err)
+	}
+
+	// A dangling symbolic link creates its target
+	if err := fs.Symlink("/target.txt", "/dangling.txt"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := fs.CopyFile("/dangling.txt", "/src.txt"); err != nil {
+		t.Fatalf("Copy error: %s", err)
+	}
+	if b, err := readFile(fs, "/target.txt"); err != nil || string(b) != abc {
+		t.Errorf("Invalid content of target: %q %v", b, err)
+	}
+	// Copying onto a hard link of the source does nothing
+	if err := fs.Link("/src.txt", "/srclink.txt"); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+	if err := fs.CopyFile("/srclink.txt", "/src.txt"); err != nil {
+		t.Errorf("Copy error: %s", err)
+	}
+}
+
+func TestErrIsDirectory(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	if _, err := fs.OpenFile("/tmp", os.O_RDWR, 0); !errors.Is(err, vfs.ErrIsDirectory) {
+		t.Errorf("Expected vfs.ErrIsDirectory, got %v", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0755)
+	for _, name := range []string{"/dir/c", "/dir/a", "/dir/b"} {
+		if err := vfs.WriteFile(fs, name, nil, 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+
+	d, err := fs.OpenFile("/dir", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	if fi, err := d.Stat(); err != nil || !fi.IsDir() || fi.Name() != "dir" {
+		t.Errorf("Stat: %v %v", fi, err)
+	}
+	if fis, err := d.Readdir(2); err != nil || len(fis) != 2 || fis[0].Name() != "a" || fis[1].Name() != "b" {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if fis, err := d.Readdir(2); err != nil || len(fis) != 1 || fis[0].Name() != "c" {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if _, err := d.Readdir(2); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+	if fis, err := d.Readdir(-1); err != nil || len(fis) != 0 {
+		t.Errorf("Readdir: %v %v", fis, err)
+	}
+	if _, err := d.Seek(0, os.SEEK_SET); err != nil {
+		t.Errorf("Seek: %s", err)
+	}
+	if fis, err := d.Readdir(-1); err != nil || len(fis) != 3 {
+		t.Errorf("Readdir after seek: %v %v", fis, err)
+	}
+	if _, err := d.Read(make([]byte, 1)); !errors.Is(err, vfs.ErrIsDirectory) {
+		t.Errorf("Expected vfs.ErrIsDirectory, got %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+}
+
+func TestFileStat(t *testing.T) {
+	fs := Create()
+	f, err := fs.OpenFile("/file", os.O_CREATE|os.O_RDWR, 0640)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(dots)); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "file" || fi.Size() != int64(len(dots)) || fi.Mode() != 0640 || fi.IsDir() {
+		t.Errorf("Unexpected FileInfo: %s %d %s", fi.Name(), fi.Size(), fi.Mode())
+	}
+	if _, err := f.Readdir(-1); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected vfs.ErrNotDirectory, got %v", err)
+	}
+}
+
+func TestDirMode(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/tmp", 0755); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	fi, err := fs.Stat("/tmp")
+	if err != nil {
+		t.Fatalf("Stat error: %s", err)
+	}
+	if m := fi.Mode(); m != os.ModeDir|0755 || !m.IsDir() {
+		t.Errorf("Invalid mode: %s", m)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	fs := Create()
+	_ = vfs.Symlinker(fs)
+	if err := fs.Mkdir("/dir", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	if _, err := writeFile(fs, "/dir/file", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.Symlink("/dir/file", "/abslink"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := fs.Symlink("dir", "/dirlink"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := fs.Symlink("file", "/dir/rellink"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := fs.Symlink("/dir", "/dirlink"); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+
+	for _, name := range []string{"/abslink", "/dir/rellink", "/dirlink/file", "/dirlink/rellink"} {
+		if b, err := readFile(fs, name); err != nil || string(b) != dots {
+			t.Errorf("Read %q through link: %q %v", name, b, err)
+		}
+	}
+
+	// Stat follows links, Lstat does not
+	if fi, err := fs.Stat("/abslink"); err != nil || fi.Name() != "abslink" || fi.Mode()&os.ModeSymlink != 0 || fi.Size() != int64(len(dots)) {
+		t.Errorf("Invalid stat: %v %v", fi, err)
+	}
+	if fi, err := fs.Lstat("/abslink"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Invalid lstat: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/dirlink"); err != nil || !fi.IsDir() {
+		t.Errorf("Invalid stat: %v %v", fi, err)
+	}
+	if fis, err := fs.ReadDir("/dirlink"); err != nil || len(fis) != 2 {
+		t.Errorf("Invalid readdir through link: %v %v", fis, err)
+	}
+
+	if target, err := fs.Readlink("/dir/rellink"); err != nil || target != "file" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+	if _, err := fs.Readlink("/dir/file"); err == nil {
+		t.Errorf("Expected readlink error on regular file")
+	}
+
+	// Dangling link creates its target
+	if err := fs.Symlink("/dir/new", "/dangling"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if _, err := fs.Stat("/dangling"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := writeFile(fs, "/dangling", os.O_CREATE|os.O_RDWR, 0666, []byte(abc)); err != nil {
+		t.Fatalf("Write through dangling link: %s", err)
+	}
+	if b, err := readFile(fs, "/dir/new"); err != nil || string(b) != abc {
+		t.Errorf("Invalid target content: %q %v", b, err)
+	}
+
+	// Removing the link keeps the target
+	if err := fs.Remove("/abslink"); err != nil {
+		t.Fatalf("Remove error: %s", err)
+	}
+	if _, err := fs.Stat("/dir/file"); err != nil {
+		t.Errorf("Target removed: %s", err)
+	}
+
+	// Loops are detected
+	fs.Symlink("/loop2", "/loop1")
+	fs.Symlink("/loop1", "/loop2")
+	if _, err := fs.Stat("/loop1"); err == nil {
+		t.Errorf("Expected error for symlink loop")
+	}
+}
+
+func TestRemoveNotEmpty(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	if _, err := writeFile(fs, "/tmp/file", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.Remove("/tmp"); err == nil {
+		t.Errorf("Expected error removing non empty directory")
+	}
+	if err := fs.Remove("/"); err == nil {
+		t.Errorf("Expected error removing root")
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	fs := Create()
+	_ = vfs.RemoveAller(fs)
+	if err := fs.MkdirAll("/tmp/a/b", 0777); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	if _, err := writeFile(fs, "/tmp/a/b/file", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.RemoveAll("/tmp/a"); err != nil {
+		t.Fatalf("RemoveAll error: %s", err)
+	}
+	if _, err := fs.Stat("/tmp/a"); !os.IsNotExist(err) {
+		t.Errorf("Expected /tmp/a to be removed: %v", err)
+	}
+	if _, err := fs.Stat("/tmp"); err != nil {
+		t.Errorf("Parent removed: %s", err)
+	}
+	if err := fs.RemoveAll("/nonexisting/dir"); err != nil {
+		t.Errorf("Unexpected error removing non existing path: %s", err)
+	}
+	if err := fs.RemoveAll("/"); err == nil {
+		t.Errorf("Expected error removing root")
+	}
+}
+
+func TestMkdirAll(t *testing.T) {
+	fs := Create()
+	_ = vfs.MkdirAller(fs)
+	if err := fs.MkdirAll("/usr/src/linux", 0755); err != nil {
+		t.Fatalf("MkdirAll error: %s", err)
+	}
+	for _, dir := range []string{"/usr", "/usr/src", "/usr/src/linux"} {
+		if fi, err := fs.Stat(dir); err != nil || !fi.IsDir() || fi.Mode().Perm() != 0755 {
+			t.Errorf("Invalid directory %q: %v %v", dir, fi, err)
+		}
+	}
+	// Existing directories are fine
+	if err := fs.MkdirAll("/usr/src", 0700); err != nil {
+		t.Errorf("MkdirAll on existing directory: %s", err)
+	}
+	if err := fs.MkdirAll("relative/dir", 0755); err != nil {
+		t.Errorf("MkdirAll relative: %s", err)
+	}
+	if _, err := fs.Stat("/relative/dir"); err != nil {
+		t.Errorf("Relative directory not created: %s", err)
+	}
+
+	// Through symbolic links
+	if err := fs.Symlink("/usr/src", "/src"); err != nil {
+		t.Fatalf("Symlink error: %s", err)
+	}
+	if err := fs.MkdirAll("/src/go", 0755); err != nil {
+		t.Errorf("MkdirAll through link: %s", err)
+	}
+	if _, err := fs.Stat("/usr/src/go"); err != nil {
+		t.Errorf("Directory not created through link: %s", err)
+	}
+
+	if _, err := writeFile(fs, "/usr/file", os.O_CREATE|os.O_RDWR, 0666, []byte(dots)); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.MkdirAll("/usr/file/dir", 0755); err == nil {
+		t.Errorf("Expected error creating directory below file")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	fs := Create()
+	if err := vfs.WriteFile(fs, "/file", nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Symlink("/file", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	if err := fs.Chmod("/link", 0600|os.ModeSetuid); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := fs.Chtimes("/file", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+	if err := fs.Chown("/file", 1000, 100); err != nil {
+		t.Fatalf("Chown: %s", err)
+	}
+
+	fi, err := fs.Stat("/link")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Mode() != 0600|os.ModeSetuid {
+		t.Errorf("Unexpected mode: %s", fi.Mode())
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("Unexpected modification time: %s", fi.ModTime())
+	}
+	if uid, gid, ok := vfs.FileOwner(fi); !ok || uid != 1000 || gid != 100 {
+		t.Errorf("Unexpected owner: %d:%d (%t)", uid, gid, ok)
+	}
+	if fi, _ := fs.Lstat("/link"); fi.Mode() != os.ModeSymlink|0777 {
+		t.Errorf("Link mode changed: %s", fi.Mode())
+	}
+
+	if err := fs.Chmod("/missing", 0600); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := Create(WithClock(func() time.Time { return now }))
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	fs.Mkdir("/dir", 0755)
+	for _, name := range []string{"/", "/file", "/dir"} {
+		if fi, err := fs.Stat(name); err != nil || !fi.ModTime().Equal(now) {
+			t.Errorf("Unexpected modification time of %s: %v %v", name, fi, err)
+		}
+	}
+
+	now = now.Add(time.Hour)
+	f, err := fs.OpenFile("/file", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	f.Close()
+	fs.Rename("/dir", "/renamed")
+	for _, name := range []string{"/file", "/renamed"} {
+		if fi, err := fs.Stat(name); err != nil || !fi.ModTime().Equal(now) {
+			t.Errorf("Unexpected modification time of %s: %v %v", name, fi, err)
+		}
+	}
+}
+
+func TestWithOwner(t *testing.T) {
+	fs := Create(WithOwner(1000, 100))
+	vfs.MkdirAll(fs, "/dir/sub", 0755)
+	vfs.WriteFile(fs, "/dir/sub/file", nil, 0644)
+	fs.Symlink("file", "/dir/sub/link")
+	for _, name := range []string{"/", "/dir", "/dir/sub/file", "/dir/sub/link"} {
+		fi, err := fs.Lstat(name)
+		if err != nil {
+			t.Fatalf("Lstat: %s", err)
+		}
+		if uid, gid, ok := vfs.FileOwner(fi); !ok || uid != 1000 || gid != 100 {
+			t.Errorf("Unexpected owner of %s: %d:%d (%t)", name, uid, gid, ok)
+		}
+	}
+
+	// -1 keeps the current value
+	if err := fs.Chown("/dir/sub/file", 0, -1); err != nil {
+		t.Fatalf("Chown: %s", err)
+	}
+	fi, _ := fs.Stat("/dir/sub/file")
+	if uid, gid, _ := vfs.FileOwner(fi); uid != 0 || gid != 100 {
+		t.Errorf("Unexpected owner: %d:%d", uid, gid)
+	}
+}
+
+func nlink(t *testing.T, fs vfs.Filesystem, name string) int {
+	t.Helper()
+	fi, err := fs.Lstat(name)
+	if err != nil {
+		t.Fatalf("Lstat error: %s", err)
+	}
+	return fi.Sys().(SysInfo).Nlink
+}
+
+func TestLink(t *testing.T) {
+	fs := Create()
+	_ = vfs.Linker(fs)
+	fs.Mkdir("/dir", 0777)
+	if _, err := writeFile(fs, "/file", os.O_CREATE|os.O_RDWR, 0666, []byte("abc")); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if err := fs.Link("/file", "/dir/link"); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+	if n := nlink(t, fs, "/file"); n != 2 {
+		t.Errorf("Expected 2 links, got %d", n)
+	}
+	fi1, _ := fs.Stat("/file")
+	fi2, _ := fs.Stat("/dir/link")
+	if fi1.Sys().(SysInfo).Ino != fi2.Sys().(SysInfo).Ino || fi2.Name() != "link" {
+		t.Errorf("Links must share the inode: %v %v", fi1.Sys(), fi2.Sys())
+	}
+
+	// Content and attributes are shared
+	if _, err := writeFile(fs, "/dir/link", os.O_WRONLY|os.O_APPEND, 0, []byte("def")); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	if b, err := readFile(fs, "/file"); err != nil || string(b) != "abcdef" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	if err := fs.Chmod("/file", 0600); err != nil {
+		t.Fatalf("Chmod error: %s", err)
+	}
+	if fi, _ := fs.Stat("/dir/link"); fi.Mode() != 0600 {
+		t.Errorf("Mode not shared: %s", fi.Mode())
+	}
+
+	if err := fs.Link("/file", "/dir/link"); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	if err := fs.Link("/missing", "/other"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := fs.Link("/dir", "/dirlink"); err == nil {
+		t.Errorf("Linking a directory succeeded")
+	}
+
+	// Removing a link keeps the file
+	if err := fs.Remove("/file"); err != nil {
+		t.Fatalf("Remove error: %s", err)
+	}
+	if n := nlink(t, fs, "/dir/link"); n != 1 {
+		t.Errorf("Expected 1 link, got %d", n)
+	}
+	if b, err := readFile(fs, "/dir/link"); err != nil || string(b) != "abcdef" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+}
+
+func TestLinkSymlink(t *testing.T) {
+	fs := Create()
+	fs.Symlink("/target", "/symlink")
+	if err := fs.Link("/symlink", "/link"); err != nil {
+		t.Fatalf("Link error: %s", err)
+	}
+	if target, err := fs.Readlink("/link"); err != nil || target != "/target" {
+		t.Errorf("Link of a symbolic link must be a symbolic link: %q %v", target, err)
+	}
+}
+
+func TestNlinkDir(t *testing.T) {
+	fs := Create()
+	vfs.MkdirAll(fs, "/dir/a", 0777)
+	fs.Mkdir("/dir/b", 0777)
+	writeFile(fs, "/dir/file", os.O_CREATE|os.O_RDWR, 0666, nil)
+	if n := nlink(t, fs, "/dir"); n != 4 {
+		t.Errorf("Expected 4 links of directory, got %d", n)
+	}
+	fi, _ := fs.Stat("/dir")
+	if n, ok := vfs.FileLinks(fi); !ok || n != 4 {
+		t.Errorf("FileLinks: %d %t", n, ok)
+	}
+}
+
+func TestRemoveAllLinks(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0777)
+	writeFile(fs, "/dir/file", os.O_CREATE|os.O_RDWR, 0666, nil)
+	fs.Link("/dir/file", "/link")
+	if err := fs.RemoveAll("/dir"); err != nil {
+		t.Fatalf("RemoveAll error: %s", err)
+	}
+	if n := nlink(t, fs, "/link"); n != 1 {
+		t.Errorf("Expected 1 link, got %d", n)
+	}
+}
+
+// TestConcurrentDirectories modifies directories in parallel to structural changes of the tree.
+func TestConcurrentDirectories(t *testing.T) {
+	fs := Create()
+	const workers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dir := fmt.Sprintf("/dir%d/sub", i%4)
+			for j := 0; j < 50; j++ {
+				name := fmt.Sprintf("%s/file%d-%d", dir, i, j)
+				if err := vfs.MkdirAll(fs, dir, 0755); err != nil {
+					t.Errorf("MkdirAll: %s", err)
+					return
+				}
+				if err := vfs.WriteFile(fs, name, []byte(name), 0644); err != nil {
+					t.Errorf("WriteFile: %s", err)
+					return
+				}
+				if b, err := vfs.ReadFile(fs, name); err != nil || string(b) != name {
+					t.Errorf("ReadFile: %q %v", b, err)
+				}
+				fs.ReadDir(dir)
+				fs.Stat(dir)
+				if j%2 == 0 {
+					if err := fs.Remove(name); err != nil {
+						t.Errorf("Remove: %s", err)
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			fs.Snapshot()
+			fs.Mkdir("/tmp", 0755)
+			fs.Rename("/tmp", "/moved")
+			fs.RemoveAll("/moved")
+		}
+	}()
+	wg.Wait()
+
+	for i := 0; i < 4; i++ {
+		fis, err := fs.ReadDir(fmt.Sprintf("/dir%d/sub", i))
+		if err != nil || len(fis) != workers/4*25 {
+			t.Errorf("Expected %d files, got %d %v", workers/4*25, len(fis), err)
+		}
+	}
+}
+
+// TestConcurrentCreate creates the same file exclusively in parallel, exactly one open succeeds.
+func TestConcurrentCreate(t *testing.T) {
+	fs := Create()
+	var created int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := fs.OpenFile("/file", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+			if err == nil {
+				atomic.AddInt32(&created, 1)
+				f.Close()
+			} else if !os.IsExist(err) {
+				t.Errorf("Unexpected error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if created != 1 {
+		t.Errorf("Expected a single created file, got %d", created)
+	}
+}
+
+// TestConcurrentRemoveDir removes a directory while files are created in it,
+// files are never created in the removed directory.
+func TestConcurrentRemoveDir(t *testing.T) {
+	fs := Create()
+	for i := 0; i < 100; i++ {
+		fs.Mkdir("/dir", 0755)
+		var wg sync.WaitGroup
+		var created int32
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := vfs.WriteFile(fs, "/dir/file", nil, 0644); err == nil {
+				atomic.StoreInt32(&created, 1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			fs.Remove("/dir")
+		}()
+		wg.Wait()
+		_, err := fs.Stat("/dir/file")
+		if exists := err == nil; exists != (created == 1) {
+			t.Fatalf("File created: %t, exists: %t", created == 1, exists)
+		}
+		fs.RemoveAll("/dir")
+	}
+}
+
+func BenchmarkParallelCreate(b *testing.B) {
+	fs := Create()
+	var n int64
+	b.RunParallel(func(pb *testing.PB) {
+		dir := fmt.Sprintf("/dir%d", atomic.AddInt64(&n, 1))
+		fs.Mkdir(dir, 0755)
+		for i := 0; pb.Next(); i++ {
+			f, err := fs.OpenFile(fmt.Sprintf("%s/file%d", dir, i), os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				b.Fatalf("OpenFile: %s", err)
+			}
+			f.Close()
+		}
+	})
+}
+
+func BenchmarkParallelWrite(b *testing.B) {
+	fs := Create()
+	data := make([]byte, 4096)
+	var n int64
+	b.SetBytes(int64(len(data)))
+	b.RunParallel(func(pb *testing.PB) {
+		dir := fmt.Sprintf("/dir%d", atomic.AddInt64(&n, 1))
+		fs.Mkdir(dir, 0755)
+		for i := 0; pb.Next(); i++ {
+			if err := vfs.WriteFile(fs, fmt.Sprintf("%s/file%d", dir, i%100), data, 0644); err != nil {
+				b.Fatalf("WriteFile: %s", err)
+			}
+		}
+	})
+}
+
+func TestSparseFile(t *testing.T) {
+	fs := Create()
+	f, err := fs.OpenFile("/sparse", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(1<<30, io.SeekStart); err != nil {
+		t.Fatalf("Seek beyond end: %s", err)
+	}
+	if _, err := f.Write([]byte(abc)); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	if fi, err := fs.Stat("/sparse"); err != nil || fi.Size() != 1<<30+int64(len(abc)) {
+		t.Fatalf("Unexpected size: %v %v", fi, err)
+	}
+	p := make([]byte, 4)
+	if _, err := f.ReadAt(p, 1<<29); err != nil || string(p) != "\x00\x00\x00\x00" {
+		t.Errorf("Unexpected hole: %q %v", p, err)
+	}
+	if _, err := f.ReadAt(p, 1<<30); err != nil || string(p) != abc[:4] {
+		t.Errorf("Unexpected content: %q %v", p, err)
+	}
+}
+
+func TestOpenDirIterator(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0755)
+	for _, name := range []string{"/dir/c", "/dir/a", "/dir/d", "/dir/b"} {
+		vfs.WriteFile(fs, name, nil, 0644)
+	}
+	if _, err := fs.OpenDir("/dir/a"); err == nil {
+		t.Errorf("OpenDir of a file succeeded")
+	}
+
+	d, err := fs.OpenDir("/dir")
+	if err != nil {
+		t.Fatalf("OpenDir: %s", err)
+	}
+	defer d.Close()
+	if fis, err := d.Next(2); err != nil || len(fis) != 2 || fis[0].Name() != "a" || fis[1].Name() != "b" {
+		t.Errorf("Next: %v %v", fis, err)
+	}
+	// Removed entries are skipped
+	fs.Remove("/dir/c")
+	if fis, err := d.Next(2); err != nil || len(fis) != 1 || fis[0].Name() != "d" {
+		t.Errorf("Next: %v %v", fis, err)
+	}
+	if fis, err := d.Next(2); err != io.EOF || len(fis) != 0 {
+		t.Errorf("Expected EOF, got %v %v", fis, err)
+	}
+}
+
+func TestChdir(t *testing.T) {
+	fs := Create()
+	if err := vfs.MkdirAll(fs, "/home/user/src", 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	vfs.WriteFile(fs, "/home/file", nil, 0644)
+	fs.Symlink("/home/user", "/link")
+
+	if err := fs.Chdir("/link"); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	if wd, err := fs.Getwd(); err != nil || wd != "/home/user" {
+		t.Errorf("Unexpected working directory: %q %v", wd, err)
+	}
+	if err := vfs.WriteFile(fs, "src/main.go", []byte("package main"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if _, err := fs.Stat("/home/user/src/main.go"); err != nil {
+		t.Errorf("File not created in working directory: %s", err)
+	}
+	if _, err := fs.Stat("../file"); err != nil {
+		t.Errorf("Stat of parent entry: %s", err)
+	}
+	if err := fs.Chdir("src"); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	if fis, err := fs.ReadDir("."); err != nil || len(fis) != 1 || fis[0].Name() != "main.go" {
+		t.Errorf("Unexpected entries: %v %v", fis, err)
+	}
+
+	// The working directory follows renames
+	if err := fs.Rename("/home/user", "/home/other"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if wd, _ := fs.Getwd(); wd != "/home/other/src" {
+		t.Errorf("Unexpected working directory after rename: %q", wd)
+	}
+	if _, err := fs.Stat("main.go"); err != nil {
+		t.Errorf("Stat after rename: %s", err)
+	}
+
+	if err := fs.Chdir("/home/file"); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+	if err := fs.Chdir("missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if wd, _ := fs.Getwd(); wd != "/home/other/src" {
+		t.Errorf("Working directory changed by failed Chdir: %q", wd)
+	}
 }
@@ -0,0 +1,51 @@
+package memfs
+
+import (
+	"sync"
+)
+
+// Pool hands out empty MemFS instances and recycles returned ones,
+// avoiding repeated allocations for short-lived filesystems like per-request sandboxes.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	mutex sync.Mutex
+	free  []*MemFS
+	size  int
+}
+
+// NewPool creates a pool pre-warmed with size filesystems.
+// At most size idle filesystems are retained.
+func NewPool(size int) *Pool {
+	p := &Pool{
+		free: make([]*MemFS, 0, size),
+		size: size,
+	}
+	for i := 0; i < size; i++ {
+		p.free = append(p.free, Create())
+	}
+	return p
+}
+
+// Get returns an empty filesystem from the pool or creates a new one.
+func (p *Pool) Get() *MemFS {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if l := len(p.free); l > 0 {
+		fs := p.free[l-1]
+		p.free[l-1] = nil
+		p.free = p.free[:l-1]
+		return fs
+	}
+	return Create()
+}
+
+// Put resets the filesystem and returns it to the pool.
+// The filesystem must not be used afterwards.
+func (p *Pool) Put(fs *MemFS) {
+	fs.Reset()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.free) < p.size {
+		p.free = append(p.free, fs)
+	}
+}
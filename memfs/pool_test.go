@@ -0,0 +1,107 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(1)
+	fs := p.Get()
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	p.Put(fs)
+
+	fs2 := p.Get()
+	if fs2 != fs {
+		t.Errorf("Expected recycled filesystem")
+	}
+	if fis, err := fs2.ReadDir("/"); err != nil || len(fis) != 0 {
+		t.Errorf("Expected empty filesystem: %v %s", fis, err)
+	}
+
+	// Pool is empty, a new filesystem is created
+	if fs3 := p.Get(); fs3 == nil || fs3 == fs2 {
+		t.Errorf("Expected new filesystem")
+	}
+
+	// Excess filesystems are dropped
+	p.Put(Create())
+	p.Put(Create())
+	if l := len(p.free); l != 1 {
+		t.Errorf("Expected 1 idle filesystem, got %d", l)
+	}
+}
+
+func TestReset(t *testing.T) {
+	fs := Create()
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Fatalf("Mkdir error: %s", err)
+	}
+	f, err := fs.OpenFile("/tmp/file", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatalf("Create error: %s", err)
+	}
+
+	fs.Reset()
+
+	if _, err := fs.Stat("/tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected /tmp to be removed: %s", err)
+	}
+	if _, err := f.Write([]byte(dots)); err != nil {
+		t.Errorf("Open handle not usable: %s", err)
+	}
+	if err := fs.Mkdir("/tmp", 0777); err != nil {
+		t.Errorf("Mkdir after reset: %s", err)
+	}
+}
+
+func TestResetRoot(t *testing.T) {
+	fs := Create()
+	events, err := fs.Watch("/")
+	if err != nil {
+		t.Fatalf("Watch error: %s", err)
+	}
+	if err := fs.Chmod("/", 0700); err != nil {
+		t.Fatalf("Chmod error: %s", err)
+	}
+	if err := fs.SetXattr("/", "user.test", []byte("value")); err != nil {
+		t.Fatalf("SetXattr error: %s", err)
+	}
+
+	fs.Reset()
+
+	fi, err := fs.Stat("/")
+	if err != nil {
+		t.Fatalf("Stat error: %s", err)
+	}
+	if mode := fi.Mode(); mode != os.ModeDir|0755 {
+		t.Errorf("Expected root mode %s, got %s", os.ModeDir|0755, mode)
+	}
+	if attrs, err := fs.ListXattr("/"); err != nil || len(attrs) != 0 {
+		t.Errorf("Expected no extended attributes: %q %v", attrs, err)
+	}
+	// Queued events are dropped and the channel is closed
+	for range events {
+	}
+	if err := fs.Unwatch(events); err != os.ErrInvalid {
+		t.Errorf("Expected watch to be removed, got %v", err)
+	}
+}
+
+func BenchmarkPool(b *testing.B) {
+	p := NewPool(1)
+	for i := 0; i < b.N; i++ {
+		fs := p.Get()
+		fs.Mkdir("/tmp", 0777)
+		p.Put(fs)
+	}
+}
+
+func BenchmarkCreate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		fs := Create()
+		fs.Mkdir("/tmp", 0777)
+	}
+}
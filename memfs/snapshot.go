@@ -0,0 +1,84 @@
+package memfs
+
+import (
+	"sync"
+
+	"github.com/blang/vfs"
+)
+
+// Snapshot is an immutable point-in-time copy of a MemFS.
+// File contents are shared with the filesystem and only the modified chunks
+// are copied when either side writes, so taking and restoring a snapshot is cheap.
+type Snapshot struct {
+	root *fileInfo
+	// opts recreate the options of the filesystem the snapshot was taken of
+	opts []Option
+}
+
+// Snapshot returns a copy of the current state of the filesystem.
+func (fs *MemFS) Snapshot() *Snapshot {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	return &Snapshot{
+		root: fs.root.share(nil, nil, make(map[*inode]*inode)),
+		opts: []Option{WithClock(fs.now), WithOwner(fs.uid, fs.gid), WithPathSeparator(fs.sep)},
+	}
+}
+
+// Restore replaces all files and directories with the state of the given snapshot.
+// The snapshot stays unchanged and can be restored again,
+// the working directory is reset to root.
+// Open file handles stay usable but are detached from the filesystem.
+func (fs *MemFS) Restore(s *Snapshot) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	fs.root = s.root.share(nil, fs, make(map[*inode]*inode))
+	fs.wd = fs.root
+}
+
+// Filesystem returns a read-only filesystem of the snapshot,
+// which uses the clock, owner and path separator of the snapshotted filesystem.
+func (s *Snapshot) Filesystem() vfs.Filesystem {
+	fs := Create(s.opts...)
+	fs.Restore(s)
+	return vfs.ReadOnly(fs)
+}
+
+// share returns a copy of the tree rooted at fi, which belongs to fs.
+// The contents of files are shared between both trees and marked copy-on-write.
+// Hard links within the tree are kept, inodes maps the copied inodes to their copies.
+func (fi *fileInfo) share(parent *fileInfo, fs vfs.Filesystem, inodes map[*inode]*inode) *fileInfo {
+	c := &fileInfo{
+		name:   fi.name,
+		dir:    fi.dir,
+		parent: parent,
+		fs:     fs,
+		link:   fi.link,
+		inode:  inodes[fi.inode],
+	}
+	if c.inode == nil {
+		c.inode = &inode{
+			ino:     fi.ino,
+			mode:    fi.mode,
+			modTime: fi.modTime,
+			mutex:   &sync.RWMutex{},
+			uid:     fi.uid,
+			gid:     fi.gid,
+			nlink:   fi.nlink,
+			xattrs:  copyXattrs(fi.xattrs),
+		}
+		inodes[fi.inode] = c.inode
+		if fi.data != nil {
+			fi.mutex.Lock()
+			c.data = fi.data.share()
+			fi.mutex.Unlock()
+		}
+	}
+	if fi.childs != nil {
+		c.childs = make(map[string]*fileInfo, len(fi.childs))
+		for name, child := range fi.childs {
+			c.childs[name] = child.share(c, fs, inodes)
+		}
+	}
+	return c
+}
@@ -0,0 +1,145 @@
+package memfs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0755)
+	if err := vfs.WriteFile(fs, "/dir/file", []byte(dots), 0640); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	f, err := fs.OpenFile("/dir/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+
+	snap := fs.Snapshot()
+
+	// Modify the filesystem after the snapshot, also using a handle opened before
+	if _, err := f.Write([]byte(abc[:4])); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/new", []byte(abc), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Chmod("/dir/file", 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		fs.Restore(snap)
+		if b, err := vfs.ReadFile(fs, "/dir/file"); err != nil || string(b) != dots {
+			t.Errorf("Unexpected content after restore: %q %v", b, err)
+		}
+		if fi, err := fs.Stat("/dir/file"); err != nil || fi.Mode() != 0640 {
+			t.Errorf("Unexpected mode after restore: %v %v", fi, err)
+		}
+		if _, err := fs.Stat("/new"); !os.IsNotExist(err) {
+			t.Errorf("Expected /new to be removed: %v", err)
+		}
+
+		// Changes of the restored filesystem must not leak into the snapshot
+		g, err := fs.OpenFile("/dir/file", os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile: %s", err)
+		}
+		g.Write([]byte(abc))
+		g.Truncate(2)
+		g.Close()
+	}
+
+	// The open handle is detached but usable
+	if _, err := f.Write([]byte(abc)); err != nil {
+		t.Errorf("Open handle not usable: %s", err)
+	}
+}
+
+func TestSnapshotFilesystem(t *testing.T) {
+	fs := Create()
+	if err := vfs.WriteFile(fs, "/file", []byte(dots), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	snap := fs.Snapshot()
+	if err := vfs.WriteFile(fs, "/file", []byte(abc), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	sfs := snap.Filesystem()
+	if b, err := vfs.ReadFile(sfs, "/file"); err != nil || string(b) != dots {
+		t.Errorf("Unexpected content of snapshot: %q %v", b, err)
+	}
+	if err := vfs.WriteFile(sfs, "/file", []byte(abc), 0644); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestSnapshotFilesystemOptions(t *testing.T) {
+	clock := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := Create(WithPathSeparator('\\'), WithOwner(1000, 1000), WithClock(func() time.Time { return clock }))
+	fs.Mkdir(`\dir`, 0755)
+	vfs.WriteFile(fs, `\dir\file`, []byte(dots), 0644)
+
+	sfs := fs.Snapshot().Filesystem()
+	if sep := sfs.PathSeparator(); sep != '\\' {
+		t.Errorf("Unexpected path separator %q", sep)
+	}
+	if b, err := vfs.ReadFile(sfs, `C:\dir\file`); err != nil || string(b) != dots {
+		t.Errorf("Unexpected content of snapshot: %q %v", b, err)
+	}
+	var visited []string
+	vfs.Walk(sfs, `\`, func(path string, fi os.FileInfo, err error) error {
+		visited = append(visited, path)
+		return err
+	})
+	if expected := []string{`\`, `\dir`, `\dir\file`}; !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Walk visited %q, expected %q", visited, expected)
+	}
+
+	mfs := sfs.(*vfs.RoFS).Filesystem.(*MemFS)
+	if mfs.uid != 1000 || mfs.gid != 1000 || !mfs.now().Equal(clock) {
+		t.Errorf("Options not applied: owner %d:%d, clock %s", mfs.uid, mfs.gid, mfs.now())
+	}
+}
+
+func BenchmarkSnapshotRestore(b *testing.B) {
+	fs := Create()
+	for _, name := range []string{"/a", "/b", "/c", "/d"} {
+		fs.Mkdir(name, 0755)
+		for _, file := range []string{"/1", "/2", "/3", "/4"} {
+			vfs.WriteFile(fs, name+file, []byte(large), 0644)
+		}
+	}
+	snap := fs.Snapshot()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fs.Restore(snap)
+	}
+}
+
+func TestSnapshotHardLinks(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/a", []byte("content"), 0644)
+	fs.Link("/a", "/b")
+	s := fs.Snapshot()
+
+	vfs.WriteFile(fs, "/a", []byte("changed"), 0644)
+	if b, _ := vfs.ReadFile(fs, "/b"); string(b) != "changed" {
+		t.Errorf("Hard link broken by snapshot: %q", b)
+	}
+	fs.Restore(s)
+	if b, _ := vfs.ReadFile(fs, "/b"); string(b) != "content" {
+		t.Errorf("Unexpected restored content: %q", b)
+	}
+	vfs.WriteFile(fs, "/b", []byte("again"), 0644)
+	if b, _ := vfs.ReadFile(fs, "/a"); string(b) != "again" {
+		t.Errorf("Hard link broken by restore: %q", b)
+	}
+}
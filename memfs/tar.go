@@ -0,0 +1,204 @@
+package memfs
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	filepath "path"
+	"sort"
+	"strings"
+
+	"github.com/blang/vfs"
+)
+
+// Dump writes all files, directories and symbolic links of the filesystem as tar stream to w.
+// Modes, modification times, owners and extended attributes are preserved, see Load.
+func (fs *MemFS) Dump(w io.Writer) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	tw := tar.NewWriter(w)
+	if err := dump(tw, fs.root, "", make(map[*inode]string)); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// paxXattr is the prefix of PAX records holding extended attributes, as written by GNU tar.
+const paxXattr = "SCHILY.xattr."
+
+// dump writes the entries of dir, the first entry of every file is recorded in links
+// and further hard links of the file are written as links to it.
+func dump(tw *tar.Writer, dir *fileInfo, prefix string, links map[*inode]string) error {
+	names := make([]string, 0, len(dir.childs))
+	for name := range dir.childs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fi := dir.childs[name]
+		hdr, err := tar.FileInfoHeader(fi, fi.link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = prefix + name
+		hdr.Uid, hdr.Gid = fi.uid, fi.gid
+		hdr.Format = tar.FormatPAX
+		for attr, value := range fi.xattrs {
+			if hdr.PAXRecords == nil {
+				hdr.PAXRecords = make(map[string]string)
+			}
+			hdr.PAXRecords[paxXattr+attr] = string(value)
+		}
+		if fi.dir {
+			hdr.Name += "/"
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if err := dump(tw, fi, hdr.Name, links); err != nil {
+				return err
+			}
+			continue
+		}
+		if first, ok := links[fi.inode]; ok {
+			hdr.Typeflag = tar.TypeLink
+			hdr.Linkname = first
+			hdr.Size = 0
+		} else {
+			links[fi.inode] = hdr.Name
+		}
+		if fi.link != "" || hdr.Typeflag == tar.TypeLink {
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		fi.mutex.RLock()
+		hdr.Size = fi.data.Size()
+		err = tw.WriteHeader(hdr)
+		if err == nil {
+			err = fi.data.writeTo(tw)
+		}
+		fi.mutex.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load creates a new filesystem from the tar stream read from r, e.g. written by Dump.
+// Parent directories missing in the stream are created with mode 0755,
+// hard links share the file of their target.
+// The options configure the created filesystem, e.g. the owner of the missing directories.
+func Load(r io.Reader, opts ...Option) (*MemFS, error) {
+	fs := Create(opts...)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := fs.load(hdr, tr); err != nil {
+			return nil, &os.PathError{"load", hdr.Name, err}
+		}
+	}
+}
+
+// load adds the entry described by hdr to the filesystem.
+func (fs *MemFS) load(hdr *tar.Header, r io.Reader) error {
+	name := filepath.Clean("/" + hdr.Name)
+	if name == "/" {
+		return nil
+	}
+	parent, err := fs.loadDir(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(name)
+	fi := &fileInfo{
+		name:   base,
+		parent: parent,
+		fs:     fs,
+		inode:  fs.newInode(0),
+	}
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if existing, ok := parent.childs[base]; ok && existing.dir {
+			// Implicitly created by a previous entry
+			fi = existing
+		} else {
+			fi.dir = true
+			fi.childs = make(map[string]*fileInfo)
+		}
+	case tar.TypeReg:
+		fi.data = &Chunks{}
+		if err := fi.data.readFrom(r); err != nil {
+			return err
+		}
+	case tar.TypeLink:
+		_, target, err := fs.fileInfo(filepath.Clean("/" + hdr.Linkname))
+		if err != nil {
+			return err
+		}
+		if target == nil || target.dir {
+			return os.ErrNotExist
+		}
+		// The attributes of the target are kept
+		fi.link = target.link
+		fi.inode = target.inode
+		fi.nlink++
+		parent.childs[base] = fi
+		return nil
+	case tar.TypeSymlink:
+		fi.link = hdr.Linkname
+	default:
+		return fmt.Errorf("Unsupported entry type %q", hdr.Typeflag)
+	}
+	fi.mode = hdr.FileInfo().Mode() &^ os.ModeDir
+	fi.modTime = hdr.ModTime
+	fi.uid, fi.gid = hdr.Uid, hdr.Gid
+	for key, value := range hdr.PAXRecords {
+		if strings.HasPrefix(key, paxXattr) {
+			if fi.xattrs == nil {
+				fi.xattrs = make(map[string][]byte)
+			}
+			fi.xattrs[strings.TrimPrefix(key, paxXattr)] = []byte(value)
+		}
+	}
+	parent.childs[base] = fi
+	return nil
+}
+
+// loadDir returns the directory of the given path, missing directories are created.
+func (fs *MemFS) loadDir(path string) (*fileInfo, error) {
+	if path == "/" {
+		return fs.root, nil
+	}
+	parent, err := fs.loadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(path)
+	dir, ok := parent.childs[base]
+	if !ok {
+		dir = &fileInfo{
+			name:   base,
+			dir:    true,
+			parent: parent,
+			fs:     fs,
+			childs: make(map[string]*fileInfo),
+			inode:  fs.newInode(0755),
+		}
+		parent.childs[base] = dir
+	}
+	if !dir.dir {
+		return nil, vfs.ErrNotDirectory
+	}
+	return dir, nil
+}
@@ -0,0 +1,170 @@
+package memfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+func TestDumpLoad(t *testing.T) {
+	fs := Create()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	if err := vfs.MkdirAll(fs, "/dir/sub", 0750); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/sub/file", []byte(dots), 0640); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/empty", nil, 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Symlink("dir/sub/file", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	for _, name := range []string{"/dir", "/dir/sub/file", "/empty"} {
+		fs.Chtimes(name, mtime, mtime)
+	}
+	fs.Chown("/dir/sub/file", 1000, 100)
+
+	var buf bytes.Buffer
+	if err := fs.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %s", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+
+	if b, err := vfs.ReadFile(loaded, "/link"); err != nil || string(b) != dots {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+	if target, err := loaded.Readlink("/link"); err != nil || target != "dir/sub/file" {
+		t.Errorf("Unexpected link: %q %v", target, err)
+	}
+	for name, mode := range map[string]os.FileMode{
+		"/dir":          os.ModeDir | 0750,
+		"/dir/sub":      os.ModeDir | 0750,
+		"/dir/sub/file": 0640,
+		"/empty":        0600,
+	} {
+		fi, err := loaded.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat: %s", err)
+		}
+		if fi.Mode() != mode {
+			t.Errorf("Unexpected mode of %s: %s", name, fi.Mode())
+		}
+		if name != "/dir/sub" && !fi.ModTime().Equal(mtime) {
+			t.Errorf("Unexpected modtime of %s: %s", name, fi.ModTime())
+		}
+	}
+	if fi, _ := loaded.Stat("/dir/sub/file"); fi != nil {
+		if uid, gid, ok := vfs.FileOwner(fi); !ok || uid != 1000 || gid != 100 {
+			t.Errorf("Unexpected owner: %d %d", uid, gid)
+		}
+	}
+}
+
+func TestLoadImplicitDirs(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a/b/file", Mode: 0644, Size: 3, Typeflag: tar.TypeReg})
+	tw.Write([]byte("abc"))
+	tw.WriteHeader(&tar.Header{Name: "a/hard", Typeflag: tar.TypeLink, Linkname: "a/b/file"})
+	tw.WriteHeader(&tar.Header{Name: "a/", Mode: 0700, Typeflag: tar.TypeDir})
+	tw.Close()
+
+	fs, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if fi, err := fs.Stat("/a/b"); err != nil || fi.Mode() != os.ModeDir|0755 {
+		t.Errorf("Unexpected implicit directory: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat("/a"); err != nil || fi.Mode() != os.ModeDir|0700 {
+		t.Errorf("Unexpected directory: %v %v", fi, err)
+	}
+	if names, err := fs.ReadDir("/a"); err != nil || len(names) != 2 {
+		t.Errorf("Directory entries lost: %v %v", names, err)
+	}
+	if b, err := vfs.ReadFile(fs, "/a/hard"); err != nil || string(b) != "abc" {
+		t.Errorf("Unexpected content: %q %v", b, err)
+	}
+}
+
+func TestLoadUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "fifo", Typeflag: tar.TypeFifo})
+	tw.Close()
+	if _, err := Load(&buf); err == nil {
+		t.Errorf("Expected error loading unsupported entry")
+	}
+}
+
+func TestDumpLoadHardLinks(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/a", []byte("content"), 0644)
+	fs.Link("/a", "/b")
+
+	var buf bytes.Buffer
+	if err := fs.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %s", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if n := nlink(t, loaded, "/b"); n != 2 {
+		t.Errorf("Expected 2 links, got %d", n)
+	}
+	vfs.WriteFile(loaded, "/b", []byte("changed"), 0644)
+	if b, _ := vfs.ReadFile(loaded, "/a"); string(b) != "changed" {
+		t.Errorf("Hard link loaded as copy: %q", b)
+	}
+}
+
+// TestDumpReproducible dumps the same tree twice, which must yield equal streams with a fixed clock.
+func TestDumpReproducible(t *testing.T) {
+	clock := func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	dump := func() []byte {
+		fs := Create(WithClock(clock), WithOwner(1000, 100))
+		vfs.MkdirAll(fs, "/dir/sub", 0755)
+		vfs.WriteFile(fs, "/dir/sub/file", []byte("content"), 0644)
+		fs.Symlink("sub/file", "/dir/link")
+		var buf bytes.Buffer
+		if err := fs.Dump(&buf); err != nil {
+			t.Fatalf("Dump: %s", err)
+		}
+		return buf.Bytes()
+	}
+	first := dump()
+	time.Sleep(10 * time.Millisecond)
+	if !bytes.Equal(first, dump()) {
+		t.Errorf("Dumps differ")
+	}
+}
+
+func TestLoadOptions(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0644})
+	tw.Close()
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs, err := Load(&buf, WithClock(func() time.Time { return now }), WithOwner(1000, 100))
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	fi, err := fs.Stat("/dir")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if uid, gid, _ := vfs.FileOwner(fi); uid != 1000 || gid != 100 || !fi.ModTime().Equal(now) {
+		t.Errorf("Unexpected implicit directory: %d:%d %s", uid, gid, fi.ModTime())
+	}
+}
@@ -0,0 +1,174 @@
+package memfs
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/blang/vfs"
+)
+
+// watch delivers the events of a watched path.
+// Events are queued, so the filesystem never blocks on slow receivers.
+type watch struct {
+	path   string
+	events chan vfs.Event
+	wake   chan struct{}
+	done   chan struct{}
+
+	mutex sync.Mutex
+	queue []vfs.Event
+}
+
+// watches holds the active watches of a filesystem.
+type watches struct {
+	mutex sync.Mutex
+	m     map[<-chan vfs.Event]*watch
+	// active is the number of watches, read without holding mutex
+	active int32
+}
+
+// Watch reports changes of the named file, or of the named directory and its direct entries.
+// Events are emitted for files created, written, truncated, removed, renamed and for changed metadata.
+// Watches follow paths, not files: a watched file which is renamed is no longer reported.
+// Restore does not emit events, Reset stops all watches.
+// It implements vfs.Watcher.
+func (fs *MemFS) Watch(name string) (<-chan vfs.Event, error) {
+	fs.lock.RLock()
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfo(name)
+	if err == nil && fi == nil {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		fs.lock.RUnlock()
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	w := &watch{
+		path:   fi.AbsPath(),
+		events: make(chan vfs.Event),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	fs.lock.RUnlock()
+
+	fs.watches.mutex.Lock()
+	if fs.watches.m == nil {
+		fs.watches.m = make(map[<-chan vfs.Event]*watch)
+	}
+	fs.watches.m[w.events] = w
+	atomic.StoreInt32(&fs.watches.active, int32(len(fs.watches.m)))
+	fs.watches.mutex.Unlock()
+	go w.run()
+	return w.events, nil
+}
+
+// unwatchAll stops all watches and closes their channels.
+func (fs *MemFS) unwatchAll() {
+	fs.watches.mutex.Lock()
+	m := fs.watches.m
+	fs.watches.m = nil
+	atomic.StoreInt32(&fs.watches.active, 0)
+	fs.watches.mutex.Unlock()
+	for _, w := range m {
+		close(w.done)
+	}
+}
+
+// Unwatch stops a watch started by Watch and closes its channel.
+// Channels not returned by Watch return os.ErrInvalid.
+// It implements vfs.Watcher.
+func (fs *MemFS) Unwatch(events <-chan vfs.Event) error {
+	fs.watches.mutex.Lock()
+	w, ok := fs.watches.m[events]
+	delete(fs.watches.m, events)
+	atomic.StoreInt32(&fs.watches.active, int32(len(fs.watches.m)))
+	fs.watches.mutex.Unlock()
+	if !ok {
+		return os.ErrInvalid
+	}
+	close(w.done)
+	return nil
+}
+
+// watched reports if any watch is active.
+func (fs *MemFS) watched() bool {
+	return atomic.LoadInt32(&fs.watches.active) > 0
+}
+
+// emit queues an event for all watches of path or its parent directory.
+func (fs *MemFS) emit(op vfs.EventOp, path string) {
+	if !fs.watched() {
+		return
+	}
+	dir := vfs.Dir(fs, path)
+	fs.watches.mutex.Lock()
+	defer fs.watches.mutex.Unlock()
+	for _, w := range fs.watches.m {
+		if w.path == path || w.path == dir {
+			w.push(vfs.Event{Name: path, Op: op})
+		}
+	}
+}
+
+// emitTree emits op for fi and all its descendants, deepest first.
+// The caller must hold the lock of the filesystem.
+func (fs *MemFS) emitTree(op vfs.EventOp, fi *fileInfo, path string) {
+	for name, child := range fi.childs {
+		fs.emitTree(op, child, vfs.Join(fs, path, name))
+	}
+	fs.emit(op, path)
+}
+
+// emitFile emits op for a file written through an open handle,
+// unless the file was removed from the filesystem.
+// The caller must not hold the lock of the filesystem.
+func (fs *MemFS) emitFile(op vfs.EventOp, fi *fileInfo) {
+	if !fs.watched() {
+		return
+	}
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+	n := fi
+	for ; n.parent != nil; n = n.parent {
+		if c, _ := n.parent.child(n.name); c != n {
+			return
+		}
+	}
+	if n == fs.root {
+		fs.emit(op, fi.AbsPath())
+	}
+}
+
+func (w *watch) push(ev vfs.Event) {
+	w.mutex.Lock()
+	w.queue = append(w.queue, ev)
+	w.mutex.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued events until the watch is stopped.
+func (w *watch) run() {
+	defer close(w.events)
+	for {
+		w.mutex.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mutex.Unlock()
+		for _, ev := range queue {
+			select {
+			case w.events <- ev:
+			case <-w.done:
+				return
+			}
+		}
+		select {
+		case <-w.wake:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package memfs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// expectEvents receives the expected events in order.
+func expectEvents(t *testing.T, events <-chan vfs.Event, expected ...vfs.Event) {
+	t.Helper()
+	for _, exp := range expected {
+		select {
+		case ev := <-events:
+			if ev != exp {
+				t.Errorf("Expected event %s, got %s", exp, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Missing event %s", exp)
+		}
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0755)
+	events, err := fs.Watch("/dir")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	defer fs.Unwatch(events)
+
+	f, err := fs.OpenFile("/dir/file", os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	f.Write([]byte(abc))
+	f.Truncate(2)
+	f.Close()
+	fs.Chmod("/dir/file", 0600)
+	fs.Rename("/dir/file", "/dir/moved")
+	fs.Rename("/dir/moved", "/moved")
+	fs.MkdirAll("/dir/sub/subsub", 0755)
+	// Events of deeper descendants are not reported
+	vfs.WriteFile(fs, "/dir/sub/subsub/file", []byte(abc), 0644)
+	fs.Symlink("/moved", "/dir/link")
+	fs.Remove("/dir/link")
+	fs.RemoveAll("/dir/sub")
+
+	expectEvents(t, events,
+		vfs.Event{Name: "/dir/file", Op: vfs.EventCreate},
+		vfs.Event{Name: "/dir/file", Op: vfs.EventWrite},
+		vfs.Event{Name: "/dir/file", Op: vfs.EventWrite},
+		vfs.Event{Name: "/dir/file", Op: vfs.EventChmod},
+		vfs.Event{Name: "/dir/file", Op: vfs.EventRename},
+		vfs.Event{Name: "/dir/moved", Op: vfs.EventCreate},
+		vfs.Event{Name: "/dir/moved", Op: vfs.EventRename},
+		vfs.Event{Name: "/dir/sub", Op: vfs.EventCreate},
+		vfs.Event{Name: "/dir/link", Op: vfs.EventCreate},
+		vfs.Event{Name: "/dir/link", Op: vfs.EventRemove},
+		vfs.Event{Name: "/dir/sub", Op: vfs.EventRemove},
+	)
+}
+
+func TestWatchFile(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/file", []byte(dots), 0644)
+	f, err := fs.OpenFile("/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	events, err := fs.Watch("/file")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+
+	vfs.WriteFile(fs, "/other", []byte(abc), 0644)
+	f.Write([]byte(abc))
+	fs.Remove("/file")
+	// Writes to removed files are not reported
+	f.Write([]byte(abc))
+	vfs.WriteFile(fs, "/file", []byte(abc), 0644)
+
+	expectEvents(t, events,
+		vfs.Event{Name: "/file", Op: vfs.EventWrite},
+		vfs.Event{Name: "/file", Op: vfs.EventRemove},
+		vfs.Event{Name: "/file", Op: vfs.EventCreate},
+		vfs.Event{Name: "/file", Op: vfs.EventWrite},
+	)
+
+	if err := fs.Unwatch(events); err != nil {
+		t.Fatalf("Unwatch: %s", err)
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("Expected closed channel")
+	}
+	if err := fs.Unwatch(events); err != os.ErrInvalid {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestWatchMissing(t *testing.T) {
+	fs := Create()
+	if _, err := fs.Watch("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package memfs
+
+import (
+	"os"
+	"sort"
+
+	"github.com/blang/vfs"
+)
+
+// GetXattr returns the value of the extended attribute attr of the named file, following symbolic links.
+// It implements vfs.Xattrer.
+func (fs *MemFS) GetXattr(name, attr string) ([]byte, error) {
+	var value []byte
+	err := fs.getAttr("getxattr", name, func(fi *fileInfo) error {
+		v, ok := fi.xattrs[attr]
+		if !ok {
+			return vfs.ErrNoXattr
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+// ListXattr returns the sorted names of the extended attributes of the named file, following symbolic links.
+// It implements vfs.Xattrer.
+func (fs *MemFS) ListXattr(name string) ([]string, error) {
+	var names []string
+	err := fs.getAttr("listxattr", name, func(fi *fileInfo) error {
+		names = make([]string, 0, len(fi.xattrs))
+		for attr := range fi.xattrs {
+			names = append(names, attr)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file, following symbolic links.
+// It implements vfs.Xattrer.
+func (fs *MemFS) SetXattr(name, attr string, value []byte) error {
+	if attr == "" {
+		return &os.PathError{Op: "setxattr", Path: name, Err: os.ErrInvalid}
+	}
+	value = append([]byte{}, value...)
+	return fs.setAttr("setxattr", name, func(fi *fileInfo) {
+		if fi.xattrs == nil {
+			fi.xattrs = make(map[string][]byte)
+		}
+		fi.xattrs[attr] = value
+	})
+}
+
+// RemoveXattr removes the extended attribute attr of the named file, following symbolic links.
+// It implements vfs.Xattrer.
+func (fs *MemFS) RemoveXattr(name, attr string) error {
+	err := fs.getAttr("removexattr", name, func(fi *fileInfo) error {
+		if _, ok := fi.xattrs[attr]; !ok {
+			return vfs.ErrNoXattr
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return fs.setAttr("removexattr", name, func(fi *fileInfo) {
+		delete(fi.xattrs, attr)
+	})
+}
+
+// getAttr calls get with the named file while holding its read lock.
+func (fs *MemFS) getAttr(op, name string, get func(fi *fileInfo) error) error {
+	fs.lock.RLock()
+	defer fs.lock.RUnlock()
+
+	name = vfs.Clean(fs, name)
+	_, fi, err := fs.fileInfoFollow(name)
+	if err == nil && fi == nil {
+		err = os.ErrNotExist
+	}
+	if err == nil {
+		fi.mutex.RLock()
+		err = get(fi)
+		fi.mutex.RUnlock()
+	}
+	if err != nil {
+		return &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return nil
+}
+
+// copyXattrs returns a copy of the extended attributes, the values are never modified in place.
+func copyXattrs(xattrs map[string][]byte) map[string][]byte {
+	if xattrs == nil {
+		return nil
+	}
+	c := make(map[string][]byte, len(xattrs))
+	for attr, value := range xattrs {
+		c[attr] = value
+	}
+	return c
+}
@@ -0,0 +1,97 @@
+package memfs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+)
+
+func TestXattr(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	fs.Symlink("file", "/link")
+
+	value := []byte("value")
+	if err := fs.SetXattr("/link", "user.b", value); err != nil {
+		t.Fatalf("SetXattr: %s", err)
+	}
+	value[0] = 'V'
+	if err := fs.SetXattr("/file", "user.a", nil); err != nil {
+		t.Fatalf("SetXattr: %s", err)
+	}
+	if v, err := fs.GetXattr("/file", "user.b"); err != nil || string(v) != "value" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+	if attrs, err := fs.ListXattr("/link"); err != nil || !reflect.DeepEqual(attrs, []string{"user.a", "user.b"}) {
+		t.Errorf("Unexpected attributes: %q %v", attrs, err)
+	}
+	if err := fs.RemoveXattr("/file", "user.b"); err != nil {
+		t.Fatalf("RemoveXattr: %s", err)
+	}
+	if _, err := fs.GetXattr("/file", "user.b"); !errors.Is(err, vfs.ErrNoXattr) {
+		t.Errorf("Expected ErrNoXattr, got %v", err)
+	}
+	if err := fs.RemoveXattr("/file", "user.b"); !errors.Is(err, vfs.ErrNoXattr) {
+		t.Errorf("Expected ErrNoXattr, got %v", err)
+	}
+	if _, err := fs.ListXattr("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := fs.SetXattr("/file", "", nil); err == nil {
+		t.Errorf("Expected error for empty attribute name")
+	}
+}
+
+func TestXattrHardLink(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	fs.Link("/file", "/hard")
+	fs.SetXattr("/hard", "user.a", []byte("1"))
+	if v, err := fs.GetXattr("/file", "user.a"); err != nil || string(v) != "1" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+}
+
+func TestXattrSnapshot(t *testing.T) {
+	fs := Create()
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	fs.SetXattr("/file", "user.a", []byte("1"))
+	snap := fs.Snapshot()
+	fs.SetXattr("/file", "user.a", []byte("2"))
+	fs.SetXattr("/file", "user.b", []byte("3"))
+
+	fs.Restore(snap)
+	if attrs, _ := fs.ListXattr("/file"); !reflect.DeepEqual(attrs, []string{"user.a"}) {
+		t.Errorf("Unexpected attributes: %q", attrs)
+	}
+	if v, _ := fs.GetXattr("/file", "user.a"); string(v) != "1" {
+		t.Errorf("Unexpected value: %q", v)
+	}
+}
+
+func TestDumpLoadXattrs(t *testing.T) {
+	fs := Create()
+	fs.Mkdir("/dir", 0755)
+	vfs.WriteFile(fs, "/dir/file", []byte(dots), 0644)
+	fs.SetXattr("/dir", "user.dir", []byte("d"))
+	fs.SetXattr("/dir/file", "user.bin", []byte{0, 1, 2})
+
+	var buf bytes.Buffer
+	if err := fs.Dump(&buf); err != nil {
+		t.Fatalf("Dump: %s", err)
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if v, err := loaded.GetXattr("/dir", "user.dir"); err != nil || string(v) != "d" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+	if v, err := loaded.GetXattr("/dir/file", "user.bin"); err != nil || !bytes.Equal(v, []byte{0, 1, 2}) {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+}
@@ -0,0 +1,11 @@
+// Package mockfs defines a filesystem for tests which only answers the operations
+// a test expects and reports every other operation as a test failure.
+//
+// Expectations are declared per operation and path, calls can be counted:
+//
+//	fs := mockfs.Create(t)
+//	fs.On("OpenFile", "/etc/passwd").Return(vfs.DummyFile(nil), nil).Once()
+//	fs.On("Stat", "").Return(nil, os.ErrNotExist)
+//	runCode(fs)
+//	fs.AssertExpectations()
+package mockfs
@@ -0,0 +1,29 @@
+package mockfs_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/mockfs"
+)
+
+// configExists is the code under test.
+func configExists(fs vfs.Filesystem) bool {
+	_, err := fs.Stat("/etc/app.conf")
+	return err == nil
+}
+
+func ExampleFS() {
+	t := &testing.T{} // Use the *testing.T of the test
+	fs := mockfs.Create(t)
+	fs.On("Stat", "/etc/app.conf").Return(nil, os.ErrNotExist).Once()
+
+	fmt.Println(configExists(fs))
+	fs.AssertExpectations()
+	fmt.Println(fs.Calls())
+	// Output:
+	// false
+	// [Stat[/etc/app.conf]]
+}
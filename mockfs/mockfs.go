@@ -0,0 +1,249 @@
+package mockfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/blang/vfs"
+)
+
+// ErrUnexpectedCall is returned by operations without a matching expectation.
+var ErrUnexpectedCall = errors.New("Unexpected call")
+
+// FS is a filesystem answering operations from expectations.
+type FS struct {
+	t testing.TB
+
+	mutex        sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+}
+
+// Create returns a mock without expectations, failures are reported to t.
+func Create(t testing.TB) *FS {
+	return &FS{t: t}
+}
+
+// Call describes an operation invoked on the mock.
+type Call struct {
+	// Method is the name of the Filesystem method, e.g. "OpenFile".
+	Method string
+	// Args are the arguments of the call.
+	Args []interface{}
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s%v", c.Method, c.Args)
+}
+
+// Expectation describes an operation expected by the test and its results.
+type Expectation struct {
+	fs      *FS
+	method  string
+	path    string
+	returns []interface{}
+	times   int
+	calls   int
+}
+
+// On adds an expectation of the method, named like the methods of vfs.Filesystem,
+// called with path as first path argument and returns it. An empty path matches every path.
+// Calls are matched against the expectations in the order they were added,
+// an expectation limited by Times no longer matches once the limit is reached.
+func (fs *FS) On(method, path string) *Expectation {
+	e := &Expectation{fs: fs, method: method, path: path}
+	fs.mutex.Lock()
+	fs.expectations = append(fs.expectations, e)
+	fs.mutex.Unlock()
+	return e
+}
+
+// Return sets the results of the matching calls, in the order of the results of the method.
+// Missing results are the zero values, e.g. Return() lets Remove succeed.
+func (e *Expectation) Return(values ...interface{}) *Expectation {
+	e.fs.mutex.Lock()
+	e.returns = values
+	e.fs.mutex.Unlock()
+	return e
+}
+
+// Times expects exactly n calls.
+func (e *Expectation) Times(n int) *Expectation {
+	e.fs.mutex.Lock()
+	e.times = n
+	e.fs.mutex.Unlock()
+	return e
+}
+
+// Once expects exactly one call.
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Calls returns the number of calls matched by the expectation.
+func (e *Expectation) Calls() int {
+	e.fs.mutex.Lock()
+	defer e.fs.mutex.Unlock()
+	return e.calls
+}
+
+// Calls returns all operations invoked on the mock, including unexpected ones.
+func (fs *FS) Calls() []Call {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return append([]Call(nil), fs.calls...)
+}
+
+// AssertExpectations reports every expectation which was never called,
+// or not as often as set by Times, as a test failure.
+func (fs *FS) AssertExpectations() {
+	fs.t.Helper()
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	for _, e := range fs.expectations {
+		switch {
+		case e.times > 0 && e.calls != e.times:
+			fs.t.Errorf("mockfs: expected %d calls of %s %q, got %d", e.times, e.method, e.path, e.calls)
+		case e.calls == 0:
+			fs.t.Errorf("mockfs: expected call of %s %q", e.method, e.path)
+		}
+	}
+}
+
+// call records the call and returns the results of the matching expectation,
+// ok is false for unexpected calls.
+func (fs *FS) call(method, path string, args ...interface{}) (returns []interface{}, ok bool) {
+	fs.t.Helper()
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	c := Call{Method: method, Args: args}
+	fs.calls = append(fs.calls, c)
+	for _, e := range fs.expectations {
+		if e.method != method || (e.path != "" && e.path != path) || (e.times > 0 && e.calls >= e.times) {
+			continue
+		}
+		e.calls++
+		return e.returns, true
+	}
+	fs.t.Errorf("mockfs: unexpected call %s", c)
+	return nil, false
+}
+
+// result returns the result i of the type of v, the zero value if it is missing.
+func (fs *FS) result(method string, returns []interface{}, i int, v interface{}) {
+	fs.t.Helper()
+	if i >= len(returns) || returns[i] == nil {
+		return
+	}
+	var ok bool
+	switch v := v.(type) {
+	case *vfs.File:
+		*v, ok = returns[i].(vfs.File)
+	case *os.FileInfo:
+		*v, ok = returns[i].(os.FileInfo)
+	case *[]os.FileInfo:
+		*v, ok = returns[i].([]os.FileInfo)
+	case *error:
+		*v, ok = returns[i].(error)
+	}
+	if !ok {
+		fs.t.Errorf("mockfs: invalid result %d of %s: %T", i, method, returns[i])
+	}
+}
+
+// PathSeparator returns '/'.
+func (fs *FS) PathSeparator() uint8 {
+	return '/'
+}
+
+// OpenFile returns the vfs.File and error of the matching expectation.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	fs.t.Helper()
+	returns, ok := fs.call("OpenFile", name, name, flag, perm)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrUnexpectedCall}
+	}
+	var f vfs.File
+	var err error
+	fs.result("OpenFile", returns, 0, &f)
+	fs.result("OpenFile", returns, 1, &err)
+	return f, err
+}
+
+// Remove returns the error of the matching expectation.
+func (fs *FS) Remove(name string) error {
+	fs.t.Helper()
+	returns, ok := fs.call("Remove", name, name)
+	if !ok {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrUnexpectedCall}
+	}
+	var err error
+	fs.result("Remove", returns, 0, &err)
+	return err
+}
+
+// Rename returns the error of the matching expectation, which is matched against oldpath.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	fs.t.Helper()
+	returns, ok := fs.call("Rename", oldpath, oldpath, newpath)
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrUnexpectedCall}
+	}
+	var err error
+	fs.result("Rename", returns, 0, &err)
+	return err
+}
+
+// Mkdir returns the error of the matching expectation.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	fs.t.Helper()
+	returns, ok := fs.call("Mkdir", name, name, perm)
+	if !ok {
+		return &os.PathError{Op: "mkdir", Path: name, Err: ErrUnexpectedCall}
+	}
+	var err error
+	fs.result("Mkdir", returns, 0, &err)
+	return err
+}
+
+// Stat returns the os.FileInfo and error of the matching expectation.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fs.t.Helper()
+	return fs.stat("Stat", name)
+}
+
+// Lstat returns the os.FileInfo and error of the matching expectation.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fs.t.Helper()
+	return fs.stat("Lstat", name)
+}
+
+func (fs *FS) stat(method, name string) (os.FileInfo, error) {
+	fs.t.Helper()
+	returns, ok := fs.call(method, name, name)
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: ErrUnexpectedCall}
+	}
+	var fi os.FileInfo
+	var err error
+	fs.result(method, returns, 0, &fi)
+	fs.result(method, returns, 1, &err)
+	return fi, err
+}
+
+// ReadDir returns the []os.FileInfo and error of the matching expectation.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.t.Helper()
+	returns, ok := fs.call("ReadDir", path, path)
+	if !ok {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: ErrUnexpectedCall}
+	}
+	var fis []os.FileInfo
+	var err error
+	fs.result("ReadDir", returns, 0, &fis)
+	fs.result("ReadDir", returns, 1, &err)
+	return fis, err
+}
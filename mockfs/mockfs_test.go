@@ -0,0 +1,92 @@
+package mockfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+)
+
+// recorder collects the failures reported by the mock.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(Create(t))
+}
+
+func TestExpectations(t *testing.T) {
+	fs := Create(t)
+	file := vfs.DummyFile(nil)
+	fi := vfs.DumFileInfo{IName: "passwd"}
+	fs.On("OpenFile", "/etc/passwd").Return(file, nil).Once()
+	fs.On("OpenFile", "").Return(nil, os.ErrPermission)
+	fs.On("Stat", "/etc/passwd").Return(fi, nil)
+	fs.On("Remove", "").Return()
+
+	if f, err := fs.OpenFile("/etc/passwd", os.O_RDONLY, 0); f != file || err != nil {
+		t.Errorf("Unexpected result: %v %v", f, err)
+	}
+	// The first expectation is used up
+	if _, err := fs.OpenFile("/etc/passwd", os.O_RDONLY, 0); err != os.ErrPermission {
+		t.Errorf("Expected permission error, got %v", err)
+	}
+	if got, err := fs.Stat("/etc/passwd"); got != fi || err != nil {
+		t.Errorf("Unexpected result: %v %v", got, err)
+	}
+	if err := fs.Remove("/tmp/file"); err != nil {
+		t.Errorf("Unexpected result: %v", err)
+	}
+	fs.AssertExpectations()
+
+	calls := fs.Calls()
+	if len(calls) != 4 || calls[0].String() != "OpenFile[/etc/passwd 0 ----------]" || calls[3].Method != "Remove" {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+}
+
+func TestUnexpected(t *testing.T) {
+	r := &recorder{TB: t}
+	fs := Create(r)
+	fs.On("Mkdir", "/a").Once()
+	fs.On("Stat", "/b")
+	fs.On("ReadDir", "/c").Return("wrong", nil)
+
+	if err := fs.Mkdir("/a", 0755); err != nil {
+		t.Errorf("Unexpected result: %v", err)
+	}
+	if err := fs.Mkdir("/a", 0755); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("Expected ErrUnexpectedCall, got %v", err)
+	}
+	if err := fs.Rename("/a", "/b"); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("Expected ErrUnexpectedCall, got %v", err)
+	}
+	fs.ReadDir("/c")
+	fs.AssertExpectations()
+
+	expected := []string{
+		"unexpected call Mkdir[/a -rwxr-xr-x]",
+		"unexpected call Rename[/a /b]",
+		"invalid result 0 of ReadDir: string",
+		"expected call of Stat \"/b\"",
+	}
+	if len(r.failures) != 4 {
+		t.Fatalf("Unexpected failures: %q", r.failures)
+	}
+	for i, failure := range r.failures {
+		if !strings.Contains(failure, expected[i]) {
+			t.Errorf("Unexpected failure %q, expected %q", failure, expected[i])
+		}
+	}
+}
@@ -1,23 +1,23 @@
 package mountfs
 
 import (
-	"errors"
 	"github.com/blang/vfs"
 	"os"
-	filepath "path"
+	"sort"
 	"strings"
+	"time"
 )
 
 // ErrBoundary is returned if an operation
 // can not act across filesystem boundaries.
-var ErrBoundary = errors.New("Crossing boundary")
+// It is the same error as vfs.ErrCrossDevice.
+var ErrBoundary = vfs.ErrCrossDevice
 
 // Create a new MountFS based on a root filesystem.
 func Create(rootFS vfs.Filesystem) *MountFS {
 	return &MountFS{
-		rootFS:  rootFS,
-		mounts:  make(map[string]vfs.Filesystem),
-		parents: make(map[string][]string),
+		rootFS: rootFS,
+		mounts: make(map[string]vfs.Filesystem),
 	}
 }
 
@@ -30,10 +30,13 @@ func Create(rootFS vfs.Filesystem) *MountFS {
 // It's not possible to mount a specific source directory, only the
 // root of the filesystem can be mounted, use a chroot in this case.
 // The resulting filesystem is case-sensitive.
+//
+// Mountpoints are listed by ReadDir of their parent directory.
+// Missing parent directories of mountpoints are synthesized as read-only directories,
+// so Walk descends into all mounted filesystems.
 type MountFS struct {
-	rootFS  vfs.Filesystem
-	mounts  map[string]vfs.Filesystem
-	parents map[string][]string
+	rootFS vfs.Filesystem
+	mounts map[string]vfs.Filesystem
 }
 
 // Mount mounts a filesystem on the given path.
@@ -42,29 +45,95 @@ type MountFS struct {
 // Path `/` can be used to change rootfs.
 // Only absolute paths are allowed.
 func (fs *MountFS) Mount(mount vfs.Filesystem, path string) error {
-	pathSeparator := string(fs.rootFS.PathSeparator())
-
-	// Clean path and make absolute
-	path = filepath.Clean(path)
-	segm := vfs.SplitPath(path, pathSeparator)
-	segm[0] = "" // make absolute
-	path = strings.Join(segm, pathSeparator)
+	path = fs.mountPath(path)
 
 	// Change rootfs disabled
 	if path == "" {
 		fs.rootFS = mount
 		return nil
 	}
+	fs.mounts[path] = mount
+	return nil
+}
 
-	parent := strings.Join(segm[0:len(segm)-1], pathSeparator)
-	if parent == "" {
-		parent = "/"
+// Unmount removes the filesystem mounted on the given path.
+// Filesystems mounted below path stay mounted.
+// The root filesystem can not be unmounted.
+func (fs *MountFS) Unmount(path string) error {
+	mountPath := fs.mountPath(path)
+	if _, ok := fs.mounts[mountPath]; !ok {
+		return &os.PathError{Op: "unmount", Path: path, Err: os.ErrInvalid}
 	}
-	fs.parents[parent] = append(fs.parents[parent], path)
-	fs.mounts[path] = mount
+	delete(fs.mounts, mountPath)
 	return nil
 }
 
+// Mounts returns the sorted paths of all mountpoints, the root filesystem is not included.
+func (fs *MountFS) Mounts() []string {
+	paths := make([]string, 0, len(fs.mounts))
+	for path := range fs.mounts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// clean returns the cleaned absolute form of path.
+func (fs MountFS) clean(path string) string {
+	vol := vfs.VolumeName(fs, path)
+	return vfs.Clean(fs, vol+string(fs.PathSeparator())+path[len(vol):])
+}
+
+// isRoot reports whether the cleaned path is the root directory.
+func (fs MountFS) isRoot(path string) bool {
+	_, name := vfs.Split(fs, path)
+	return name == ""
+}
+
+// mountPath returns the cleaned absolute path used as key of a mountpoint,
+// the root is the empty string.
+func (fs *MountFS) mountPath(path string) string {
+	path = fs.clean(path)
+	if fs.isRoot(path) {
+		return ""
+	}
+	return path
+}
+
+// mountChilds returns the names of the entries of the directory path leading to mountpoints.
+// The names of mountpoints map to their path, other names to the empty string.
+func (fs MountFS) mountChilds(path string) map[string]string {
+	pathSeparator := string(fs.PathSeparator())
+	prefix := vfs.JoinPath(fs, fs.clean(path), "")
+	childs := make(map[string]string)
+	for mountPath := range fs.mounts {
+		if !strings.HasPrefix(mountPath, prefix) {
+			continue
+		}
+		rest := mountPath[len(prefix):]
+		if i := strings.Index(rest, pathSeparator); i >= 0 {
+			if _, ok := childs[rest[:i]]; !ok {
+				childs[rest[:i]] = ""
+			}
+		} else {
+			childs[rest] = mountPath
+		}
+	}
+	return childs
+}
+
+// mountDirInfo describes a synthesized parent directory of a mountpoint.
+type mountDirInfo struct {
+	name string
+}
+
+func (fi mountDirInfo) Name() string       { return fi.name }
+func (fi mountDirInfo) Size() int64        { return 0 }
+func (fi mountDirInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (fi mountDirInfo) ModTime() time.Time { return time.Time{} }
+func (fi mountDirInfo) IsDir() bool        { return true }
+func (fi mountDirInfo) Sys() interface{}   { return nil }
+
 // PathSeparator returns the path separator
 func (fs MountFS) PathSeparator() uint8 {
 	return fs.rootFS.PathSeparator()
@@ -72,17 +141,15 @@ func (fs MountFS) PathSeparator() uint8 {
 
 // findMount finds a valid mountpoint for the given path.
 // It returns the corresponding filesystem and the path inside of this filesystem.
-func findMount(path string, mounts map[string]vfs.Filesystem, fallback vfs.Filesystem, pathSeparator string) (vfs.Filesystem, string) {
-	path = filepath.Clean(path)
-	segs := vfs.SplitPath(path, pathSeparator)
-	l := len(segs)
-	for i := l; i > 0; i-- {
-		mountPath := strings.Join(segs[0:i], pathSeparator)
-		if fs, ok := mounts[mountPath]; ok {
-			return fs, "/" + strings.Join(segs[i:l], pathSeparator)
+func (fs MountFS) findMount(path string) (vfs.Filesystem, string) {
+	pathSeparator := string(fs.PathSeparator())
+	path = fs.clean(path)
+	for mountPath := path; !fs.isRoot(mountPath); mountPath = vfs.Dir(fs, mountPath) {
+		if mount, ok := fs.mounts[mountPath]; ok {
+			return mount, pathSeparator + strings.TrimPrefix(path[len(mountPath):], pathSeparator)
 		}
 	}
-	return fallback, path
+	return fs.rootFS, path
 }
 
 type innerFile struct {
@@ -98,23 +165,37 @@ func (f innerFile) Name() string {
 // OpenFile find the mount of the given path and executes OpenFile
 // on the corresponding filesystem.
 // It wraps the resulting file to return the path inside mountfs on Name()
+// Directories containing mountpoints are opened as listing of ReadDir.
 func (fs MountFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) == 0 && len(fs.mountChilds(name)) > 0 {
+		if fi, err := fs.Stat(name); err == nil && fi.IsDir() {
+			return vfs.DirFile(fs, name)
+		}
+	}
+	mount, innerPath := fs.findMount(name)
 	file, err := mount.OpenFile(innerPath, flag, perm)
 	return innerFile{File: file, name: name}, err
 }
 
 // Remove removes a file or directory
 func (fs MountFS) Remove(name string) error {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	mount, innerPath := fs.findMount(name)
 	return mount.Remove(innerPath)
 }
 
+// RemoveAll removes path and any children it contains
+// using the filesystem path is mounted on.
+// Mountpoints below path are not removed.
+func (fs MountFS) RemoveAll(path string) error {
+	mount, innerPath := fs.findMount(path)
+	return vfs.RemoveAll(mount, innerPath)
+}
+
 // Rename renames a file.
 // Renames across filesystems are not allowed.
 func (fs MountFS) Rename(oldpath, newpath string) error {
-	oldMount, oldInnerPath := findMount(oldpath, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
-	newMount, newInnerPath := findMount(newpath, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	oldMount, oldInnerPath := fs.findMount(oldpath)
+	newMount, newInnerPath := fs.findMount(newpath)
 	if oldMount != newMount {
 		return ErrBoundary
 	}
@@ -123,10 +204,84 @@ func (fs MountFS) Rename(oldpath, newpath string) error {
 
 // Mkdir creates a directory
 func (fs MountFS) Mkdir(name string, perm os.FileMode) error {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	mount, innerPath := fs.findMount(name)
 	return mount.Mkdir(innerPath, perm)
 }
 
+// Symlink creates newname as a symbolic link to oldname
+// on the filesystem newname is mounted on.
+// The link target is stored unchanged and resolved by that filesystem.
+func (fs MountFS) Symlink(oldname, newname string) error {
+	mount, innerPath := fs.findMount(newname)
+	return vfs.Symlink(mount, oldname, innerPath)
+}
+
+// Link creates newname as a hard link to oldname.
+// Both paths must be on the same mounted filesystem, ErrBoundary is returned otherwise.
+func (fs MountFS) Link(oldname, newname string) error {
+	oldMount, oldInner := fs.findMount(oldname)
+	newMount, newInner := fs.findMount(newname)
+	if oldMount != newMount {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: ErrBoundary}
+	}
+	return vfs.Link(oldMount, oldInner, newInner)
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs MountFS) Readlink(name string) (string, error) {
+	mount, innerPath := fs.findMount(name)
+	return vfs.Readlink(mount, innerPath)
+}
+
+// MkdirAll creates a directory along with any necessary parents
+// on the filesystem path is mounted on.
+func (fs MountFS) MkdirAll(path string, perm os.FileMode) error {
+	mount, innerPath := fs.findMount(path)
+	return vfs.MkdirAll(mount, innerPath, perm)
+}
+
+// Chmod changes the mode of the named file.
+func (fs MountFS) Chmod(name string, mode os.FileMode) error {
+	mount, innerPath := fs.findMount(name)
+	return vfs.Chmod(mount, innerPath, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs MountFS) Chown(name string, uid, gid int) error {
+	mount, innerPath := fs.findMount(name)
+	return vfs.Chown(mount, innerPath, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs MountFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	mount, innerPath := fs.findMount(name)
+	return vfs.Chtimes(mount, innerPath, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs MountFS) GetXattr(name, attr string) ([]byte, error) {
+	mount, innerPath := fs.findMount(name)
+	return vfs.GetXattr(mount, innerPath, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs MountFS) SetXattr(name, attr string, value []byte) error {
+	mount, innerPath := fs.findMount(name)
+	return vfs.SetXattr(mount, innerPath, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs MountFS) ListXattr(name string) ([]string, error) {
+	mount, innerPath := fs.findMount(name)
+	return vfs.ListXattr(mount, innerPath)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs MountFS) RemoveXattr(name, attr string) error {
+	mount, innerPath := fs.findMount(name)
+	return vfs.RemoveXattr(mount, innerPath, attr)
+}
+
 type innerFileInfo struct {
 	os.FileInfo
 	name string
@@ -136,44 +291,71 @@ func (fi innerFileInfo) Name() string {
 	return fi.name
 }
 
-// Stat returns the fileinfo of a file
+// Stat returns the fileinfo of a file.
+// Missing parent directories of mountpoints are synthesized.
 func (fs MountFS) Stat(name string) (os.FileInfo, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	mount, innerPath := fs.findMount(name)
 	fi, err := mount.Stat(innerPath)
-	if innerPath == "/" {
-		return innerFileInfo{FileInfo: fi, name: filepath.Base(name)}, err
+	if fs.isRoot(innerPath) {
+		return innerFileInfo{FileInfo: fi, name: vfs.Base(fs, name)}, err
+	}
+	if os.IsNotExist(err) && len(fs.mountChilds(name)) > 0 {
+		return mountDirInfo{name: vfs.Base(fs, name)}, nil
 	}
 	return fi, err
 }
 
 // Lstat returns the fileinfo of a file or link.
+// Missing parent directories of mountpoints are synthesized.
 func (fs MountFS) Lstat(name string) (os.FileInfo, error) {
-	mount, innerPath := findMount(name, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	mount, innerPath := fs.findMount(name)
 	fi, err := mount.Lstat(innerPath)
-	if innerPath == "/" {
-		return innerFileInfo{FileInfo: fi, name: filepath.Base(name)}, err
+	if fs.isRoot(innerPath) {
+		return innerFileInfo{FileInfo: fi, name: vfs.Base(fs, name)}, err
+	}
+	if os.IsNotExist(err) && len(fs.mountChilds(name)) > 0 {
+		return mountDirInfo{name: vfs.Base(fs, name)}, nil
 	}
 	return fi, err
 }
 
 // ReadDir reads the directory named by path and returns a list of sorted directory entries.
+// Mountpoints and directories leading to them are included,
+// a missing directory containing mountpoints is synthesized.
 func (fs MountFS) ReadDir(path string) ([]os.FileInfo, error) {
-	path = filepath.Clean(path)
-	mount, innerPath := findMount(path, fs.mounts, fs.rootFS, string(fs.PathSeparator()))
+	path = fs.clean(path)
+	mount, innerPath := fs.findMount(path)
 
 	fis, err := mount.ReadDir(innerPath)
+	childs := fs.mountChilds(path)
 	if err != nil {
-		return fis, err
+		if len(childs) == 0 || !os.IsNotExist(err) {
+			return fis, err
+		}
+		fis = nil
+	}
+	if len(childs) == 0 {
+		return fis, nil
 	}
 
-	// Add mountpoints
-	if childs, ok := fs.parents[path]; ok {
-		for _, c := range childs {
-			mfi, err := fs.Stat(c)
-			if err == nil {
-				fis = append(fis, mfi)
+	// Mountpoints hide entries of the same name
+	entries := make(map[string]os.FileInfo, len(fis)+len(childs))
+	for _, fi := range fis {
+		entries[fi.Name()] = fi
+	}
+	for name, mountPath := range childs {
+		if mountPath != "" {
+			if mfi, err := fs.Stat(mountPath); err == nil {
+				entries[name] = mfi
 			}
+		} else if fi, ok := entries[name]; !ok || !fi.IsDir() {
+			entries[name] = mountDirInfo{name: name}
 		}
 	}
-	return fis, err
+	fis = make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		fis = append(fis, fi)
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, nil
 }
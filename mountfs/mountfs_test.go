@@ -3,7 +3,9 @@ package mountfs
 import (
 	"errors"
 	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -50,6 +52,7 @@ var mountTests = []mountTest{
 
 func TestInterface(t *testing.T) {
 	_ = vfs.Filesystem(Create(nil))
+	_ = vfs.Xattrer(Create(nil))
 }
 
 func TestFindMount(t *testing.T) {
@@ -66,7 +69,7 @@ func TestFindMount(t *testing.T) {
 			expMountPath := expRes.mountPath
 			expInnerPath := expRes.innerPath
 
-			res, resInnerPath := findMount(path, mounts, fallback, "/")
+			res, resInnerPath := MountFS{rootFS: fallback, mounts: mounts}.findMount(path)
 			if res == nil {
 				t.Errorf("Got nil")
 				continue
@@ -388,3 +391,149 @@ func TestReadDirMountPoints(t *testing.T) {
 		t.Errorf("Expected mountpoint, but got: %s", fis)
 	}
 }
+
+func TestSymlink(t *testing.T) {
+	fs := Create(vfs.Dummy(errors.New("Rootfs")))
+	mfs := memfs.Create()
+	fs.Mount(mfs, "/mnt")
+
+	if err := vfs.Symlink(fs, "/target", "/mnt/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	if target, err := mfs.Readlink("/link"); err != nil || target != "/target" {
+		t.Errorf("Link not created on mount: %q %v", target, err)
+	}
+	if target, err := vfs.Readlink(fs, "/mnt/link"); err != nil || target != "/target" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+	if err := vfs.Symlink(fs, "/target", "/link"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported on rootfs, got %v", err)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	fs := Create(vfs.Dummy(errors.New("Rootfs")))
+	mfs := memfs.Create()
+	fs.Mount(mfs, "/mnt")
+	vfs.WriteFile(mfs, "/file", nil, 0644)
+
+	if err := vfs.Chmod(fs, "/mnt/file", 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if fi, _ := mfs.Stat("/file"); fi.Mode().Perm() != 0600 {
+		t.Errorf("Mode not changed on mount: %s", fi.Mode())
+	}
+	if err := vfs.Chown(fs, "/file", 1, 1); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported on rootfs, got %v", err)
+	}
+}
+
+func TestUnmount(t *testing.T) {
+	root, mnt := memfs.Create(), memfs.Create()
+	vfs.WriteFile(root, "/file", []byte("root"), 0644)
+	vfs.WriteFile(mnt, "/file", []byte("mount"), 0644)
+	fs := Create(root)
+	fs.Mount(mnt, "/mnt")
+	fs.Mount(mnt, "/mnt/")
+	fs.Mount(mnt, "/a/b")
+
+	if m := fs.Mounts(); !reflect.DeepEqual(m, []string{"/a/b", "/mnt"}) {
+		t.Errorf("Unexpected mounts: %q", m)
+	}
+	if err := fs.Unmount("/mnt/"); err != nil {
+		t.Fatalf("Unmount: %s", err)
+	}
+	if err := fs.Unmount("/mnt"); err == nil {
+		t.Errorf("Expected error unmounting twice")
+	}
+	if err := fs.Unmount("/"); err == nil {
+		t.Errorf("Expected error unmounting root")
+	}
+	if m := fs.Mounts(); !reflect.DeepEqual(m, []string{"/a/b"}) {
+		t.Errorf("Unexpected mounts: %q", m)
+	}
+	if _, err := fs.Stat("/mnt/file"); !os.IsNotExist(err) {
+		t.Errorf("Unmounted filesystem still used: %v", err)
+	}
+}
+
+func TestNestedMounts(t *testing.T) {
+	root, outer, inner := memfs.Create(), memfs.Create(), memfs.Create()
+	vfs.WriteFile(root, "/file", nil, 0644)
+	vfs.MkdirAll(root, "/data/hidden", 0755)
+	vfs.WriteFile(outer, "/outer", nil, 0644)
+	vfs.WriteFile(inner, "/inner", nil, 0644)
+
+	fs := Create(root)
+	// Overlaps the existing directory /data of the root filesystem
+	fs.Mount(outer, "/data")
+	// Neither /mnt nor /mnt/deep exist
+	fs.Mount(inner, "/mnt/deep/inner")
+	fs.Mount(inner, "/data/sub/inner")
+
+	var visited []string
+	err := vfs.Walk(fs, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	expected := []string{
+		"/",
+		"/data", "/data/outer", "/data/sub", "/data/sub/inner", "/data/sub/inner/inner",
+		"/file",
+		"/mnt", "/mnt/deep", "/mnt/deep/inner", "/mnt/deep/inner/inner",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Walk visited %q, expected %q", visited, expected)
+	}
+
+	if fi, err := fs.Stat("/mnt/deep"); err != nil || !fi.IsDir() || fi.Name() != "deep" {
+		t.Errorf("Unexpected synthesized directory: %v %v", fi, err)
+	}
+	if _, err := fs.Stat("/mnt/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := fs.ReadDir("/mnt/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	d, err := fs.OpenFile("/mnt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open synthesized directory: %s", err)
+	}
+	defer d.Close()
+	if fis, err := d.Readdir(-1); err != nil || len(fis) != 1 || fis[0].Name() != "deep" {
+		t.Errorf("Unexpected listing: %v %v", fis, err)
+	}
+}
+
+func TestBackslash(t *testing.T) {
+	root := memfs.Create(memfs.WithPathSeparator('\\'))
+	mount := memfs.Create(memfs.WithPathSeparator('\\'))
+	vfs.WriteFile(mount, `\file`, []byte("mounted"), 0644)
+
+	fs := Create(root)
+	fs.Mount(mount, "/mnt/data")
+	if mounts := fs.Mounts(); !reflect.DeepEqual(mounts, []string{`\mnt\data`}) {
+		t.Errorf("Unexpected mounts: %q", mounts)
+	}
+	for _, name := range []string{`\mnt\data\file`, "/mnt/data/file", `\mnt/x\..\data\file`} {
+		if b, err := vfs.ReadFile(fs, name); err != nil || string(b) != "mounted" {
+			t.Errorf("ReadFile %s: %q %v", name, b, err)
+		}
+	}
+	if fi, err := fs.Stat(`\mnt\data`); err != nil || fi.Name() != "data" {
+		t.Errorf("Stat of mountpoint: %v %v", fi, err)
+	}
+	if fis, err := fs.ReadDir("/mnt"); err != nil || len(fis) != 1 || fis[0].Name() != "data" {
+		t.Errorf("Unexpected listing: %v %v", fis, err)
+	}
+	if err := fs.Unmount(`\mnt\data\`); err != nil {
+		t.Errorf("Unmount: %s", err)
+	}
+}
@@ -3,6 +3,8 @@ package vfs
 import (
 	"io/ioutil"
 	"os"
+	"syscall"
+	"time"
 )
 
 // OsFS represents a filesystem backed by the filesystem of the underlying OS.
@@ -33,9 +35,24 @@ func (fs OsFS) Mkdir(name string, perm os.FileMode) error {
 	return os.Mkdir(name, perm)
 }
 
-// Rename wraps os.Rename
+// RemoveAll wraps os.RemoveAll
+func (fs OsFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// MkdirAll wraps os.MkdirAll
+func (fs OsFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Rename wraps os.Rename.
+// Renames across devices return a *os.LinkError matching both ErrCrossDevice and syscall.EXDEV.
 func (fs OsFS) Rename(oldpath, newpath string) error {
-	return os.Rename(oldpath, newpath)
+	err := os.Rename(oldpath, newpath)
+	if lerr, ok := err.(*os.LinkError); ok && lerr.Err == syscall.EXDEV {
+		lerr.Err = WrapError(ErrCrossDevice, lerr.Err)
+	}
+	return err
 }
 
 // Stat wraps os.Stat
@@ -52,3 +69,43 @@ func (fs OsFS) Lstat(name string) (os.FileInfo, error) {
 func (fs OsFS) ReadDir(path string) ([]os.FileInfo, error) {
 	return ioutil.ReadDir(path)
 }
+
+// OpenDir opens the named directory, which is read in batches using os.File.Readdir.
+// It implements DirOpener.
+func (fs OsFS) OpenDir(path string) (DirIterator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return FileDirIterator(f), nil
+}
+
+// Symlink wraps os.Symlink
+func (fs OsFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+// Link wraps os.Link
+func (fs OsFS) Link(oldname, newname string) error {
+	return os.Link(oldname, newname)
+}
+
+// Readlink wraps os.Readlink
+func (fs OsFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// Chmod wraps os.Chmod
+func (fs OsFS) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Chown wraps os.Chown
+func (fs OsFS) Chown(name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
+
+// Chtimes wraps os.Chtimes
+func (fs OsFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
@@ -1,12 +1,19 @@
 package vfs
 
 import (
+	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestOSInterface(t *testing.T) {
 	_ = Filesystem(OS())
+	_ = DirOpener(OS())
 }
 
 func TestOSCreate(t *testing.T) {
@@ -25,3 +32,100 @@ func TestOSCreate(t *testing.T) {
 		t.Errorf("Remove: %s", err)
 	}
 }
+
+func TestOSSymlink(t *testing.T) {
+	fs := OS()
+	_ = Symlinker(fs)
+
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	link := dir + "/link"
+	if err := fs.Symlink("target", link); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	if target, err := fs.Readlink(link); err != nil || target != "target" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+}
+
+func TestOSRenameCrossDevice(t *testing.T) {
+	fs := OS()
+	other, err := ioutil.TempDir("/dev/shm", "vfs")
+	if err != nil {
+		t.Skip("No second filesystem available")
+	}
+	defer os.RemoveAll(other)
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteFile(fs, dir+"/file", nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	err = fs.Rename(dir+"/file", other+"/file")
+	if err == nil {
+		t.Skip("Temporary directories on the same device")
+	}
+	if !errors.Is(err, ErrCrossDevice) || !errors.Is(err, syscall.EXDEV) {
+		t.Errorf("Expected ErrCrossDevice and EXDEV, got %v", err)
+	}
+}
+
+func TestOSRemoveAllMkdirAll(t *testing.T) {
+	fs := OS()
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := MkdirAll(fs, dir+"/a/b", 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := RemoveAll(fs, dir+"/a"); err != nil {
+		t.Fatalf("RemoveAll: %s", err)
+	}
+	if _, err := fs.Stat(dir + "/a"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestOSAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := OS()
+	name := filepath.Join(dir, "file")
+	if err := WriteFile(fs, name, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	if err := Chmod(fs, name, 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := Chtimes(fs, name, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+	fi, err := fs.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Mode().Perm() != 0600 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("Unexpected attributes: %s %s", fi.Mode(), fi.ModTime())
+	}
+	if uid, _, ok := FileOwner(fi); ok && uid != os.Getuid() {
+		t.Errorf("Unexpected owner: %d", uid)
+	}
+	if err := Chown(fs, name, os.Getuid(), os.Getgid()); err != nil && runtime.GOOS != "windows" {
+		t.Errorf("Chown: %s", err)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build windows
+
+package vfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOSWindowsPathHelpers(t *testing.T) {
+	fs := OS()
+	for _, p := range []string{`C:\Windows\..\Users\.\`, `c:/a/b`, `C:a\..\b`, `C:`, `\a\\b`, `a/b/../c`, ``} {
+		if got, expected := Clean(fs, p), filepath.Clean(p); got != expected {
+			t.Errorf("Clean(%q) = %q, filepath.Clean = %q", p, got, expected)
+		}
+		if got, expected := VolumeName(fs, p), filepath.VolumeName(p); got != expected {
+			t.Errorf("VolumeName(%q) = %q, filepath.VolumeName = %q", p, got, expected)
+		}
+		// Unlike filepath.IsAbs, IsAbs does not require a volume name
+		if got, expected := IsAbs(fs, p), filepath.IsAbs(p); got != expected && VolumeName(fs, p) != "" {
+			t.Errorf("IsAbs(%q) = %t, filepath.IsAbs = %t", p, got, expected)
+		}
+		if got, expected := Base(fs, p), filepath.Base(p); got != expected {
+			t.Errorf("Base(%q) = %q, filepath.Base = %q", p, got, expected)
+		}
+		if got, expected := Dir(fs, p), filepath.Dir(p); got != expected {
+			t.Errorf("Dir(%q) = %q, filepath.Dir = %q", p, got, expected)
+		}
+	}
+}
+
+func TestOSWindowsPaths(t *testing.T) {
+	fs := OS()
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	if VolumeName(fs, dir) == "" || !IsAbs(fs, dir) {
+		t.Fatalf("Expected absolute path with drive letter, got %q", dir)
+	}
+
+	if err := MkdirAll(fs, Join(fs, dir, "a", "b"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	// Slashes are accepted as separators
+	name := filepath.ToSlash(dir) + "/a/b/file.txt"
+	if err := WriteFile(fs, name, []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if b, err := ReadFile(fs, Join(fs, dir, `a\b\..\b\file.txt`)); err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+
+	var walked []string
+	err = Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	expected := []string{dir, Join(fs, dir, "a"), Join(fs, dir, "a", "b"), Join(fs, dir, "a", "b", "file.txt")}
+	if err != nil || len(walked) != len(expected) {
+		t.Fatalf("Walk: %q %v", walked, err)
+	}
+	for i := range expected {
+		if walked[i] != expected[i] {
+			t.Errorf("Walked %q, expected %q", walked[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,6 @@
+// Package oswatch defines the filesystem of the os with change notifications using fsnotify.
+// It lives in its own package, so the core package depends on the standard library only.
+//
+//	fs := oswatch.Create()
+//	events, err := vfs.Watch(fs, "/etc/app.conf")
+package oswatch
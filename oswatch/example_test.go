@@ -0,0 +1,22 @@
+package oswatch_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/oswatch"
+)
+
+func ExampleCreate() {
+	// The OS filesystem with Watch, e.g. to reload a configuration on changes
+	fs := oswatch.Create()
+	events, err := vfs.Watch(fs, "/etc/app.conf")
+	if err != nil {
+		fmt.Printf("Watch: %s\n", err)
+		return
+	}
+	defer fs.Unwatch(events)
+	for e := range events {
+		fmt.Println(e)
+	}
+}
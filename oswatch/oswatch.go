@@ -0,0 +1,116 @@
+package oswatch
+
+import (
+	"os"
+	"sync"
+
+	"github.com/blang/vfs"
+	"github.com/fsnotify/fsnotify"
+)
+
+// FS is the filesystem of the os which reports changes of files using fsnotify.
+// All other operations are the ones of vfs.OsFS.
+type FS struct {
+	vfs.OsFS
+
+	mutex   sync.Mutex
+	watches map[<-chan vfs.Event]*watch
+}
+
+// Create returns the filesystem of the os with change notifications.
+func Create() *FS {
+	return &FS{watches: make(map[<-chan vfs.Event]*watch)}
+}
+
+// watch translates the events of a fsnotify.Watcher.
+type watch struct {
+	watcher *fsnotify.Watcher
+	events  chan vfs.Event
+	done    chan struct{}
+}
+
+// Watch reports changes of the named file or directory using fsnotify.
+// It implements vfs.Watcher.
+func (fs *FS) Watch(name string) (<-chan vfs.Event, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	if err := watcher.Add(name); err != nil {
+		watcher.Close()
+		if _, serr := os.Stat(name); serr != nil {
+			return nil, serr
+		}
+		return nil, &os.PathError{Op: "watch", Path: name, Err: err}
+	}
+	w := &watch{watcher: watcher, events: make(chan vfs.Event), done: make(chan struct{})}
+	fs.mutex.Lock()
+	fs.watches[w.events] = w
+	fs.mutex.Unlock()
+	go w.run()
+	return w.events, nil
+}
+
+// Unwatch stops a watch started by Watch and closes its channel.
+// Channels not returned by Watch return os.ErrInvalid.
+// It implements vfs.Watcher.
+func (fs *FS) Unwatch(events <-chan vfs.Event) error {
+	fs.mutex.Lock()
+	w, ok := fs.watches[events]
+	delete(fs.watches, events)
+	fs.mutex.Unlock()
+	if !ok {
+		return os.ErrInvalid
+	}
+	close(w.done)
+	return w.watcher.Close()
+}
+
+func (w *watch) run() {
+	defer close(w.events)
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			e := vfs.Event{Name: ev.Name, Op: eventOp(ev.Op)}
+			if e.Op == 0 {
+				continue
+			}
+			select {
+			case w.events <- e:
+			case <-w.done:
+				return
+			}
+		case _, ok := <-w.watcher.Errors:
+			// Errors like overflows can not be reported through the channel
+			if !ok {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// eventOp converts the operations reported by fsnotify.
+func eventOp(op fsnotify.Op) vfs.EventOp {
+	var e vfs.EventOp
+	if op.Has(fsnotify.Create) {
+		e |= vfs.EventCreate
+	}
+	if op.Has(fsnotify.Write) {
+		e |= vfs.EventWrite
+	}
+	if op.Has(fsnotify.Remove) {
+		e |= vfs.EventRemove
+	}
+	if op.Has(fsnotify.Rename) {
+		e |= vfs.EventRename
+	}
+	if op.Has(fsnotify.Chmod) {
+		e |= vfs.EventChmod
+	}
+	return e
+}
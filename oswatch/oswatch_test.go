@@ -0,0 +1,60 @@
+package oswatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(Create())
+	_ = vfs.Watcher(Create())
+	_ = vfs.Symlinker(Create())
+	_ = vfs.Attributer(Create())
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-watch")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := Create()
+	events, err := fs.Watch(dir)
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	name := filepath.Join(dir, "file")
+	if err := vfs.WriteFile(fs, name, []byte("data"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Name != name || ev.Op&vfs.EventCreate == 0 {
+			t.Errorf("Unexpected event: %s", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Missing create event")
+	}
+
+	if err := fs.Unwatch(events); err != nil {
+		t.Fatalf("Unwatch: %s", err)
+	}
+	for range events {
+		// Drain events until the channel is closed
+	}
+	if err := fs.Unwatch(events); err != os.ErrInvalid {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	if err := Create().Unwatch(events); err != os.ErrInvalid {
+		t.Errorf("Expected ErrInvalid for a watch of another FS, got %v", err)
+	}
+	if _, err := fs.Watch(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package vfs
 
 import (
+	"path"
 	"strings"
+	"unicode/utf8"
 )
 
 // SplitPath splits the given path in segments:
@@ -21,9 +23,107 @@ func SplitPath(path string, sep string) []string {
 	}
 
 	if len(path) > 0 && !strings.HasPrefix(path, sep) && !strings.HasPrefix(path, "."+sep) {
-		path = "./" + path
+		path = "." + sep + path
 	}
 	parts := strings.Split(path, sep)
 
 	return parts
 }
+
+// The following helpers handle the paths of a Filesystem like package filepath handles
+// the paths of the OS, but use the separator of the Filesystem instead of the one of the OS.
+// If the separator is not a slash, slashes are accepted as separators as well.
+// On filesystems separated by a backslash, paths may start with a drive letter volume name like "C:".
+
+// isSeparator reports whether c separates the segments of paths on a filesystem separated by sep.
+func isSeparator(c, sep uint8) bool {
+	return c == sep || c == '/'
+}
+
+// pathSegments returns the non-empty segments of the path without its volume name.
+func pathSegments(fs Filesystem, p string) []string {
+	sep := fs.PathSeparator()
+	p = p[len(VolumeName(fs, p)):]
+	return strings.FieldsFunc(p, func(r rune) bool {
+		return r < utf8.RuneSelf && isSeparator(uint8(r), sep)
+	})
+}
+
+// VolumeName returns the leading volume name of the path, e.g. "C:" for `C:\Windows`
+// on a filesystem separated by a backslash. Other filesystems have no volume names.
+func VolumeName(fs Filesystem, path string) string {
+	if fs.PathSeparator() != '\\' || len(path) < 2 || path[1] != ':' {
+		return ""
+	}
+	if c := path[0]; 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+		return path[:2]
+	}
+	return ""
+}
+
+// IsAbs reports whether the path is absolute, the volume name is optional.
+func IsAbs(fs Filesystem, path string) bool {
+	path = path[len(VolumeName(fs, path)):]
+	return path != "" && isSeparator(path[0], fs.PathSeparator())
+}
+
+// Clean returns the shortest path equivalent to the path by purely lexical processing like path.Clean.
+// The volume name is kept, slashes are replaced by the separator of the filesystem.
+func Clean(fs Filesystem, p string) string {
+	sep := string(fs.PathSeparator())
+	vol := VolumeName(fs, p)
+	p = p[len(vol):]
+	if sep == "/" {
+		return path.Clean(p)
+	}
+	p = path.Clean(strings.Replace(p, sep, "/", -1))
+	return vol + strings.Replace(p, "/", sep, -1)
+}
+
+// Join joins any number of path elements into a single path using the separator of the filesystem.
+// Empty elements are ignored, the result is cleaned. Join returns "" if all elements are empty.
+func Join(fs Filesystem, elem ...string) string {
+	for i, e := range elem {
+		if e != "" {
+			return Clean(fs, strings.Join(elem[i:], string(fs.PathSeparator())))
+		}
+	}
+	return ""
+}
+
+// Split splits the path immediately following the final separator into a directory and a file name.
+// If there is no separator in the path, Split returns an empty dir, or the volume name, and the path as file.
+func Split(fs Filesystem, path string) (dir, file string) {
+	sep := fs.PathSeparator()
+	vol := VolumeName(fs, path)
+	i := len(path) - 1
+	for i >= len(vol) && !isSeparator(path[i], sep) {
+		i--
+	}
+	return path[:i+1], path[i+1:]
+}
+
+// Base returns the last element of the path, trailing separators are removed.
+// Base returns "." for an empty path and the separator for a path consisting only of separators.
+func Base(fs Filesystem, path string) string {
+	if path == "" {
+		return "."
+	}
+	sep := fs.PathSeparator()
+	for len(path) > 0 && isSeparator(path[len(path)-1], sep) {
+		path = path[:len(path)-1]
+	}
+	_, path = Split(fs, path[len(VolumeName(fs, path)):])
+	if path == "" {
+		return string(sep)
+	}
+	return path
+}
+
+// Dir returns all but the last element of the path, the cleaned directory of the path.
+// Dir returns "." for a path without separators.
+func Dir(fs Filesystem, path string) string {
+	vol := VolumeName(fs, path)
+	dir, _ := Split(fs, path[len(vol):])
+	return vol + Clean(fs, dir)
+}
@@ -27,3 +27,88 @@ func TestSplitPath(t *testing.T) {
 		t.Errorf("Invalid path: %q", p)
 	}
 }
+
+// sepFS is a filesystem separated by sep.
+type sepFS struct {
+	Filesystem
+	sep uint8
+}
+
+func (fs sepFS) PathSeparator() uint8 {
+	return fs.sep
+}
+
+var windowsFS = sepFS{sep: '\\'}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		fs       Filesystem
+		path     string
+		expected string
+	}{
+		{Dummy(nil), "", "."},
+		{Dummy(nil), "/a/../b//c/", "/b/c"},
+		{Dummy(nil), `/a\b`, `/a\b`},
+		{windowsFS, "", "."},
+		{windowsFS, `\a\..\b\\c\`, `\b\c`},
+		{windowsFS, `/a/b\c`, `\a\b\c`},
+		{windowsFS, `C:\Windows\..\Users\.`, `C:\Users`},
+		{windowsFS, `c:\..`, `c:\`},
+		{windowsFS, `C:a\..\b`, `C:b`},
+		{windowsFS, `C:`, `C:.`},
+		{windowsFS, `1:\a`, `1:\a`},
+	}
+	for _, test := range tests {
+		if p := Clean(test.fs, test.path); p != test.expected {
+			t.Errorf("Clean(%q) with separator %c = %q, expected %q", test.path, test.fs.PathSeparator(), p, test.expected)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	if p := Join(windowsFS, "", `C:\Users`, "", "name", `..\other`); p != `C:\Users\other` {
+		t.Errorf("Unexpected path %q", p)
+	}
+	if p := Join(Dummy(nil), "/a", "b/", "c"); p != "/a/b/c" {
+		t.Errorf("Unexpected path %q", p)
+	}
+	if p := Join(windowsFS, "", ""); p != "" {
+		t.Errorf("Unexpected path %q", p)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		path, dir, file, base, dirname string
+	}{
+		{`C:\Users\name.txt`, `C:\Users\`, "name.txt", "name.txt", `C:\Users`},
+		{`C:name.txt`, `C:`, "name.txt", "name.txt", `C:.`},
+		{`C:\`, `C:\`, "", `\`, `C:\`},
+		{`\dir/file`, `\dir/`, "file", "file", `\dir`},
+		{`dir\`, `dir\`, "", "dir", "dir"},
+		{"file", "", "file", "file", "."},
+		{"", "", "", ".", "."},
+	}
+	for _, test := range tests {
+		if dir, file := Split(windowsFS, test.path); dir != test.dir || file != test.file {
+			t.Errorf("Split(%q) = %q, %q, expected %q, %q", test.path, dir, file, test.dir, test.file)
+		}
+		if base := Base(windowsFS, test.path); base != test.base {
+			t.Errorf("Base(%q) = %q, expected %q", test.path, base, test.base)
+		}
+		if dir := Dir(windowsFS, test.path); dir != test.dirname {
+			t.Errorf("Dir(%q) = %q, expected %q", test.path, dir, test.dirname)
+		}
+	}
+}
+
+func TestIsAbs(t *testing.T) {
+	for path, expected := range map[string]bool{`C:\a`: true, `\a`: true, `/a`: true, `C:a`: false, `a`: false, ``: false} {
+		if IsAbs(windowsFS, path) != expected {
+			t.Errorf("IsAbs(%q) != %t", path, expected)
+		}
+	}
+	if IsAbs(Dummy(nil), `C:\a`) || VolumeName(Dummy(nil), `C:\a`) != "" {
+		t.Errorf("Volume name on filesystem separated by a slash")
+	}
+}
@@ -0,0 +1,332 @@
+package vfs
+
+import (
+	"os"
+	"time"
+)
+
+// Access bits checked against the permission bits of the owner, group or others.
+const (
+	permRead    os.FileMode = 4
+	permWrite   os.FileMode = 2
+	permExecute os.FileMode = 1
+)
+
+// EnforcePermissions creates a wrapper around the given filesystem which checks the
+// permission bits of files like a unix system does for the user uid in the group gid,
+// so access control can be tested in memory.
+//
+// Traversing a directory requires the execute bit, listing it the read bit.
+// Symbolic links are resolved by the wrapper, so the directories they lead through are checked as well.
+// Files are opened for reading and writing if the read and write bits allow it.
+// Creating, removing and renaming entries requires write and execute bits on the directory.
+// Only the owner changes the mode and times of a file, the group only to gid.
+// The permission bits of the owner apply if the file is owned by uid, the ones of the group
+// if it belongs to gid, otherwise the ones of others, also if the owner is unknown.
+// The user 0 is allowed everything. Denied operations return an error containing os.ErrPermission.
+//
+// New files and directories are owned by uid and gid if the wrapped filesystem supports Chown.
+// Open files are not checked again.
+func EnforcePermissions(fs Filesystem, uid, gid int) *PermFS {
+	return &PermFS{Filesystem: fs, uid: uid, gid: gid}
+}
+
+// PermFS represents a filesystem enforcing permissions for a user
+// and works as a wrapper around existing filesystems.
+type PermFS struct {
+	Filesystem
+	uid int
+	gid int
+}
+
+// allowed reports whether the permission bits of fi grant access to the user.
+func (fs *PermFS) allowed(fi os.FileInfo, access os.FileMode) bool {
+	perm := fi.Mode().Perm()
+	uid, gid, ok := FileOwner(fi)
+	switch {
+	case ok && uid == fs.uid:
+		perm >>= 6
+	case ok && gid == fs.gid:
+		perm >>= 3
+	}
+	return perm&access == access
+}
+
+// isOwner reports whether the user owns the named file.
+func (fs *PermFS) isOwner(name string) bool {
+	fi, err := fs.Filesystem.Stat(name)
+	if err != nil {
+		// Reported by the operation itself
+		return true
+	}
+	uid, _, ok := FileOwner(fi)
+	return ok && uid == fs.uid
+}
+
+// traversable resolves name component by component like the kernel does and reports
+// whether the user may traverse every directory on the way, including the directories
+// reached through symbolic links. The last component is followed if follow is set.
+// Missing entries end the resolution, they are left to the wrapped filesystem to report.
+func (fs *PermFS) traversable(name string, follow bool) bool {
+	sep := string(fs.PathSeparator())
+	dir := "."
+	if IsAbs(fs, name) {
+		dir = VolumeName(fs, name) + sep
+	}
+	pending := pathSegments(fs, name)
+	links := 0
+	for len(pending) > 0 {
+		seg := pending[0]
+		pending = pending[1:]
+		if seg == "." {
+			continue
+		}
+		if fi, err := fs.Filesystem.Stat(dir); err == nil && !fs.allowed(fi, permExecute) {
+			return false
+		}
+		next := Join(fs, dir, seg)
+		if seg == ".." || len(pending) == 0 && !follow {
+			dir = next
+			continue
+		}
+		fi, err := fs.Filesystem.Lstat(next)
+		if err != nil {
+			return true
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			dir = next
+			continue
+		}
+		links++
+		target, err := Readlink(fs.Filesystem, next)
+		if err != nil || links > maxSymlinks {
+			return true
+		}
+		if IsAbs(fs, target) {
+			dir = VolumeName(fs, target) + sep
+		}
+		pending = append(pathSegments(fs, target), pending...)
+	}
+	return true
+}
+
+// check returns an error unless the user may traverse to name and access it.
+// Symbolic links are followed, the last component only if follow is set.
+// Missing files are left to the wrapped filesystem to report.
+func (fs *PermFS) check(op, name string, access os.FileMode, follow bool) error {
+	if fs.uid == 0 {
+		return nil
+	}
+	if !fs.traversable(name, follow) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	if access != 0 {
+		if fi, err := fs.Filesystem.Stat(name); err == nil && !fs.allowed(fi, access) {
+			return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+		}
+	}
+	return nil
+}
+
+// checkParent returns an error unless the user may change the entries of the directory of name.
+func (fs *PermFS) checkParent(op, name string) error {
+	if err := fs.check(op, Dir(fs, Clean(fs, name)), permWrite|permExecute, true); err != nil {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+	}
+	return nil
+}
+
+// own transfers a new file to the user, if the wrapped filesystem supports it.
+func (fs *PermFS) own(name string) {
+	Chown(fs.Filesystem, name, fs.uid, fs.gid)
+}
+
+// OpenFile opens the named file if the permissions allow the access requested by flag.
+// Creating a file requires write permission of its directory.
+func (fs *PermFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	access := permRead
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_WRONLY:
+		access = permWrite
+	case os.O_RDWR:
+		access = permRead | permWrite
+	}
+	if flag&os.O_TRUNC != 0 {
+		access |= permWrite
+	}
+	created := false
+	if flag&os.O_CREATE != 0 {
+		if _, err := fs.Filesystem.Stat(name); os.IsNotExist(err) {
+			if err := fs.checkParent("open", name); err != nil {
+				return nil, err
+			}
+			created = true
+		}
+	}
+	if !created {
+		if err := fs.check("open", name, access, true); err != nil {
+			return nil, err
+		}
+	}
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	if err == nil && created {
+		fs.own(name)
+	}
+	return f, err
+}
+
+// Remove removes the named file or directory if the permissions of its directory allow it.
+func (fs *PermFS) Remove(name string) error {
+	if err := fs.checkParent("remove", name); err != nil {
+		return err
+	}
+	return fs.Filesystem.Remove(name)
+}
+
+// Rename renames a file if the permissions of both directories allow it.
+func (fs *PermFS) Rename(oldpath, newpath string) error {
+	if fs.checkParent("rename", oldpath) != nil || fs.checkParent("rename", newpath) != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrPermission}
+	}
+	return fs.Filesystem.Rename(oldpath, newpath)
+}
+
+// Mkdir creates a directory if the permissions of its parent allow it.
+func (fs *PermFS) Mkdir(name string, perm os.FileMode) error {
+	if err := fs.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	if err := fs.Filesystem.Mkdir(name, perm); err != nil {
+		return err
+	}
+	fs.own(name)
+	return nil
+}
+
+// Stat returns the FileInfo of the named file if its directories can be traversed.
+func (fs *PermFS) Stat(name string) (os.FileInfo, error) {
+	if err := fs.check("stat", name, 0, true); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Stat(name)
+}
+
+// Lstat returns the FileInfo of the named file without following a symbolic link
+// if its directories can be traversed.
+func (fs *PermFS) Lstat(name string) (os.FileInfo, error) {
+	if err := fs.check("lstat", name, 0, false); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.Lstat(name)
+}
+
+// ReadDir reads the directory named by path if it is readable.
+func (fs *PermFS) ReadDir(path string) ([]os.FileInfo, error) {
+	if err := fs.check("readdir", path, permRead, true); err != nil {
+		return nil, err
+	}
+	return fs.Filesystem.ReadDir(path)
+}
+
+// Symlink creates newname as a symbolic link to oldname if the permissions of its directory allow it.
+func (fs *PermFS) Symlink(oldname, newname string) error {
+	if fs.checkParent("symlink", newname) != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrPermission}
+	}
+	return Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Link creates newname as a hard link to oldname if the permissions of its directory allow it.
+func (fs *PermFS) Link(oldname, newname string) error {
+	if fs.check("link", oldname, 0, false) != nil || fs.checkParent("link", newname) != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: os.ErrPermission}
+	}
+	return Link(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link if its directories can be traversed.
+func (fs *PermFS) Readlink(name string) (string, error) {
+	if err := fs.check("readlink", name, 0, false); err != nil {
+		return "", err
+	}
+	return Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file if the user owns it.
+func (fs *PermFS) Chmod(name string, mode os.FileMode) error {
+	if err := fs.check("chmod", name, 0, true); err != nil {
+		return err
+	}
+	if fs.uid != 0 && !fs.isOwner(name) {
+		return &os.PathError{Op: "chmod", Path: name, Err: os.ErrPermission}
+	}
+	return Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+// Only the user 0 changes the owner, the owner changes the group to gid.
+func (fs *PermFS) Chown(name string, uid, gid int) error {
+	if err := fs.check("chown", name, 0, true); err != nil {
+		return err
+	}
+	if fs.uid != 0 && (!fs.isOwner(name) || uid != -1 && uid != fs.uid || gid != -1 && gid != fs.gid) {
+		return &os.PathError{Op: "chown", Path: name, Err: os.ErrPermission}
+	}
+	return Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file if the user owns it.
+func (fs *PermFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := fs.check("chtimes", name, 0, true); err != nil {
+		return err
+	}
+	if fs.uid != 0 && !fs.isOwner(name) {
+		return &os.PathError{Op: "chtimes", Path: name, Err: os.ErrPermission}
+	}
+	return Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file if it is readable.
+func (fs *PermFS) GetXattr(name, attr string) ([]byte, error) {
+	if err := fs.check("getxattr", name, permRead, true); err != nil {
+		return nil, err
+	}
+	return GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file if it is writable.
+func (fs *PermFS) SetXattr(name, attr string, value []byte) error {
+	if err := fs.check("setxattr", name, permWrite, true); err != nil {
+		return err
+	}
+	return SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file if it is readable.
+func (fs *PermFS) ListXattr(name string) ([]string, error) {
+	if err := fs.check("listxattr", name, permRead, true); err != nil {
+		return nil, err
+	}
+	return ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file if it is writable.
+func (fs *PermFS) RemoveXattr(name, attr string) error {
+	if err := fs.check("removexattr", name, permWrite, true); err != nil {
+		return err
+	}
+	return RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file if it is readable.
+func (fs *PermFS) Watch(name string) (<-chan Event, error) {
+	if err := fs.check("watch", name, permRead, true); err != nil {
+		return nil, err
+	}
+	return Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *PermFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
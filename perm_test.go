@@ -0,0 +1,201 @@
+package vfs_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestPermInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+	_ = vfs.Symlinker(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+	_ = vfs.Linker(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+	_ = vfs.Attributer(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+	_ = vfs.Watcher(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+}
+
+func TestPermConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return vfs.EnforcePermissions(memfs.Create(memfs.WithOwner(1000, 1000)), 1000, 1000)
+	})
+}
+
+// permFS returns a memfs owned by 1000:1000 with files of the given modes.
+func permFS(t *testing.T, modes map[string]os.FileMode) vfs.Filesystem {
+	t.Helper()
+	fs := memfs.Create(memfs.WithOwner(1000, 1000))
+	for _, name := range []string{"/dir", "/dir/sub", "/locked", "/readonly"} {
+		fs.Mkdir(name, 0755)
+	}
+	for _, name := range []string{"/file", "/dir/file", "/dir/sub/file", "/locked/file", "/readonly/file"} {
+		vfs.WriteFile(fs, name, []byte(name), 0644)
+	}
+	for name, mode := range modes {
+		if err := fs.Chmod(name, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func TestPermOpen(t *testing.T) {
+	fs := permFS(t, map[string]os.FileMode{"/file": 0400, "/dir/file": 0640})
+	owner := vfs.EnforcePermissions(fs, 1000, 1000)
+	group := vfs.EnforcePermissions(fs, 2000, 1000)
+	other := vfs.EnforcePermissions(fs, 2000, 2000)
+	root := vfs.EnforcePermissions(fs, 0, 0)
+
+	tests := []struct {
+		fs      vfs.Filesystem
+		name    string
+		flag    int
+		allowed bool
+	}{
+		{owner, "/file", os.O_RDONLY, true},
+		{owner, "/file", os.O_RDWR, false},
+		{owner, "/file", os.O_WRONLY, false},
+		{owner, "/file", os.O_RDONLY | os.O_TRUNC, false},
+		{other, "/file", os.O_RDONLY, false},
+		{root, "/file", os.O_RDWR, true},
+		{group, "/dir/file", os.O_RDONLY, true},
+		{group, "/dir/file", os.O_WRONLY, false},
+		{other, "/dir/file", os.O_RDONLY, false},
+		{owner, "/dir/file", os.O_RDWR | os.O_APPEND, true},
+	}
+	for _, test := range tests {
+		f, err := test.fs.OpenFile(test.name, test.flag, 0)
+		if test.allowed && err != nil {
+			t.Errorf("OpenFile %s with flag %x failed: %s", test.name, test.flag, err)
+		}
+		if !test.allowed && !os.IsPermission(err) {
+			t.Errorf("OpenFile %s with flag %x: expected permission error, got %v", test.name, test.flag, err)
+		}
+		if err == nil {
+			f.Close()
+		}
+	}
+}
+
+func TestPermTraverse(t *testing.T) {
+	fs := permFS(t, map[string]os.FileMode{"/dir": 0600, "/locked": 0300})
+	user := vfs.EnforcePermissions(fs, 1000, 1000)
+
+	// Without execute bit, nothing below the directory is accessible
+	for _, name := range []string{"/dir/file", "/dir/sub", "/dir/sub/file"} {
+		if _, err := user.Stat(name); !os.IsPermission(err) {
+			t.Errorf("Stat %s: expected permission error, got %v", name, err)
+		}
+	}
+	if fis, err := user.ReadDir("/dir"); err != nil || len(fis) != 2 {
+		t.Errorf("ReadDir of readable directory: %v %v", fis, err)
+	}
+	// Without read bit, the directory is not listed but traversed
+	if _, err := user.ReadDir("/locked"); !os.IsPermission(err) {
+		t.Errorf("ReadDir: expected permission error, got %v", err)
+	}
+	if b, err := vfs.ReadFile(user, "/locked/file"); err != nil || string(b) != "/locked/file" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+	if _, err := user.Stat("/dir/missing"); !os.IsPermission(err) {
+		t.Errorf("Stat of missing file: expected permission error, got %v", err)
+	}
+	if _, err := user.Stat("/missing/file"); !os.IsNotExist(err) {
+		t.Errorf("Stat of missing directory: expected not exist error, got %v", err)
+	}
+}
+
+func TestPermTraverseSymlinks(t *testing.T) {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/secret/sub", 0755)
+	fs.Chmod("/secret", 0700)
+	vfs.WriteFile(fs, "/secret/sub/f", []byte("secret"), 0644)
+	fs.Symlink("/secret/sub", "/pub")
+	fs.Symlink("secret/sub/f", "/rel")
+	fs.Symlink("/pub", "/chain")
+	user := vfs.EnforcePermissions(fs, 1000, 1000)
+
+	// The directories reached through symbolic links are traversed as well
+	for _, name := range []string{"/secret/sub/f", "/pub/f", "/rel", "/chain/f", "/pub/../sub/f"} {
+		if _, err := vfs.ReadFile(user, name); !os.IsPermission(err) {
+			t.Errorf("ReadFile %s: expected permission error, got %v", name, err)
+		}
+	}
+	if _, err := user.Stat("/pub"); !os.IsPermission(err) {
+		t.Errorf("Stat: expected permission error, got %v", err)
+	}
+	// The link itself is accessible
+	if _, err := user.Lstat("/pub"); err != nil {
+		t.Errorf("Lstat: %s", err)
+	}
+	if target, err := user.Readlink("/rel"); err != nil || target != "secret/sub/f" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+
+	fs.Chmod("/secret", 0711)
+	if b, err := vfs.ReadFile(user, "/pub/f"); err != nil || string(b) != "secret" {
+		t.Errorf("ReadFile through traversable link: %q %v", b, err)
+	}
+}
+
+func TestPermModify(t *testing.T) {
+	fs := permFS(t, map[string]os.FileMode{"/readonly": 0555})
+	user := vfs.EnforcePermissions(fs, 1000, 1000)
+
+	if err := vfs.WriteFile(user, "/readonly/new", nil, 0644); !os.IsPermission(err) {
+		t.Errorf("Create: expected permission error, got %v", err)
+	}
+	if err := user.Mkdir("/readonly/new", 0755); !os.IsPermission(err) {
+		t.Errorf("Mkdir: expected permission error, got %v", err)
+	}
+	if err := user.Remove("/readonly/file"); !os.IsPermission(err) {
+		t.Errorf("Remove: expected permission error, got %v", err)
+	}
+	if err := user.Rename("/readonly/file", "/moved"); !os.IsPermission(err) {
+		t.Errorf("Rename: expected permission error, got %v", err)
+	}
+	if err := user.Symlink("/file", "/readonly/link"); !os.IsPermission(err) {
+		t.Errorf("Symlink: expected permission error, got %v", err)
+	}
+	// Existing files stay writable
+	if err := vfs.WriteFile(user, "/readonly/file", []byte("changed"), 0644); err != nil {
+		t.Errorf("WriteFile: %s", err)
+	}
+	if err := user.Rename("/dir/file", "/moved"); err != nil {
+		t.Errorf("Rename: %s", err)
+	}
+}
+
+func TestPermOwnership(t *testing.T) {
+	fs := permFS(t, map[string]os.FileMode{"/dir": 0777})
+	user := vfs.EnforcePermissions(fs, 2000, 2000)
+
+	if err := vfs.WriteFile(user, "/dir/new", nil, 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	user.Mkdir("/dir/newdir", 0700)
+	for _, name := range []string{"/dir/new", "/dir/newdir"} {
+		fi, _ := fs.Stat(name)
+		if uid, gid, _ := vfs.FileOwner(fi); uid != 2000 || gid != 2000 {
+			t.Errorf("New file %s owned by %d:%d", name, uid, gid)
+		}
+	}
+
+	if err := user.Chmod("/dir/file", 0777); !os.IsPermission(err) {
+		t.Errorf("Chmod of foreign file: expected permission error, got %v", err)
+	}
+	if err := user.Chmod("/dir/new", 0644); err != nil {
+		t.Errorf("Chmod of own file: %s", err)
+	}
+	if err := user.Chown("/dir/new", 1000, -1); !os.IsPermission(err) {
+		t.Errorf("Chown to other user: expected permission error, got %v", err)
+	}
+	if err := user.Chown("/dir/new", -1, 2000); err != nil {
+		t.Errorf("Chown to own group: %s", err)
+	}
+	if err := vfs.EnforcePermissions(fs, 0, 0).Chown("/dir/new", 1000, 1000); err != nil {
+		t.Errorf("Chown by root: %s", err)
+	}
+}
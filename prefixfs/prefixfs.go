@@ -2,11 +2,13 @@ package prefixfs
 
 import (
 	"os"
+	"time"
 
 	"github.com/blang/vfs"
 )
 
 // A FS that prefixes the path in each vfs.Filesystem operation.
+// Paths are joined lexically, "../" may leave the prefix. Use vfs.Chroot to confine untrusted code.
 type FS struct {
 	vfs.Filesystem
 
@@ -19,9 +21,9 @@ func Create(root vfs.Filesystem, prefix string) *FS {
 	return &FS{root, prefix}
 }
 
-// PrefixPath returns path with the prefix prefixed.
+// PrefixPath returns path with the prefix prefixed, joined by the separator of the filesystem.
 func (fs *FS) PrefixPath(path string) string {
-	return fs.Prefix + string(fs.PathSeparator()) + path
+	return vfs.Join(fs, fs.Prefix, path)
 }
 
 // PathSeparator implements vfs.Filesystem.
@@ -37,6 +39,11 @@ func (fs *FS) Remove(name string) error {
 	return fs.Filesystem.Remove(fs.PrefixPath(name))
 }
 
+// RemoveAll implements vfs.RemoveAller.
+func (fs *FS) RemoveAll(path string) error {
+	return vfs.RemoveAll(fs.Filesystem, fs.PrefixPath(path))
+}
+
 // Rename implements vfs.Filesystem.
 func (fs *FS) Rename(oldpath, newpath string) error {
 	return fs.Filesystem.Rename(fs.PrefixPath(oldpath), fs.PrefixPath(newpath))
@@ -47,6 +54,11 @@ func (fs *FS) Mkdir(name string, perm os.FileMode) error {
 	return fs.Filesystem.Mkdir(fs.PrefixPath(name), perm)
 }
 
+// MkdirAll implements vfs.MkdirAller.
+func (fs *FS) MkdirAll(path string, perm os.FileMode) error {
+	return vfs.MkdirAll(fs.Filesystem, fs.PrefixPath(path), perm)
+}
+
 // Stat implements vfs.Filesystem.
 func (fs *FS) Stat(name string) (os.FileInfo, error) {
 	return fs.Filesystem.Stat(fs.PrefixPath(name))
@@ -61,3 +73,59 @@ func (fs *FS) Lstat(name string) (os.FileInfo, error) {
 func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
 	return fs.Filesystem.ReadDir(fs.PrefixPath(path))
 }
+
+// OpenDir implements vfs.DirOpener.
+func (fs *FS) OpenDir(path string) (vfs.DirIterator, error) {
+	return vfs.OpenDir(fs.Filesystem, fs.PrefixPath(path))
+}
+
+// Symlink implements vfs.Symlinker.
+// The link target is stored unchanged and not prefixed.
+func (fs *FS) Symlink(oldname, newname string) error {
+	return vfs.Symlink(fs.Filesystem, oldname, fs.PrefixPath(newname))
+}
+
+// Link implements vfs.Linker.
+func (fs *FS) Link(oldname, newname string) error {
+	return vfs.Link(fs.Filesystem, fs.PrefixPath(oldname), fs.PrefixPath(newname))
+}
+
+// Readlink implements vfs.Symlinker.
+func (fs *FS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, fs.PrefixPath(name))
+}
+
+// Chmod implements vfs.Attributer.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return vfs.Chmod(fs.Filesystem, fs.PrefixPath(name), mode)
+}
+
+// Chown implements vfs.Attributer.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	return vfs.Chown(fs.Filesystem, fs.PrefixPath(name), uid, gid)
+}
+
+// Chtimes implements vfs.Attributer.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return vfs.Chtimes(fs.Filesystem, fs.PrefixPath(name), atime, mtime)
+}
+
+// GetXattr implements vfs.Xattrer.
+func (fs *FS) GetXattr(name, attr string) ([]byte, error) {
+	return vfs.GetXattr(fs.Filesystem, fs.PrefixPath(name), attr)
+}
+
+// SetXattr implements vfs.Xattrer.
+func (fs *FS) SetXattr(name, attr string, value []byte) error {
+	return vfs.SetXattr(fs.Filesystem, fs.PrefixPath(name), attr, value)
+}
+
+// ListXattr implements vfs.Xattrer.
+func (fs *FS) ListXattr(name string) ([]string, error) {
+	return vfs.ListXattr(fs.Filesystem, fs.PrefixPath(name))
+}
+
+// RemoveXattr implements vfs.Xattrer.
+func (fs *FS) RemoveXattr(name, attr string) error {
+	return vfs.RemoveXattr(fs.Filesystem, fs.PrefixPath(name), attr)
+}
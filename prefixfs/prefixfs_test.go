@@ -227,3 +227,49 @@ func TestReadDir(t *testing.T) {
 		t.Error("ReadDir: slices not equal")
 	}
 }
+
+func TestSymlink(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+
+	err := vfs.Symlink(fs, "target", "link")
+	if err != nil {
+		t.Errorf("Symlink: %v", err)
+	}
+
+	target, err := vfs.Readlink(rfs, prefix("link"))
+	if err != nil || target != "target" {
+		t.Errorf("root:%v not a link to target (%v, %v)", prefix("link"), target, err)
+	}
+
+	target, err = fs.Readlink("link")
+	if err != nil || target != "target" {
+		t.Errorf("Readlink: %v (%v)", target, err)
+	}
+}
+
+func TestChmod(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+	vfs.WriteFile(rfs, prefix("file"), nil, 0644)
+
+	if err := vfs.Chmod(fs, "file", 0600); err != nil {
+		t.Errorf("Chmod: %v", err)
+	}
+	if fi, err := rfs.Stat(prefix("file")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("root:%v mode not changed (%v)", prefix("file"), err)
+	}
+}
+
+func TestXattr(t *testing.T) {
+	rfs := rootfs()
+	fs := Create(rfs, prefixPath)
+	vfs.WriteFile(fs, "file", nil, 0644)
+
+	if err := fs.SetXattr("file", "user.a", []byte("1")); err != nil {
+		t.Fatalf("SetXattr: %s", err)
+	}
+	if v, err := vfs.GetXattr(rfs, prefix("file"), "user.a"); err != nil || string(v) != "1" {
+		t.Errorf("Unexpected value on root: %q %v", v, err)
+	}
+}
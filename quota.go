@@ -0,0 +1,301 @@
+package vfs
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Quota creates a wrapper around the given filesystem which limits the total size
+// of all files to maxBytes and the number of files, directories and symbolic links to maxFiles.
+// A limit <= 0 disables the corresponding check.
+// Operations exceeding a limit fail with ErrQuotaExceeded.
+//
+// The usage of existing content is determined once by walking the filesystem,
+// afterwards only changes made through the wrapper are tracked.
+// Writes through the wrapper are serialized to keep the usage exact.
+func Quota(fs Filesystem, maxBytes int64, maxFiles int) *QuotaFS {
+	q := &QuotaFS{Filesystem: fs, maxBytes: maxBytes, maxFiles: maxFiles}
+	q.bytes, q.files = usage(fs, string(fs.PathSeparator()))
+	// The root itself does not count
+	q.files--
+	return q
+}
+
+// QuotaFS represents a filesystem with limited size and number of files
+// and works as a wrapper around existing filesystems.
+type QuotaFS struct {
+	Filesystem
+	maxBytes int64
+	maxFiles int
+
+	mutex sync.Mutex
+	bytes int64
+	files int
+}
+
+// usage returns the total size of regular files and the number of entries
+// of the tree rooted at path, including path itself.
+func usage(fs Filesystem, path string) (bytes int64, files int) {
+	Walk(fs, path, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		files++
+		bytes += regularSize(fi)
+		return nil
+	})
+	return bytes, files
+}
+
+// regularSize returns the size of a regular file, other files do not count.
+func regularSize(fi os.FileInfo) int64 {
+	if fi.Mode().IsRegular() {
+		return fi.Size()
+	}
+	return 0
+}
+
+// Usage returns the total size of all files and the number of files,
+// directories and symbolic links currently in use.
+func (fs *QuotaFS) Usage() (bytes int64, files int) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.bytes, fs.files
+}
+
+// reserve checks if the filesystem can grow by bytes and files.
+// The caller must hold the lock.
+func (fs *QuotaFS) reserve(bytes int64, files int) error {
+	if fs.maxBytes > 0 && bytes > 0 && fs.bytes+bytes > fs.maxBytes {
+		return ErrQuotaExceeded
+	}
+	if fs.maxFiles > 0 && files > 0 && fs.files+files > fs.maxFiles {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// OpenFile opens the named file.
+// Creating a new file counts against the file limit.
+func (fs *QuotaFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	var size int64
+	fi, err := fs.Filesystem.Stat(name)
+	if err == nil {
+		size = regularSize(fi)
+	}
+	created := flag&os.O_CREATE != 0 && os.IsNotExist(err)
+	if created {
+		if err := fs.reserve(0, 1); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return f, err
+	}
+	if created {
+		fs.files++
+	} else if flag&os.O_TRUNC != 0 {
+		fs.bytes -= size
+	}
+	return &quotaFile{File: f, fs: fs, append: flag&os.O_APPEND != 0}, nil
+}
+
+// Remove removes the named file or directory and releases its usage.
+func (fs *QuotaFS) Remove(name string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fi, err := fs.Filesystem.Lstat(name)
+	if err != nil {
+		return fs.Filesystem.Remove(name)
+	}
+	size := regularSize(fi)
+	if err := fs.Filesystem.Remove(name); err != nil {
+		return err
+	}
+	fs.files--
+	fs.bytes -= size
+	return nil
+}
+
+// Rename renames a file, a replaced file releases its usage.
+func (fs *QuotaFS) Rename(oldpath, newpath string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fi, err := fs.Filesystem.Lstat(newpath)
+	replaced := err == nil && !fi.IsDir()
+	var size int64
+	if replaced {
+		size = regularSize(fi)
+	}
+	if err := fs.Filesystem.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	if replaced {
+		fs.files--
+		fs.bytes -= size
+	}
+	return nil
+}
+
+// Mkdir creates a directory, which counts against the file limit.
+func (fs *QuotaFS) Mkdir(name string, perm os.FileMode) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := fs.reserve(0, 1); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	if err := fs.Filesystem.Mkdir(name, perm); err != nil {
+		return err
+	}
+	fs.files++
+	return nil
+}
+
+// Symlink creates a symbolic link, which counts against the file limit.
+// It returns ErrUnsupported if the wrapped filesystem does not support symbolic links.
+func (fs *QuotaFS) Symlink(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	if err := fs.reserve(0, 1); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	if err := Symlink(fs.Filesystem, oldname, newname); err != nil {
+		return err
+	}
+	fs.files++
+	return nil
+}
+
+// Link creates a hard link, which counts against both limits like a copy of the file.
+// It returns ErrUnsupported if the wrapped filesystem does not support hard links.
+func (fs *QuotaFS) Link(oldname, newname string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fi, err := fs.Filesystem.Lstat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	size := regularSize(fi)
+	if err := fs.reserve(size, 1); err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	if err := Link(fs.Filesystem, oldname, newname); err != nil {
+		return err
+	}
+	fs.files++
+	fs.bytes += size
+	return nil
+}
+
+// Readlink returns the destination of the named symbolic link
+// if the wrapped filesystem supports symbolic links.
+func (fs *QuotaFS) Readlink(name string) (string, error) {
+	return Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file.
+func (fs *QuotaFS) Chmod(name string, mode os.FileMode) error {
+	return Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *QuotaFS) Chown(name string, uid, gid int) error {
+	return Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *QuotaFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *QuotaFS) GetXattr(name, attr string) ([]byte, error) {
+	return GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+// Extended attributes do not count towards the quota.
+func (fs *QuotaFS) SetXattr(name, attr string, value []byte) error {
+	return SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *QuotaFS) ListXattr(name string) ([]string, error) {
+	return ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *QuotaFS) RemoveXattr(name, attr string) error {
+	return RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file.
+func (fs *QuotaFS) Watch(name string) (<-chan Event, error) {
+	return Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *QuotaFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
+
+// quotaFile tracks the growth of a file opened through a QuotaFS.
+type quotaFile struct {
+	File
+	fs     *QuotaFS
+	append bool
+}
+
+func (f *quotaFile) Write(p []byte) (int, error) {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	fi, err := f.File.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size, off := fi.Size(), fi.Size()
+	if !f.append {
+		if off, err = f.File.Seek(0, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+	if err := f.fs.reserve(off+int64(len(p))-size, 0); err != nil {
+		return 0, &os.PathError{Op: "write", Path: f.Name(), Err: err}
+	}
+	n, err := f.File.Write(p)
+	if fi, serr := f.File.Stat(); serr == nil {
+		f.fs.bytes += fi.Size() - size
+	}
+	return n, err
+}
+
+func (f *quotaFile) Truncate(size int64) error {
+	f.fs.mutex.Lock()
+	defer f.fs.mutex.Unlock()
+
+	fi, err := f.File.Stat()
+	if err != nil {
+		return err
+	}
+	old := fi.Size()
+	if err := f.fs.reserve(size-old, 0); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.Name(), Err: err}
+	}
+	if err := f.File.Truncate(size); err != nil {
+		return err
+	}
+	f.fs.bytes += size - old
+	return nil
+}
@@ -0,0 +1,121 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func assertUsage(t *testing.T, fs *vfs.QuotaFS, bytes int64, files int) {
+	t.Helper()
+	if b, f := fs.Usage(); b != bytes || f != files {
+		t.Errorf("Unexpected usage: %d bytes, %d files, expected %d bytes, %d files", b, f, bytes, files)
+	}
+}
+
+func TestQuotaExisting(t *testing.T) {
+	mfs := memfs.Create()
+	mfs.Mkdir("/dir", 0777)
+	vfs.WriteFile(mfs, "/dir/file", []byte("content"), 0666)
+
+	fs := vfs.Quota(mfs, 100, 10)
+	assertUsage(t, fs, 7, 2)
+}
+
+func TestQuotaBytes(t *testing.T) {
+	fs := vfs.Quota(memfs.Create(), 10, 0)
+
+	if err := vfs.WriteFile(fs, "/file", []byte("12345678"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	assertUsage(t, fs, 8, 1)
+
+	f, err := fs.OpenFile("/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	// Overwriting existing content does not grow the file
+	if _, err := f.Write([]byte("abcdefgh")); err != nil {
+		t.Errorf("Write: %s", err)
+	}
+	if _, err := f.Write([]byte("abc")); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := f.Write([]byte("ab")); err != nil {
+		t.Errorf("Write: %s", err)
+	}
+	assertUsage(t, fs, 10, 1)
+	if err := f.Truncate(11); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if err := f.Truncate(4); err != nil {
+		t.Errorf("Truncate: %s", err)
+	}
+	f.Close()
+	assertUsage(t, fs, 4, 1)
+
+	// Appending always grows the file
+	f, err = fs.OpenFile("/file", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	if _, err := f.Write([]byte("1234567")); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	f.Close()
+
+	// Truncating on open releases the content
+	if err := vfs.WriteFile(fs, "/file", []byte("123"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	assertUsage(t, fs, 3, 1)
+	if err := fs.Remove("/file"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	assertUsage(t, fs, 0, 0)
+}
+
+func TestQuotaFiles(t *testing.T) {
+	fs := vfs.Quota(memfs.Create(), 0, 3)
+
+	if err := vfs.MkdirAll(fs, "/a/b", 0777); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/a/b/file", []byte("content"), 0666); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Mkdir("/c", 0777); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := vfs.Create(fs, "/file"); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if err := fs.Symlink("/a", "/link"); !errors.Is(err, vfs.ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	// Opening an existing file does not count
+	if err := vfs.WriteFile(fs, "/a/b/file", nil, 0666); err != nil {
+		t.Errorf("WriteFile: %s", err)
+	}
+	assertUsage(t, fs, 0, 3)
+
+	if err := vfs.RemoveAll(fs, "/a/b"); err != nil {
+		t.Fatalf("RemoveAll: %s", err)
+	}
+	assertUsage(t, fs, 0, 1)
+}
+
+func TestQuotaRename(t *testing.T) {
+	fs := vfs.Quota(memfs.Create(), 0, 0)
+	vfs.WriteFile(fs, "/a", []byte("aaa"), 0666)
+	vfs.WriteFile(fs, "/b", []byte("bbbbb"), 0666)
+	assertUsage(t, fs, 8, 2)
+
+	if err := fs.Rename("/a", "/c"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	assertUsage(t, fs, 8, 2)
+}
@@ -3,50 +3,238 @@ package vfs
 import (
 	"errors"
 	"os"
+	"strings"
+	"time"
 )
 
 // ReadOnly creates a readonly wrapper around the given filesystem.
 // It disables the following operations:
 //
 // 	- Create
-// 	- Remove
+// 	- Remove, RemoveAll
 // 	- Rename
-// 	- Mkdir
+// 	- Mkdir, MkdirAll
+// 	- Symlink, Link
+// 	- Chmod, Chown, Chtimes
+// 	- SetXattr, RemoveXattr
 //
 // And disables OpenFile flags: os.O_CREATE, os.O_APPEND, os.O_WRONLY
 //
 // OpenFile returns a File with disabled Write() method otherwise.
+// Use ReadOnlyExcept to allow changes inside some paths.
 func ReadOnly(fs Filesystem) *RoFS {
 	return &RoFS{Filesystem: fs}
 }
 
+// ReadOnlyExcept creates a readonly wrapper around the given filesystem,
+// which allows all operations inside the given absolute paths, e.g.:
+//
+// 	vfs.ReadOnlyExcept(fs, "/tmp", "/var/run")
+//
+// Paths are compared after cleaning, "/tmp/../etc" is not writable.
+// Relative paths are never writable. Symbolic links are not resolved,
+// a link inside a writable path can be used to change its target.
+// Renames are only allowed if both paths are writable.
+func ReadOnlyExcept(fs Filesystem, writable ...string) *RoFS {
+	ro := &RoFS{Filesystem: fs}
+	for _, path := range writable {
+		ro.writable = append(ro.writable, cleanPath(fs, path))
+	}
+	return ro
+}
+
 // RoFS represents a read-only filesystem and
 // works as a wrapper around existing filesystems.
 type RoFS struct {
 	Filesystem
+	writable []string
+}
+
+// cleanPath returns the shortest path equivalent to the absolute path by lexical processing
+// using Clean, "" for relative paths. Slashes are replaced by the separator of the filesystem.
+func cleanPath(fs Filesystem, path string) string {
+	if !IsAbs(fs, path) {
+		return ""
+	}
+	return Clean(fs, path)
+}
+
+// isWritable reports whether all names are inside the writable paths.
+func (fs RoFS) isWritable(names ...string) bool {
+	if len(fs.writable) == 0 {
+		return false
+	}
+	for _, name := range names {
+		name = cleanPath(fs, name)
+		if name == "" || !fs.inWritable(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// inWritable reports whether the cleaned path is one of the writable paths or below it.
+func (fs RoFS) inWritable(name string) bool {
+	for _, w := range fs.writable {
+		if name == w || strings.HasPrefix(name, JoinPath(fs, w, "")) {
+			return true
+		}
+	}
+	return false
 }
 
 // ErrorReadOnly is returned on every disabled operation.
 var ErrReadOnly = errors.New("Filesystem is read-only")
 
-// Remove is disabled and returns ErrorReadOnly
+// Remove is disabled and returns ErrorReadOnly, except inside writable paths
 func (fs RoFS) Remove(name string) error {
+	if fs.isWritable(name) {
+		return fs.Filesystem.Remove(name)
+	}
 	return ErrReadOnly
 }
 
-// Rename is disabled and returns ErrorReadOnly
+// RemoveAll is disabled and returns ErrorReadOnly, except inside writable paths.
+// Like the generic RemoveAll, a missing path is not an error.
+func (fs RoFS) RemoveAll(path string) error {
+	if fs.isWritable(path) {
+		return RemoveAll(fs.Filesystem, path)
+	}
+	if _, err := fs.Filesystem.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return ErrReadOnly
+}
+
+// MkdirAll is disabled and returns ErrorReadOnly, except inside writable paths.
+// Like the generic MkdirAll, an existing directory is not an error.
+func (fs RoFS) MkdirAll(path string, perm os.FileMode) error {
+	if fs.isWritable(path) {
+		return MkdirAll(fs.Filesystem, path, perm)
+	}
+	if fi, err := fs.Filesystem.Stat(path); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &os.PathError{Op: "mkdir", Path: path, Err: ErrNotDirectory}
+	}
+	return ErrReadOnly
+}
+
+// Rename is disabled and returns ErrorReadOnly, except inside writable paths
 func (fs RoFS) Rename(oldpath, newpath string) error {
+	if fs.isWritable(oldpath, newpath) {
+		return fs.Filesystem.Rename(oldpath, newpath)
+	}
 	return ErrReadOnly
 }
 
-// Mkdir is disabled and returns ErrorReadOnly
+// Mkdir is disabled and returns ErrorReadOnly, except inside writable paths
 func (fs RoFS) Mkdir(name string, perm os.FileMode) error {
+	if fs.isWritable(name) {
+		return fs.Filesystem.Mkdir(name, perm)
+	}
+	return ErrReadOnly
+}
+
+// Symlink is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) Symlink(oldname, newname string) error {
+	if fs.isWritable(newname) {
+		return Symlink(fs.Filesystem, oldname, newname)
+	}
 	return ErrReadOnly
 }
 
+// Link is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) Link(oldname, newname string) error {
+	if fs.isWritable(oldname, newname) {
+		return Link(fs.Filesystem, oldname, newname)
+	}
+	return ErrReadOnly
+}
+
+// Chmod is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) Chmod(name string, mode os.FileMode) error {
+	if fs.isWritable(name) {
+		return Chmod(fs.Filesystem, name, mode)
+	}
+	return ErrReadOnly
+}
+
+// Chown is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) Chown(name string, uid, gid int) error {
+	if fs.isWritable(name) {
+		return Chown(fs.Filesystem, name, uid, gid)
+	}
+	return ErrReadOnly
+}
+
+// Chtimes is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if fs.isWritable(name) {
+		return Chtimes(fs.Filesystem, name, atime, mtime)
+	}
+	return ErrReadOnly
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file
+// if the wrapped filesystem supports extended attributes.
+func (fs RoFS) GetXattr(name, attr string) ([]byte, error) {
+	return GetXattr(fs.Filesystem, name, attr)
+}
+
+// ListXattr returns the names of the extended attributes of the named file
+// if the wrapped filesystem supports extended attributes.
+func (fs RoFS) ListXattr(name string) ([]string, error) {
+	return ListXattr(fs.Filesystem, name)
+}
+
+// SetXattr is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) SetXattr(name, attr string, value []byte) error {
+	if fs.isWritable(name) {
+		return SetXattr(fs.Filesystem, name, attr, value)
+	}
+	return ErrReadOnly
+}
+
+// RemoveXattr is disabled and returns ErrorReadOnly, except inside writable paths
+func (fs RoFS) RemoveXattr(name, attr string) error {
+	if fs.isWritable(name) {
+		return RemoveXattr(fs.Filesystem, name, attr)
+	}
+	return ErrReadOnly
+}
+
+// Readlink returns the destination of the named symbolic link
+// if the wrapped filesystem supports symbolic links.
+func (fs RoFS) Readlink(name string) (string, error) {
+	return Readlink(fs.Filesystem, name)
+}
+
+// OpenDir opens the named directory for iteration.
+// It implements DirOpener.
+func (fs RoFS) OpenDir(path string) (DirIterator, error) {
+	return OpenDir(fs.Filesystem, path)
+}
+
+// Watch reports changes of the named file
+// if the wrapped filesystem supports watching.
+func (fs RoFS) Watch(name string) (<-chan Event, error) {
+	return Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs RoFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
+
 // OpenFile returns ErrorReadOnly if flag contains os.O_CREATE, os.O_APPEND, os.O_WRONLY.
 // Otherwise it returns a read-only File with disabled Write(..) operation.
+// Files inside writable paths are opened unchanged.
 func (fs RoFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if fs.isWritable(name) {
+		return fs.Filesystem.OpenFile(name, flag, perm)
+	}
 	if flag&os.O_CREATE == os.O_CREATE {
 		return nil, ErrReadOnly
 	}
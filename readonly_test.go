@@ -2,7 +2,9 @@ package vfs
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -91,3 +93,107 @@ func TestROOpenFileWrite(t *testing.T) {
 		t.Errorf("Written expected 0: %d", written)
 	}
 }
+
+func TestROExcept(t *testing.T) {
+	ro := ReadOnlyExcept(baseFSDummy, "/tmp/", "/var/run")
+
+	// Writable paths are forwarded and return the dummy error
+	for _, name := range []string{"/tmp", "/tmp/file", "/var/run/app.pid", "/etc/../tmp/file", "//tmp/./dir/"} {
+		if err := ro.Mkdir(name, 0777); err != errDummy {
+			t.Errorf("Mkdir %s: expected dummy error, got %v", name, err)
+		}
+		if _, err := ro.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0666); err != errDummy {
+			t.Errorf("OpenFile %s: expected dummy error, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"/", "/tmpfile", "/var", "/tmp/../etc/passwd", "tmp/file", "../tmp/file"} {
+		if err := ro.Mkdir(name, 0777); err != ErrReadOnly {
+			t.Errorf("Mkdir %s: expected ErrReadOnly, got %v", name, err)
+		}
+		if err := ro.Remove(name); err != ErrReadOnly {
+			t.Errorf("Remove %s: expected ErrReadOnly, got %v", name, err)
+		}
+		if _, err := ro.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0666); err != ErrReadOnly {
+			t.Errorf("OpenFile %s: expected ErrReadOnly, got %v", name, err)
+		}
+	}
+
+	if err := ro.Rename("/tmp/a", "/var/run/b"); err != errDummy {
+		t.Errorf("Rename between writable paths: expected dummy error, got %v", err)
+	}
+	if err := ro.Rename("/etc/passwd", "/tmp/passwd"); err != ErrReadOnly {
+		t.Errorf("Rename from read-only path: expected ErrReadOnly, got %v", err)
+	}
+	if err := ro.Rename("/tmp/passwd", "/etc/passwd"); err != ErrReadOnly {
+		t.Errorf("Rename to read-only path: expected ErrReadOnly, got %v", err)
+	}
+	if err := ro.Symlink("/etc/passwd", "/tmp/link"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Symlink into writable path: expected ErrUnsupported, got %v", err)
+	}
+	if err := ro.Chmod("/etc/passwd", 0777); err != ErrReadOnly {
+		t.Errorf("Chmod: expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestROExceptRoot(t *testing.T) {
+	ro := ReadOnlyExcept(baseFSDummy, "/")
+	if err := ro.Remove("/etc/passwd"); err != errDummy {
+		t.Errorf("Expected dummy error, got %v", err)
+	}
+	if err := ro.Remove("relative"); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestROMkdirAllRemoveAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-readonly")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	for _, ro := range []*RoFS{ReadOnly(OS()), ReadOnlyExcept(OS(), filepath.Join(dir, "scratch"))} {
+		if err := ro.MkdirAll(dir, 0755); err != nil {
+			t.Errorf("MkdirAll of existing directory: %s", err)
+		}
+		if err := ro.MkdirAll(filepath.Join(dir, "new"), 0755); err != ErrReadOnly {
+			t.Errorf("Expected ErrReadOnly, got %v", err)
+		}
+		if err := ro.MkdirAll(file, 0755); err == nil || err == ErrReadOnly {
+			t.Errorf("Expected not a directory error, got %v", err)
+		}
+		if err := ro.RemoveAll(filepath.Join(dir, "missing")); err != nil {
+			t.Errorf("RemoveAll of missing path: %s", err)
+		}
+		if err := ro.RemoveAll(file); err != ErrReadOnly {
+			t.Errorf("Expected ErrReadOnly, got %v", err)
+		}
+	}
+}
+
+// backslashFS is separated by backslashes like the filesystems of Windows.
+type backslashFS struct {
+	Filesystem
+}
+
+func (fs backslashFS) PathSeparator() uint8 {
+	return '\\'
+}
+
+func TestROExceptMixedSeparators(t *testing.T) {
+	ro := ReadOnlyExcept(backslashFS{Dummy(errDummy)}, `\jail/`)
+	for _, name := range []string{`\jail\x/../../secret`, `/jail/../secret`, `\jail/..\secret`, `\jailbreak`} {
+		if _, err := ro.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0666); err != ErrReadOnly {
+			t.Errorf("OpenFile %s: expected ErrReadOnly, got %v", name, err)
+		}
+	}
+	for _, name := range []string{`\jail\x`, `/jail/x`, `\jail/sub\..\x`} {
+		if _, err := ro.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0666); err != errDummy {
+			t.Errorf("OpenFile %s: expected dummy error, got %v", name, err)
+		}
+	}
+}
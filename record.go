@@ -0,0 +1,717 @@
+package vfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned by a replayed filesystem for operations missing in the trace.
+var ErrNotRecorded = errors.New("Operation not recorded")
+
+// Record creates a wrapper around the given filesystem which writes every operation,
+// its arguments and results to w as a trace, one JSON object per line.
+// Operations on files opened through the wrapper are recorded as well,
+// the trace contains the content read but not the content written.
+// Errors writing the trace do not fail the operations, they are reported by Err.
+// Watches are forwarded to fs but not recorded, as their events arrive asynchronously.
+//
+// Replay serves the operations of a trace without the wrapped filesystem.
+func Record(fs Filesystem, w io.Writer) *RecordFS {
+	r := &RecordFS{Filesystem: fs, enc: json.NewEncoder(w)}
+	r.record(&traceEntry{Op: "pathseparator", Ret: int64(fs.PathSeparator())})
+	return r
+}
+
+// RecordFS represents a filesystem which records every operation
+// and works as a wrapper around existing filesystems.
+type RecordFS struct {
+	Filesystem
+
+	mutex sync.Mutex
+	enc   *json.Encoder
+	files int
+	err   error
+}
+
+// traceEntry is a recorded operation, results are omitted when matching operations.
+type traceEntry struct {
+	Op     string      `json:"op"`
+	Name   string      `json:"name,omitempty"`
+	Name2  string      `json:"name2,omitempty"`
+	File   int         `json:"file,omitempty"`
+	Args   []int64     `json:"args,omitempty"`
+	Ret    int64       `json:"ret,omitempty"`
+	Data   []byte      `json:"data,omitempty"`
+	Target string      `json:"target,omitempty"`
+	Names  []string    `json:"names,omitempty"`
+	Info   *traceInfo  `json:"info,omitempty"`
+	Infos  []traceInfo `json:"infos,omitempty"`
+	Err    *traceError `json:"err,omitempty"`
+}
+
+// key identifies the operation of the entry by its arguments.
+func (e *traceEntry) key() string {
+	return fmt.Sprint(e.Op, "|", e.Name, "|", e.Name2, "|", e.File, "|", e.Args)
+}
+
+// traceInfo is a recorded os.FileInfo.
+type traceInfo struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modtime"`
+	Dir     bool        `json:"dir,omitempty"`
+}
+
+func encodeInfo(fi os.FileInfo) traceInfo {
+	return traceInfo{Name: fi.Name(), Size: fi.Size(), Mode: fi.Mode(), ModTime: fi.ModTime(), Dir: fi.IsDir()}
+}
+
+func encodeInfos(fis []os.FileInfo) []traceInfo {
+	infos := make([]traceInfo, len(fis))
+	for i, fi := range fis {
+		infos[i] = encodeInfo(fi)
+	}
+	return infos
+}
+
+func (i traceInfo) decode() os.FileInfo {
+	return DumFileInfo{IName: i.Name, ISize: i.Size, IMode: i.Mode, IModTime: i.ModTime, IDir: i.Dir}
+}
+
+func decodeInfos(infos []traceInfo) []os.FileInfo {
+	if infos == nil {
+		return nil
+	}
+	fis := make([]os.FileInfo, len(infos))
+	for i, info := range infos {
+		fis[i] = info.decode()
+	}
+	return fis
+}
+
+// traceErrors are the well-known errors which are replayed as themselves,
+// so checks like os.IsNotExist or err == io.EOF keep working.
+var traceErrors = []struct {
+	code string
+	err  error
+}{
+	{"eof", io.EOF},
+	{"unexpectedeof", io.ErrUnexpectedEOF},
+	{"notexist", os.ErrNotExist},
+	{"exist", os.ErrExist},
+	{"permission", os.ErrPermission},
+	{"invalid", os.ErrInvalid},
+	{"isdir", ErrIsDirectory},
+	{"notdir", ErrNotDirectory},
+	{"notempty", ErrNotEmpty},
+	{"readonly", ErrReadOnly},
+	{"unsupported", ErrUnsupported},
+	{"quota", ErrQuotaExceeded},
+	{"timeout", ErrRemoteTimeout},
+	{"crossdevice", ErrCrossDevice},
+	{"toomanylinks", ErrTooManyLinks},
+	{"noxattr", ErrNoXattr},
+}
+
+// traceError is a recorded error, *os.PathError and *os.LinkError keep their structure.
+type traceError struct {
+	Type  string `json:"type,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Name2 string `json:"name2,omitempty"`
+	Code  string `json:"code,omitempty"`
+	Msg   string `json:"msg"`
+}
+
+func encodeError(err error) *traceError {
+	if err == nil {
+		return nil
+	}
+	e := &traceError{}
+	switch perr := err.(type) {
+	case *os.PathError:
+		e.Type, e.Op, e.Name, err = "path", perr.Op, perr.Path, perr.Err
+	case *os.LinkError:
+		e.Type, e.Op, e.Name, e.Name2, err = "link", perr.Op, perr.Old, perr.New, perr.Err
+	}
+	e.Msg = err.Error()
+	for _, known := range traceErrors {
+		if errors.Is(err, known.err) {
+			e.Code = known.code
+			break
+		}
+	}
+	return e
+}
+
+func (e *traceError) decode() error {
+	if e == nil {
+		return nil
+	}
+	err := errors.New(e.Msg)
+	for _, known := range traceErrors {
+		if known.code == e.Code {
+			err = known.err
+			break
+		}
+	}
+	switch e.Type {
+	case "path":
+		return &os.PathError{Op: e.Op, Path: e.Name, Err: err}
+	case "link":
+		return &os.LinkError{Op: e.Op, Old: e.Name, New: e.Name2, Err: err}
+	}
+	return err
+}
+
+// record writes the entry to the trace.
+func (fs *RecordFS) record(e *traceEntry) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if err := fs.enc.Encode(e); err != nil && fs.err == nil {
+		fs.err = err
+	}
+}
+
+// Err returns the first error which occurred writing the trace.
+func (fs *RecordFS) Err() error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return fs.err
+}
+
+// OpenFile opens the file and records the operation.
+// The returned file records its operations as well.
+func (fs *RecordFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	e := &traceEntry{Op: "openfile", Name: name, Args: []int64{int64(flag), int64(perm)}, Err: encodeError(err)}
+	if err != nil {
+		fs.record(e)
+		return f, err
+	}
+	fs.mutex.Lock()
+	fs.files++
+	id := fs.files
+	fs.mutex.Unlock()
+	e.Ret = int64(id)
+	fs.record(e)
+	return &recordFile{File: f, fs: fs, id: id}, nil
+}
+
+// Remove removes the named file or directory and records the operation.
+func (fs *RecordFS) Remove(name string) error {
+	err := fs.Filesystem.Remove(name)
+	fs.record(&traceEntry{Op: "remove", Name: name, Err: encodeError(err)})
+	return err
+}
+
+// Rename renames a file and records the operation.
+func (fs *RecordFS) Rename(oldpath, newpath string) error {
+	err := fs.Filesystem.Rename(oldpath, newpath)
+	fs.record(&traceEntry{Op: "rename", Name: oldpath, Name2: newpath, Err: encodeError(err)})
+	return err
+}
+
+// Mkdir creates a directory and records the operation.
+func (fs *RecordFS) Mkdir(name string, perm os.FileMode) error {
+	err := fs.Filesystem.Mkdir(name, perm)
+	fs.record(&traceEntry{Op: "mkdir", Name: name, Args: []int64{int64(perm)}, Err: encodeError(err)})
+	return err
+}
+
+// Stat returns the FileInfo of the named file and records the operation.
+func (fs *RecordFS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Stat(name)
+	fs.record(statEntry("stat", name, fi, err))
+	return fi, err
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links and records the operation.
+func (fs *RecordFS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.Filesystem.Lstat(name)
+	fs.record(statEntry("lstat", name, fi, err))
+	return fi, err
+}
+
+func statEntry(op, name string, fi os.FileInfo, err error) *traceEntry {
+	e := &traceEntry{Op: op, Name: name, Err: encodeError(err)}
+	if err == nil {
+		info := encodeInfo(fi)
+		e.Info = &info
+	}
+	return e
+}
+
+// ReadDir reads the named directory and records the operation.
+func (fs *RecordFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	fs.record(&traceEntry{Op: "readdir", Name: path, Infos: encodeInfos(fis), Err: encodeError(err)})
+	return fis, err
+}
+
+// Symlink creates a symbolic link and records the operation.
+func (fs *RecordFS) Symlink(oldname, newname string) error {
+	err := Symlink(fs.Filesystem, oldname, newname)
+	fs.record(&traceEntry{Op: "symlink", Name: oldname, Name2: newname, Err: encodeError(err)})
+	return err
+}
+
+// Link creates a hard link and records the operation.
+func (fs *RecordFS) Link(oldname, newname string) error {
+	err := Link(fs.Filesystem, oldname, newname)
+	fs.record(&traceEntry{Op: "link", Name: oldname, Name2: newname, Err: encodeError(err)})
+	return err
+}
+
+// Readlink returns the destination of a symbolic link and records the operation.
+func (fs *RecordFS) Readlink(name string) (string, error) {
+	target, err := Readlink(fs.Filesystem, name)
+	fs.record(&traceEntry{Op: "readlink", Name: name, Target: target, Err: encodeError(err)})
+	return target, err
+}
+
+// Chmod changes the mode of the named file and records the operation.
+func (fs *RecordFS) Chmod(name string, mode os.FileMode) error {
+	err := Chmod(fs.Filesystem, name, mode)
+	fs.record(&traceEntry{Op: "chmod", Name: name, Args: []int64{int64(mode)}, Err: encodeError(err)})
+	return err
+}
+
+// Chown changes the numeric uid and gid of the named file and records the operation.
+func (fs *RecordFS) Chown(name string, uid, gid int) error {
+	err := Chown(fs.Filesystem, name, uid, gid)
+	fs.record(&traceEntry{Op: "chown", Name: name, Args: []int64{int64(uid), int64(gid)}, Err: encodeError(err)})
+	return err
+}
+
+// Chtimes changes the access and modification times of the named file and records the operation.
+func (fs *RecordFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	err := Chtimes(fs.Filesystem, name, atime, mtime)
+	fs.record(&traceEntry{Op: "chtimes", Name: name, Args: []int64{atime.UnixNano(), mtime.UnixNano()}, Err: encodeError(err)})
+	return err
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file and records the operation.
+func (fs *RecordFS) GetXattr(name, attr string) ([]byte, error) {
+	value, err := GetXattr(fs.Filesystem, name, attr)
+	fs.record(&traceEntry{Op: "getxattr", Name: name, Name2: attr, Data: value, Err: encodeError(err)})
+	return value, err
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file and records the operation.
+// Like written content, the value is not recorded.
+func (fs *RecordFS) SetXattr(name, attr string, value []byte) error {
+	err := SetXattr(fs.Filesystem, name, attr, value)
+	fs.record(&traceEntry{Op: "setxattr", Name: name, Name2: attr, Err: encodeError(err)})
+	return err
+}
+
+// ListXattr returns the names of the extended attributes of the named file and records the operation.
+func (fs *RecordFS) ListXattr(name string) ([]string, error) {
+	attrs, err := ListXattr(fs.Filesystem, name)
+	fs.record(&traceEntry{Op: "listxattr", Name: name, Names: attrs, Err: encodeError(err)})
+	return attrs, err
+}
+
+// RemoveXattr removes the extended attribute attr of the named file and records the operation.
+func (fs *RecordFS) RemoveXattr(name, attr string) error {
+	err := RemoveXattr(fs.Filesystem, name, attr)
+	fs.record(&traceEntry{Op: "removexattr", Name: name, Name2: attr, Err: encodeError(err)})
+	return err
+}
+
+// Watch reports changes of the named file, the watch is not recorded.
+func (fs *RecordFS) Watch(name string) (<-chan Event, error) {
+	return Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *RecordFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
+
+// recordFile records the operations on an open file.
+type recordFile struct {
+	File
+	fs *RecordFS
+	id int
+}
+
+func (f *recordFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.fs.record(&traceEntry{Op: "read", File: f.id, Args: []int64{int64(len(p))}, Ret: int64(n), Data: p[:n], Err: encodeError(err)})
+	return n, err
+}
+
+func (f *recordFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off)
+	f.fs.record(&traceEntry{Op: "readat", File: f.id, Args: []int64{int64(len(p)), off}, Ret: int64(n), Data: p[:n], Err: encodeError(err)})
+	return n, err
+}
+
+func (f *recordFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.fs.record(&traceEntry{Op: "write", File: f.id, Args: []int64{int64(len(p))}, Ret: int64(n), Err: encodeError(err)})
+	return n, err
+}
+
+func (f *recordFile) Seek(offset int64, whence int) (int64, error) {
+	n, err := f.File.Seek(offset, whence)
+	f.fs.record(&traceEntry{Op: "seek", File: f.id, Args: []int64{offset, int64(whence)}, Ret: n, Err: encodeError(err)})
+	return n, err
+}
+
+func (f *recordFile) Truncate(size int64) error {
+	err := f.File.Truncate(size)
+	f.fs.record(&traceEntry{Op: "truncate", File: f.id, Args: []int64{size}, Err: encodeError(err)})
+	return err
+}
+
+func (f *recordFile) Sync() error {
+	err := f.File.Sync()
+	f.fs.record(&traceEntry{Op: "sync", File: f.id, Err: encodeError(err)})
+	return err
+}
+
+func (f *recordFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	e := statEntry("fstat", "", fi, err)
+	e.File = f.id
+	f.fs.record(e)
+	return fi, err
+}
+
+func (f *recordFile) Readdir(n int) ([]os.FileInfo, error) {
+	fis, err := f.File.Readdir(n)
+	f.fs.record(&traceEntry{Op: "freaddir", File: f.id, Args: []int64{int64(n)}, Infos: encodeInfos(fis), Err: encodeError(err)})
+	return fis, err
+}
+
+func (f *recordFile) Close() error {
+	err := f.File.Close()
+	f.fs.record(&traceEntry{Op: "close", File: f.id, Err: encodeError(err)})
+	return err
+}
+
+// Replay creates a filesystem serving the operations of a trace written by Record.
+// Every recorded operation is served once with its recorded results, operations
+// with the same arguments in the order they were recorded. Independent operations
+// may be replayed in a different order, e.g. by concurrent goroutines.
+// Operations which are not in the trace, or not anymore, fail with ErrNotRecorded.
+// Watches are not recorded, so ReplayFS does not implement Watcher.
+func Replay(r io.Reader) (*ReplayFS, error) {
+	fs := &ReplayFS{sep: '/', entries: make(map[string][]*traceEntry)}
+	dec := json.NewDecoder(r)
+	for {
+		e := &traceEntry{}
+		err := dec.Decode(e)
+		if err == io.EOF {
+			return fs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == "pathseparator" {
+			fs.sep = uint8(e.Ret)
+			continue
+		}
+		k := e.key()
+		fs.entries[k] = append(fs.entries[k], e)
+	}
+}
+
+// ReplayFS represents a filesystem serving the operations of a trace.
+type ReplayFS struct {
+	sep     uint8
+	mutex   sync.Mutex
+	entries map[string][]*traceEntry
+}
+
+// next returns the next recorded entry of the operation described by e.
+func (fs *ReplayFS) next(e *traceEntry) (*traceEntry, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	k := e.key()
+	queue := fs.entries[k]
+	if len(queue) == 0 {
+		return nil, ErrNotRecorded
+	}
+	fs.entries[k] = queue[1:]
+	return queue[0], nil
+}
+
+// Remaining returns the number of recorded operations which were not replayed.
+func (fs *ReplayFS) Remaining() int {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	n := 0
+	for _, queue := range fs.entries {
+		n += len(queue)
+	}
+	return n
+}
+
+// PathSeparator returns the path separator of the recorded filesystem.
+func (fs *ReplayFS) PathSeparator() uint8 {
+	return fs.sep
+}
+
+// OpenFile replays opening the named file.
+func (fs *ReplayFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	e, err := fs.next(&traceEntry{Op: "openfile", Name: name, Args: []int64{int64(flag), int64(perm)}})
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if e.Err != nil {
+		return nil, e.Err.decode()
+	}
+	return &replayFile{fs: fs, id: int(e.Ret), name: name}, nil
+}
+
+// Remove replays removing the named file or directory.
+func (fs *ReplayFS) Remove(name string) error {
+	e, err := fs.next(&traceEntry{Op: "remove", Name: name})
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Rename replays renaming a file.
+func (fs *ReplayFS) Rename(oldpath, newpath string) error {
+	e, err := fs.next(&traceEntry{Op: "rename", Name: oldpath, Name2: newpath})
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Mkdir replays creating a directory.
+func (fs *ReplayFS) Mkdir(name string, perm os.FileMode) error {
+	e, err := fs.next(&traceEntry{Op: "mkdir", Name: name, Args: []int64{int64(perm)}})
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Stat replays the FileInfo of the named file.
+func (fs *ReplayFS) Stat(name string) (os.FileInfo, error) {
+	return fs.stat("stat", name)
+}
+
+// Lstat replays the FileInfo of the named file without following symbolic links.
+func (fs *ReplayFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.stat("lstat", name)
+}
+
+func (fs *ReplayFS) stat(op, name string) (os.FileInfo, error) {
+	e, err := fs.next(&traceEntry{Op: op, Name: name})
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if e.Err != nil || e.Info == nil {
+		return nil, e.Err.decode()
+	}
+	return e.Info.decode(), nil
+}
+
+// ReadDir replays reading the named directory.
+func (fs *ReplayFS) ReadDir(path string) ([]os.FileInfo, error) {
+	e, err := fs.next(&traceEntry{Op: "readdir", Name: path})
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	return decodeInfos(e.Infos), e.Err.decode()
+}
+
+// Symlink replays creating a symbolic link.
+func (fs *ReplayFS) Symlink(oldname, newname string) error {
+	e, err := fs.next(&traceEntry{Op: "symlink", Name: oldname, Name2: newname})
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Link replays creating a hard link.
+func (fs *ReplayFS) Link(oldname, newname string) error {
+	e, err := fs.next(&traceEntry{Op: "link", Name: oldname, Name2: newname})
+	if err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Readlink replays reading the destination of a symbolic link.
+func (fs *ReplayFS) Readlink(name string) (string, error) {
+	e, err := fs.next(&traceEntry{Op: "readlink", Name: name})
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return e.Target, e.Err.decode()
+}
+
+// Chmod replays changing the mode of the named file.
+func (fs *ReplayFS) Chmod(name string, mode os.FileMode) error {
+	e, err := fs.next(&traceEntry{Op: "chmod", Name: name, Args: []int64{int64(mode)}})
+	if err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Chown replays changing the owner of the named file.
+func (fs *ReplayFS) Chown(name string, uid, gid int) error {
+	e, err := fs.next(&traceEntry{Op: "chown", Name: name, Args: []int64{int64(uid), int64(gid)}})
+	if err != nil {
+		return &os.PathError{Op: "chown", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// Chtimes replays changing the times of the named file.
+func (fs *ReplayFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	e, err := fs.next(&traceEntry{Op: "chtimes", Name: name, Args: []int64{atime.UnixNano(), mtime.UnixNano()}})
+	if err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// GetXattr replays reading the extended attribute attr of the named file.
+func (fs *ReplayFS) GetXattr(name, attr string) ([]byte, error) {
+	e, err := fs.next(&traceEntry{Op: "getxattr", Name: name, Name2: attr})
+	if err != nil {
+		return nil, &os.PathError{Op: "getxattr", Path: name, Err: err}
+	}
+	return e.Data, e.Err.decode()
+}
+
+// SetXattr replays setting the extended attribute attr of the named file.
+func (fs *ReplayFS) SetXattr(name, attr string, value []byte) error {
+	e, err := fs.next(&traceEntry{Op: "setxattr", Name: name, Name2: attr})
+	if err != nil {
+		return &os.PathError{Op: "setxattr", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// ListXattr replays listing the extended attributes of the named file.
+func (fs *ReplayFS) ListXattr(name string) ([]string, error) {
+	e, err := fs.next(&traceEntry{Op: "listxattr", Name: name})
+	if err != nil {
+		return nil, &os.PathError{Op: "listxattr", Path: name, Err: err}
+	}
+	return e.Names, e.Err.decode()
+}
+
+// RemoveXattr replays removing the extended attribute attr of the named file.
+func (fs *ReplayFS) RemoveXattr(name, attr string) error {
+	e, err := fs.next(&traceEntry{Op: "removexattr", Name: name, Name2: attr})
+	if err != nil {
+		return &os.PathError{Op: "removexattr", Path: name, Err: err}
+	}
+	return e.Err.decode()
+}
+
+// replayFile replays the operations on a file opened from a trace.
+type replayFile struct {
+	fs   *ReplayFS
+	id   int
+	name string
+}
+
+// next returns the next recorded entry of the operation op with the arguments args.
+func (f *replayFile) next(op string, args ...int64) (*traceEntry, error) {
+	e, err := f.fs.next(&traceEntry{Op: op, File: f.id, Args: args})
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: f.name, Err: err}
+	}
+	return e, nil
+}
+
+func (f *replayFile) Name() string {
+	return f.name
+}
+
+func (f *replayFile) Read(p []byte) (int, error) {
+	e, err := f.next("read", int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	return copy(p, e.Data), e.Err.decode()
+}
+
+func (f *replayFile) ReadAt(p []byte, off int64) (int, error) {
+	e, err := f.next("readat", int64(len(p)), off)
+	if err != nil {
+		return 0, err
+	}
+	return copy(p, e.Data), e.Err.decode()
+}
+
+func (f *replayFile) Write(p []byte) (int, error) {
+	e, err := f.next("write", int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	return int(e.Ret), e.Err.decode()
+}
+
+func (f *replayFile) Seek(offset int64, whence int) (int64, error) {
+	e, err := f.next("seek", offset, int64(whence))
+	if err != nil {
+		return 0, err
+	}
+	return e.Ret, e.Err.decode()
+}
+
+func (f *replayFile) Truncate(size int64) error {
+	e, err := f.next("truncate", size)
+	if err != nil {
+		return err
+	}
+	return e.Err.decode()
+}
+
+func (f *replayFile) Sync() error {
+	e, err := f.next("sync")
+	if err != nil {
+		return err
+	}
+	return e.Err.decode()
+}
+
+func (f *replayFile) Stat() (os.FileInfo, error) {
+	e, err := f.next("fstat")
+	if err != nil {
+		return nil, err
+	}
+	if e.Err != nil || e.Info == nil {
+		return nil, e.Err.decode()
+	}
+	return e.Info.decode(), nil
+}
+
+func (f *replayFile) Readdir(n int) ([]os.FileInfo, error) {
+	e, err := f.next("freaddir", int64(n))
+	if err != nil {
+		return nil, err
+	}
+	return decodeInfos(e.Infos), e.Err.decode()
+}
+
+func (f *replayFile) Close() error {
+	e, err := f.next("close")
+	if err != nil {
+		return err
+	}
+	return e.Err.decode()
+}
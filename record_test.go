@@ -0,0 +1,127 @@
+package vfs_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestRecordInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Symlinker(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Attributer(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Symlinker(&vfs.ReplayFS{})
+	_ = vfs.Attributer(&vfs.ReplayFS{})
+	_ = vfs.Linker(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Xattrer(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Watcher(vfs.Record(vfs.OS(), io.Discard))
+	_ = vfs.Linker(&vfs.ReplayFS{})
+	_ = vfs.Xattrer(&vfs.ReplayFS{})
+}
+
+// recordWorkload runs a few operations on fs and describes their results.
+func recordWorkload(fs vfs.Filesystem) []string {
+	var results []string
+	report := func(args ...interface{}) {
+		results = append(results, fmt.Sprint(args...))
+	}
+
+	report(fs.Mkdir("/dir", 0755))
+	report(vfs.WriteFile(fs, "/dir/file", []byte("content"), 0644))
+	b, err := vfs.ReadFile(fs, "/dir/file")
+	report(string(b), err)
+	_, err = fs.Stat("/missing")
+	report(os.IsNotExist(err))
+	report(fs.Rename("/dir/file", "/dir/renamed"))
+	fis, err := fs.ReadDir("/dir")
+	for _, fi := range fis {
+		report(fi.Name(), fi.Size(), fi.Mode())
+	}
+	report(err)
+
+	f, err := fs.OpenFile("/dir/renamed", os.O_RDWR, 0)
+	report(err)
+	if err == nil {
+		buf := make([]byte, 4)
+		n, err := f.ReadAt(buf, 5)
+		report(n, string(buf[:n]), err == io.EOF)
+		report(f.Seek(0, io.SeekEnd))
+		report(f.Write([]byte("!")))
+		report(f.Truncate(3))
+		report(f.Close())
+	}
+	err = vfs.Symlink(fs, "/dir/renamed", "/link")
+	report(errors.Is(err, vfs.ErrUnsupported))
+	report(vfs.Link(fs, "/dir/renamed", "/hardlink"))
+
+	report(vfs.SetXattr(fs, "/hardlink", "user.tag", []byte("value")))
+	value, err := vfs.GetXattr(fs, "/dir/renamed", "user.tag")
+	report(string(value), err)
+	report(vfs.ListXattr(fs, "/dir/renamed"))
+	report(vfs.RemoveXattr(fs, "/dir/renamed", "user.tag"))
+	_, err = vfs.GetXattr(fs, "/dir/renamed", "user.tag")
+	report(errors.Is(err, vfs.ErrNoXattr))
+	return results
+}
+
+func TestRecordReplay(t *testing.T) {
+	var trace bytes.Buffer
+	rec := vfs.Record(memfs.Create(), &trace)
+	expected := recordWorkload(rec)
+	if err := rec.Err(); err != nil {
+		t.Fatalf("Recording failed: %s", err)
+	}
+
+	fs, err := vfs.Replay(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatalf("Replay: %s", err)
+	}
+	if fs.PathSeparator() != '/' {
+		t.Errorf("Unexpected path separator %q", fs.PathSeparator())
+	}
+	if results := recordWorkload(fs); !reflect.DeepEqual(results, expected) {
+		t.Errorf("Replayed %q, recorded %q", results, expected)
+	}
+	if n := fs.Remaining(); n != 0 {
+		t.Errorf("Expected all operations replayed, %d remaining", n)
+	}
+
+	// Every operation is served once
+	if _, err := fs.Stat("/missing"); !errors.Is(err, vfs.ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, got %v", err)
+	}
+	if err := fs.Mkdir("/other", 0755); !errors.Is(err, vfs.ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, got %v", err)
+	}
+}
+
+func TestReplayMalformed(t *testing.T) {
+	if _, err := vfs.Replay(strings.NewReader(`{"op": "stat"`)); err == nil {
+		t.Errorf("Expected error for malformed trace")
+	}
+}
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+func TestRecordWriteError(t *testing.T) {
+	fs := vfs.Record(memfs.Create(), failWriter{})
+	if err := fs.Mkdir("/dir", 0755); err != nil {
+		t.Errorf("Operation failed by trace: %s", err)
+	}
+	if fs.Err() == nil {
+		t.Errorf("Expected trace error")
+	}
+}
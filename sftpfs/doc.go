@@ -0,0 +1,4 @@
+// Package sftpfs defines a filesystem accessing a remote server over SFTP.
+//
+// It uses github.com/pkg/sftp, the SSH connection and the client are managed by the caller.
+package sftpfs
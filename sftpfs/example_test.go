@@ -0,0 +1,34 @@
+package sftpfs_test
+
+import (
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/mountfs"
+	"github.com/blang/vfs/sftpfs"
+)
+
+func ExampleFS() {
+	conn, err := ssh.Dial("tcp", "example.com:22", &ssh.ClientConfig{
+		User:            "deploy",
+		Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	// Mount the remote server into a virtual tree
+	fs := mountfs.Create(memfs.Create())
+	fs.Mount(sftpfs.Create(client), "/remote")
+
+	vfs.WriteFile(fs, "/remote/tmp/deployed", []byte("v1"), 0644)
+}
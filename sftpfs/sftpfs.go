@@ -0,0 +1,199 @@
+package sftpfs
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/blang/vfs"
+)
+
+// FS represents a remote filesystem accessed over SFTP.
+type FS struct {
+	client *sftp.Client
+}
+
+// Create returns a filesystem using the given SFTP client.
+// Paths are interpreted by the server, relative paths are usually
+// relative to the home directory of the user.
+// Operations failing with a network timeout, e.g. after a deadline of the connection,
+// return errors matching vfs.ErrRemoteTimeout.
+func Create(client *sftp.Client) *FS {
+	return &FS{client: client}
+}
+
+// PathSeparator returns the path separator
+func (fs *FS) PathSeparator() uint8 {
+	return '/'
+}
+
+// OpenFile opens the named remote file.
+// If the file is created, its permissions are set to perm if the server supports it.
+// Directories can be opened for reading and are listed using ReadDir.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	created := false
+	if flag&os.O_CREATE != 0 {
+		_, err := fs.client.Lstat(name)
+		created = os.IsNotExist(err)
+	}
+	f, err := fs.client.OpenFile(name, flag)
+	if err != nil {
+		// Not every server allows to open a directory
+		if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+			if fi, serr := fs.client.Stat(name); serr == nil && fi.IsDir() {
+				return vfs.DirFile(fs, name)
+			}
+		}
+		return nil, pathError("open", name, err)
+	}
+	if created {
+		// Not every server supports setting attributes
+		f.Chmod(perm)
+	}
+	return &file{File: f, fs: fs}, nil
+}
+
+// file adds Readdir to a remote file.
+type file struct {
+	*sftp.File
+	fs  *FS
+	dir vfs.File
+}
+
+func (f *file) Readdir(n int) ([]os.FileInfo, error) {
+	if f.dir == nil {
+		d, err := vfs.DirFile(f.fs, f.Name())
+		if err != nil {
+			return nil, err
+		}
+		f.dir = d
+	}
+	return f.dir.Readdir(n)
+}
+
+// Remove removes the named file or empty directory.
+func (fs *FS) Remove(name string) error {
+	return pathError("remove", name, fs.client.Remove(name))
+}
+
+// RemoveAll removes path and any children it contains.
+func (fs *FS) RemoveAll(path string) error {
+	if _, err := fs.client.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return pathError("removeall", path, fs.client.RemoveAll(path))
+}
+
+// Rename renames a file, replacing newpath if the server supports POSIX renames.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	var err error
+	if _, ok := fs.client.HasExtension("posix-rename@openssh.com"); ok {
+		err = fs.client.PosixRename(oldpath, newpath)
+	} else {
+		err = fs.client.Rename(oldpath, newpath)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: unwrap(err)}
+	}
+	return nil
+}
+
+// Mkdir creates a directory with the given permissions if the server supports setting them.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	if _, err := fs.client.Lstat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := fs.client.Mkdir(name); err != nil {
+		return pathError("mkdir", name, err)
+	}
+	fs.client.Chmod(name, perm)
+	return nil
+}
+
+// Stat returns the FileInfo of the named remote file.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	fi, err := fs.client.Stat(name)
+	return fi, pathError("stat", name, err)
+}
+
+// Lstat returns the FileInfo of the named remote file without following symbolic links.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	fi, err := fs.client.Lstat(name)
+	return fi, pathError("lstat", name, err)
+}
+
+// ReadDir returns the entries of the named remote directory sorted by name.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	fis, err := fs.client.ReadDir(path)
+	if err != nil {
+		return nil, pathError("readdir", path, err)
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (fs *FS) Symlink(oldname, newname string) error {
+	if err := fs.client.Symlink(oldname, newname); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: unwrap(err)}
+	}
+	return nil
+}
+
+// Link creates newname as a hard link to oldname.
+func (fs *FS) Link(oldname, newname string) error {
+	if err := fs.client.Link(oldname, newname); err != nil {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: unwrap(err)}
+	}
+	return nil
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	target, err := fs.client.ReadLink(name)
+	return target, pathError("readlink", name, err)
+}
+
+// Chmod changes the mode of the named remote file.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return pathError("chmod", name, fs.client.Chmod(name, mode))
+}
+
+// Chown changes the numeric uid and gid of the named remote file.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	return pathError("chown", name, fs.client.Chown(name, uid, gid))
+}
+
+// Chtimes changes the access and modification times of the named remote file.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return pathError("chtimes", name, fs.client.Chtimes(name, atime, mtime))
+}
+
+// pathError wraps err in a *os.PathError, replacing the path reported by the client.
+func pathError(op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: name, Err: unwrap(err)}
+}
+
+// unwrap strips the path of errors returned by the client
+// and classifies network timeouts as vfs.ErrRemoteTimeout.
+func unwrap(err error) error {
+	var perr *os.PathError
+	var lerr *os.LinkError
+	if errors.As(err, &perr) {
+		err = perr.Err
+	} else if errors.As(err, &lerr) {
+		err = lerr.Err
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return vfs.WrapError(vfs.ErrRemoteTimeout, err)
+	}
+	return err
+}
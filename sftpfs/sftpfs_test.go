@@ -0,0 +1,202 @@
+package sftpfs
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/blang/vfs"
+)
+
+// testFS returns a filesystem connected to an in-memory SFTP server.
+func testFS(t *testing.T) *FS {
+	c, s := net.Pipe()
+	server := sftp.NewRequestServer(s, sftp.InMemHandler())
+	go server.Serve()
+	client, err := sftp.NewClientPipe(c, c)
+	if err != nil {
+		t.Fatalf("NewClientPipe: %s", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return Create(client)
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(&FS{})
+}
+
+func TestReadWrite(t *testing.T) {
+	fs := testFS(t)
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	b, err := vfs.ReadFile(fs, "/file")
+	if err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q, %v", b, err)
+	}
+
+	f, err := fs.OpenFile("/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	if _, err := f.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %s", err)
+	}
+	f.Write([]byte("NT"))
+	buf := make([]byte, 4)
+	if _, err := f.ReadAt(buf, 1); err != nil || string(buf) != "oNTe" {
+		t.Errorf("ReadAt: %q, %v", buf, err)
+	}
+	if err := f.Truncate(3); err != nil {
+		t.Errorf("Truncate: %s", err)
+	}
+	f.Close()
+	if fi, err := fs.Stat("/file"); err != nil || fi.Size() != 3 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+
+	if _, err := fs.OpenFile("/missing", os.O_RDONLY, 0); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	fs := testFS(t)
+	if err := fs.Mkdir("/dir", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := fs.Mkdir("/dir", 0755); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	for _, name := range []string{"/dir/b", "/dir/a"} {
+		if err := vfs.WriteFile(fs, name, []byte(name), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	fis, err := fs.ReadDir("/dir")
+	if err != nil {
+		t.Fatalf("ReadDir: %s", err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("Unexpected entries: %q", names)
+	}
+
+	d, err := vfs.Open(fs, "/dir")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if fis, err := d.Readdir(1); err != nil || len(fis) != 1 || fis[0].Name() != "a" {
+		t.Errorf("Readdir: %v, %v", fis, err)
+	}
+	d.Close()
+	f, err := vfs.Open(fs, "/dir/a")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if fi, err := f.Stat(); err != nil || fi.Size() != int64(len("/dir/a")) {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	if _, err := f.Readdir(-1); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+	f.Close()
+
+	if err := fs.Rename("/dir/a", "/dir/c"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if _, err := fs.Stat("/dir/c"); err != nil {
+		t.Errorf("Expected renamed file: %s", err)
+	}
+	if err := fs.Remove("/dir/b"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if _, err := fs.Lstat("/dir/b"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := vfs.RemoveAll(fs, "/dir"); err != nil {
+		t.Fatalf("RemoveAll: %s", err)
+	}
+	if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := vfs.RemoveAll(fs, "/dir"); err != nil {
+		t.Errorf("RemoveAll of missing path: %s", err)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	fs := testFS(t)
+	vfs.WriteFile(fs, "/file", []byte("content"), 0644)
+	if err := fs.Symlink("/file", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	if target, err := fs.Readlink("/link"); err != nil || target != "/file" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if fi, err := fs.Lstat("/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link: %v, %v", fi, err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	fs := testFS(t)
+	vfs.MkdirAll(fs, "/a/b", 0755)
+	vfs.WriteFile(fs, "/a/b/file", nil, 0644)
+
+	var paths []string
+	err := vfs.Walk(fs, "/a", func(path string, fi os.FileInfo, err error) error {
+		paths = append(paths, path)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/a", "/a/b", "/a/b/file"}) {
+		t.Errorf("Unexpected paths: %q", paths)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := pathError("stat", "/name", &os.PathError{Op: "sftp", Path: "other", Err: os.ErrNotExist})
+	var perr *os.PathError
+	if !errors.As(err, &perr) || perr.Path != "/name" || perr.Op != "stat" || !os.IsNotExist(err) {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+	if pathError("stat", "/name", nil) != nil {
+		t.Errorf("Expected nil error")
+	}
+
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	err = pathError("stat", "/name", &os.PathError{Op: "sftp", Path: "other", Err: timeout})
+	if !errors.Is(err, vfs.ErrRemoteTimeout) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected ErrRemoteTimeout, got %v", err)
+	}
+	if errors.Is(pathError("stat", "/name", os.ErrNotExist), vfs.ErrRemoteTimeout) {
+		t.Errorf("Unexpected ErrRemoteTimeout")
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	fs := testFS(t)
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := vfs.Chtimes(fs, "/file", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+	if err := vfs.Chmod(fs, "/missing", 0600); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package vfs
+
+import (
+	"os"
+)
+
+// Symlinker is implemented by filesystems supporting symbolic links.
+type Symlinker interface {
+	// Symlink creates newname as a symbolic link to oldname.
+	Symlink(oldname, newname string) error
+	// Readlink returns the destination of the named symbolic link.
+	Readlink(name string) (string, error)
+}
+
+// Symlink creates newname as a symbolic link to oldname on the given Filesystem.
+// If the Filesystem does not implement Symlinker, a *os.LinkError containing ErrUnsupported is returned.
+func Symlink(fs Filesystem, oldname, newname string) error {
+	if l, ok := fs.(Symlinker); ok {
+		return l.Symlink(oldname, newname)
+	}
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: ErrUnsupported}
+}
+
+// Readlink returns the destination of the named symbolic link on the given Filesystem.
+// If the Filesystem does not implement Symlinker, a *os.PathError containing ErrUnsupported is returned.
+func Readlink(fs Filesystem, name string) (string, error) {
+	if l, ok := fs.(Symlinker); ok {
+		return l.Readlink(name)
+	}
+	return "", &os.PathError{Op: "readlink", Path: name, Err: ErrUnsupported}
+}
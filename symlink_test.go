@@ -0,0 +1,60 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestSymlinkUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	if err := vfs.Symlink(fs, "/a", "/b"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if _, err := vfs.Readlink(fs, "/b"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestSymlinkReadOnly(t *testing.T) {
+	mfs := memfs.Create()
+	if err := vfs.Symlink(mfs, "/target", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	fs := vfs.ReadOnly(mfs)
+	if err := vfs.Symlink(fs, "/target", "/link2"); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if target, err := vfs.Readlink(fs, "/link"); err != nil || target != "/target" {
+		t.Errorf("Readlink: %q %v", target, err)
+	}
+}
+
+func TestWalkSymlink(t *testing.T) {
+	fs := walkTestFS(t)
+	if err := vfs.Symlink(fs, "/a", "/c/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	var visited []string
+	err := vfs.Walk(fs, "/c", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		if path == "/c/link" && info.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("Expected symlink fileinfo")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	if expected := []string{"/c", "/c/link"}; !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+}
@@ -0,0 +1,9 @@
+// Package syncfs compares directory trees of two filesystems and synchronizes them.
+//
+// Diff reports the changes needed to make a destination tree equal to a source tree,
+// Apply executes them. Reversing the changes synchronizes in the other direction:
+//
+//	changes, err := syncfs.Diff(backup, vfs.OS(), "/", "/home/user", nil)
+//	// Restore the deleted and modified files from the backup instead
+//	err = syncfs.Apply(vfs.OS(), backup, "/home/user", "/", changes.Reverse())
+package syncfs
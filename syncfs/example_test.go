@@ -0,0 +1,27 @@
+package syncfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/syncfs"
+)
+
+func ExampleSync() {
+	src, backup := memfs.Create(), memfs.Create()
+	vfs.WriteFile(src, "/notes.txt", []byte("hello"), 0644)
+	vfs.WriteFile(backup, "/old.txt", []byte("bye"), 0644)
+
+	// Report what a backup would change
+	changes, _ := syncfs.Sync(backup, src, "/", "/", &syncfs.Options{DryRun: true})
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+
+	// Back up for real
+	syncfs.Sync(backup, src, "/", "/", nil)
+	// Output:
+	// added notes.txt
+	// removed old.txt
+}
@@ -0,0 +1,306 @@
+package syncfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blang/vfs"
+)
+
+// Kind describes how an entry differs between the trees.
+type Kind int
+
+const (
+	// Added entries only exist in the source tree.
+	Added Kind = iota
+	// Changed entries exist in both trees with different type, content, mode or symbolic link target.
+	Changed
+	// Removed entries only exist in the destination tree.
+	Removed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Changed:
+		return "changed"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Change describes a single entry which differs between the trees.
+type Change struct {
+	// Path relative to the roots of the trees, segments are separated by '/'.
+	Path string
+	Kind Kind
+	// Src and Dst describe the entry in the source and destination tree, they are nil if it does not exist.
+	Src, Dst os.FileInfo
+}
+
+func (c Change) String() string {
+	return c.Kind.String() + " " + c.Path
+}
+
+// Changes are sorted by path, parents before their entries.
+type Changes []Change
+
+// Reverse returns the changes needed to make the source tree equal to the destination tree.
+func (c Changes) Reverse() Changes {
+	r := make(Changes, len(c))
+	for i, change := range c {
+		r[i] = Change{Path: change.Path, Kind: change.Kind, Src: change.Dst, Dst: change.Src}
+		switch change.Kind {
+		case Added:
+			r[i].Kind = Removed
+		case Removed:
+			r[i].Kind = Added
+		}
+	}
+	return r
+}
+
+// Options control how trees are compared and synchronized.
+// The zero value compares files by size, mode and modification time.
+type Options struct {
+	// Hash compares the content of regular files with equal size
+	// instead of their modification times.
+	Hash bool
+	// Filter is called with the path relative to the root of every entry of both trees,
+	// returning false ignores it. Ignoring a directory ignores all its entries.
+	Filter func(path string, fi os.FileInfo) bool
+	// DryRun makes Sync only report the changes without applying them.
+	DryRun bool
+}
+
+// Diff compares the tree srcRoot on src with the tree dstRoot on dst
+// and returns the changes needed to make the destination equal to the source.
+// The options may be nil.
+func Diff(dst, src vfs.Filesystem, dstRoot, srcRoot string, opts *Options) (Changes, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	srcTree, err := tree(src, srcRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+	dstTree, err := tree(dst, dstRoot, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes Changes
+	for path, sfi := range srcTree {
+		dfi, ok := dstTree[path]
+		if !ok {
+			changes = append(changes, Change{Path: path, Kind: Added, Src: sfi})
+			continue
+		}
+		equal, err := equal(dst, src, join(dst, dstRoot, path), join(src, srcRoot, path), dfi, sfi, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !equal {
+			changes = append(changes, Change{Path: path, Kind: Changed, Src: sfi, Dst: dfi})
+		}
+	}
+	for path, dfi := range dstTree {
+		if _, ok := srcTree[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: Removed, Dst: dfi})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// Sync makes the tree dstRoot on dst equal to the tree srcRoot on src and returns the applied changes.
+// With Options.DryRun the changes are only reported.
+// The options may be nil.
+func Sync(dst, src vfs.Filesystem, dstRoot, srcRoot string, opts *Options) (Changes, error) {
+	changes, err := Diff(dst, src, dstRoot, srcRoot, opts)
+	if err != nil || (opts != nil && opts.DryRun) {
+		return changes, err
+	}
+	return changes, Apply(dst, src, dstRoot, srcRoot, changes)
+}
+
+// Apply executes the changes reported by Diff, copying entries from srcRoot on src to dstRoot on dst.
+// Removed entries are removed first, deepest first, afterwards entries are added and changed in order.
+// Modes, extended attributes and modification times are copied if the filesystems support them.
+// Apply stops at the first error.
+func Apply(dst, src vfs.Filesystem, dstRoot, srcRoot string, changes Changes) error {
+	if err := vfs.MkdirAll(dst, dstRoot, 0755); err != nil {
+		return err
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+		if c.Kind != Removed {
+			continue
+		}
+		if err := vfs.RemoveAll(dst, join(dst, dstRoot, c.Path)); err != nil {
+			return err
+		}
+	}
+	// Directories get their attributes after all entries are applied,
+	// so they stay writable and keep their modification time
+	var dirs []string
+	for _, c := range changes {
+		if c.Kind == Removed {
+			continue
+		}
+		dstPath, srcPath := join(dst, dstRoot, c.Path), join(src, srcRoot, c.Path)
+		dir, err := apply(dst, src, dstPath, srcPath, c)
+		if err != nil {
+			return err
+		}
+		if dir {
+			dirs = append(dirs, c.Path)
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fi, err := src.Lstat(join(src, srcRoot, dirs[i]))
+		if err != nil {
+			return err
+		}
+		if err := attributes(dst, src, join(dst, dstRoot, dirs[i]), join(src, srcRoot, dirs[i]), fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply adds or changes a single entry and reports whether it is a directory.
+// The attributes of directories are not applied.
+func apply(dst, src vfs.Filesystem, dstPath, srcPath string, c Change) (bool, error) {
+	fi, err := src.Lstat(srcPath)
+	if err != nil {
+		return false, err
+	}
+	if c.Kind == Changed && !(fi.IsDir() && c.Dst != nil && c.Dst.IsDir()) {
+		if err := vfs.RemoveAll(dst, dstPath); err != nil {
+			return false, err
+		}
+	}
+	switch {
+	case fi.IsDir():
+		if c.Kind == Changed && c.Dst != nil && c.Dst.IsDir() {
+			// Only the mode of the directory differs
+			return true, nil
+		}
+		return true, dst.Mkdir(dstPath, fi.Mode().Perm()|0700)
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := vfs.Readlink(src, srcPath)
+		if err != nil {
+			return false, err
+		}
+		return false, vfs.Symlink(dst, target, dstPath)
+	}
+	if err := vfs.CopyFile(dst, src, dstPath, srcPath); err != nil {
+		return false, err
+	}
+	return false, attributes(dst, src, dstPath, srcPath, fi)
+}
+
+// attributes copies the mode, extended attributes and modification time of fi, if the Filesystems support it.
+func attributes(dst, src vfs.Filesystem, dstPath, srcPath string, fi os.FileInfo) error {
+	err := vfs.Chmod(dst, dstPath, fi.Mode())
+	if err == nil || errors.Is(err, vfs.ErrUnsupported) {
+		err = vfs.CopyXattrs(dst, src, dstPath, srcPath)
+	}
+	if err == nil || errors.Is(err, vfs.ErrUnsupported) {
+		err = vfs.Chtimes(dst, dstPath, fi.ModTime(), fi.ModTime())
+	}
+	if errors.Is(err, vfs.ErrUnsupported) {
+		return nil
+	}
+	return err
+}
+
+// tree returns the entries below root by their relative path, the root itself is not included.
+// A missing root is an empty tree.
+func tree(fs vfs.Filesystem, root string, opts *Options) (map[string]os.FileInfo, error) {
+	entries := make(map[string]os.FileInfo)
+	sep := string(fs.PathSeparator())
+	prefix := strings.TrimSuffix(root, sep) + sep
+	err := vfs.Walk(fs, root, func(path string, fi os.FileInfo, err error) error {
+		if path == root {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		rel := strings.Replace(strings.TrimPrefix(path, prefix), sep, "/", -1)
+		if opts.Filter != nil && !opts.Filter(rel, fi) {
+			if fi.IsDir() {
+				return vfs.SkipDir
+			}
+			return nil
+		}
+		entries[rel] = fi
+		return nil
+	})
+	return entries, err
+}
+
+// equal reports whether both entries are equal depending on the options.
+func equal(dst, src vfs.Filesystem, dstPath, srcPath string, dfi, sfi os.FileInfo, opts *Options) (bool, error) {
+	dmode, smode := dfi.Mode(), sfi.Mode()
+	if dmode.Type() != smode.Type() || dmode.Perm() != smode.Perm() {
+		return false, nil
+	}
+	switch {
+	case smode.IsDir():
+		return true, nil
+	case smode&os.ModeSymlink != 0:
+		dtarget, err := vfs.Readlink(dst, dstPath)
+		if err != nil {
+			return false, err
+		}
+		starget, err := vfs.Readlink(src, srcPath)
+		return dtarget == starget, err
+	}
+	if dfi.Size() != sfi.Size() {
+		return false, nil
+	}
+	if !opts.Hash {
+		return dfi.ModTime().Equal(sfi.ModTime()), nil
+	}
+	dhash, err := hash(dst, dstPath)
+	if err != nil {
+		return false, err
+	}
+	shash, err := hash(src, srcPath)
+	return bytes.Equal(dhash, shash), err
+}
+
+// hash returns the SHA-256 checksum of the content of the named file.
+func hash(fs vfs.Filesystem, name string) ([]byte, error) {
+	f, err := vfs.Open(fs, name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// join returns the path of the relative '/' separated path below root.
+func join(fs vfs.Filesystem, root, path string) string {
+	sep := string(fs.PathSeparator())
+	return strings.TrimSuffix(root, sep) + sep + strings.Replace(path, "/", sep, -1)
+}
@@ -0,0 +1,193 @@
+package syncfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+var mtime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeFiles creates the files with the given contents and a fixed modification time.
+func writeFiles(t *testing.T, fs vfs.Filesystem, files map[string]string) {
+	for name, content := range files {
+		if err := vfs.MkdirAll(fs, filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("MkdirAll: %s", err)
+		}
+		if err := vfs.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+		if err := vfs.Chtimes(fs, name, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes: %s", err)
+		}
+	}
+}
+
+func changeStrings(changes Changes) []string {
+	var s []string
+	for _, c := range changes {
+		s = append(s, c.String())
+	}
+	return s
+}
+
+func TestDiff(t *testing.T) {
+	src, dst := memfs.Create(), memfs.Create()
+	writeFiles(t, src, map[string]string{
+		"/src/same":         "same",
+		"/src/content":      "newer",
+		"/src/added":        "added",
+		"/src/dir/file":     "file",
+		"/src/newdir/file":  "file",
+		"/src/type":         "file",
+		"/src/ignored/file": "file",
+	})
+	writeFiles(t, dst, map[string]string{
+		"/dst/same":          "same",
+		"/dst/content":       "old",
+		"/dst/removed":       "removed",
+		"/dst/dir/file":      "file",
+		"/dst/olddir/file":   "file",
+		"/dst/type/file":     "file",
+		"/dst/ignored/other": "file",
+	})
+	src.Chmod("/src/dir/file", 0600)
+	src.Symlink("same", "/src/link")
+	dst.Symlink("content", "/dst/link")
+
+	opts := &Options{Filter: func(path string, fi os.FileInfo) bool {
+		return path != "ignored"
+	}}
+	changes, err := Diff(dst, src, "/dst", "/src", opts)
+	if err != nil {
+		t.Fatalf("Diff: %s", err)
+	}
+	expected := []string{
+		"added added",
+		"changed content",
+		"changed dir/file",
+		"changed link",
+		"added newdir",
+		"added newdir/file",
+		"removed olddir",
+		"removed olddir/file",
+		"removed removed",
+		"changed type",
+		"removed type/file",
+	}
+	if s := changeStrings(changes); !reflect.DeepEqual(s, expected) {
+		t.Errorf("Unexpected changes:\n%q\nexpected:\n%q", s, expected)
+	}
+	if c := changes[0]; c.Src == nil || c.Dst != nil || c.Src.Name() != "added" {
+		t.Errorf("Unexpected FileInfos: %v %v", c.Src, c.Dst)
+	}
+
+	reversed := changeStrings(changes.Reverse())
+	if reversed[0] != "removed added" || reversed[6] != "added olddir" {
+		t.Errorf("Unexpected reversed changes: %q", reversed)
+	}
+}
+
+func TestDiffHash(t *testing.T) {
+	src, dst := memfs.Create(), memfs.Create()
+	writeFiles(t, src, map[string]string{"/a": "same", "/b": "abcd"})
+	writeFiles(t, dst, map[string]string{"/a": "same", "/b": "efgh"})
+	later := mtime.Add(time.Hour)
+	src.Chtimes("/a", later, later)
+
+	changes, err := Diff(dst, src, "/", "/", nil)
+	if err != nil {
+		t.Fatalf("Diff: %s", err)
+	}
+	if s := fmt.Sprint(changeStrings(changes)); s != "[changed a]" {
+		t.Errorf("Unexpected changes by modification time: %s", s)
+	}
+	changes, err = Diff(dst, src, "/", "/", &Options{Hash: true})
+	if err != nil {
+		t.Fatalf("Diff: %s", err)
+	}
+	if s := fmt.Sprint(changeStrings(changes)); s != "[changed b]" {
+		t.Errorf("Unexpected changes by content: %s", s)
+	}
+}
+
+func TestSync(t *testing.T) {
+	src, dst := memfs.Create(), memfs.Create()
+	writeFiles(t, src, map[string]string{"/a": "a", "/dir/b": "b", "/type/c": "c"})
+	writeFiles(t, dst, map[string]string{"/backup/a": "old", "/backup/removed/d": "d", "/backup/type": "file"})
+	src.Chmod("/dir", 0500)
+	src.Symlink("a", "/link")
+
+	changes, err := Sync(dst, src, "/backup", "/", &Options{DryRun: true})
+	if err != nil || len(changes) == 0 {
+		t.Fatalf("Sync: %v %s", changes, err)
+	}
+	if b, _ := vfs.ReadFile(dst, "/backup/a"); string(b) != "old" {
+		t.Errorf("Dry run changed the destination")
+	}
+
+	if _, err := Sync(dst, src, "/backup", "/", nil); err != nil {
+		t.Fatalf("Sync: %s", err)
+	}
+	if changes, err := Diff(dst, src, "/backup", "/", &Options{Hash: true}); err != nil || len(changes) != 0 {
+		t.Errorf("Trees differ after sync: %q %v", changeStrings(changes), err)
+	}
+	if fi, err := dst.Stat("/backup/dir"); err != nil || fi.Mode().Perm() != 0500 {
+		t.Errorf("Directory mode not synced: %v %v", fi, err)
+	}
+
+	// Sync back in the other direction
+	vfs.WriteFile(dst, "/backup/new", []byte("new"), 0644)
+	dst.Remove("/backup/a")
+	changes, err = Diff(dst, src, "/backup", "/", nil)
+	if err != nil {
+		t.Fatalf("Diff: %s", err)
+	}
+	if err := Apply(src, dst, "/", "/backup", changes.Reverse()); err != nil {
+		t.Fatalf("Apply: %s", err)
+	}
+	if _, err := src.Stat("/a"); !os.IsNotExist(err) {
+		t.Errorf("Expected /a to be removed: %v", err)
+	}
+	if b, err := vfs.ReadFile(src, "/new"); err != nil || string(b) != "new" {
+		t.Errorf("Invalid file synced back: %q %v", b, err)
+	}
+}
+
+func TestSyncXattrs(t *testing.T) {
+	src, dst := memfs.Create(), memfs.Create()
+	writeFiles(t, src, map[string]string{"/a": "a"})
+	src.SetXattr("/a", "user.checksum", []byte("sum"))
+
+	if _, err := Sync(dst, src, "/backup", "/", nil); err != nil {
+		t.Fatalf("Sync: %s", err)
+	}
+	if v, err := dst.GetXattr("/backup/a", "user.checksum"); err != nil || string(v) != "sum" {
+		t.Errorf("Extended attribute not synced: %q %v", v, err)
+	}
+}
+
+func TestSyncOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncfs")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := memfs.Create()
+	writeFiles(t, src, map[string]string{"/a": "a", "/dir/b": "b"})
+	root := filepath.Join(dir, "root")
+	if _, err := Sync(vfs.OS(), src, root, "/", nil); err != nil {
+		t.Fatalf("Sync: %s", err)
+	}
+	if changes, err := Diff(vfs.OS(), src, root, "/", nil); err != nil || len(changes) != 0 {
+		t.Errorf("Trees differ after sync: %q %v", changeStrings(changes), err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package tarfs defines a read-only filesystem backed by a tar archive.
+//
+// The archive is indexed once on creation, file contents are read
+// from the underlying io.ReaderAt on demand. Gzip-compressed archives
+// are decompressed into memory.
+package tarfs
@@ -0,0 +1,28 @@
+package tarfs_test
+
+import (
+	"io/ioutil"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/mountfs"
+	"github.com/blang/vfs/tarfs"
+)
+
+func ExampleFS() {
+	// Load an archive, e.g. embedded into the binary
+	b, err := ioutil.ReadFile("assets.tar.gz")
+	if err != nil {
+		return
+	}
+	assets, err := tarfs.FromBytes(b)
+	if err != nil {
+		return
+	}
+
+	// Mount the archive into a larger virtual tree
+	fs := mountfs.Create(memfs.Create())
+	fs.Mount(assets, "/assets")
+
+	vfs.ReadFile(fs, "/assets/index.html")
+}
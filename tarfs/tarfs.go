@@ -0,0 +1,311 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	filepath "path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/blang/vfs"
+)
+
+// ErrReadOnly is returned by every operation modifying the archive.
+var ErrReadOnly = vfs.ErrReadOnly
+
+// maxLinkDepth limits the number of symbolic links followed resolving a path.
+const maxLinkDepth = 40
+
+// FS represents a read-only filesystem backed by a tar archive.
+type FS struct {
+	r     io.ReaderAt
+	nodes map[string]*node
+}
+
+// node is a file, directory or symbolic link of the archive.
+type node struct {
+	hdr    *tar.Header
+	offset int64
+	childs []string
+}
+
+// Create indexes the tar archive of the given size read from r.
+// Gzip-compressed archives are detected and decompressed into memory.
+func Create(r io.ReaderAt, size int64) (*FS, error) {
+	magic := make([]byte, 2)
+	if n, _ := r.ReadAt(magic, 0); n == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+		r, size = bytes.NewReader(b), int64(len(b))
+	}
+
+	fs := &FS{
+		r: r,
+		nodes: map[string]*node{
+			"/": {hdr: dirHeader("/")},
+		},
+	}
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	var links []*node
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		offset, _ := sr.Seek(0, io.SeekCurrent)
+		name := clean(hdr.Name)
+		if name == "/" {
+			continue
+		}
+		n := &node{hdr: hdr, offset: offset}
+		if old, ok := fs.nodes[name]; ok {
+			// Later entries replace earlier ones, a directory keeps its children
+			if old.hdr.Typeflag == tar.TypeDir && hdr.Typeflag == tar.TypeDir {
+				n.childs = old.childs
+			}
+		} else {
+			fs.parent(name).childs = append(fs.parent(name).childs, filepath.Base(name))
+		}
+		fs.nodes[name] = n
+		if hdr.Typeflag == tar.TypeLink {
+			links = append(links, n)
+		}
+	}
+
+	// Hard links share the content of their target
+	for _, n := range links {
+		target, ok := fs.nodes[clean(n.hdr.Linkname)]
+		if !ok || target.hdr.Typeflag == tar.TypeLink {
+			continue
+		}
+		hdr := *n.hdr
+		hdr.Typeflag = target.hdr.Typeflag
+		hdr.Size = target.hdr.Size
+		n.hdr, n.offset = &hdr, target.offset
+	}
+	for _, n := range fs.nodes {
+		sort.Strings(n.childs)
+	}
+	return fs, nil
+}
+
+// FromBytes indexes the tar archive in b, which may be gzip-compressed.
+func FromBytes(b []byte) (*FS, error) {
+	return Create(bytes.NewReader(b), int64(len(b)))
+}
+
+func clean(name string) string {
+	return filepath.Clean("/" + name)
+}
+
+func dirHeader(name string) *tar.Header {
+	return &tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755}
+}
+
+// parent returns the parent directory of name, creating missing directories.
+func (fs *FS) parent(name string) *node {
+	dir := filepath.Dir(name)
+	if n, ok := fs.nodes[dir]; ok {
+		return n
+	}
+	n := &node{hdr: dirHeader(dir)}
+	p := fs.parent(dir)
+	p.childs = append(p.childs, filepath.Base(dir))
+	fs.nodes[dir] = n
+	return n
+}
+
+// lookup returns the node of name, following symbolic links if follow is set.
+// Symbolic links of parent directories are always followed.
+func (fs *FS) lookup(name string, follow bool) (string, *node, error) {
+	return fs.resolve(clean(name), follow, 0)
+}
+
+func (fs *FS) resolve(name string, follow bool, depth int) (string, *node, error) {
+	if n, ok := fs.nodes[name]; ok {
+		if !follow || n.hdr.Typeflag != tar.TypeSymlink {
+			return name, n, nil
+		}
+		if depth == maxLinkDepth {
+			return "", nil, syscall.ELOOP
+		}
+		return fs.resolve(fs.linkPath(name, n), true, depth+1)
+	}
+	if name == "/" {
+		return "", nil, os.ErrNotExist
+	}
+	// The path might contain a symbolic link as parent
+	dir, n, err := fs.resolve(filepath.Dir(name), true, depth)
+	if err != nil {
+		return "", nil, err
+	}
+	if n.hdr.Typeflag != tar.TypeDir {
+		return "", nil, vfs.ErrNotDirectory
+	}
+	target := filepath.Join(dir, filepath.Base(name))
+	if _, ok := fs.nodes[target]; !ok {
+		return "", nil, os.ErrNotExist
+	}
+	return fs.resolve(target, follow, depth+1)
+}
+
+// linkPath returns the absolute target of a symbolic link.
+func (fs *FS) linkPath(name string, n *node) string {
+	if strings.HasPrefix(n.hdr.Linkname, "/") {
+		return clean(n.hdr.Linkname)
+	}
+	return clean(filepath.Join(filepath.Dir(name), n.hdr.Linkname))
+}
+
+// PathSeparator returns the path separator
+func (fs *FS) PathSeparator() uint8 {
+	return '/'
+}
+
+// OpenFile opens the named file for reading.
+// Flags requesting write access return ErrReadOnly.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: ErrReadOnly}
+	}
+	_, n, err := fs.lookup(name, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if n.hdr.Typeflag == tar.TypeDir {
+		return vfs.DirFile(fs, name)
+	}
+	return &file{
+		SectionReader: io.NewSectionReader(fs.r, n.offset, n.hdr.Size),
+		name:          name,
+		info:          fileInfo{n.hdr.FileInfo(), filepath.Base(clean(name))},
+	}, nil
+}
+
+// Remove is disabled and returns ErrReadOnly
+func (fs *FS) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: ErrReadOnly}
+}
+
+// Rename is disabled and returns ErrReadOnly
+func (fs *FS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrReadOnly}
+}
+
+// Mkdir is disabled and returns ErrReadOnly
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: ErrReadOnly}
+}
+
+// Symlink is disabled and returns ErrReadOnly
+func (fs *FS) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: ErrReadOnly}
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	_, n, err := fs.lookup(name, false)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if n.hdr.Typeflag != tar.TypeSymlink {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return n.hdr.Linkname, nil
+}
+
+// Stat returns the FileInfo of the named file, following symbolic links.
+// The FileInfo's Sys() returns the *tar.Header of the entry.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	_, n, err := fs.lookup(name, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{n.hdr.FileInfo(), filepath.Base(clean(name))}, nil
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	_, n, err := fs.lookup(name, false)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return fileInfo{n.hdr.FileInfo(), filepath.Base(clean(name))}, nil
+}
+
+// ReadDir returns the entries of the named directory sorted by name.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	dir, n, err := fs.lookup(path, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	if n.hdr.Typeflag != tar.TypeDir {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: vfs.ErrNotDirectory}
+	}
+	fis := make([]os.FileInfo, 0, len(n.childs))
+	for _, name := range n.childs {
+		child := fs.nodes[filepath.Join(dir, name)]
+		fis = append(fis, fileInfo{child.hdr.FileInfo(), name})
+	}
+	return fis, nil
+}
+
+// fileInfo overrides the name of a header's FileInfo with the name used to access it.
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi fileInfo) Name() string {
+	return fi.name
+}
+
+// file is an open file of the archive.
+type file struct {
+	*io.SectionReader
+	name string
+	info os.FileInfo
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Readdir(int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: vfs.ErrNotDirectory}
+}
+
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Truncate(int64) error {
+	return ErrReadOnly
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (f *file) Close() error {
+	return nil
+}
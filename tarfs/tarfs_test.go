@@ -0,0 +1,232 @@
+package tarfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+var modTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// archive returns a tar archive of the given headers,
+// regular files contain their name as content.
+func archive(t *testing.T, compress bool, hdrs ...*tar.Header) []byte {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	for _, hdr := range hdrs {
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(hdr.Name))
+		}
+		hdr.ModTime = modTime
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			tw.Write([]byte(hdr.Name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zw != nil {
+		zw.Close()
+	}
+	return buf.Bytes()
+}
+
+func testArchive(t *testing.T, compress bool) *FS {
+	b := archive(t, compress,
+		&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0700},
+		&tar.Header{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0644},
+		&tar.Header{Name: "implicit/sub/file", Typeflag: tar.TypeReg, Mode: 0600},
+		&tar.Header{Name: "./top", Typeflag: tar.TypeReg, Mode: 0644},
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "dir"},
+		&tar.Header{Name: "dir/rel", Typeflag: tar.TypeSymlink, Linkname: "../top"},
+		&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "dir/file"},
+	)
+	fs, err := FromBytes(b)
+	if err != nil {
+		t.Fatalf("FromBytes: %s", err)
+	}
+	return fs
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(&FS{})
+}
+
+func TestReadDir(t *testing.T) {
+	fs := testArchive(t, false)
+	tests := map[string][]string{
+		"/":             {"dir", "hard", "implicit", "link", "top"},
+		"/dir":          {"file", "rel"},
+		"/implicit":     {"sub"},
+		"implicit/sub/": {"file"},
+		"/link":         {"file", "rel"},
+	}
+	for path, expected := range tests {
+		fis, err := fs.ReadDir(path)
+		if err != nil {
+			t.Errorf("ReadDir %s: %s", path, err)
+			continue
+		}
+		var names []string
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("ReadDir %s: Expected %q, got %q", path, expected, names)
+		}
+	}
+	if _, err := fs.ReadDir("/top"); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+	if _, err := fs.ReadDir("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestStat(t *testing.T) {
+	fs := testArchive(t, false)
+
+	fi, err := fs.Stat("/dir/file")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "file" || fi.Size() != 8 || fi.Mode() != 0644 || !fi.ModTime().Equal(modTime) {
+		t.Errorf("Unexpected FileInfo: %s %d %s %s", fi.Name(), fi.Size(), fi.Mode(), fi.ModTime())
+	}
+	if _, ok := fi.Sys().(*tar.Header); !ok {
+		t.Errorf("Expected *tar.Header, got %T", fi.Sys())
+	}
+	if fi, err := fs.Stat("/implicit"); err != nil || !fi.IsDir() {
+		t.Errorf("Expected implicit directory: %v, %v", fi, err)
+	}
+	if fi, err := fs.Stat("/link"); err != nil || !fi.IsDir() || fi.Name() != "link" {
+		t.Errorf("Expected followed link: %v, %v", fi, err)
+	}
+	if fi, err := fs.Lstat("/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link: %v, %v", fi, err)
+	}
+	if target, err := fs.Readlink("/dir/rel"); err != nil || target != "../top" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if _, err := fs.Readlink("/top"); err == nil {
+		t.Errorf("Expected error reading non-link")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		fs := testArchive(t, compress)
+		tests := map[string]string{
+			"/dir/file":          "dir/file",
+			"/implicit/sub/file": "implicit/sub/file",
+			"/link/file":         "dir/file",
+			"/link/rel":          "./top",
+			"/hard":              "dir/file",
+		}
+		for path, expected := range tests {
+			b, err := vfs.ReadFile(fs, path)
+			if err != nil {
+				t.Errorf("ReadFile %s: %s", path, err)
+			} else if string(b) != expected {
+				t.Errorf("ReadFile %s: Expected %q, got %q", path, expected, b)
+			}
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	fs := testArchive(t, false)
+	f, err := fs.OpenFile("/implicit/sub/file", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+
+	if f.Name() != "/implicit/sub/file" {
+		t.Errorf("Unexpected name: %s", f.Name())
+	}
+	if fi, err := f.Stat(); err != nil || fi.Name() != "file" || fi.Size() != 17 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	buf := make([]byte, 3)
+	if _, err := f.ReadAt(buf, 9); err != nil || string(buf) != "sub" {
+		t.Errorf("ReadAt: %q, %v", buf, err)
+	}
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatalf("Seek: %s", err)
+	}
+	if b, err := ioutil.ReadAll(f); err != nil || string(b) != "file" {
+		t.Errorf("ReadAll: %q, %v", b, err)
+	}
+	if _, err := f.Write([]byte("x")); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	fs := testArchive(t, false)
+	if _, err := fs.OpenFile("/top", os.O_RDWR, 0); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := fs.OpenFile("/new", os.O_CREATE, 0644); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := fs.Remove("/top"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := fs.Rename("/top", "/moved"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := fs.Mkdir("/new", 0755); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	d, err := fs.OpenFile("/dir", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile /dir: %s", err)
+	}
+	if _, err := d.Read(make([]byte, 1)); !errors.Is(err, vfs.ErrIsDirectory) {
+		t.Errorf("Expected ErrIsDirectory, got %v", err)
+	}
+	if fis, err := d.Readdir(-1); err != nil || len(fis) == 0 {
+		t.Errorf("Readdir: %v, %v", fis, err)
+	}
+}
+
+func TestSymlinkLoop(t *testing.T) {
+	fs, err := FromBytes(archive(t, false,
+		&tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "b"},
+		&tar.Header{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a"},
+	))
+	if err != nil {
+		t.Fatalf("FromBytes: %s", err)
+	}
+	if _, err := fs.Stat("/a"); err == nil {
+		t.Errorf("Expected error following link loop")
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	b := archive(t, false, &tar.Header{Name: "file", Typeflag: tar.TypeReg})
+	b[148] = 'x' // checksum
+	if _, err := FromBytes(b); err == nil {
+		t.Errorf("Expected error on corrupt archive")
+	}
+}
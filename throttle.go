@@ -0,0 +1,232 @@
+package vfs
+
+import (
+	mrand "math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// Throttle creates a wrapper around the given filesystem which simulates a slow disk.
+// Every operation, including those on opened files, is delayed by opLatency.
+// Reads and writes of all files share a throughput of bytesPerSec, they are delayed
+// until the transfer fits into the rate. Zero disables the latency or the rate limit.
+// Set Jitter to add a random delay to every operation.
+func Throttle(fs Filesystem, bytesPerSec int64, opLatency time.Duration) *ThrottleFS {
+	return &ThrottleFS{Filesystem: fs, bytesPerSec: bytesPerSec, latency: opLatency}
+}
+
+// ThrottleFS represents a filesystem with limited throughput and added latency
+// and works as a wrapper around existing filesystems.
+type ThrottleFS struct {
+	Filesystem
+	// Jitter is the maximum random delay added to the latency of every operation.
+	Jitter time.Duration
+
+	bytesPerSec int64
+	latency     time.Duration
+
+	mutex sync.Mutex
+	// next is the time at which the transfers reserved so far are complete
+	next time.Time
+}
+
+// wait delays an operation by the latency and jitter.
+func (fs *ThrottleFS) wait() {
+	d := fs.latency
+	if fs.Jitter > 0 {
+		d += time.Duration(mrand.Int63n(int64(fs.Jitter)))
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// transfer delays a read or write of n bytes until it fits into the rate.
+func (fs *ThrottleFS) transfer(n int) {
+	if fs.bytesPerSec <= 0 || n <= 0 {
+		return
+	}
+	d := time.Duration(int64(n) * int64(time.Second) / fs.bytesPerSec)
+	fs.mutex.Lock()
+	now := time.Now()
+	if fs.next.Before(now) {
+		fs.next = now
+	}
+	fs.next = fs.next.Add(d)
+	delay := fs.next.Sub(now)
+	fs.mutex.Unlock()
+	time.Sleep(delay)
+}
+
+// OpenFile opens the named file after the latency, the returned file is throttled as well.
+func (fs *ThrottleFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	fs.wait()
+	f, err := fs.Filesystem.OpenFile(name, flag, perm)
+	if err != nil {
+		return f, err
+	}
+	return &throttleFile{File: f, fs: fs}, nil
+}
+
+// Remove removes the named file or directory after the latency.
+func (fs *ThrottleFS) Remove(name string) error {
+	fs.wait()
+	return fs.Filesystem.Remove(name)
+}
+
+// Rename renames a file after the latency.
+func (fs *ThrottleFS) Rename(oldpath, newpath string) error {
+	fs.wait()
+	return fs.Filesystem.Rename(oldpath, newpath)
+}
+
+// Mkdir creates a directory after the latency.
+func (fs *ThrottleFS) Mkdir(name string, perm os.FileMode) error {
+	fs.wait()
+	return fs.Filesystem.Mkdir(name, perm)
+}
+
+// Stat returns the FileInfo of the named file after the latency.
+func (fs *ThrottleFS) Stat(name string) (os.FileInfo, error) {
+	fs.wait()
+	return fs.Filesystem.Stat(name)
+}
+
+// Lstat returns the FileInfo of the named file without following symbolic links after the latency.
+func (fs *ThrottleFS) Lstat(name string) (os.FileInfo, error) {
+	fs.wait()
+	return fs.Filesystem.Lstat(name)
+}
+
+// ReadDir reads the named directory after the latency.
+func (fs *ThrottleFS) ReadDir(path string) ([]os.FileInfo, error) {
+	fs.wait()
+	return fs.Filesystem.ReadDir(path)
+}
+
+// Symlink creates newname as a symbolic link to oldname after the latency.
+func (fs *ThrottleFS) Symlink(oldname, newname string) error {
+	fs.wait()
+	return Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Link creates newname as a hard link to oldname after the latency.
+func (fs *ThrottleFS) Link(oldname, newname string) error {
+	fs.wait()
+	return Link(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link after the latency.
+func (fs *ThrottleFS) Readlink(name string) (string, error) {
+	fs.wait()
+	return Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file after the latency.
+func (fs *ThrottleFS) Chmod(name string, mode os.FileMode) error {
+	fs.wait()
+	return Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file after the latency.
+func (fs *ThrottleFS) Chown(name string, uid, gid int) error {
+	fs.wait()
+	return Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file after the latency.
+func (fs *ThrottleFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	fs.wait()
+	return Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file after the latency.
+func (fs *ThrottleFS) GetXattr(name, attr string) ([]byte, error) {
+	fs.wait()
+	return GetXattr(fs.Filesystem, name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file after the latency.
+func (fs *ThrottleFS) SetXattr(name, attr string, value []byte) error {
+	fs.wait()
+	return SetXattr(fs.Filesystem, name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file after the latency.
+func (fs *ThrottleFS) ListXattr(name string) ([]string, error) {
+	fs.wait()
+	return ListXattr(fs.Filesystem, name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file after the latency.
+func (fs *ThrottleFS) RemoveXattr(name, attr string) error {
+	fs.wait()
+	return RemoveXattr(fs.Filesystem, name, attr)
+}
+
+// Watch reports changes of the named file.
+func (fs *ThrottleFS) Watch(name string) (<-chan Event, error) {
+	return Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *ThrottleFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
+
+// throttleFile delays the operations on an open file.
+type throttleFile struct {
+	File
+	fs *ThrottleFS
+}
+
+func (f *throttleFile) Read(p []byte) (int, error) {
+	f.fs.wait()
+	n, err := f.File.Read(p)
+	f.fs.transfer(n)
+	return n, err
+}
+
+func (f *throttleFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.wait()
+	n, err := f.File.ReadAt(p, off)
+	f.fs.transfer(n)
+	return n, err
+}
+
+func (f *throttleFile) Write(p []byte) (int, error) {
+	f.fs.wait()
+	f.fs.transfer(len(p))
+	return f.File.Write(p)
+}
+
+func (f *throttleFile) Seek(offset int64, whence int) (int64, error) {
+	f.fs.wait()
+	return f.File.Seek(offset, whence)
+}
+
+func (f *throttleFile) Truncate(size int64) error {
+	f.fs.wait()
+	return f.File.Truncate(size)
+}
+
+func (f *throttleFile) Sync() error {
+	f.fs.wait()
+	return f.File.Sync()
+}
+
+func (f *throttleFile) Stat() (os.FileInfo, error) {
+	f.fs.wait()
+	return f.File.Stat()
+}
+
+func (f *throttleFile) Readdir(n int) ([]os.FileInfo, error) {
+	f.fs.wait()
+	return f.File.Readdir(n)
+}
+
+func (f *throttleFile) Close() error {
+	f.fs.wait()
+	return f.File.Close()
+}
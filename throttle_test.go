@@ -0,0 +1,66 @@
+package vfs_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestThrottleInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.Throttle(vfs.OS(), 0, 0))
+	_ = vfs.Symlinker(vfs.Throttle(vfs.OS(), 0, 0))
+	_ = vfs.Linker(vfs.Throttle(vfs.OS(), 0, 0))
+	_ = vfs.Attributer(vfs.Throttle(vfs.OS(), 0, 0))
+	_ = vfs.Watcher(vfs.Throttle(vfs.OS(), 0, 0))
+}
+
+func TestThrottleConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return vfs.Throttle(memfs.Create(), 0, 0)
+	})
+}
+
+func TestThrottleLatency(t *testing.T) {
+	fs := vfs.Throttle(memfs.Create(), 0, 10*time.Millisecond)
+	fs.Jitter = 5 * time.Millisecond
+
+	start := time.Now()
+	fs.Mkdir("/dir", 0755)
+	fs.Stat("/dir")
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("Expected at least 20ms for 2 operations, took %s", d)
+	}
+}
+
+func TestThrottleRate(t *testing.T) {
+	fs := vfs.Throttle(memfs.Create(), 10000, 0)
+
+	// Concurrent transfers share the rate
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, name := range []string{"/a", "/b"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := vfs.WriteFile(fs, name, make([]byte, 500), 0644); err != nil {
+				t.Errorf("WriteFile: %s", err)
+			}
+		}(name)
+	}
+	wg.Wait()
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("Expected at least 100ms writing 1000 bytes, took %s", d)
+	}
+
+	start = time.Now()
+	if b, err := vfs.ReadFile(fs, "/a"); err != nil || len(b) != 500 {
+		t.Fatalf("ReadFile: %d %v", len(b), err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms reading 500 bytes, took %s", d)
+	}
+}
@@ -0,0 +1,265 @@
+package vfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Tree writes the tree below root on the given Filesystem to w, one entry per line with
+// its mode and size, sorted by name. Symbolic links are not followed but show their target.
+// The size of directories is omitted, as it differs between filesystems.
+//
+//	drwxr-xr-x        - /
+//	-rw-r--r--        7 ├── a.txt
+//	drwxr-xr-x        - └── dir
+//	Lrwxrwxrwx        5     └── link -> a.txt
+func Tree(fs Filesystem, root string, w io.Writer) error {
+	fi, err := fs.Lstat(root)
+	if err != nil {
+		return err
+	}
+	return tree(fs, root, root, fi, "", "", w)
+}
+
+// tree writes the entry path and all entries below it, name is shown after the prefix.
+// Entries of a directory are indented by indent.
+func tree(fs Filesystem, path, name string, fi os.FileInfo, prefix, indent string, w io.Writer) error {
+	size := "-"
+	if !fi.IsDir() {
+		size = fmt.Sprint(fi.Size())
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := Readlink(fs, path)
+		if err != nil {
+			return err
+		}
+		name += " -> " + target
+	}
+	if _, err := fmt.Fprintf(w, "%s %8s %s%s\n", fi.Mode(), size, prefix, name); err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+	fis, err := fs.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	for i, child := range fis {
+		branch, next := "├── ", "│   "
+		if i == len(fis)-1 {
+			branch, next = "└── ", "    "
+		}
+		if err := tree(fs, JoinPath(fs, path, child.Name()), child.Name(), child, indent+branch, indent+next, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MismatchKind describes how an entry differs between two trees.
+type MismatchKind int
+
+const (
+	// OnlyInA entries do not exist in the second tree, their entries are not reported.
+	OnlyInA MismatchKind = iota
+	// OnlyInB entries do not exist in the first tree, their entries are not reported.
+	OnlyInB
+	// TypeDiffers entries are of a different type, e.g. a file and a directory.
+	TypeDiffers
+	// ModeDiffers entries have different permission bits.
+	ModeDiffers
+	// ContentDiffers files have a different size or content.
+	ContentDiffers
+	// TargetDiffers symbolic links point to different targets.
+	TargetDiffers
+)
+
+func (k MismatchKind) String() string {
+	switch k {
+	case OnlyInA:
+		return "only in a"
+	case OnlyInB:
+		return "only in b"
+	case TypeDiffers:
+		return "type differs"
+	case ModeDiffers:
+		return "mode differs"
+	case ContentDiffers:
+		return "content differs"
+	case TargetDiffers:
+		return "target differs"
+	}
+	return "unknown"
+}
+
+// Mismatch describes a single difference of an entry between two trees.
+type Mismatch struct {
+	// Path relative to the root, segments are separated by '/'. The root itself is ".".
+	Path string
+	Kind MismatchKind
+	// A and B describe the entry in both trees, they are nil if it does not exist.
+	A, B os.FileInfo
+}
+
+func (m Mismatch) String() string {
+	return m.Path + ": " + m.Kind.String()
+}
+
+// Mismatches are sorted by path, parents before their entries.
+type Mismatches []Mismatch
+
+// String returns one mismatch per line, e.g. for test failures.
+func (m Mismatches) String() string {
+	lines := make([]string, len(m))
+	for i, mismatch := range m {
+		lines[i] = mismatch.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Equal deeply compares the trees below root on both Filesystems and returns their differences,
+// none if the trees are equal. Entries are compared by type, permission bits, content of files
+// and targets of symbolic links, which are not followed. Modification times and owners are ignored.
+// Both roots missing is reported as the error of the first Filesystem.
+func Equal(a, b Filesystem, root string) (Mismatches, error) {
+	afi, aerr := a.Lstat(root)
+	if aerr != nil && !os.IsNotExist(aerr) {
+		return nil, aerr
+	}
+	bfi, berr := b.Lstat(root)
+	if berr != nil && !os.IsNotExist(berr) {
+		return nil, berr
+	}
+	if aerr != nil && berr != nil {
+		return nil, aerr
+	}
+	var m Mismatches
+	err := equalEntry(a, b, root, root, ".", afi, bfi, &m)
+	return m, err
+}
+
+// equalEntry appends the differences of the entry rel and all entries below it to m.
+func equalEntry(a, b Filesystem, apath, bpath, rel string, afi, bfi os.FileInfo, m *Mismatches) error {
+	switch {
+	case bfi == nil:
+		*m = append(*m, Mismatch{Path: rel, Kind: OnlyInA, A: afi})
+		return nil
+	case afi == nil:
+		*m = append(*m, Mismatch{Path: rel, Kind: OnlyInB, B: bfi})
+		return nil
+	}
+	amode, bmode := afi.Mode(), bfi.Mode()
+	if amode.Type() != bmode.Type() {
+		*m = append(*m, Mismatch{Path: rel, Kind: TypeDiffers, A: afi, B: bfi})
+		return nil
+	}
+	if amode.Perm() != bmode.Perm() {
+		*m = append(*m, Mismatch{Path: rel, Kind: ModeDiffers, A: afi, B: bfi})
+	}
+	switch {
+	case amode.IsDir():
+		return equalDir(a, b, apath, bpath, rel, m)
+	case amode&os.ModeSymlink != 0:
+		atarget, err := Readlink(a, apath)
+		if err != nil {
+			return err
+		}
+		btarget, err := Readlink(b, bpath)
+		if err != nil {
+			return err
+		}
+		if atarget != btarget {
+			*m = append(*m, Mismatch{Path: rel, Kind: TargetDiffers, A: afi, B: bfi})
+		}
+	case amode.IsRegular():
+		same, err := sameContent(a, b, apath, bpath, afi, bfi)
+		if err != nil {
+			return err
+		}
+		if !same {
+			*m = append(*m, Mismatch{Path: rel, Kind: ContentDiffers, A: afi, B: bfi})
+		}
+	}
+	return nil
+}
+
+// equalDir compares the entries of both directories by name.
+func equalDir(a, b Filesystem, apath, bpath, rel string, m *Mismatches) error {
+	afis, err := a.ReadDir(apath)
+	if err != nil {
+		return err
+	}
+	bfis, err := b.ReadDir(bpath)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string][2]os.FileInfo)
+	for _, fi := range afis {
+		e := entries[fi.Name()]
+		e[0] = fi
+		entries[fi.Name()] = e
+	}
+	for _, fi := range bfis {
+		e := entries[fi.Name()]
+		e[1] = fi
+		entries[fi.Name()] = e
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := name
+		if rel != "." {
+			child = rel + "/" + name
+		}
+		e := entries[name]
+		if err := equalEntry(a, b, JoinPath(a, apath, name), JoinPath(b, bpath, name), child, e[0], e[1], m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameContent reports whether both files have the same content.
+func sameContent(a, b Filesystem, apath, bpath string, afi, bfi os.FileInfo) (bool, error) {
+	if afi.Size() != bfi.Size() {
+		return false, nil
+	}
+	af, err := Open(a, apath)
+	if err != nil {
+		return false, err
+	}
+	defer af.Close()
+	bf, err := Open(b, bpath)
+	if err != nil {
+		return false, err
+	}
+	defer bf.Close()
+
+	abuf, bbuf := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		an, aerr := io.ReadFull(af, abuf)
+		bn, berr := io.ReadFull(bf, bbuf)
+		if !bytes.Equal(abuf[:an], bbuf[:bn]) {
+			return false, nil
+		}
+		aeof := aerr == io.EOF || aerr == io.ErrUnexpectedEOF
+		beof := berr == io.EOF || berr == io.ErrUnexpectedEOF
+		switch {
+		case aerr != nil && !aeof:
+			return false, aerr
+		case berr != nil && !beof:
+			return false, berr
+		case aeof || beof:
+			return aeof == beof, nil
+		}
+	}
+}
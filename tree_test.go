@@ -0,0 +1,119 @@
+package vfs_test
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+// treeFixture creates a small tree used by the tests.
+func treeFixture(t *testing.T) *memfs.MemFS {
+	fs := memfs.Create()
+	if err := vfs.MkdirAll(fs, "/dir/sub", 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	for name, content := range map[string]string{"/a.txt": "content", "/dir/b": "b", "/dir/sub/c": ""} {
+		if err := vfs.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	if err := fs.Symlink("a.txt", "/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	return fs
+}
+
+func TestTree(t *testing.T) {
+	fs := treeFixture(t)
+	var buf bytes.Buffer
+	if err := vfs.Tree(fs, "/", &buf); err != nil {
+		t.Fatalf("Tree: %s", err)
+	}
+	expected := `drwxr-xr-x        - /
+-rw-r--r--        7 ├── a.txt
+drwxr-xr-x        - ├── dir
+-rw-r--r--        1 │   ├── b
+drwxr-xr-x        - │   └── sub
+-rw-r--r--        0 │       └── c
+Lrwxrwxrwx        5 └── link -> a.txt
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected tree:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := vfs.Tree(fs, "/dir/sub", &buf); err != nil {
+		t.Fatalf("Tree: %s", err)
+	}
+	if expected := "drwxr-xr-x        - /dir/sub\n-rw-r--r--        0 └── c\n"; buf.String() != expected {
+		t.Errorf("Unexpected tree:\n%s", buf.String())
+	}
+
+	if err := vfs.Tree(fs, "/missing", &buf); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a, b := treeFixture(t), treeFixture(t)
+	if m, err := vfs.Equal(a, b, "/"); err != nil || len(m) != 0 {
+		t.Errorf("Expected equal trees: %s %v", m, err)
+	}
+
+	vfs.WriteFile(b, "/a.txt", []byte("CONTENT"), 0644)
+	b.Chmod("/dir", 0700)
+	b.RemoveAll("/dir/sub")
+	vfs.WriteFile(b, "/dir/new", nil, 0644)
+	b.Remove("/link")
+	b.Symlink("dir/b", "/link")
+	a.Mkdir("/type", 0755)
+	vfs.WriteFile(b, "/type", nil, 0644)
+
+	m, err := vfs.Equal(a, b, "/")
+	if err != nil {
+		t.Fatalf("Equal: %s", err)
+	}
+	expected := []string{
+		"a.txt: content differs",
+		"dir: mode differs",
+		"dir/new: only in b",
+		"dir/sub: only in a",
+		"link: target differs",
+		"type: type differs",
+	}
+	var actual []string
+	for _, mismatch := range m {
+		actual = append(actual, mismatch.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected mismatches:\n%s", m)
+	}
+	if m[2].A != nil || m[2].B == nil || m[2].B.Name() != "new" {
+		t.Errorf("Unexpected infos of %s: %v %v", m[2].Path, m[2].A, m[2].B)
+	}
+
+	if m, err := vfs.Equal(a, b, "/dir/b"); err != nil || len(m) != 0 {
+		t.Errorf("Expected equal files: %s %v", m, err)
+	}
+	if m, err := vfs.Equal(a, b, "/dir/sub"); err != nil || m.String() != ".: only in a" {
+		t.Errorf("Unexpected mismatches: %s %v", m, err)
+	}
+	if _, err := vfs.Equal(a, b, "/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestEqualLargeContent(t *testing.T) {
+	a, b := memfs.Create(), memfs.Create()
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	vfs.WriteFile(a, "/file", content, 0644)
+	content[len(content)-1] = 'x'
+	vfs.WriteFile(b, "/file", content, 0644)
+	if m, err := vfs.Equal(a, b, "/file"); err != nil || m.String() != ".: content differs" {
+		t.Errorf("Unexpected mismatches: %s %v", m, err)
+	}
+}
@@ -0,0 +1,778 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTxDone is returned by every operation on a transaction which has already been committed or rolled back.
+var ErrTxDone = errors.New("Transaction has already been committed or rolled back")
+
+// Begin starts a transaction on the given filesystem.
+// The returned Tx is a Filesystem showing the wrapped filesystem with the changes
+// of the transaction applied. Creates, writes, renames and removes are buffered
+// in memory and applied to the wrapped filesystem on Commit, Rollback discards them.
+// Begin fails with ErrReadOnly if fs is a read-only wrapper without writable paths.
+//
+// Only absolute paths are supported, relative paths are rejected with os.ErrInvalid.
+// Changes made to the wrapped filesystem outside of the transaction are visible to it
+// and may let Commit fail, they are not isolated.
+func Begin(fs Filesystem) (*Tx, error) {
+	if ro, ok := fs.(*RoFS); ok && len(ro.writable) == 0 {
+		return nil, ErrReadOnly
+	}
+	return &Tx{fs: fs, nodes: make(map[string]*txNode)}, nil
+}
+
+// Tx represents a transaction on a filesystem created by Begin.
+type Tx struct {
+	fs    Filesystem
+	mutex sync.Mutex
+	// nodes holds the paths changed by the transaction, removed paths hide the wrapped filesystem
+	nodes map[string]*txNode
+	ops   []txOp
+	done  bool
+}
+
+// txNode is the state of a path changed by the transaction.
+type txNode struct {
+	removed bool
+	dir     bool
+	mode    os.FileMode
+	modTime time.Time
+	data    []byte
+	// base is the path on the wrapped filesystem holding the unchanged content
+	// or entries of a renamed node, "" if the node is held in memory
+	base string
+}
+
+// info returns the FileInfo of the in-memory node.
+func (n *txNode) info(name string) os.FileInfo {
+	mode := n.mode
+	if n.dir {
+		mode |= os.ModeDir
+	}
+	return DumFileInfo{IName: name, ISize: int64(len(n.data)), IMode: mode, IModTime: n.modTime, IDir: n.dir}
+}
+
+// writeAt writes p at off, the content is extended if necessary.
+func (n *txNode) writeAt(p []byte, off int64) {
+	if end := off + int64(len(p)); end > int64(len(n.data)) {
+		if end > int64(cap(n.data)) {
+			grown := make([]byte, len(n.data), 2*end)
+			copy(grown, n.data)
+			n.data = grown
+		}
+		l := int64(len(n.data))
+		n.data = n.data[:end]
+		for i := l; i < off; i++ {
+			n.data[i] = 0
+		}
+	}
+	copy(n.data[off:], p)
+	n.modTime = time.Now()
+}
+
+// PathSeparator returns the path separator of the wrapped filesystem.
+func (tx *Tx) PathSeparator() uint8 {
+	return tx.fs.PathSeparator()
+}
+
+// clean returns the cleaned absolute path of name,
+// "" is the root like for the segments of SplitPath.
+func (tx *Tx) clean(name string) (string, error) {
+	sep := string(tx.fs.PathSeparator())
+	if name == "" {
+		return sep, nil
+	}
+	p := cleanPath(tx.fs, name)
+	if p == "" {
+		return "", os.ErrInvalid
+	}
+	return p, nil
+}
+
+// parent returns the parent directory of the cleaned path p, the root is its own parent.
+func (tx *Tx) parent(p string) string {
+	return Dir(tx.fs, p)
+}
+
+// base returns the last element of the cleaned path p, it is empty for the root.
+func (tx *Tx) base(p string) string {
+	_, name := Split(tx.fs, p)
+	return name
+}
+
+// below returns the prefix of the paths below the cleaned path p.
+func (tx *Tx) below(p string) string {
+	return JoinPath(tx.fs, p, "")
+}
+
+// lookup returns the node of the cleaned path p and the path on the wrapped filesystem
+// holding its content, the node is nil if the path is unchanged by the transaction.
+// The caller must hold the lock.
+func (tx *Tx) lookup(p string) (*txNode, string, error) {
+	if n, ok := tx.nodes[p]; ok {
+		if n.removed {
+			return nil, "", os.ErrNotExist
+		}
+		return n, n.base, nil
+	}
+	for a := p; tx.base(a) != ""; {
+		a = tx.parent(a)
+		if n, ok := tx.nodes[a]; ok {
+			// The nearest changed ancestor decides where the unchanged entries live
+			if n.removed || n.base == "" {
+				return nil, "", os.ErrNotExist
+			}
+			return nil, JoinPath(tx.fs, n.base, p[len(tx.below(a)):]), nil
+		}
+	}
+	return nil, p, nil
+}
+
+// stat returns the node, the path on the wrapped filesystem and the FileInfo of the cleaned path p.
+// The returned error is not wrapped in a *os.PathError.
+// The caller must hold the lock.
+func (tx *Tx) stat(p string, follow bool) (*txNode, string, os.FileInfo, error) {
+	n, base, err := tx.lookup(p)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if n != nil && base == "" {
+		return n, base, n.info(tx.base(p)), nil
+	}
+	var fi os.FileInfo
+	if follow {
+		fi, err = tx.fs.Stat(base)
+	} else {
+		fi, err = tx.fs.Lstat(base)
+	}
+	if err != nil {
+		if perr, ok := err.(*os.PathError); ok {
+			err = perr.Err
+		}
+		return nil, "", nil, err
+	}
+	if n != nil {
+		// Renamed, the wrapped filesystem still uses the old name
+		fi = DumFileInfo{IName: tx.base(p), ISize: fi.Size(), IMode: fi.Mode(), IModTime: fi.ModTime(), IDir: fi.IsDir(), ISys: fi.Sys()}
+	}
+	return n, base, fi, nil
+}
+
+// checkParent returns an error if the parent of the cleaned path p is not an existing directory.
+// The caller must hold the lock.
+func (tx *Tx) checkParent(p string) error {
+	_, _, fi, err := tx.stat(tx.parent(p), true)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return ErrNotDirectory
+	}
+	return nil
+}
+
+// removeNodes forgets the changes below the cleaned path p.
+// The caller must hold the lock.
+func (tx *Tx) removeNodes(p string) {
+	prefix := tx.below(p)
+	for k := range tx.nodes {
+		if strings.HasPrefix(k, prefix) {
+			delete(tx.nodes, k)
+		}
+	}
+}
+
+func writable(flag int) bool {
+	return flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND) != 0
+}
+
+// OpenFile opens the named file. Files opened for writing are read into memory,
+// written content is only visible inside the transaction until Commit.
+func (tx *Tx) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	p, err := tx.clean(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	tx.mutex.Lock()
+	f, dir, err := tx.openFile(p, name, flag, perm)
+	tx.mutex.Unlock()
+	if dir {
+		return DirFile(tx, name)
+	}
+	return f, err
+}
+
+// openFile opens the cleaned path p, dir is set if it is a directory.
+// The caller must hold the lock.
+func (tx *Tx) openFile(p, name string, flag int, perm os.FileMode) (f File, dir bool, err error) {
+	if tx.done {
+		return nil, false, &os.PathError{Op: "open", Path: name, Err: ErrTxDone}
+	}
+	n, base, fi, err := tx.stat(p, true)
+	if os.IsNotExist(err) {
+		if flag&os.O_CREATE == 0 {
+			return nil, false, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		if err := tx.checkParent(p); err != nil {
+			return nil, false, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		n = &txNode{mode: perm & os.ModePerm, modTime: time.Now()}
+		tx.nodes[p] = n
+		tx.ops = append(tx.ops, txPut{p, n})
+		return &txFile{tx: tx, name: name, node: n, flag: flag}, false, nil
+	}
+	if err != nil {
+		return nil, false, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, false, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	}
+	if fi.IsDir() {
+		if writable(flag) {
+			return nil, false, &os.PathError{Op: "open", Path: name, Err: ErrIsDirectory}
+		}
+		return nil, true, nil
+	}
+	if !writable(flag) {
+		if n == nil || n.base != "" {
+			f, err := tx.fs.OpenFile(base, os.O_RDONLY, 0)
+			return f, false, err
+		}
+		return &txFile{tx: tx, name: name, node: n, flag: flag}, false, nil
+	}
+	if n == nil || n.base != "" {
+		// Copy the file into memory on the first write
+		var data []byte
+		if flag&os.O_TRUNC == 0 {
+			if data, err = ReadFile(tx.fs, base); err != nil {
+				return nil, false, err
+			}
+		}
+		n = &txNode{mode: fi.Mode(), modTime: fi.ModTime(), data: data}
+		tx.nodes[p] = n
+		tx.ops = append(tx.ops, txPut{p, n})
+	} else if flag&os.O_TRUNC != 0 {
+		n.data = nil
+		n.modTime = time.Now()
+	}
+	return &txFile{tx: tx, name: name, node: n, flag: flag}, false, nil
+}
+
+// Remove removes the named file or empty directory inside the transaction.
+func (tx *Tx) Remove(name string) error {
+	p, err := tx.clean(name)
+	if err == nil && tx.base(p) == "" {
+		err = os.ErrInvalid
+	}
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return &os.PathError{Op: "remove", Path: name, Err: ErrTxDone}
+	}
+	_, _, fi, err := tx.stat(p, false)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	if fi.IsDir() {
+		entries, err := tx.readDir(p)
+		if err != nil {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+		if len(entries) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: ErrNotEmpty}
+		}
+	}
+	tx.removeNodes(p)
+	tx.nodes[p] = &txNode{removed: true}
+	tx.ops = append(tx.ops, txRemove{p})
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath inside the transaction.
+// An existing file at newpath is replaced, an existing directory is not.
+func (tx *Tx) Rename(oldpath, newpath string) error {
+	oldp, err := tx.clean(oldpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	newp, err := tx.clean(newpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrTxDone}
+	}
+	n, base, fi, err := tx.stat(oldp, false)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	if oldp == newp {
+		return nil
+	}
+	if tx.base(oldp) == "" || strings.HasPrefix(newp, tx.below(oldp)) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrInvalid}
+	}
+	if _, _, nfi, err := tx.stat(newp, false); err == nil {
+		if fi.IsDir() || nfi.IsDir() {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrExist}
+		}
+	} else if !os.IsNotExist(err) {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+	if err := tx.checkParent(newp); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+
+	if n == nil {
+		n = &txNode{dir: fi.IsDir(), mode: fi.Mode(), modTime: fi.ModTime(), base: base}
+	}
+	tx.removeNodes(newp)
+	prefix := tx.below(oldp)
+	moved := make(map[string]*txNode)
+	for k, c := range tx.nodes {
+		if strings.HasPrefix(k, prefix) {
+			moved[newp+k[len(oldp):]] = c
+			delete(tx.nodes, k)
+		}
+	}
+	for k, c := range moved {
+		tx.nodes[k] = c
+	}
+	tx.nodes[newp] = n
+	tx.nodes[oldp] = &txNode{removed: true}
+	tx.ops = append(tx.ops, txRename{oldp, newp})
+	return nil
+}
+
+// Mkdir creates a new directory inside the transaction.
+func (tx *Tx) Mkdir(name string, perm os.FileMode) error {
+	p, err := tx.clean(name)
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return &os.PathError{Op: "mkdir", Path: name, Err: ErrTxDone}
+	}
+	if _, _, _, err := tx.stat(p, false); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	} else if !os.IsNotExist(err) {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	if err := tx.checkParent(p); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	tx.nodes[p] = &txNode{dir: true, mode: perm & os.ModePerm, modTime: time.Now()}
+	tx.ops = append(tx.ops, txMkdir{p, perm})
+	return nil
+}
+
+// Stat returns the FileInfo of the named file inside the transaction.
+func (tx *Tx) Stat(name string) (os.FileInfo, error) {
+	return tx.statOp("stat", name, true)
+}
+
+// Lstat returns the FileInfo of the named file inside the transaction,
+// symbolic links are not followed.
+func (tx *Tx) Lstat(name string) (os.FileInfo, error) {
+	return tx.statOp("lstat", name, false)
+}
+
+func (tx *Tx) statOp(op, name string, follow bool) (os.FileInfo, error) {
+	p, err := tx.clean(name)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return nil, &os.PathError{Op: op, Path: name, Err: ErrTxDone}
+	}
+	_, _, fi, err := tx.stat(p, follow)
+	if err != nil {
+		return nil, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+// ReadDir reads the directory named by path inside the transaction
+// and returns a list of sorted directory entries.
+func (tx *Tx) ReadDir(path string) ([]os.FileInfo, error) {
+	p, err := tx.clean(path)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: ErrTxDone}
+	}
+	fis, err := tx.readDir(p)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	return fis, nil
+}
+
+// readDir merges the entries of the wrapped filesystem with the changes of the transaction.
+// The caller must hold the lock.
+func (tx *Tx) readDir(p string) ([]os.FileInfo, error) {
+	n, base, fi, err := tx.stat(p, true)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, ErrNotDirectory
+	}
+	entries := make(map[string]os.FileInfo)
+	if n == nil || n.base != "" {
+		fis, err := tx.fs.ReadDir(base)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			entries[fi.Name()] = fi
+		}
+	}
+	for k, c := range tx.nodes {
+		if k == p || tx.parent(k) != p {
+			continue
+		}
+		if c.removed {
+			delete(entries, tx.base(k))
+		} else if _, _, fi, err := tx.stat(k, false); err == nil {
+			entries[fi.Name()] = fi
+		}
+	}
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		fis = append(fis, fi)
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, nil
+}
+
+// Commit applies the changes of the transaction to the wrapped filesystem in the order
+// they were made. Replaced and removed files are moved aside until all changes are applied.
+// If a change fails, the applied ones are undone as far as possible and the error is returned.
+// Files opened inside the transaction are unusable afterwards.
+func (tx *Tx) Commit() error {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	c := &txCommit{fs: tx.fs}
+	for _, op := range tx.ops {
+		if err := op.apply(c); err != nil {
+			for i := len(c.undos) - 1; i >= 0; i-- {
+				c.undos[i]()
+			}
+			return err
+		}
+	}
+	for _, backup := range c.backups {
+		// Backups inside of removed directories are already gone
+		RemoveAll(tx.fs, backup)
+	}
+	tx.nodes, tx.ops = nil, nil
+	return nil
+}
+
+// Rollback discards the changes of the transaction.
+// Files opened inside the transaction are unusable afterwards.
+func (tx *Tx) Rollback() error {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.nodes, tx.ops = nil, nil
+	return nil
+}
+
+// txCommit tracks the applied changes of a commit.
+type txCommit struct {
+	fs    Filesystem
+	undos []func() error
+	// backups holds the current paths of the files moved aside
+	backups []string
+}
+
+// moveAside renames the path p to a hidden name in the same directory
+// and restores it on undo.
+func (c *txCommit) moveAside(p string) error {
+	dir, name := Split(c.fs, p)
+	var backup string
+	for {
+		backup = dir + "." + name + ".tx-" + nextRandom()
+		if _, err := c.fs.Lstat(backup); os.IsNotExist(err) {
+			break
+		}
+	}
+	if err := c.fs.Rename(p, backup); err != nil {
+		return err
+	}
+	c.moved(p, backup)
+	c.backups = append(c.backups, backup)
+	c.undos = append(c.undos, func() error {
+		return c.fs.Rename(backup, p)
+	})
+	return nil
+}
+
+// moved updates the backups after oldpath has been renamed to newpath.
+func (c *txCommit) moved(oldpath, newpath string) {
+	prefix := JoinPath(c.fs, oldpath, "")
+	for i, backup := range c.backups {
+		if strings.HasPrefix(backup, prefix) {
+			c.backups[i] = newpath + backup[len(oldpath):]
+		}
+	}
+}
+
+// txOp is a change of a transaction applied on commit.
+type txOp interface {
+	apply(c *txCommit) error
+}
+
+type txMkdir struct {
+	path string
+	perm os.FileMode
+}
+
+func (op txMkdir) apply(c *txCommit) error {
+	if err := c.fs.Mkdir(op.path, op.perm); err != nil {
+		return err
+	}
+	c.undos = append(c.undos, func() error {
+		return c.fs.Remove(op.path)
+	})
+	return nil
+}
+
+// txPut writes the final content of a node, which is changed in place if it exists.
+type txPut struct {
+	path string
+	node *txNode
+}
+
+func (op txPut) apply(c *txCommit) error {
+	fi, err := c.fs.Stat(op.path)
+	if os.IsNotExist(err) {
+		f, err := c.fs.OpenFile(op.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, op.node.mode.Perm())
+		if err != nil {
+			return err
+		}
+		c.undos = append(c.undos, func() error {
+			return c.fs.Remove(op.path)
+		})
+		return writeClose(f, op.node.data)
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.PathError{Op: "open", Path: op.path, Err: ErrIsDirectory}
+	}
+	old, err := ReadFile(c.fs, op.path)
+	if err != nil {
+		return err
+	}
+	c.undos = append(c.undos, func() error {
+		return WriteFile(c.fs, op.path, old, 0)
+	})
+	f, err := c.fs.OpenFile(op.path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	return writeClose(f, op.node.data)
+}
+
+// writeClose writes data to f and closes it.
+func writeClose(f File, data []byte) error {
+	n, err := f.Write(data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// txRename moves a replaced file aside before renaming.
+type txRename struct {
+	oldpath, newpath string
+}
+
+func (op txRename) apply(c *txCommit) error {
+	if _, err := c.fs.Lstat(op.newpath); err == nil {
+		if err := c.moveAside(op.newpath); err != nil {
+			return err
+		}
+	}
+	if err := c.fs.Rename(op.oldpath, op.newpath); err != nil {
+		return err
+	}
+	c.moved(op.oldpath, op.newpath)
+	c.undos = append(c.undos, func() error {
+		return c.fs.Rename(op.newpath, op.oldpath)
+	})
+	return nil
+}
+
+// txRemove moves the removed file aside, it is removed after the commit.
+type txRemove struct {
+	path string
+}
+
+func (op txRemove) apply(c *txCommit) error {
+	return c.moveAside(op.path)
+}
+
+// txFile is a file opened for writing inside a transaction.
+type txFile struct {
+	tx     *Tx
+	name   string
+	node   *txNode
+	flag   int
+	offset int64
+}
+
+func (f *txFile) Name() string {
+	return f.name
+}
+
+func (f *txFile) Sync() error {
+	return nil
+}
+
+func (f *txFile) Stat() (os.FileInfo, error) {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	return f.node.info(f.tx.base(f.name)), nil
+}
+
+func (f *txFile) Readdir(n int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: ErrNotDirectory}
+}
+
+// check returns an error if the transaction is finished or the file was not opened for the access.
+// The caller must hold the lock.
+func (f *txFile) check(op string, write bool) error {
+	if f.tx.done {
+		return &os.PathError{Op: op, Path: f.name, Err: ErrTxDone}
+	}
+	if write && !writable(f.flag) || !write && f.flag&os.O_WRONLY != 0 {
+		return &os.PathError{Op: op, Path: f.name, Err: os.ErrPermission}
+	}
+	return nil
+}
+
+func (f *txFile) Truncate(size int64) error {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	if err := f.check("truncate", true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: os.ErrInvalid}
+	}
+	if size < int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size]
+		f.node.modTime = time.Now()
+	} else {
+		f.node.writeAt(nil, size)
+	}
+	return nil
+}
+
+func (f *txFile) Read(p []byte) (int, error) {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	n, err := f.readAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *txFile) ReadAt(p []byte, off int64) (int, error) {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	if off < 0 {
+		return 0, &os.PathError{Op: "read", Path: f.name, Err: os.ErrInvalid}
+	}
+	n, err := f.readAt(p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// readAt reads from off, io.EOF is returned if off is at the end.
+// The caller must hold the lock.
+func (f *txFile) readAt(p []byte, off int64) (int, error) {
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	if off >= int64(len(f.node.data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	return copy(p, f.node.data[off:]), nil
+}
+
+func (f *txFile) Write(p []byte) (int, error) {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+	f.node.writeAt(p, f.offset)
+	f.offset += int64(len(p))
+	return len(p), nil
+}
+
+func (f *txFile) Seek(offset int64, whence int) (int64, error) {
+	f.tx.mutex.Lock()
+	defer f.tx.mutex.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	case io.SeekStart:
+	default:
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: f.name, Err: os.ErrInvalid}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *txFile) Close() error {
+	return nil
+}
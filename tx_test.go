@@ -0,0 +1,231 @@
+package vfs_test
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestTxConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		tx, err := vfs.Begin(memfs.Create())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	})
+}
+
+// txTestFS returns a memfs with a few files and directories.
+func txTestFS(t *testing.T) vfs.Filesystem {
+	t.Helper()
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/etc/app", 0755)
+	vfs.WriteFile(fs, "/etc/app/config", []byte("old"), 0644)
+	vfs.WriteFile(fs, "/etc/app/obsolete", []byte("obsolete"), 0644)
+	vfs.WriteFile(fs, "/etc/hosts", []byte("hosts"), 0644)
+	return fs
+}
+
+// txTree returns the paths and contents of all files of fs.
+func txTree(t *testing.T, fs vfs.Filesystem) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			tree[path] = "dir"
+			return nil
+		}
+		b, err := vfs.ReadFile(fs, path)
+		tree[path] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	return tree
+}
+
+// txChanges applies the same batch of changes to fs.
+func txChanges(t *testing.T, fs vfs.Filesystem) {
+	t.Helper()
+	if err := vfs.WriteFile(fs, "/etc/app/config", []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Remove("/etc/app/obsolete"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if err := fs.Mkdir("/etc/app/conf.d", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "/etc/app/conf.d/extra", []byte("extra"), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Rename("/etc/app", "/etc/app2"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if err := fs.Remove("/etc/hosts"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if err := fs.Rename("/etc/app2/config", "/etc/hosts"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+}
+
+func TestTxCommit(t *testing.T) {
+	fs := txTestFS(t)
+	expected := txTestFS(t)
+	txChanges(t, expected)
+
+	tx, err := vfs.Begin(fs)
+	if err != nil {
+		t.Fatalf("Begin: %s", err)
+	}
+	txChanges(t, tx)
+
+	// The wrapped filesystem is unchanged until commit
+	if tree := txTree(t, fs); !reflect.DeepEqual(tree, txTree(t, txTestFS(t))) {
+		t.Errorf("Filesystem changed before commit: %v", tree)
+	}
+	if tree := txTree(t, tx); !reflect.DeepEqual(tree, txTree(t, expected)) {
+		t.Errorf("Unexpected view of transaction: %v, expected %v", tree, txTree(t, expected))
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	if tree := txTree(t, fs); !reflect.DeepEqual(tree, txTree(t, expected)) {
+		t.Errorf("Unexpected filesystem after commit: %v, expected %v", tree, txTree(t, expected))
+	}
+
+	if err := tx.Commit(); err != vfs.ErrTxDone {
+		t.Errorf("Expected ErrTxDone, got %v", err)
+	}
+	if _, err := tx.Stat("/"); !errors.Is(err, vfs.ErrTxDone) {
+		t.Errorf("Expected ErrTxDone, got %v", err)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	fs := txTestFS(t)
+	tx, _ := vfs.Begin(fs)
+	txChanges(t, tx)
+	f, err := tx.OpenFile("/etc/hosts", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %s", err)
+	}
+	if tree := txTree(t, fs); !reflect.DeepEqual(tree, txTree(t, txTestFS(t))) {
+		t.Errorf("Filesystem changed by rollback: %v", tree)
+	}
+	if _, err := f.Write([]byte("late")); !errors.Is(err, vfs.ErrTxDone) {
+		t.Errorf("Expected ErrTxDone writing after rollback, got %v", err)
+	}
+	if err := tx.Rollback(); err != vfs.ErrTxDone {
+		t.Errorf("Expected ErrTxDone, got %v", err)
+	}
+}
+
+// failRenameFS fails renaming to a single path.
+type failRenameFS struct {
+	vfs.Filesystem
+	newpath string
+}
+
+func (fs failRenameFS) Rename(oldpath, newpath string) error {
+	if newpath == fs.newpath {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errors.New("broken")}
+	}
+	return fs.Filesystem.Rename(oldpath, newpath)
+}
+
+func TestTxCommitUndo(t *testing.T) {
+	fs := failRenameFS{txTestFS(t), "/etc/app2"}
+	tx, _ := vfs.Begin(fs)
+	txChanges(t, tx)
+
+	if err := tx.Commit(); err == nil {
+		t.Fatalf("Expected commit to fail")
+	}
+	if tree := txTree(t, fs); !reflect.DeepEqual(tree, txTree(t, txTestFS(t))) {
+		t.Errorf("Failed commit not undone: %v", tree)
+	}
+}
+
+func TestTxRenameReplace(t *testing.T) {
+	fs := txTestFS(t)
+	tx, _ := vfs.Begin(fs)
+	if err := tx.Rename("/etc/app/config", "/etc/hosts"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if err := tx.Rename("/etc/hosts", "/etc/app"); !os.IsExist(err) {
+		t.Errorf("Expected exist error replacing a directory, got %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	expected := map[string]string{
+		"/":                 "dir",
+		"/etc":              "dir",
+		"/etc/app":          "dir",
+		"/etc/app/obsolete": "obsolete",
+		"/etc/hosts":        "old",
+	}
+	if tree := txTree(t, fs); !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Unexpected filesystem after commit: %v", tree)
+	}
+}
+
+func TestTxBackslash(t *testing.T) {
+	fs := memfs.Create(memfs.WithPathSeparator('\\'))
+	vfs.MkdirAll(fs, `\etc\app`, 0755)
+	vfs.WriteFile(fs, `\etc\app\config`, []byte("old"), 0644)
+	tx, _ := vfs.Begin(fs)
+
+	if err := tx.Rename("/etc/app", `\etc\moved`); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if err := tx.Rename(`\etc`, `/etc\moved/sub`); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid error moving a directory into itself, got %v", err)
+	}
+	if err := tx.Remove(`\`); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid error removing the root, got %v", err)
+	}
+	if err := vfs.WriteFile(tx, `/etc/moved\config`, []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	if b, err := vfs.ReadFile(fs, `\etc\moved\config`); err != nil || string(b) != "new" {
+		t.Errorf("ReadFile: %q %v", b, err)
+	}
+	if _, err := fs.Stat(`\etc\app`); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestTxBegin(t *testing.T) {
+	if _, err := vfs.Begin(vfs.ReadOnly(memfs.Create())); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := vfs.Begin(vfs.ReadOnlyExcept(memfs.Create(), "/tmp")); err != nil {
+		t.Errorf("Begin: %s", err)
+	}
+
+	tx, _ := vfs.Begin(memfs.Create())
+	if err := tx.Mkdir("relative", 0755); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid relative path, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+// Package unionfs defines a filesystem which stacks a writable upper layer
+// on top of a read-only lower layer.
+//
+// Files of both layers are visible, files of the upper layer take precedence.
+// Modifications are applied to the upper layer only: files of the lower layer
+// are copied up before they are written and deletions are recorded
+// as whiteout markers in the upper layer.
+package unionfs
@@ -0,0 +1,21 @@
+package unionfs_test
+
+import (
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/unionfs"
+)
+
+func ExampleFS() {
+	// Stack an in-memory scratch layer on top of the OS filesystem
+	fs := unionfs.Create(memfs.Create(), vfs.ReadOnly(vfs.OS()))
+
+	// Reads are served from the OS filesystem
+	vfs.ReadFile(fs, "/etc/hosts")
+
+	// Writes copy the file up to memory, /etc/hosts stays untouched
+	vfs.WriteFile(fs, "/etc/hosts", []byte("127.0.0.1 localhost\n"), 0644)
+
+	// Deletions only hide the file of the OS filesystem
+	fs.Remove("/etc/hosts")
+}
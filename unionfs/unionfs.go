@@ -0,0 +1,517 @@
+package unionfs
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+const (
+	// WhiteoutPrefix is prepended to the name of a marker file in the upper layer
+	// which hides the file of the same name in the lower layer.
+	WhiteoutPrefix = ".wh."
+	// OpaqueMarker is the name of a marker file in a directory of the upper layer
+	// which hides the whole content of the directory in the lower layer.
+	OpaqueMarker = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// writeFlags are the flags of OpenFile which require the file in the upper layer.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+
+// FS is a union of a writable upper and a read-only lower filesystem.
+// Both layers have to use the same path separator.
+type FS struct {
+	upper vfs.Filesystem
+	lower vfs.Filesystem
+}
+
+// Create returns a union filesystem of upper stacked on lower.
+// The lower filesystem is never modified.
+func Create(upper, lower vfs.Filesystem) *FS {
+	return &FS{upper: upper, lower: lower}
+}
+
+// PathSeparator returns the path separator of the upper layer
+func (fs *FS) PathSeparator() uint8 {
+	return fs.upper.PathSeparator()
+}
+
+// clean returns the absolute, cleaned form of p.
+func (fs *FS) clean(p string) string {
+	vol := vfs.VolumeName(fs, p)
+	return vfs.Clean(fs, vol+string(fs.PathSeparator())+p[len(vol):])
+}
+
+// split splits a cleaned path into its parent directory and base name.
+// The base name of the root is empty.
+func (fs *FS) split(p string) (string, string) {
+	dir, name := vfs.Split(fs, p)
+	return vfs.Clean(fs, dir), name
+}
+
+// isRoot reports whether a cleaned path is the root directory.
+func (fs *FS) isRoot(p string) bool {
+	_, name := fs.split(p)
+	return name == ""
+}
+
+// dir returns the parent directory of a cleaned path.
+func (fs *FS) dir(p string) string {
+	dir, _ := fs.split(p)
+	return dir
+}
+
+// join joins a directory and a name.
+func (fs *FS) join(dir, name string) string {
+	return vfs.JoinPath(fs, dir, name)
+}
+
+func (fs *FS) whiteout(p string) string {
+	dir, name := fs.split(p)
+	return fs.join(dir, WhiteoutPrefix+name)
+}
+
+func exists(fs vfs.Filesystem, p string) bool {
+	_, err := fs.Lstat(p)
+	return err == nil
+}
+
+// hidden reports whether the lower layer is hidden at p by a whiteout
+// of p or one of its parents, or an opaque directory above p.
+func (fs *FS) hidden(p string) bool {
+	for dir, name := fs.split(p); name != ""; dir, name = fs.split(dir) {
+		if exists(fs.upper, fs.join(dir, WhiteoutPrefix+name)) || exists(fs.upper, fs.join(dir, OpaqueMarker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerVisible reports whether p exists in the lower layer and is not hidden.
+func (fs *FS) lowerVisible(p string) bool {
+	return exists(fs.lower, p) && !fs.hidden(p)
+}
+
+// checkName rejects names reserved for whiteout markers.
+func checkName(op, p string, name string) error {
+	if strings.HasPrefix(name, WhiteoutPrefix) {
+		return &os.PathError{Op: op, Path: p, Err: os.ErrInvalid}
+	}
+	return nil
+}
+
+// stat looks up p in the upper, then the lower layer using the given stat function.
+func (fs *FS) stat(op, name string, stat func(vfs.Filesystem, string) (os.FileInfo, error)) (os.FileInfo, error) {
+	p := fs.clean(name)
+	if _, base := fs.split(p); strings.HasPrefix(base, WhiteoutPrefix) {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	fi, err := stat(fs.upper, p)
+	if err == nil || !os.IsNotExist(err) {
+		return fi, err
+	}
+	if fs.hidden(p) {
+		return nil, &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return stat(fs.lower, p)
+}
+
+// Stat returns the FileInfo of the named file of the upper or the lower layer.
+// A symbolic link is followed through both layers.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	p, err := fs.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := fs.stat("stat", p, vfs.Filesystem.Stat)
+	if err != nil || p == fs.clean(name) {
+		return fi, err
+	}
+	_, base := fs.split(fs.clean(name))
+	return namedInfo{FileInfo: fi, name: base}, nil
+}
+
+// namedInfo describes the target of a symbolic link under the name of the link.
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi namedInfo) Name() string {
+	return fi.name
+}
+
+// maxLinkDepth limits the number of symbolic links followed resolving a path.
+const maxLinkDepth = 40
+
+// resolve follows a symbolic link at name through both layers and returns the cleaned target.
+// Links of the parent directories are resolved by the layers themselves,
+// so they can not point into the other layer.
+func (fs *FS) resolve(op, name string) (string, error) {
+	p := fs.clean(name)
+	for i := 0; i < maxLinkDepth; i++ {
+		fi, err := fs.Lstat(p)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return p, nil
+		}
+		target, err := fs.Readlink(p)
+		if err != nil {
+			return "", err
+		}
+		if !vfs.IsAbs(fs, target) {
+			target = fs.join(fs.dir(p), target)
+		}
+		p = fs.clean(target)
+	}
+	return "", &os.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+}
+
+// Lstat returns the FileInfo of the named file of the upper or the lower layer
+// without following symbolic links.
+func (fs *FS) Lstat(name string) (os.FileInfo, error) {
+	return fs.stat("lstat", name, vfs.Filesystem.Lstat)
+}
+
+// ReadDir merges the entries of the directory in both layers, sorted by name.
+// Entries of the upper layer take precedence, whiteouts are not listed.
+func (fs *FS) ReadDir(path string) ([]os.FileInfo, error) {
+	p := fs.clean(path)
+	fi, err := fs.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: vfs.ErrNotDirectory}
+	}
+
+	entries := make(map[string]os.FileInfo)
+	whiteouts := make(map[string]bool)
+	opaque := false
+	var upper []os.FileInfo
+	if exists(fs.upper, p) {
+		if upper, err = fs.upper.ReadDir(p); err != nil {
+			return nil, err
+		}
+	}
+	for _, fi := range upper {
+		switch {
+		case fi.Name() == OpaqueMarker:
+			opaque = true
+		case strings.HasPrefix(fi.Name(), WhiteoutPrefix):
+			whiteouts[strings.TrimPrefix(fi.Name(), WhiteoutPrefix)] = true
+		default:
+			entries[fi.Name()] = fi
+		}
+	}
+	if lfi, err := fs.lower.Stat(p); err == nil && lfi.IsDir() && !opaque && !fs.hidden(p) {
+		lower, err := fs.lower.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range lower {
+			if _, ok := entries[fi.Name()]; !ok && !whiteouts[fi.Name()] {
+				entries[fi.Name()] = fi
+			}
+		}
+	}
+
+	fis := make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		fis = append(fis, fi)
+	}
+	sort.Sort(byName(fis))
+	return fis, nil
+}
+
+type byName []os.FileInfo
+
+func (f byName) Len() int           { return len(f) }
+func (f byName) Less(i, j int) bool { return f[i].Name() < f[j].Name() }
+func (f byName) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+// copyUp copies p and its parent directories from the lower to the upper layer
+// unless they already exist in the upper layer. Paths hidden by a whiteout are not copied.
+func (fs *FS) copyUp(p string) error {
+	if exists(fs.upper, p) {
+		return nil
+	}
+	if fs.hidden(p) {
+		return &os.PathError{Op: "copyup", Path: p, Err: os.ErrNotExist}
+	}
+	if !fs.isRoot(p) {
+		if err := fs.copyUp(fs.dir(p)); err != nil {
+			return err
+		}
+	}
+	fi, err := fs.lower.Lstat(p)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fi.IsDir():
+		return fs.upper.Mkdir(p, fi.Mode().Perm())
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := vfs.Readlink(fs.lower, p)
+		if err != nil {
+			return err
+		}
+		return vfs.Symlink(fs.upper, target, p)
+	}
+	return vfs.CopyFile(fs.upper, fs.lower, p, p)
+}
+
+// prepare makes sure the parent of p exists in the upper layer and
+// removes a whiteout of p, so a new file can be created at p.
+// It returns whether p was whited out.
+func (fs *FS) prepare(p string) (bool, error) {
+	if err := fs.copyUp(fs.dir(p)); err != nil {
+		return false, err
+	}
+	wh := fs.whiteout(p)
+	if !exists(fs.upper, wh) {
+		return false, nil
+	}
+	return true, fs.upper.Remove(wh)
+}
+
+func (fs *FS) markOpaque(dir string) error {
+	f, err := fs.upper.OpenFile(fs.join(dir, OpaqueMarker), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (fs *FS) markWhiteout(p string) error {
+	f, err := fs.upper.OpenFile(fs.whiteout(p), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// OpenFile opens the named file of the upper or the lower layer.
+// If flag requests write access, the file is copied up to the upper layer first.
+// A symbolic link is followed through both layers.
+// Directories opened for reading list the entries of both layers.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	p, err := fs.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	_, base := fs.split(p)
+	if flag&writeFlags == 0 {
+		if strings.HasPrefix(base, WhiteoutPrefix) {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		if fi, err := fs.Stat(p); err == nil && fi.IsDir() {
+			return vfs.DirFile(fs, p)
+		}
+		if exists(fs.upper, p) || !fs.lowerVisible(p) {
+			return fs.upper.OpenFile(p, flag, perm)
+		}
+		return fs.lower.OpenFile(p, flag, perm)
+	}
+
+	if err := checkName("open", name, base); err != nil {
+		return nil, err
+	}
+	if !exists(fs.upper, p) {
+		if fs.lowerVisible(p) {
+			if err := fs.copyUp(p); err != nil {
+				return nil, err
+			}
+		} else {
+			if flag&os.O_CREATE == 0 {
+				return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+			}
+			if _, err := fs.Stat(fs.dir(p)); err != nil {
+				return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+			}
+			if _, err := fs.prepare(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return fs.upper.OpenFile(p, flag, perm)
+}
+
+// Mkdir creates a directory in the upper layer.
+// A directory replacing a deleted directory of the lower layer starts out empty.
+func (fs *FS) Mkdir(name string, perm os.FileMode) error {
+	p := fs.clean(name)
+	_, base := fs.split(p)
+	if err := checkName("mkdir", name, base); err != nil {
+		return err
+	}
+	if _, err := fs.Lstat(p); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if _, err := fs.Stat(fs.dir(p)); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrNotExist}
+	}
+	whitedOut, err := fs.prepare(p)
+	if err != nil {
+		return err
+	}
+	if err := fs.upper.Mkdir(p, perm); err != nil {
+		return err
+	}
+	if whitedOut && exists(fs.lower, p) {
+		return fs.markOpaque(p)
+	}
+	return nil
+}
+
+// Remove removes the named file or empty directory.
+// If the file exists in the lower layer, a whiteout is recorded in the upper layer.
+func (fs *FS) Remove(name string) error {
+	p := fs.clean(name)
+	if fs.isRoot(p) {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrInvalid}
+	}
+	fi, err := fs.Lstat(p)
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+	if fi.IsDir() {
+		fis, err := fs.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		if len(fis) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: vfs.ErrNotEmpty}
+		}
+	}
+	lower := fs.lowerVisible(p)
+	if exists(fs.upper, p) {
+		// Directories of the upper layer may still contain markers
+		if err := vfs.RemoveAll(fs.upper, p); err != nil {
+			return err
+		}
+	}
+	if lower {
+		if _, err := fs.prepare(p); err != nil {
+			return err
+		}
+		return fs.markWhiteout(p)
+	}
+	return nil
+}
+
+// Rename renames a file, which is copied up first if it exists in the lower layer.
+// Directories existing in the lower layer can not be renamed and return vfs.ErrCrossDevice,
+// the same way overlay filesystems of the operating system do.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	oldp, newp := fs.clean(oldpath), fs.clean(newpath)
+	_, base := fs.split(newp)
+	if err := checkName("rename", newpath, base); err != nil {
+		return err
+	}
+	fi, err := fs.Lstat(oldp)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	lower := fs.lowerVisible(oldp)
+	if fi.IsDir() && lower {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: vfs.ErrCrossDevice}
+	}
+	if nfi, err := fs.Lstat(newp); err == nil && nfi.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: vfs.ErrIsDirectory}
+	}
+	if _, err := fs.Stat(fs.dir(newp)); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if err := fs.copyUp(oldp); err != nil {
+		return err
+	}
+	if _, err := fs.prepare(newp); err != nil {
+		return err
+	}
+	if err := fs.upper.Rename(oldp, newp); err != nil {
+		return err
+	}
+	if fi.IsDir() && exists(fs.lower, newp) {
+		if err := fs.markOpaque(newp); err != nil {
+			return err
+		}
+	}
+	if lower {
+		return fs.markWhiteout(oldp)
+	}
+	return nil
+}
+
+// Symlink creates a symbolic link in the upper layer.
+func (fs *FS) Symlink(oldname, newname string) error {
+	p := fs.clean(newname)
+	_, base := fs.split(p)
+	if err := checkName("symlink", newname, base); err != nil {
+		return err
+	}
+	if _, err := fs.Lstat(p); err == nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrExist}
+	}
+	if _, err := fs.Stat(fs.dir(p)); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: os.ErrNotExist}
+	}
+	if _, err := fs.prepare(p); err != nil {
+		return err
+	}
+	return vfs.Symlink(fs.upper, oldname, p)
+}
+
+// Readlink returns the destination of the named symbolic link of the upper or the lower layer.
+func (fs *FS) Readlink(name string) (string, error) {
+	p := fs.clean(name)
+	if exists(fs.upper, p) || !fs.lowerVisible(p) {
+		return vfs.Readlink(fs.upper, p)
+	}
+	return vfs.Readlink(fs.lower, p)
+}
+
+// Chmod changes the mode of the named file, which is copied up first.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	p, err := fs.copyUpTarget("chmod", name)
+	if err != nil {
+		return err
+	}
+	return vfs.Chmod(fs.upper, p, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file, which is copied up first.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	p, err := fs.copyUpTarget("chown", name)
+	if err != nil {
+		return err
+	}
+	return vfs.Chown(fs.upper, p, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file, which is copied up first.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	p, err := fs.copyUpTarget("chtimes", name)
+	if err != nil {
+		return err
+	}
+	return vfs.Chtimes(fs.upper, p, atime, mtime)
+}
+
+// copyUpTarget follows a symbolic link at name and copies the target up.
+func (fs *FS) copyUpTarget(op, name string) (string, error) {
+	p, err := fs.resolve(op, name)
+	if err != nil {
+		return "", err
+	}
+	if exists(fs.upper, p) {
+		return p, nil
+	}
+	if !fs.lowerVisible(p) {
+		return "", &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return p, fs.copyUp(p)
+}
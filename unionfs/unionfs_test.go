@@ -0,0 +1,331 @@
+package unionfs
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+// layers returns an empty upper and a populated lower layer.
+func layers(t *testing.T) (*memfs.MemFS, *memfs.MemFS) {
+	lower := memfs.Create()
+	if err := vfs.MkdirAll(lower, "/dir/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/file", "/dir/a", "/dir/b", "/dir/sub/c"} {
+		if err := vfs.WriteFile(lower, p, []byte("lower "+p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return memfs.Create(), lower
+}
+
+func names(t *testing.T, fs vfs.Filesystem, path string) []string {
+	fis, err := fs.ReadDir(path)
+	if err != nil {
+		t.Fatalf("ReadDir %s: %s", path, err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names
+}
+
+func assertContent(t *testing.T, fs vfs.Filesystem, path, content string) {
+	b, err := vfs.ReadFile(fs, path)
+	if err != nil {
+		t.Fatalf("ReadFile %s: %s", path, err)
+	}
+	if string(b) != content {
+		t.Errorf("Unexpected content of %s: %q", path, b)
+	}
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(Create(memfs.Create(), memfs.Create()))
+}
+
+func TestReadThrough(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	assertContent(t, fs, "/dir/a", "lower /dir/a")
+	if fi, err := fs.Stat("/dir/sub"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	if _, err := fs.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if n := names(t, upper, "/"); len(n) != 0 {
+		t.Errorf("Expected empty upper layer, got %q", n)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+	if err := vfs.WriteFile(fs, "/dir/new", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("/dir/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := vfs.Open(fs, "/dir")
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer d.Close()
+	fis, err := d.Readdir(-1)
+	if err != nil {
+		t.Fatalf("Readdir: %s", err)
+	}
+	var got []string
+	for _, fi := range fis {
+		got = append(got, fi.Name())
+	}
+	if expected := []string{"b", "new", "sub"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected entries: %q", got)
+	}
+}
+
+func TestCopyUp(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	f, err := fs.OpenFile("/dir/sub/c", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	f.Write([]byte(" changed"))
+	f.Close()
+
+	assertContent(t, fs, "/dir/sub/c", "lower /dir/sub/c changed")
+	assertContent(t, upper, "/dir/sub/c", "lower /dir/sub/c changed")
+	assertContent(t, lower, "/dir/sub/c", "lower /dir/sub/c")
+	if fi, err := upper.Stat("/dir/sub"); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("Expected copied up parent: %v, %v", fi, err)
+	}
+
+	if _, err := fs.OpenFile("/dir/missing", os.O_WRONLY, 0); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/new", []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if n := names(t, fs, "/dir"); !reflect.DeepEqual(n, []string{"a", "b", "new", "sub"}) {
+		t.Errorf("Unexpected entries: %q", n)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	if err := fs.Remove("/dir/a"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if _, err := fs.Stat("/dir/a"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if _, err := fs.OpenFile("/dir/a", os.O_RDONLY, 0); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if n := names(t, fs, "/dir"); !reflect.DeepEqual(n, []string{"b", "sub"}) {
+		t.Errorf("Unexpected entries: %q", n)
+	}
+	if _, err := lower.Stat("/dir/a"); err != nil {
+		t.Errorf("Lower layer modified: %s", err)
+	}
+	if _, err := fs.Stat("/dir/" + WhiteoutPrefix + "a"); !os.IsNotExist(err) {
+		t.Errorf("Expected hidden whiteout, got %v", err)
+	}
+
+	// Recreate the file
+	if err := vfs.WriteFile(fs, "/dir/a", []byte("new"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	assertContent(t, fs, "/dir/a", "new")
+
+	if err := fs.Remove("/dir"); !errors.Is(err, vfs.ErrNotEmpty) {
+		t.Errorf("Expected ErrNotEmpty, got %v", err)
+	}
+	if err := fs.Remove("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestRemoveAllMkdir(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	if err := vfs.RemoveAll(fs, "/dir"); err != nil {
+		t.Fatalf("RemoveAll: %s", err)
+	}
+	if n := names(t, fs, "/"); !reflect.DeepEqual(n, []string{"file"}) {
+		t.Errorf("Unexpected entries: %q", n)
+	}
+	if _, err := fs.Stat("/dir/sub/c"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	// A recreated directory does not show the old content
+	if err := fs.Mkdir("/dir", 0700); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if n := names(t, fs, "/dir"); len(n) != 0 {
+		t.Errorf("Expected empty directory, got %q", n)
+	}
+	if err := fs.Mkdir("/dir", 0700); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	if err := fs.Mkdir("/dir/"+WhiteoutPrefix+"x", 0700); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Expected invalid name error, got %v", err)
+	}
+	if err := fs.Remove("/dir"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestCreateInRemovedDir(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+	if err := vfs.RemoveAll(fs, "/dir"); err != nil {
+		t.Fatalf("RemoveAll: %s", err)
+	}
+
+	// Nothing is created below a removed directory, it is not copied up again
+	if err := vfs.WriteFile(fs, "/dir/new", nil, 0644); !os.IsNotExist(err) {
+		t.Errorf("WriteFile: expected not exist error, got %v", err)
+	}
+	if err := vfs.WriteFile(fs, "/dir/sub/new", nil, 0644); !os.IsNotExist(err) {
+		t.Errorf("WriteFile: expected not exist error, got %v", err)
+	}
+	if err := fs.Symlink("/file", "/dir/link"); !os.IsNotExist(err) {
+		t.Errorf("Symlink: expected not exist error, got %v", err)
+	}
+	if err := fs.Rename("/file", "/dir/file"); !os.IsNotExist(err) {
+		t.Errorf("Rename: expected not exist error, got %v", err)
+	}
+	if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+		t.Errorf("Expected removed directory, got %v", err)
+	}
+	if exists(upper, "/dir") {
+		t.Errorf("Removed directory copied up")
+	}
+	if err := fs.copyUp("/dir/sub"); !os.IsNotExist(err) {
+		t.Errorf("copyUp of hidden path: expected not exist error, got %v", err)
+	}
+}
+
+func TestRename(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	if err := fs.Rename("/dir/a", "/a"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	assertContent(t, fs, "/a", "lower /dir/a")
+	if _, err := fs.Stat("/dir/a"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+
+	if err := fs.Rename("/dir", "/moved"); !errors.Is(err, vfs.ErrCrossDevice) {
+		t.Errorf("Expected ErrCrossDevice, got %v", err)
+	}
+	if err := fs.Mkdir("/new", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := fs.Rename("/new", "/renamed"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if fi, err := fs.Stat("/renamed"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	upper, lower := layers(t)
+	if err := lower.Symlink("/file", "/link"); err != nil {
+		t.Fatal(err)
+	}
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	if target, err := fs.Readlink("/link"); err != nil || target != "/file" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if err := fs.Symlink("/dir/a", "/dir/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+	assertContent(t, fs, "/dir/link", "lower /dir/a")
+	if err := fs.Symlink("/dir/b", "/link"); !os.IsExist(err) {
+		t.Errorf("Expected exist error, got %v", err)
+	}
+	if fi, err := fs.Stat("/dir/link"); err != nil || fi.Name() != "link" || fi.Size() != 12 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+
+	fs.Symlink("/loop2", "/loop1")
+	fs.Symlink("/loop1", "/loop2")
+	if _, err := fs.Stat("/loop1"); !errors.Is(err, syscall.ELOOP) {
+		t.Errorf("Expected ELOOP, got %v", err)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	upper, lower := layers(t)
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	if err := vfs.Chmod(fs, "/dir/a", 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if fi, err := fs.Stat("/dir/a"); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	if fi, _ := lower.Stat("/dir/a"); fi.Mode().Perm() != 0644 {
+		t.Errorf("Lower layer modified: %s", fi.Mode())
+	}
+	if err := vfs.Chtimes(fs, "/missing", time.Now(), time.Now()); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestBackslash(t *testing.T) {
+	lower := memfs.Create(memfs.WithPathSeparator('\\'))
+	vfs.MkdirAll(lower, `\dir\sub`, 0755)
+	vfs.WriteFile(lower, `\dir\a`, []byte("a"), 0644)
+	vfs.WriteFile(lower, `\dir\sub\c`, []byte("c"), 0644)
+	lower.Symlink("/dir/a", `\link`)
+	upper := memfs.Create(memfs.WithPathSeparator('\\'))
+	fs := Create(upper, vfs.ReadOnly(lower))
+
+	assertContent(t, fs, `dir/sub\..\a`, "a")
+	assertContent(t, fs, `\link`, "a")
+	if err := fs.Remove("/dir/sub/c"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if err := fs.Remove(`\dir\sub`); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if _, err := upper.Stat(`\dir\` + WhiteoutPrefix + "sub"); err != nil {
+		t.Errorf("Expected whiteout in upper layer: %s", err)
+	}
+	for _, name := range []string{`\dir\sub`, `/dir\sub/c`, `\dir/x\..\sub\c`} {
+		if _, err := fs.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("Stat %s: expected not exist error, got %v", name, err)
+		}
+	}
+	if err := fs.Remove(`\`); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Remove of root: expected invalid error, got %v", err)
+	}
+}
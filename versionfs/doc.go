@@ -0,0 +1,10 @@
+// Package versionfs defines a filesystem wrapper which retains the previous revisions
+// of files in a separate store, as a basis for undo features and change auditing.
+//
+// Before a file is changed, replaced or removed, its content is copied to the store:
+//
+//	fs := versionfs.Create(vfs.OS(), memfs.Create())
+//	vfs.WriteFile(fs, "/tmp/notes", []byte("v2"), 0644)
+//	versions, err := fs.Versions("/tmp/notes")
+//	err = fs.Restore("/tmp/notes", versions[0].ID)
+package versionfs
@@ -0,0 +1,26 @@
+package versionfs_test
+
+import (
+	"fmt"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/versionfs"
+)
+
+func ExampleFS() {
+	fs := versionfs.Create(memfs.Create(), memfs.Create())
+	vfs.WriteFile(fs, "/notes", []byte("first draft"), 0644)
+	vfs.WriteFile(fs, "/notes", []byte("oops"), 0644)
+
+	versions, _ := fs.Versions("/notes")
+	fmt.Println(len(versions))
+
+	// Undo the last change
+	fs.Restore("/notes", versions[len(versions)-1].ID)
+	b, _ := vfs.ReadFile(fs, "/notes")
+	fmt.Println(string(b))
+	// Output:
+	// 1
+	// first draft
+}
@@ -0,0 +1,316 @@
+package versionfs
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// FS is a filesystem which retains the previous revisions of the files of the wrapped filesystem.
+//
+// A revision is retained before a file opened for writing is changed the first time,
+// once per opened file, and before a file is removed or replaced by a rename.
+// Only regular files are versioned. The history belongs to the path,
+// it does not follow a file which is renamed.
+type FS struct {
+	vfs.Filesystem
+	store vfs.Filesystem
+
+	// mutex serializes the allocation of version IDs
+	mutex sync.Mutex
+}
+
+// Create returns a wrapper of fs retaining revisions in store.
+// Every versioned path uses a directory in the root of store.
+func Create(fs vfs.Filesystem, store vfs.Filesystem) *FS {
+	return &FS{Filesystem: fs, store: store}
+}
+
+// Version describes a retained revision of a file.
+type Version struct {
+	// ID identifies the revision among the versions of its path, later revisions have higher IDs.
+	ID   int
+	Size int64
+	Mode os.FileMode
+	// Time is the time the revision was retained, i.e. when it was replaced.
+	Time time.Time
+}
+
+// dir returns the directory in the store holding the versions of the named file.
+func (fs *FS) dir(name string) string {
+	var cleaned []string
+	for _, seg := range strings.Split(name, string(fs.PathSeparator())) {
+		switch seg {
+		case "", ".":
+		case "..":
+			if len(cleaned) > 0 {
+				cleaned = cleaned[:len(cleaned)-1]
+			}
+		default:
+			cleaned = append(cleaned, seg)
+		}
+	}
+	return string(fs.store.PathSeparator()) + url.PathEscape("/"+strings.Join(cleaned, "/"))
+}
+
+// versions returns the versions of the named file sorted by ID.
+// The caller must hold the lock.
+func (fs *FS) versions(name string) ([]Version, error) {
+	fis, err := fs.store.ReadDir(fs.dir(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, 0, len(fis))
+	for _, fi := range fis {
+		id, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{ID: id, Size: fi.Size(), Mode: fi.Mode(), Time: fi.ModTime()})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+	return versions, nil
+}
+
+// Versions returns the retained revisions of the named file, the oldest first.
+func (fs *FS) Versions(name string) ([]Version, error) {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	versions, err := fs.versions(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "versions", Path: name, Err: err}
+	}
+	return versions, nil
+}
+
+// OpenVersion opens the revision id of the named file for reading.
+func (fs *FS) OpenVersion(name string, id int) (vfs.File, error) {
+	f, err := fs.store.OpenFile(vfs.JoinPath(fs.store, fs.dir(name), strconv.Itoa(id)), os.O_RDONLY, 0)
+	if err != nil {
+		if perr, ok := err.(*os.PathError); ok {
+			err = perr.Err
+		}
+		return nil, &os.PathError{Op: "open", Path: name + "@" + strconv.Itoa(id), Err: err}
+	}
+	return f, nil
+}
+
+// Restore replaces the content of the named file by the revision id.
+// The current content is retained as a new revision, a removed file is recreated.
+func (fs *FS) Restore(name string, id int) error {
+	src, err := fs.OpenVersion(name, id)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := fs.save(name); err != nil {
+		return err
+	}
+	dst, err := fs.Filesystem.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// save retains the current content of the named file as a new revision and returns its ID.
+// Missing files, directories and symbolic links are ignored, the returned ID is 0.
+func (fs *FS) save(name string) (int, error) {
+	fi, err := fs.Filesystem.Lstat(name)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, nil
+	}
+
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	versions, err := fs.versions(name)
+	if err != nil {
+		return 0, err
+	}
+	id := 1
+	if len(versions) > 0 {
+		id = versions[len(versions)-1].ID + 1
+	}
+	dir := fs.dir(name)
+	if len(versions) == 0 {
+		if err := fs.store.Mkdir(dir, 0700); err != nil {
+			if fi, err1 := fs.store.Stat(dir); err1 != nil || !fi.IsDir() {
+				return 0, err
+			}
+		}
+	}
+	src, err := fs.Filesystem.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	dst, err := fs.store.OpenFile(vfs.JoinPath(fs.store, dir, strconv.Itoa(id)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	_, err = io.Copy(dst, src)
+	if err1 := dst.Close(); err == nil {
+		err = err1
+	}
+	return id, err
+}
+
+// discard removes the revision id retained for a change which failed.
+func (fs *FS) discard(name string, id int) {
+	if id > 0 {
+		fs.store.Remove(vfs.JoinPath(fs.store, fs.dir(name), strconv.Itoa(id)))
+	}
+}
+
+// OpenFile opens the named file. Truncating an existing file retains its revision,
+// other files opened for writing retain it before their first change.
+func (fs *FS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND) == 0 {
+		return fs.Filesystem.OpenFile(name, flag, perm)
+	}
+	if flag&os.O_TRUNC != 0 {
+		id, err := fs.save(name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := fs.Filesystem.OpenFile(name, flag, perm)
+		if err != nil {
+			fs.discard(name, id)
+		}
+		return f, err
+	}
+	fi, err := fs.Filesystem.Lstat(name)
+	f, err1 := fs.Filesystem.OpenFile(name, flag, perm)
+	if err1 != nil || err != nil || !fi.Mode().IsRegular() {
+		// Created files have no previous revision
+		return f, err1
+	}
+	return &file{File: f, fs: fs, name: name}, nil
+}
+
+// Remove retains the revision of the named file and removes it.
+func (fs *FS) Remove(name string) error {
+	id, err := fs.save(name)
+	if err != nil {
+		return err
+	}
+	if err := fs.Filesystem.Remove(name); err != nil {
+		fs.discard(name, id)
+		return err
+	}
+	return nil
+}
+
+// Rename renames a file, a replaced file at newpath is retained.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	id, err := fs.save(newpath)
+	if err != nil {
+		return err
+	}
+	if err := fs.Filesystem.Rename(oldpath, newpath); err != nil {
+		fs.discard(newpath, id)
+		return err
+	}
+	return nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (fs *FS) Symlink(oldname, newname string) error {
+	return vfs.Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Link creates newname as a hard link to oldname.
+func (fs *FS) Link(oldname, newname string) error {
+	return vfs.Link(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return vfs.Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	return vfs.Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// Watch reports changes of the named file.
+func (fs *FS) Watch(name string) (<-chan vfs.Event, error) {
+	return vfs.Watch(fs.Filesystem, name)
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *FS) Unwatch(events <-chan vfs.Event) error {
+	return vfs.Unwatch(fs.Filesystem, events)
+}
+
+// file retains the revision of an open file before its first change.
+type file struct {
+	vfs.File
+	fs    *FS
+	name  string
+	mutex sync.Mutex
+	saved bool
+}
+
+// save retains the revision on the first call.
+func (f *file) save() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.saved {
+		return nil
+	}
+	if _, err := f.fs.save(f.name); err != nil {
+		return err
+	}
+	f.saved = true
+	return nil
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	if err := f.save(); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *file) Truncate(size int64) error {
+	if err := f.save(); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
@@ -0,0 +1,173 @@
+package versionfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(Create(memfs.Create(), memfs.Create()))
+	_ = vfs.Symlinker(Create(memfs.Create(), memfs.Create()))
+	_ = vfs.Linker(Create(memfs.Create(), memfs.Create()))
+	_ = vfs.Attributer(Create(memfs.Create(), memfs.Create()))
+	_ = vfs.Watcher(Create(memfs.Create(), memfs.Create()))
+}
+
+func TestConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return Create(memfs.Create(), memfs.Create())
+	})
+}
+
+// contents returns the contents of all versions of the named file.
+func contents(t *testing.T, fs *FS, name string) []string {
+	t.Helper()
+	versions, err := fs.Versions(name)
+	if err != nil {
+		t.Fatalf("Versions: %s", err)
+	}
+	var contents []string
+	for _, v := range versions {
+		f, err := fs.OpenVersion(name, v.ID)
+		if err != nil {
+			t.Fatalf("OpenVersion %d: %s", v.ID, err)
+		}
+		b, err := vfs.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("ReadAll: %s", err)
+		}
+		if int64(len(b)) != v.Size {
+			t.Errorf("Unexpected size of version %d: %d", v.ID, v.Size)
+		}
+		contents = append(contents, string(b))
+	}
+	return contents
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWrite(t *testing.T) {
+	fs := Create(memfs.Create(), memfs.Create())
+	fs.Mkdir("/dir", 0755)
+	for _, content := range []string{"v1", "v2", "v3"} {
+		if err := vfs.WriteFile(fs, "/dir/file", []byte(content), 0640); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+	if c := contents(t, fs, "/dir/file"); !equal(c, []string{"v1", "v2"}) {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+	versions, _ := fs.Versions("/dir/../dir/file")
+	if len(versions) != 2 || versions[0].ID != 1 || versions[1].ID != 2 || versions[0].Mode != 0640 {
+		t.Errorf("Unexpected versions of cleaned path: %v", versions)
+	}
+
+	// A single revision for every opened file
+	f, err := fs.OpenFile("/dir/file", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	f.Write([]byte("a"))
+	f.Write([]byte("b"))
+	f.Close()
+	if c := contents(t, fs, "/dir/file"); !equal(c, []string{"v1", "v2", "v3"}) {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+
+	// Reading does not retain revisions
+	vfs.ReadFile(fs, "/dir/file")
+	f, _ = fs.OpenFile("/dir/file", os.O_RDWR, 0)
+	f.Close()
+	if versions, _ := fs.Versions("/dir/file"); len(versions) != 3 {
+		t.Errorf("Expected 3 versions, got %d", len(versions))
+	}
+
+	if _, err := fs.OpenVersion("/dir/file", 42); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+	if versions, err := fs.Versions("/nonexisting"); err != nil || len(versions) != 0 {
+		t.Errorf("Unexpected versions of missing file: %v %v", versions, err)
+	}
+}
+
+func TestRemoveRestore(t *testing.T) {
+	fs := Create(memfs.Create(), memfs.Create())
+	vfs.WriteFile(fs, "/file", []byte("v1"), 0644)
+	vfs.WriteFile(fs, "/other", []byte("other"), 0644)
+
+	// Failed changes retain nothing
+	if err := fs.Rename("/nonexisting", "/file"); err == nil {
+		t.Fatalf("Expected rename to fail")
+	}
+	if c := contents(t, fs, "/file"); len(c) != 0 {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+	if err := fs.Rename("/other", "/file"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if err := fs.Remove("/file"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if c := contents(t, fs, "/file"); !equal(c, []string{"v1", "other"}) {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+
+	if err := fs.Restore("/file", 1); err != nil {
+		t.Fatalf("Restore: %s", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/file"); err != nil || string(b) != "v1" {
+		t.Errorf("Unexpected restored content: %q %v", b, err)
+	}
+	vfs.WriteFile(fs, "/file", []byte("v2"), 0644)
+	if err := fs.Restore("/file", 2); err != nil {
+		t.Fatalf("Restore: %s", err)
+	}
+	if c := contents(t, fs, "/file"); !equal(c, []string{"v1", "other", "v1", "v2"}) {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+	if b, _ := vfs.ReadFile(fs, "/file"); string(b) != "other" {
+		t.Errorf("Unexpected restored content: %q", b)
+	}
+	if err := fs.Restore("/file", 42); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestRenameReplace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "versionfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := Create(vfs.OS(), memfs.Create())
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	vfs.WriteFile(fs, a, []byte("new"), 0644)
+	vfs.WriteFile(fs, b, []byte("replaced"), 0644)
+
+	if err := fs.Rename(a, b); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if c := contents(t, fs, b); !equal(c, []string{"replaced"}) {
+		t.Errorf("Unexpected versions: %q", c)
+	}
+	if c := contents(t, fs, a); len(c) != 0 {
+		t.Errorf("Unexpected versions of renamed file: %q", c)
+	}
+}
@@ -0,0 +1,11 @@
+// Package vfstest implements a conformance test suite for vfs.Filesystem implementations.
+//
+// The suite checks that a filesystem behaves like the filesystem of the OS,
+// so backends can be swapped without subtle differences:
+//
+//	func TestMyFS(t *testing.T) {
+//		vfstest.TestFilesystem(t, func() vfs.Filesystem {
+//			return myfs.Create()
+//		})
+//	}
+package vfstest
@@ -0,0 +1,19 @@
+package vfstest_test
+
+import (
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func ExampleTestFilesystem() {
+	// Run the suite from a test of your own filesystem implementation
+	testMyFS := func(t *testing.T) {
+		vfstest.TestFilesystem(t, func() vfs.Filesystem {
+			return memfs.Create()
+		})
+	}
+	_ = testMyFS
+}
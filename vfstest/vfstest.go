@@ -0,0 +1,583 @@
+package vfstest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blang/vfs"
+)
+
+// TestFilesystem runs the conformance suite against the filesystems returned by newFS.
+// Every test calls newFS to get an empty filesystem, which must be writable.
+// Optional features like symbolic links are skipped if they return vfs.ErrUnsupported.
+func TestFilesystem(t *testing.T, newFS func() vfs.Filesystem) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, fs vfs.Filesystem)
+	}{
+		{"OpenFile", testOpenFile},
+		{"OpenFlags", testOpenFlags},
+		{"Append", testAppend},
+		{"Truncate", testTruncate},
+		{"Mkdir", testMkdir},
+		{"Remove", testRemove},
+		{"Rename", testRename},
+		{"RenameDir", testRenameDir},
+		{"RenameReplace", testRenameReplace},
+		{"ReadDir", testReadDir},
+		{"OpenDir", testOpenDir},
+		{"Walk", testWalk},
+		{"Symlink", testSymlink},
+		{"Concurrency", testConcurrency},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, newFS())
+		})
+	}
+}
+
+// join returns the absolute path of the given names using the separator of fs.
+func join(fs vfs.Filesystem, names ...string) string {
+	sep := string(fs.PathSeparator())
+	return sep + strings.Join(names, sep)
+}
+
+// writeFile creates the named file with the given content.
+func writeFile(t *testing.T, fs vfs.Filesystem, name, content string) {
+	t.Helper()
+	if err := vfs.WriteFile(fs, name, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile %s: %s", name, err)
+	}
+}
+
+// checkContent checks the content of the named file.
+func checkContent(t *testing.T, fs vfs.Filesystem, name, content string) {
+	t.Helper()
+	b, err := vfs.ReadFile(fs, name)
+	if err != nil {
+		t.Errorf("ReadFile %s: %s", name, err)
+	} else if string(b) != content {
+		t.Errorf("Unexpected content of %s: %q, expected %q", name, b, content)
+	}
+}
+
+// checkNotExist checks that the named file does not exist.
+func checkNotExist(t *testing.T, fs vfs.Filesystem, name string) {
+	t.Helper()
+	if _, err := fs.Lstat(name); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to not exist, got %v", name, err)
+	}
+}
+
+func testOpenFile(t *testing.T, fs vfs.Filesystem) {
+	name := join(fs, "file")
+	if _, err := fs.OpenFile(name, os.O_RDONLY, 0); !os.IsNotExist(err) {
+		t.Errorf("Open missing file: expected not exist error, got %v", err)
+	}
+	if _, err := fs.OpenFile(join(fs, "missing", "file"), os.O_CREATE|os.O_WRONLY, 0644); !os.IsNotExist(err) {
+		t.Errorf("Create in missing directory: expected not exist error, got %v", err)
+	}
+
+	f, err := fs.OpenFile(name, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	if fi, err := f.Stat(); err != nil || fi.Size() != 0 || !fi.Mode().IsRegular() {
+		t.Errorf("Stat of created file: %v %v", fi, err)
+	}
+	if n, err := f.Write([]byte("hello world")); err != nil || n != 11 {
+		t.Errorf("Write: %d %v", n, err)
+	}
+	if off, err := f.Seek(6, io.SeekStart); err != nil || off != 6 {
+		t.Errorf("Seek: %d %v", off, err)
+	}
+	b := make([]byte, 16)
+	if n, err := f.Read(b); err != nil || string(b[:n]) != "world" {
+		t.Errorf("Read after seek: %q %v", b[:n], err)
+	}
+	if n, err := f.Read(b); err != io.EOF || n != 0 {
+		t.Errorf("Read at end: expected io.EOF, got %d %v", n, err)
+	}
+	if n, err := f.ReadAt(b[:5], 0); err != nil || string(b[:n]) != "hello" {
+		t.Errorf("ReadAt: %q %v", b[:n], err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close: %s", err)
+	}
+
+	fi, err := fs.Stat(name)
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "file" || fi.Size() != 11 || fi.IsDir() {
+		t.Errorf("Unexpected FileInfo: name %q, size %d, dir %t", fi.Name(), fi.Size(), fi.IsDir())
+	}
+
+	if _, err := fs.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644); !os.IsExist(err) {
+		t.Errorf("Exclusive create of existing file: expected exist error, got %v", err)
+	}
+	checkContent(t, fs, name, "hello world")
+}
+
+func testOpenFlags(t *testing.T, fs vfs.Filesystem) {
+	name := join(fs, "file")
+	writeFile(t, fs, name, "content")
+
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open read-only: %s", err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Errorf("Write to read-only file succeeded")
+	}
+	f.Close()
+
+	f, err = fs.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Open write-only: %s", err)
+	}
+	if _, err := f.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read from write-only file succeeded")
+	}
+	if _, err := f.Write([]byte("C")); err != nil {
+		t.Errorf("Write: %s", err)
+	}
+	f.Close()
+	checkContent(t, fs, name, "Content")
+
+	f, err = fs.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("Open truncating: %s", err)
+	}
+	f.Close()
+	checkContent(t, fs, name, "")
+
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if _, err := fs.OpenFile(dir, os.O_WRONLY, 0); err == nil {
+		t.Errorf("Open directory for writing succeeded")
+	}
+	d, err := fs.OpenFile(dir, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open directory: %s", err)
+	}
+	if fi, err := d.Stat(); err != nil || !fi.IsDir() {
+		t.Errorf("Stat of directory handle: %v %v", fi, err)
+	}
+	d.Close()
+}
+
+func testAppend(t *testing.T, fs vfs.Filesystem) {
+	name := join(fs, "file")
+	writeFile(t, fs, name, "abc")
+
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Open append: %s", err)
+	}
+	if _, err := f.Write([]byte("def")); err != nil {
+		t.Errorf("Write: %s", err)
+	}
+	// Appending ignores the offset
+	f.Seek(0, io.SeekStart)
+	if _, err := f.Write([]byte("ghi")); err != nil {
+		t.Errorf("Write: %s", err)
+	}
+	f.Close()
+	checkContent(t, fs, name, "abcdefghi")
+}
+
+func testTruncate(t *testing.T, fs vfs.Filesystem) {
+	name := join(fs, "file")
+	writeFile(t, fs, name, "abcdef")
+
+	f, err := fs.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(3); err != nil {
+		t.Fatalf("Truncate: %s", err)
+	}
+	checkContent(t, fs, name, "abc")
+
+	// Growing fills the file with zeros
+	if err := f.Truncate(5); err != nil {
+		t.Fatalf("Truncate: %s", err)
+	}
+	checkContent(t, fs, name, "abc\x00\x00")
+	if fi, err := fs.Stat(name); err != nil || fi.Size() != 5 {
+		t.Errorf("Stat after truncate: %v %v", fi, err)
+	}
+}
+
+func testMkdir(t *testing.T, fs vfs.Filesystem) {
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if fi, err := fs.Stat(dir); err != nil || !fi.IsDir() || !fi.Mode().IsDir() {
+		t.Errorf("Stat of directory: %v %v", fi, err)
+	}
+	if err := fs.Mkdir(dir, 0755); err == nil {
+		t.Errorf("Mkdir of existing directory succeeded")
+	}
+	if err := fs.Mkdir(join(fs, "missing", "dir"), 0755); !os.IsNotExist(err) {
+		t.Errorf("Mkdir in missing directory: expected not exist error, got %v", err)
+	}
+	writeFile(t, fs, join(fs, "file"), "")
+	if err := fs.Mkdir(join(fs, "file", "dir"), 0755); err == nil {
+		t.Errorf("Mkdir below a file succeeded")
+	}
+
+	deep := join(fs, "a", "b", "c")
+	if err := vfs.MkdirAll(fs, deep, 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	if err := vfs.MkdirAll(fs, deep, 0755); err != nil {
+		t.Errorf("MkdirAll of existing directory: %s", err)
+	}
+	if fi, err := fs.Stat(deep); err != nil || !fi.IsDir() {
+		t.Errorf("Stat after MkdirAll: %v %v", fi, err)
+	}
+}
+
+func testRemove(t *testing.T, fs vfs.Filesystem) {
+	dir := join(fs, "dir")
+	name := join(fs, "dir", "file")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	writeFile(t, fs, name, "content")
+
+	if err := fs.Remove(join(fs, "missing")); !os.IsNotExist(err) {
+		t.Errorf("Remove missing file: expected not exist error, got %v", err)
+	}
+	if err := fs.Remove(dir); err == nil {
+		t.Errorf("Remove of non-empty directory succeeded")
+	}
+	if err := fs.Remove(name); err != nil {
+		t.Errorf("Remove file: %s", err)
+	}
+	checkNotExist(t, fs, name)
+	if err := fs.Remove(dir); err != nil {
+		t.Errorf("Remove empty directory: %s", err)
+	}
+	checkNotExist(t, fs, dir)
+
+	if err := vfs.MkdirAll(fs, join(fs, "tree", "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	writeFile(t, fs, join(fs, "tree", "sub", "file"), "")
+	if err := vfs.RemoveAll(fs, join(fs, "tree")); err != nil {
+		t.Errorf("RemoveAll: %s", err)
+	}
+	checkNotExist(t, fs, join(fs, "tree"))
+	if err := vfs.RemoveAll(fs, join(fs, "tree")); err != nil {
+		t.Errorf("RemoveAll of missing path: %s", err)
+	}
+}
+
+func testRename(t *testing.T, fs vfs.Filesystem) {
+	oldname, newname := join(fs, "old"), join(fs, "new")
+	writeFile(t, fs, oldname, "content")
+
+	if err := fs.Rename(oldname, newname); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	checkNotExist(t, fs, oldname)
+	checkContent(t, fs, newname, "content")
+
+	if err := fs.Rename(oldname, join(fs, "other")); !os.IsNotExist(err) {
+		t.Errorf("Rename missing file: expected not exist error, got %v", err)
+	}
+	if err := fs.Rename(newname, join(fs, "missing", "file")); !os.IsNotExist(err) {
+		t.Errorf("Rename into missing directory: expected not exist error, got %v", err)
+	}
+	checkContent(t, fs, newname, "content")
+
+	// Open handles stay usable
+	f, err := fs.OpenFile(newname, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Open: %s", err)
+	}
+	if err := fs.Rename(newname, oldname); err != nil {
+		t.Fatalf("Rename of open file: %s", err)
+	}
+	if _, err := f.Write([]byte("C")); err != nil {
+		t.Errorf("Write after rename: %s", err)
+	}
+	f.Close()
+	checkContent(t, fs, oldname, "Content")
+}
+
+func testRenameDir(t *testing.T, fs vfs.Filesystem) {
+	dir := join(fs, "dir")
+	if err := vfs.MkdirAll(fs, join(fs, "dir", "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll: %s", err)
+	}
+	writeFile(t, fs, join(fs, "dir", "sub", "file"), "content")
+
+	moved := join(fs, "moved")
+	if err := fs.Rename(dir, moved); err != nil {
+		t.Fatalf("Rename directory: %s", err)
+	}
+	checkNotExist(t, fs, dir)
+	checkContent(t, fs, join(fs, "moved", "sub", "file"), "content")
+
+	if err := fs.Rename(moved, join(fs, "moved", "sub", "dir")); err == nil {
+		t.Errorf("Rename of directory into itself succeeded")
+	}
+	checkContent(t, fs, join(fs, "moved", "sub", "file"), "content")
+}
+
+// testRenameReplace checks that renaming onto an existing file replaces it like the OS does.
+func testRenameReplace(t *testing.T, fs vfs.Filesystem) {
+	oldname, newname := join(fs, "old"), join(fs, "new")
+	writeFile(t, fs, oldname, "old")
+	writeFile(t, fs, newname, "new")
+
+	if err := fs.Rename(oldname, newname); err != nil {
+		t.Errorf("Rename replacing a file: %s", err)
+	}
+	checkNotExist(t, fs, oldname)
+	checkContent(t, fs, newname, "old")
+
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	writeFile(t, fs, join(fs, "dir", "file"), "")
+	if err := fs.Rename(newname, dir); err == nil {
+		t.Errorf("Rename replacing a non-empty directory succeeded")
+	}
+	if fi, err := fs.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("Directory changed by failed rename: %v %v", fi, err)
+	}
+}
+
+func testReadDir(t *testing.T, fs vfs.Filesystem) {
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if fis, err := fs.ReadDir(dir); err != nil || len(fis) != 0 {
+		t.Errorf("ReadDir of empty directory: %v %v", fis, err)
+	}
+
+	names := []string{"b", "c", "a", "B", "ab"}
+	for _, name := range names {
+		writeFile(t, fs, join(fs, "dir", name), name)
+	}
+	expected := "B,a,ab,b,c,d"
+	if b, _ := vfs.ReadFile(fs, join(fs, "dir", "b")); string(b) == "B" {
+		// Case-insensitive filesystems like the ones of macOS and Windows wrote B into b
+		expected = "a,ab,b,c,d"
+	}
+	if err := fs.Mkdir(join(fs, "dir", "d"), 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+
+	fis, err := fs.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %s", err)
+	}
+	var got []string
+	for _, fi := range fis {
+		got = append(got, fi.Name())
+		if fi.Name() == "d" && !fi.IsDir() {
+			t.Errorf("Expected d to be a directory")
+		}
+		if fi.Name() == "ab" && fi.Size() != 2 {
+			t.Errorf("Unexpected size of ab: %d", fi.Size())
+		}
+	}
+	if s := strings.Join(got, ","); s != expected {
+		t.Errorf("ReadDir must be sorted by name, got %s", s)
+	}
+
+	if _, err := fs.ReadDir(join(fs, "missing")); !os.IsNotExist(err) {
+		t.Errorf("ReadDir of missing directory: expected not exist error, got %v", err)
+	}
+	if _, err := fs.ReadDir(join(fs, "dir", "a")); err == nil {
+		t.Errorf("ReadDir of a file succeeded")
+	}
+
+	d, err := fs.OpenFile(dir, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Open directory: %s", err)
+	}
+	defer d.Close()
+	var count int
+	for {
+		fis, err := d.Readdir(2)
+		count += len(fis)
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(fis) == 0 {
+			t.Fatalf("Readdir: %v %v", fis, err)
+		}
+	}
+	if n := strings.Count(expected, ",") + 1; count != n {
+		t.Errorf("Readdir returned %d entries, expected %d", count, n)
+	}
+}
+
+func testOpenDir(t *testing.T, fs vfs.Filesystem) {
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	names := []string{"e", "a", "d", "b", "c"}
+	for _, name := range names {
+		writeFile(t, fs, join(fs, "dir", name), "")
+	}
+
+	d, err := vfs.OpenDir(fs, dir)
+	if err != nil {
+		t.Fatalf("OpenDir: %s", err)
+	}
+	defer d.Close()
+	var got []string
+	for {
+		fis, err := d.Next(2)
+		for _, fi := range fis {
+			got = append(got, fi.Name())
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil || len(fis) == 0 || len(fis) > 2 {
+			t.Fatalf("Next: %v %v", fis, err)
+		}
+	}
+	sort.Strings(got)
+	if s := strings.Join(got, ","); s != "a,b,c,d,e" {
+		t.Errorf("Unexpected entries: %s", s)
+	}
+
+	if _, err := vfs.OpenDir(fs, join(fs, "missing")); !os.IsNotExist(err) {
+		t.Errorf("OpenDir of missing directory: expected not exist error, got %v", err)
+	}
+}
+
+func testWalk(t *testing.T, fs vfs.Filesystem) {
+	for _, dir := range [][]string{{"root", "b"}, {"root", "a", "skip"}} {
+		if err := vfs.MkdirAll(fs, join(fs, dir...), 0755); err != nil {
+			t.Fatalf("MkdirAll: %s", err)
+		}
+	}
+	writeFile(t, fs, join(fs, "root", "a", "file"), "")
+	writeFile(t, fs, join(fs, "root", "a", "skip", "file"), "")
+	writeFile(t, fs, join(fs, "root", "c"), "")
+
+	root := join(fs, "root")
+	var visited []string
+	err := vfs.Walk(fs, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, strings.TrimPrefix(path, root))
+		if fi.IsDir() && fi.Name() == "skip" {
+			return vfs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	sep := string(fs.PathSeparator())
+	expected := []string{"", "a", "a" + sep + "file", "a" + sep + "skip", "b", "c"}
+	for i := range expected[1:] {
+		expected[i+1] = sep + expected[i+1]
+	}
+	if fmt.Sprint(visited) != fmt.Sprint(expected) {
+		t.Errorf("Walk visited %q, expected %q", visited, expected)
+	}
+
+	errStop := errors.New("stop")
+	err = vfs.Walk(fs, root, func(path string, fi os.FileInfo, err error) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("Walk must return the error of walkFn, got %v", err)
+	}
+}
+
+func testSymlink(t *testing.T, fs vfs.Filesystem) {
+	// Relative targets work for filesystems nested in others as well
+	target, link := join(fs, "target"), join(fs, "link")
+	writeFile(t, fs, target, "content")
+	if err := vfs.Symlink(fs, "target", link); errors.Is(err, vfs.ErrUnsupported) {
+		t.Skip("Symbolic links are not supported")
+	} else if err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	if fi, err := fs.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat of link: %v %v", fi, err)
+	}
+	if fi, err := fs.Stat(link); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("Stat of link: %v %v", fi, err)
+	}
+	if dst, err := vfs.Readlink(fs, link); err != nil || dst != "target" {
+		t.Errorf("Readlink: %q %v", dst, err)
+	}
+	checkContent(t, fs, link, "content")
+
+	if err := fs.Remove(link); err != nil {
+		t.Errorf("Remove link: %s", err)
+	}
+	checkContent(t, fs, target, "content")
+}
+
+func testConcurrency(t *testing.T, fs vfs.Filesystem) {
+	const workers = 8
+	dir := join(fs, "dir")
+	if err := fs.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := join(fs, "dir", fmt.Sprintf("file%d", i))
+			data := bytes.Repeat([]byte{byte('a' + i)}, 1024)
+			if err := vfs.WriteFile(fs, name, data, 0644); err != nil {
+				errs <- err
+				return
+			}
+			if b, err := vfs.ReadFile(fs, name); err != nil {
+				errs <- err
+			} else if !bytes.Equal(b, data) {
+				errs <- fmt.Errorf("Unexpected content of %s", name)
+			}
+			if _, err := fs.ReadDir(dir); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if fis, err := fs.ReadDir(dir); err != nil || len(fis) != workers {
+		t.Errorf("Expected %d files after concurrent writes, got %d %v", workers, len(fis), err)
+	}
+}
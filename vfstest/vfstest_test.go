@@ -0,0 +1,40 @@
+package vfstest
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/prefixfs"
+)
+
+func TestMemFS(t *testing.T) {
+	TestFilesystem(t, func() vfs.Filesystem {
+		return memfs.Create()
+	})
+}
+
+func TestMemFSBackslash(t *testing.T) {
+	TestFilesystem(t, func() vfs.Filesystem {
+		return memfs.Create(memfs.WithPathSeparator('\\'))
+	})
+}
+
+func TestOS(t *testing.T) {
+	var dirs []string
+	defer func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}()
+	TestFilesystem(t, func() vfs.Filesystem {
+		dir, err := ioutil.TempDir("", "vfstest")
+		if err != nil {
+			t.Fatalf("TempDir: %s", err)
+		}
+		dirs = append(dirs, dir)
+		return prefixfs.Create(vfs.OS(), dir)
+	})
+}
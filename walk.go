@@ -0,0 +1,210 @@
+package vfs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SkipDir is used as a return value from WalkFuncs to indicate that
+// the directory named in the call is to be skipped. It is not returned
+// as an error by any function.
+var SkipDir = filepath.SkipDir
+
+// WalkFunc is the type of the function called for each file or directory
+// visited by Walk. See filepath.WalkFunc for the semantics of its arguments
+// and return value.
+type WalkFunc func(path string, info os.FileInfo, err error) error
+
+// Walk walks the file tree rooted at root on the given Filesystem, calling walkFn
+// for each file or directory in the tree, including root. All errors that arise
+// visiting files and directories are filtered by walkFn. The files are walked in
+// lexical order. Walk does not follow symbolic links.
+// If the Filesystem implements DirOpener, directories are read in batches of 1024 entries
+// of which only the names are kept to sort them, so the FileInfos of huge directories
+// are never held at once.
+//
+// This is a port of the stdlib filepath.Walk function.
+func Walk(fs Filesystem, root string, walkFn WalkFunc) error {
+	info, err := fs.Lstat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fs, root, info, walkFn)
+	}
+	if err == SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walk recursively descends path, calling walkFn.
+func walk(fs Filesystem, path string, info os.FileInfo, walkFn WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+	if _, ok := fs.(DirOpener); ok {
+		return walkDir(fs, path, info, walkFn)
+	}
+
+	fis, err := fs.ReadDir(path)
+	err1 := walkFn(path, info, err)
+	// If err != nil, walk can't walk into this directory.
+	// err1 != nil means walkFn want walk to skip this directory or stop walking.
+	// Therefore, if one of err and err1 isn't nil, walk will return.
+	if err != nil || err1 != nil {
+		// The caller's behavior is controlled by the return value, which is decided
+		// by walkFn. walkFn may ignore err and return nil.
+		// If walkFn returns SkipDir, it will be handled by the caller.
+		// So walk should return whatever walkFn returns.
+		return err1
+	}
+	names := make([]string, len(fis))
+	for i, fi := range fis {
+		names[i] = fi.Name()
+	}
+	return walkEntries(fs, path, names, walkFn)
+}
+
+// walkBatch is the number of entries read at once by walkDir.
+const walkBatch = 1024
+
+// walkDir is walk for directories of a DirOpener, which are read in batches.
+// The names of all entries are collected and sorted before they are walked.
+func walkDir(fs Filesystem, path string, info os.FileInfo, walkFn WalkFunc) error {
+	d, err := OpenDir(fs, path)
+	err1 := walkFn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+	names, err := readDirNames(d)
+	d.Close()
+	sort.Strings(names)
+	if err1 := walkEntries(fs, path, names, walkFn); err1 != nil {
+		return err1
+	}
+	if err != nil {
+		// Like filepath.WalkDir, walkFn is called a second time for the directory
+		return walkFn(path, info, err)
+	}
+	return nil
+}
+
+// readDirNames returns the names of the entries of d, read in batches of walkBatch entries.
+// The names read before an error are returned with it.
+func readDirNames(d DirIterator) ([]string, error) {
+	var names []string
+	for {
+		fis, err := d.Next(walkBatch)
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return names, err
+		}
+	}
+}
+
+// walkEntries walks the entries names of the directory path.
+func walkEntries(fs Filesystem, path string, names []string, walkFn WalkFunc) error {
+	for _, name := range names {
+		filename := JoinPath(fs, path, name)
+		fileInfo, err := fs.Lstat(filename)
+		if err != nil {
+			if err := walkFn(filename, fileInfo, err); err != nil && err != SkipDir {
+				return err
+			}
+		} else {
+			err = walk(fs, filename, fileInfo, walkFn)
+			if err != nil {
+				if !fileInfo.IsDir() || err != SkipDir {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WalkDir walks the file tree rooted at root on the given Filesystem, calling fn
+// for each file or directory in the tree, including root, like filepath.WalkDir.
+// The files are walked in lexical order. WalkDir does not follow symbolic links.
+//
+// Unlike Walk, WalkDir does not call Lstat for the entries of a directory:
+// their DirEntries are built from the FileInfos returned by ReadDir.
+// On the OS only the types of the entries are read, Info calls Lstat on demand.
+func WalkDir(fs Filesystem, root string, fn iofs.WalkDirFunc) error {
+	info, err := fs.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDirEntry(fs, root, iofs.FileInfoToDirEntry(info), fn)
+	}
+	if err == SkipDir || err == iofs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDirEntry recursively descends path, calling fn.
+func walkDirEntry(fs Filesystem, path string, d iofs.DirEntry, fn iofs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == SkipDir && d.IsDir() {
+			// Successfully skipped directory
+			err = nil
+		}
+		return err
+	}
+
+	entries, err := readDirEntries(fs, path)
+	if err != nil {
+		// Second call, to report the error of reading the directory
+		err = fn(path, d, err)
+		if err != nil {
+			if err == SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		if err := walkDirEntry(fs, JoinPath(fs, path, entry.Name()), entry, fn); err != nil {
+			if err == SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// readDirEntries returns the sorted entries of the named directory.
+func readDirEntries(fs Filesystem, path string) ([]iofs.DirEntry, error) {
+	switch fs.(type) {
+	case OsFS, *OsFS:
+		return os.ReadDir(path)
+	}
+	fis, err := fs.ReadDir(path)
+	entries := make([]iofs.DirEntry, len(fis))
+	for i, fi := range fis {
+		entries[i] = iofs.FileInfoToDirEntry(fi)
+	}
+	return entries, err
+}
+
+// JoinPath joins the directory dir and the entry name using the
+// path separator of the given Filesystem.
+func JoinPath(fs Filesystem, dir, name string) string {
+	sep := string(fs.PathSeparator())
+	if dir == "" || strings.HasSuffix(dir, sep) {
+		return dir + name
+	}
+	return dir + sep + name
+}
@@ -0,0 +1,367 @@
+package vfs_test
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func walkTestFS(t *testing.T) vfs.Filesystem {
+	fs := memfs.Create()
+	for _, dir := range []string{"/a", "/a/b", "/c"} {
+		if err := fs.Mkdir(dir, 0777); err != nil {
+			t.Fatalf("Mkdir %q: %s", dir, err)
+		}
+	}
+	for _, file := range []string{"/a/b/file1", "/a/file2", "/file3"} {
+		if err := vfs.WriteFile(fs, file, []byte(file), 0666); err != nil {
+			t.Fatalf("WriteFile %q: %s", file, err)
+		}
+	}
+	return fs
+}
+
+func TestWalk(t *testing.T) {
+	fs := walkTestFS(t)
+
+	var visited []string
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			t.Errorf("Unexpected error on %q: %s", path, err)
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	expected := []string{"/", "/a", "/a/b", "/a/b/file1", "/a/file2", "/c", "/file3"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	fs := walkTestFS(t)
+
+	var visited []string
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		visited = append(visited, path)
+		if path == "/a" {
+			return vfs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	expected := []string{"/", "/a", "/c", "/file3"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+}
+
+func TestWalkError(t *testing.T) {
+	fs := walkTestFS(t)
+	errStop := errors.New("stop")
+
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if path == "/a/b" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Expected walk error, got %v", err)
+	}
+
+	// Non existing root is passed to walkFn
+	called := false
+	err = vfs.Walk(fs, "/nonexisting", func(path string, info os.FileInfo, err error) error {
+		called = true
+		if !os.IsNotExist(err) {
+			t.Errorf("Expected not exist error, got %v", err)
+		}
+		return err
+	})
+	if !called || err == nil {
+		t.Errorf("Expected error for non existing root")
+	}
+}
+
+// errDirFS fails reading directories after the first entry.
+type errDirFS struct {
+	vfs.Filesystem
+}
+
+func (fs errDirFS) OpenDir(path string) (vfs.DirIterator, error) {
+	d, err := vfs.OpenDir(fs.Filesystem, path)
+	return &errDirIterator{d}, err
+}
+
+type errDirIterator struct {
+	vfs.DirIterator
+}
+
+func (d *errDirIterator) Next(n int) ([]os.FileInfo, error) {
+	fis, _ := d.DirIterator.Next(1)
+	return fis, errors.New("broken")
+}
+
+func TestWalkDirOpener(t *testing.T) {
+	fs := errDirFS{walkTestFS(t)}
+
+	var visited []string
+	err := vfs.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Failed directories are reported a second time
+			visited = append(visited, "!"+path)
+			return nil
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	expected := []string{"/", "/a", "/a/b", "/a/b/file1", "!/a/b", "!/a", "!/"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+
+	// ReadDir is used without DirOpener
+	var n int
+	vfs.Walk(struct{ vfs.Filesystem }{fs.Filesystem}, "/", func(path string, info os.FileInfo, err error) error {
+		n++
+		return err
+	})
+	if n != 7 {
+		t.Errorf("Visited %d files, expected 7", n)
+	}
+}
+
+// reverseDirFS lists directories in reverse lexical order.
+type reverseDirFS struct {
+	vfs.Filesystem
+}
+
+func (fs reverseDirFS) OpenDir(path string) (vfs.DirIterator, error) {
+	fis, err := fs.Filesystem.ReadDir(path)
+	for i, j := 0, len(fis)-1; i < j; i, j = i+1, j-1 {
+		fis[i], fis[j] = fis[j], fis[i]
+	}
+	return &sliceDirIterator{fis}, err
+}
+
+type sliceDirIterator struct {
+	fis []os.FileInfo
+}
+
+func (d *sliceDirIterator) Next(n int) ([]os.FileInfo, error) {
+	if len(d.fis) == 0 {
+		return nil, io.EOF
+	}
+	if n <= 0 || n > len(d.fis) {
+		n = len(d.fis)
+	}
+	fis := d.fis[:n]
+	d.fis = d.fis[n:]
+	return fis, nil
+}
+
+func (d *sliceDirIterator) Close() error {
+	return nil
+}
+
+func TestWalkDirOpenerOrder(t *testing.T) {
+	fs := memfs.Create()
+	var expected []string
+	for i := 0; i < 2500; i++ {
+		name := "/f" + strconv.Itoa(10000+i)
+		vfs.WriteFile(fs, name, nil, 0644)
+		expected = append(expected, name)
+	}
+
+	var visited []string
+	err := vfs.Walk(reverseDirFS{fs}, "/", func(path string, info os.FileInfo, err error) error {
+		if path != "/" {
+			visited = append(visited, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Walk of %d entries not in lexical order", len(visited))
+	}
+}
+
+func TestWalkOSOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-walk")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	var expected []string
+	for i := 0; i < 50; i++ {
+		expected = append(expected, filepath.Join(dir, "f"+strconv.Itoa(100+i)))
+	}
+	for i := len(expected) - 1; i >= 0; i-- {
+		if err := ioutil.WriteFile(expected[i], nil, 0644); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+	}
+
+	var visited []string
+	err = vfs.Walk(vfs.OS(), dir, func(path string, info os.FileInfo, err error) error {
+		if path != dir {
+			visited = append(visited, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk: %s", err)
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Walk visited %q, expected lexical order", visited)
+	}
+}
+
+// lstatCountFS counts the calls to Lstat.
+type lstatCountFS struct {
+	vfs.Filesystem
+	n int
+}
+
+func (fs *lstatCountFS) Lstat(name string) (os.FileInfo, error) {
+	fs.n++
+	return fs.Filesystem.Lstat(name)
+}
+
+func TestWalkDir(t *testing.T) {
+	fs := &lstatCountFS{Filesystem: walkTestFS(t)}
+
+	var visited []string
+	err := vfs.WalkDir(fs, "/", func(path string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			t.Errorf("Unexpected error on %q: %s", path, err)
+		}
+		if d.IsDir() {
+			path += "/"
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir: %s", err)
+	}
+	expected := []string{"//", "/a/", "/a/b/", "/a/b/file1", "/a/file2", "/c/", "/file3"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+	// Only the root is stat'ed
+	if fs.n != 1 {
+		t.Errorf("Expected 1 call to Lstat, got %d", fs.n)
+	}
+}
+
+func TestWalkDirSkip(t *testing.T) {
+	fs := walkTestFS(t)
+
+	var visited []string
+	err := vfs.WalkDir(fs, "/", func(path string, d iofs.DirEntry, err error) error {
+		visited = append(visited, path)
+		switch path {
+		case "/a":
+			return vfs.SkipDir
+		case "/c":
+			return iofs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkDir: %s", err)
+	}
+	expected := []string{"/", "/a", "/c"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+
+	// SkipDir on a file skips the remaining files of its directory
+	visited = nil
+	vfs.WalkDir(fs, "/a", func(path string, d iofs.DirEntry, err error) error {
+		visited = append(visited, path)
+		if path == "/a/b/file1" {
+			return vfs.SkipDir
+		}
+		return nil
+	})
+	expected = []string{"/a", "/a/b", "/a/b/file1", "/a/file2"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+}
+
+func TestWalkDirError(t *testing.T) {
+	fs := walkTestFS(t)
+
+	// Non existing root is passed to fn without a DirEntry
+	called := false
+	err := vfs.WalkDir(fs, "/nonexisting", func(path string, d iofs.DirEntry, err error) error {
+		called = true
+		if d != nil || !os.IsNotExist(err) {
+			t.Errorf("Expected not exist error, got %v %v", d, err)
+		}
+		return err
+	})
+	if !called || err == nil {
+		t.Errorf("Expected error for non existing root")
+	}
+
+	// Info of entries is available on demand
+	err = vfs.WalkDir(fs, "/a", func(path string, d iofs.DirEntry, err error) error {
+		info, err := d.Info()
+		if err != nil || info.Name() != d.Name() {
+			t.Errorf("Unexpected info of %q: %v %v", path, info, err)
+		}
+		return err
+	})
+	if err != nil {
+		t.Errorf("WalkDir: %s", err)
+	}
+}
+
+func TestWalkDirOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-walkdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := vfs.OS()
+	vfs.MkdirAll(fs, filepath.Join(dir, "a", "b"), 0755)
+	vfs.WriteFile(fs, filepath.Join(dir, "a", "file"), nil, 0644)
+
+	var visited []string
+	err = vfs.WalkDir(fs, dir, func(path string, d iofs.DirEntry, err error) error {
+		rel, _ := filepath.Rel(dir, path)
+		visited = append(visited, rel+":"+strconv.FormatBool(d.IsDir()))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDir: %s", err)
+	}
+	expected := []string{".:true", "a:true", filepath.Join("a", "b") + ":true", filepath.Join("a", "file") + ":false"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Visited %q, expected %q", visited, expected)
+	}
+}
@@ -0,0 +1,74 @@
+package vfs
+
+import (
+	"os"
+	"strings"
+)
+
+// EventOp describes the kind of change reported by an Event.
+type EventOp uint32
+
+// Kinds of changes, an Event may combine several of them.
+const (
+	// EventCreate reports a new file, directory or symbolic link.
+	EventCreate EventOp = 1 << iota
+	// EventWrite reports a change of the content of a file.
+	EventWrite
+	// EventRemove reports a removed file.
+	EventRemove
+	// EventRename reports a file moved away from its path, the new path is reported as EventCreate.
+	EventRename
+	// EventChmod reports changed metadata like mode, owner or modification time.
+	EventChmod
+)
+
+var eventOpNames = []string{"CREATE", "WRITE", "REMOVE", "RENAME", "CHMOD"}
+
+// String returns the names of the contained kinds of changes, separated by "|".
+func (op EventOp) String() string {
+	var names []string
+	for i, name := range eventOpNames {
+		if op&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event describes a change of the named file.
+type Event struct {
+	Name string
+	Op   EventOp
+}
+
+func (e Event) String() string {
+	return e.Op.String() + " " + e.Name
+}
+
+// Watcher is implemented by filesystems which report changes of files.
+type Watcher interface {
+	// Watch reports changes of the named file, or of the named directory and its direct entries.
+	Watch(name string) (<-chan Event, error)
+	// Unwatch stops a watch started by Watch and closes its channel.
+	Unwatch(events <-chan Event) error
+}
+
+// Watch reports changes of the named file on the given Filesystem.
+// Watching a directory reports changes of its direct entries as well, not of deeper descendants.
+// The returned channel is closed after the watch is stopped using Unwatch.
+// If the Filesystem does not implement Watcher, a *os.PathError containing ErrUnsupported is returned.
+func Watch(fs Filesystem, name string) (<-chan Event, error) {
+	if w, ok := fs.(Watcher); ok {
+		return w.Watch(name)
+	}
+	return nil, &os.PathError{Op: "watch", Path: name, Err: ErrUnsupported}
+}
+
+// Unwatch stops a watch started by Watch on the given Filesystem.
+// If the Filesystem does not implement Watcher, ErrUnsupported is returned.
+func Unwatch(fs Filesystem, events <-chan Event) error {
+	if w, ok := fs.(Watcher); ok {
+		return w.Unwatch(events)
+	}
+	return ErrUnsupported
+}
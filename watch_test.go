@@ -0,0 +1,49 @@
+package vfs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestWatchUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	if _, err := vfs.Watch(fs, "/"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := vfs.Unwatch(fs, nil); err != vfs.ErrUnsupported {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestWatchReadOnly(t *testing.T) {
+	mfs := memfs.Create()
+	events, err := vfs.Watch(vfs.ReadOnly(mfs), "/")
+	if err != nil {
+		t.Fatalf("Watch: %s", err)
+	}
+	mfs.Mkdir("/dir", 0755)
+	select {
+	case ev := <-events:
+		if ev.Name != "/dir" || ev.Op != vfs.EventCreate {
+			t.Errorf("Unexpected event: %s", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Missing event")
+	}
+	if err := vfs.Unwatch(vfs.ReadOnly(mfs), events); err != nil {
+		t.Errorf("Unwatch: %s", err)
+	}
+}
+
+func TestEventOpString(t *testing.T) {
+	if s := (vfs.EventCreate | vfs.EventChmod).String(); s != "CREATE|CHMOD" {
+		t.Errorf("Unexpected string: %q", s)
+	}
+	if s := (vfs.Event{Name: "/file", Op: vfs.EventWrite}).String(); s != "WRITE /file" {
+		t.Errorf("Unexpected string: %q", s)
+	}
+}
@@ -0,0 +1,3 @@
+// Package webdavfs adapts a vfs.Filesystem to golang.org/x/net/webdav,
+// making it accessible to WebDAV clients like Finder or Explorer.
+package webdavfs
@@ -0,0 +1,19 @@
+package webdavfs_test
+
+import (
+	"net/http"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/webdavfs"
+)
+
+func ExampleFS() {
+	// Serve an in-memory filesystem over WebDAV
+	handler := &webdav.Handler{
+		FileSystem: webdavfs.Create(memfs.Create()),
+		LockSystem: webdav.NewMemLS(),
+	}
+	http.ListenAndServe(":8080", handler)
+}
@@ -0,0 +1,145 @@
+package webdavfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/blang/vfs"
+)
+
+// FS implements webdav.FileSystem on top of a vfs.Filesystem.
+type FS struct {
+	fs vfs.Filesystem
+}
+
+// Create returns a webdav.FileSystem serving the given filesystem.
+func Create(fs vfs.Filesystem) *FS {
+	return &FS{fs: fs}
+}
+
+// path converts a slash-separated WebDAV name to a path of the filesystem.
+func (fs *FS) path(name string) string {
+	sep := string(fs.fs.PathSeparator())
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	return strings.Replace(name, "/", sep, -1)
+}
+
+// Mkdir implements webdav.FileSystem.
+func (fs *FS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return fs.fs.Mkdir(fs.path(name), perm)
+}
+
+// OpenFile implements webdav.FileSystem.
+// Directories are opened for listing only.
+func (fs *FS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	p := fs.path(name)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		fi, err := fs.fs.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if fi.IsDir() {
+			return &dir{fs: fs.fs, path: p, info: dirInfo{fi}}, nil
+		}
+	}
+	return fs.fs.OpenFile(p, flag, perm)
+}
+
+// RemoveAll implements webdav.FileSystem.
+func (fs *FS) RemoveAll(ctx context.Context, name string) error {
+	return vfs.RemoveAll(fs.fs, fs.path(name))
+}
+
+// Rename implements webdav.FileSystem.
+func (fs *FS) Rename(ctx context.Context, oldName, newName string) error {
+	return fs.fs.Rename(fs.path(oldName), fs.path(newName))
+}
+
+// Stat implements webdav.FileSystem.
+func (fs *FS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	fi, err := fs.fs.Stat(fs.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return dirInfo{fi}, nil
+	}
+	return fi, nil
+}
+
+// dir represents an open directory, which can only be listed.
+type dir struct {
+	fs      vfs.Filesystem
+	path    string
+	info    os.FileInfo
+	entries []os.FileInfo
+	read    bool
+}
+
+func (d *dir) Stat() (os.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "read", Path: d.path, Err: vfs.ErrIsDirectory}
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, &os.PathError{Op: "write", Path: d.path, Err: vfs.ErrIsDirectory}
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+// Readdir reads the directory on the first call and
+// returns the entries in chunks of count afterwards.
+func (d *dir) Readdir(count int) ([]os.FileInfo, error) {
+	if !d.read {
+		fis, err := d.fs.ReadDir(d.path)
+		if err != nil {
+			return nil, err
+		}
+		for i, fi := range fis {
+			if fi.IsDir() {
+				fis[i] = dirInfo{fi}
+			}
+		}
+		d.entries = fis
+		d.read = true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(d.entries) {
+		count = len(d.entries)
+	}
+	entries := d.entries[:count]
+	d.entries = d.entries[count:]
+	return entries, nil
+}
+
+// dirInfo makes sure the mode of a directory has os.ModeDir set,
+// which WebDAV relies on but some filesystems omit.
+type dirInfo struct {
+	os.FileInfo
+}
+
+func (fi dirInfo) Mode() os.FileMode {
+	return fi.FileInfo.Mode() | os.ModeDir
+}
@@ -0,0 +1,102 @@
+package webdavfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func do(t *testing.T, method, url string, body string, header map[string]string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, url, err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestHandler(t *testing.T) {
+	fs := memfs.Create()
+	server := httptest.NewServer(&webdav.Handler{
+		FileSystem: Create(fs),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer server.Close()
+
+	if code, _ := do(t, "MKCOL", server.URL+"/dir", "", nil); code != http.StatusCreated {
+		t.Fatalf("MKCOL: %d", code)
+	}
+	if code, _ := do(t, "PUT", server.URL+"/dir/file.txt", "content", nil); code != http.StatusCreated {
+		t.Fatalf("PUT: %d", code)
+	}
+	if b, err := vfs.ReadFile(fs, "/dir/file.txt"); err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q, %v", b, err)
+	}
+	if code, body := do(t, "GET", server.URL+"/dir/file.txt", "", nil); code != http.StatusOK || body != "content" {
+		t.Errorf("GET: %d %q", code, body)
+	}
+	code, body := do(t, "PROPFIND", server.URL+"/dir/", "", map[string]string{"Depth": "1"})
+	if code != http.StatusMultiStatus || !strings.Contains(body, "file.txt") || !strings.Contains(body, "<D:collection") {
+		t.Errorf("PROPFIND: %d %q", code, body)
+	}
+	if code, _ := do(t, "MOVE", server.URL+"/dir/file.txt", "", map[string]string{"Destination": server.URL + "/moved.txt"}); code != http.StatusCreated {
+		t.Errorf("MOVE: %d", code)
+	}
+	if _, err := fs.Stat("/moved.txt"); err != nil {
+		t.Errorf("Expected moved file: %s", err)
+	}
+	if code, _ := do(t, "DELETE", server.URL+"/dir", "", nil); code != http.StatusNoContent {
+		t.Errorf("DELETE: %d", code)
+	}
+	if _, err := fs.Stat("/dir"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestOpenDir(t *testing.T) {
+	fs := memfs.Create()
+	vfs.MkdirAll(fs, "/dir/sub", 0755)
+	vfs.WriteFile(fs, "/dir/file", nil, 0644)
+	ctx := context.Background()
+
+	f, err := Create(fs).OpenFile(ctx, "/dir", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: %s", err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || fi.Mode()&os.ModeDir == 0 {
+		t.Errorf("Stat: %v, %v", fi, err)
+	}
+	fis, err := f.Readdir(1)
+	if err != nil || len(fis) != 1 || fis[0].Name() != "file" {
+		t.Fatalf("Readdir: %v, %v", fis, err)
+	}
+	fis, err = f.Readdir(1)
+	if err != nil || len(fis) != 1 || fis[0].Mode()&os.ModeDir == 0 {
+		t.Fatalf("Readdir: %v, %v", fis, err)
+	}
+	if _, err := f.Readdir(1); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Errorf("Expected error writing a directory")
+	}
+}
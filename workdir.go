@@ -0,0 +1,204 @@
+package vfs
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Chdirer is implemented by filesystems which resolve relative paths against a working directory.
+type Chdirer interface {
+	// Chdir changes the working directory to the named directory.
+	Chdir(dir string) error
+	// Getwd returns the absolute path of the working directory.
+	Getwd() (string, error)
+}
+
+// Chdir changes the working directory of the given Filesystem to the named directory.
+// If the Filesystem does not implement Chdirer, a *os.PathError containing ErrUnsupported is returned.
+func Chdir(fs Filesystem, dir string) error {
+	if c, ok := fs.(Chdirer); ok {
+		return c.Chdir(dir)
+	}
+	return &os.PathError{Op: "chdir", Path: dir, Err: ErrUnsupported}
+}
+
+// Getwd returns the absolute path of the working directory of the given Filesystem.
+// If the Filesystem does not implement Chdirer, a *os.PathError containing ErrUnsupported is returned.
+func Getwd(fs Filesystem) (string, error) {
+	if c, ok := fs.(Chdirer); ok {
+		return c.Getwd()
+	}
+	return "", &os.PathError{Op: "getwd", Path: ".", Err: ErrUnsupported}
+}
+
+// Chdir changes the working directory of the process, which is shared by all users of the OS filesystem.
+// Use Workdir for a working directory of its own.
+func (fs OsFS) Chdir(dir string) error {
+	return os.Chdir(dir)
+}
+
+// Getwd returns the working directory of the process.
+func (fs OsFS) Getwd() (string, error) {
+	return os.Getwd()
+}
+
+// Workdir creates a wrapper around the given filesystem with a working directory of its own,
+// starting at the absolute path dir. Relative paths are resolved against it lexically
+// and passed to the wrapped filesystem as absolute paths, so any number of wrappers
+// change their working directories independently, even on top of the OS filesystem.
+// Targets of symbolic links are stored unchanged.
+func Workdir(fs Filesystem, dir string) *WorkdirFS {
+	return &WorkdirFS{Filesystem: fs, wd: Clean(fs, dir)}
+}
+
+// WorkdirFS represents a filesystem with its own working directory
+// and works as a wrapper around existing filesystems.
+type WorkdirFS struct {
+	Filesystem
+
+	mutex sync.RWMutex
+	wd    string
+}
+
+// abs returns the absolute path of name.
+func (fs *WorkdirFS) abs(name string) string {
+	if name == "" || IsAbs(fs, name) {
+		return name
+	}
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return Join(fs, fs.wd, name)
+}
+
+// Chdir changes the working directory to the named directory, which must exist.
+// It implements Chdirer.
+func (fs *WorkdirFS) Chdir(dir string) error {
+	path := fs.abs(dir)
+	fi, err := fs.Filesystem.Stat(path)
+	if err != nil {
+		if e, ok := err.(*os.PathError); ok {
+			err = e.Err
+		}
+		return &os.PathError{Op: "chdir", Path: dir, Err: err}
+	}
+	if !fi.IsDir() {
+		return &os.PathError{Op: "chdir", Path: dir, Err: ErrNotDirectory}
+	}
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	fs.wd = Clean(fs, path)
+	return nil
+}
+
+// Getwd returns the absolute path of the working directory.
+// It implements Chdirer.
+func (fs *WorkdirFS) Getwd() (string, error) {
+	fs.mutex.RLock()
+	defer fs.mutex.RUnlock()
+	return fs.wd, nil
+}
+
+// OpenFile opens the named file.
+func (fs *WorkdirFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return fs.Filesystem.OpenFile(fs.abs(name), flag, perm)
+}
+
+// Remove removes the named file or directory.
+func (fs *WorkdirFS) Remove(name string) error {
+	return fs.Filesystem.Remove(fs.abs(name))
+}
+
+// Rename renames a file.
+func (fs *WorkdirFS) Rename(oldpath, newpath string) error {
+	return fs.Filesystem.Rename(fs.abs(oldpath), fs.abs(newpath))
+}
+
+// Mkdir creates a directory.
+func (fs *WorkdirFS) Mkdir(name string, perm os.FileMode) error {
+	return fs.Filesystem.Mkdir(fs.abs(name), perm)
+}
+
+// Stat returns the FileInfo of the named file.
+func (fs *WorkdirFS) Stat(name string) (os.FileInfo, error) {
+	return fs.Filesystem.Stat(fs.abs(name))
+}
+
+// Lstat returns the FileInfo of the named file without following a symbolic link.
+func (fs *WorkdirFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.Filesystem.Lstat(fs.abs(name))
+}
+
+// ReadDir reads the directory named by path.
+func (fs *WorkdirFS) ReadDir(path string) ([]os.FileInfo, error) {
+	return fs.Filesystem.ReadDir(fs.abs(path))
+}
+
+// OpenDir opens the named directory for iteration.
+// It implements DirOpener.
+func (fs *WorkdirFS) OpenDir(path string) (DirIterator, error) {
+	return OpenDir(fs.Filesystem, fs.abs(path))
+}
+
+// Symlink creates newname as a symbolic link to oldname, the target is stored unchanged.
+// It returns ErrUnsupported if the wrapped filesystem does not support symbolic links.
+func (fs *WorkdirFS) Symlink(oldname, newname string) error {
+	return Symlink(fs.Filesystem, oldname, fs.abs(newname))
+}
+
+// Link creates newname as a hard link to oldname.
+// It returns ErrUnsupported if the wrapped filesystem does not support hard links.
+func (fs *WorkdirFS) Link(oldname, newname string) error {
+	return Link(fs.Filesystem, fs.abs(oldname), fs.abs(newname))
+}
+
+// Readlink returns the destination of the named symbolic link
+// if the wrapped filesystem supports symbolic links.
+func (fs *WorkdirFS) Readlink(name string) (string, error) {
+	return Readlink(fs.Filesystem, fs.abs(name))
+}
+
+// Chmod changes the mode of the named file.
+func (fs *WorkdirFS) Chmod(name string, mode os.FileMode) error {
+	return Chmod(fs.Filesystem, fs.abs(name), mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func (fs *WorkdirFS) Chown(name string, uid, gid int) error {
+	return Chown(fs.Filesystem, fs.abs(name), uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file.
+func (fs *WorkdirFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return Chtimes(fs.Filesystem, fs.abs(name), atime, mtime)
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+func (fs *WorkdirFS) GetXattr(name, attr string) ([]byte, error) {
+	return GetXattr(fs.Filesystem, fs.abs(name), attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+func (fs *WorkdirFS) SetXattr(name, attr string, value []byte) error {
+	return SetXattr(fs.Filesystem, fs.abs(name), attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs *WorkdirFS) ListXattr(name string) ([]string, error) {
+	return ListXattr(fs.Filesystem, fs.abs(name))
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs *WorkdirFS) RemoveXattr(name, attr string) error {
+	return RemoveXattr(fs.Filesystem, fs.abs(name), attr)
+}
+
+// Watch reports changes of the named file, the events carry the absolute names of the wrapped filesystem.
+func (fs *WorkdirFS) Watch(name string) (<-chan Event, error) {
+	return Watch(fs.Filesystem, fs.abs(name))
+}
+
+// Unwatch stops a watch started by Watch.
+func (fs *WorkdirFS) Unwatch(events <-chan Event) error {
+	return Unwatch(fs.Filesystem, events)
+}
@@ -0,0 +1,120 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/vfstest"
+)
+
+func TestWorkdirInterface(t *testing.T) {
+	_ = vfs.Filesystem(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Chdirer(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Symlinker(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Linker(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Attributer(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Xattrer(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Watcher(vfs.Workdir(vfs.OS(), "/"))
+	_ = vfs.Chdirer(vfs.OS())
+	_ = vfs.Chdirer(memfs.Create())
+}
+
+func TestWorkdirConformance(t *testing.T) {
+	vfstest.TestFilesystem(t, func() vfs.Filesystem {
+		return vfs.Workdir(memfs.Create(), "/")
+	})
+}
+
+func TestChdirUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	if err := vfs.Chdir(fs, "/dir"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if _, err := vfs.Getwd(fs); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestWorkdir(t *testing.T) {
+	mfs := memfs.Create()
+	vfs.MkdirAll(mfs, "/a/sub", 0755)
+	vfs.MkdirAll(mfs, "/b", 0755)
+	vfs.WriteFile(mfs, "/a/file", nil, 0644)
+
+	a, b := vfs.Workdir(mfs, "/a"), vfs.Workdir(mfs, "/b")
+	if err := vfs.WriteFile(a, "sub/x", []byte("a"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := vfs.WriteFile(b, "x", []byte("b"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := a.Chdir("sub"); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	if wd, err := vfs.Getwd(a); err != nil || wd != "/a/sub" {
+		t.Errorf("Unexpected working directory: %q %v", wd, err)
+	}
+	if wd, _ := b.Getwd(); wd != "/b" {
+		t.Errorf("Working directory of other wrapper changed: %q", wd)
+	}
+	if wd, _ := mfs.Getwd(); wd != "/" {
+		t.Errorf("Working directory of wrapped filesystem changed: %q", wd)
+	}
+	for fs, content := range map[vfs.Filesystem]string{a: "a", b: "b"} {
+		if data, err := vfs.ReadFile(fs, "x"); err != nil || string(data) != content {
+			t.Errorf("Unexpected content: %q %v", data, err)
+		}
+	}
+	if err := a.Rename("../file", "moved"); err != nil {
+		t.Fatalf("Rename: %s", err)
+	}
+	if _, err := mfs.Stat("/a/sub/moved"); err != nil {
+		t.Errorf("Rename relative to working directory: %s", err)
+	}
+
+	if err := a.Chdir("x"); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+	if err := a.Chdir("missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	} else if err.(*os.PathError).Path != "missing" {
+		t.Errorf("Unexpected path in error: %v", err)
+	}
+	if err := a.Chdir("/"); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	if fis, err := a.ReadDir("."); err != nil || len(fis) != 2 {
+		t.Errorf("Unexpected entries of root: %v %v", fis, err)
+	}
+}
+
+func TestWorkdirOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-workdir")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	cwd, _ := os.Getwd()
+
+	fs := vfs.Workdir(vfs.OS(), dir)
+	if err := fs.Mkdir("sub", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := fs.Chdir("sub"); err != nil {
+		t.Fatalf("Chdir: %s", err)
+	}
+	if err := vfs.WriteFile(fs, "file", []byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "file")); err != nil || string(data) != "content" {
+		t.Errorf("Unexpected content: %q %v", data, err)
+	}
+	if wd, _ := os.Getwd(); wd != cwd {
+		t.Errorf("Working directory of the process changed: %q", wd)
+	}
+}
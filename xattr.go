@@ -0,0 +1,103 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrNoXattr is returned reading or removing an extended attribute the file does not have.
+var ErrNoXattr = errors.New("No such attribute")
+
+// Xattrer is implemented by filesystems which store extended attributes of files,
+// like checksums or the origin of a download. Symbolic links are followed.
+type Xattrer interface {
+	// GetXattr returns the value of the extended attribute attr of the named file.
+	GetXattr(name, attr string) ([]byte, error)
+	// SetXattr creates or replaces the extended attribute attr of the named file.
+	SetXattr(name, attr string, value []byte) error
+	// ListXattr returns the names of the extended attributes of the named file.
+	ListXattr(name string) ([]string, error)
+	// RemoveXattr removes the extended attribute attr of the named file.
+	RemoveXattr(name, attr string) error
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file on the given Filesystem.
+// If the Filesystem does not implement Xattrer, a *os.PathError containing ErrUnsupported is returned.
+func GetXattr(fs Filesystem, name, attr string) ([]byte, error) {
+	if x, ok := fs.(Xattrer); ok {
+		return x.GetXattr(name, attr)
+	}
+	return nil, &os.PathError{Op: "getxattr", Path: name, Err: ErrUnsupported}
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file on the given Filesystem.
+// If the Filesystem does not implement Xattrer, a *os.PathError containing ErrUnsupported is returned.
+func SetXattr(fs Filesystem, name, attr string, value []byte) error {
+	if x, ok := fs.(Xattrer); ok {
+		return x.SetXattr(name, attr, value)
+	}
+	return &os.PathError{Op: "setxattr", Path: name, Err: ErrUnsupported}
+}
+
+// ListXattr returns the names of the extended attributes of the named file on the given Filesystem.
+// If the Filesystem does not implement Xattrer, a *os.PathError containing ErrUnsupported is returned.
+func ListXattr(fs Filesystem, name string) ([]string, error) {
+	if x, ok := fs.(Xattrer); ok {
+		return x.ListXattr(name)
+	}
+	return nil, &os.PathError{Op: "listxattr", Path: name, Err: ErrUnsupported}
+}
+
+// RemoveXattr removes the extended attribute attr of the named file on the given Filesystem.
+// If the Filesystem does not implement Xattrer, a *os.PathError containing ErrUnsupported is returned.
+func RemoveXattr(fs Filesystem, name, attr string) error {
+	if x, ok := fs.(Xattrer); ok {
+		return x.RemoveXattr(name, attr)
+	}
+	return &os.PathError{Op: "removexattr", Path: name, Err: ErrUnsupported}
+}
+
+// GetXattr returns the value of the extended attribute attr of the named file.
+// Extended attributes are supported on Linux, other systems return ErrUnsupported.
+func (fs OsFS) GetXattr(name, attr string) ([]byte, error) {
+	return sysGetXattr(name, attr)
+}
+
+// SetXattr creates or replaces the extended attribute attr of the named file.
+// Unprivileged users are usually restricted to attributes in the "user." namespace.
+func (fs OsFS) SetXattr(name, attr string, value []byte) error {
+	return sysSetXattr(name, attr, value)
+}
+
+// ListXattr returns the names of the extended attributes of the named file.
+func (fs OsFS) ListXattr(name string) ([]string, error) {
+	return sysListXattr(name)
+}
+
+// RemoveXattr removes the extended attribute attr of the named file.
+func (fs OsFS) RemoveXattr(name, attr string) error {
+	return sysRemoveXattr(name, attr)
+}
+
+// CopyXattrs copies the extended attributes of srcPath on the src Filesystem to dstPath on the dst Filesystem.
+// Attributes of dstPath missing on srcPath are kept.
+func CopyXattrs(dst, src Filesystem, dstPath, srcPath string) error {
+	attrs, err := ListXattr(src, srcPath)
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		value, err := GetXattr(src, srcPath, attr)
+		if errors.Is(err, ErrNoXattr) {
+			// Removed in the meantime
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := SetXattr(dst, dstPath, attr, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package vfs
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// xattrError converts the errors of the xattr syscalls.
+func xattrError(op, name string, err error) error {
+	switch err {
+	case syscall.ENODATA:
+		err = ErrNoXattr
+	case syscall.ENOTSUP:
+		err = ErrUnsupported
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+func sysGetXattr(name, attr string) ([]byte, error) {
+	for {
+		size, err := syscall.Getxattr(name, attr, nil)
+		if err != nil {
+			return nil, xattrError("getxattr", name, err)
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Getxattr(name, attr, buf)
+		if err == syscall.ERANGE {
+			// Grown concurrently
+			continue
+		}
+		if err != nil {
+			return nil, xattrError("getxattr", name, err)
+		}
+		return buf[:n], nil
+	}
+}
+
+func sysSetXattr(name, attr string, value []byte) error {
+	if err := syscall.Setxattr(name, attr, value, 0); err != nil {
+		return xattrError("setxattr", name, err)
+	}
+	return nil
+}
+
+func sysListXattr(name string) ([]string, error) {
+	for {
+		size, err := syscall.Listxattr(name, nil)
+		if err != nil {
+			return nil, xattrError("listxattr", name, err)
+		}
+		buf := make([]byte, size)
+		n, err := syscall.Listxattr(name, buf)
+		if err == syscall.ERANGE {
+			continue
+		}
+		if err != nil {
+			return nil, xattrError("listxattr", name, err)
+		}
+		// Names are terminated by NUL
+		names := strings.Split(string(buf[:n]), "\x00")
+		return names[:len(names)-1], nil
+	}
+}
+
+func sysRemoveXattr(name, attr string) error {
+	if err := syscall.Removexattr(name, attr); err != nil {
+		return xattrError("removexattr", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package vfs
+
+import (
+	"os"
+)
+
+func sysGetXattr(name, attr string) ([]byte, error) {
+	return nil, &os.PathError{Op: "getxattr", Path: name, Err: ErrUnsupported}
+}
+
+func sysSetXattr(name, attr string, value []byte) error {
+	return &os.PathError{Op: "setxattr", Path: name, Err: ErrUnsupported}
+}
+
+func sysListXattr(name string) ([]string, error) {
+	return nil, &os.PathError{Op: "listxattr", Path: name, Err: ErrUnsupported}
+}
+
+func sysRemoveXattr(name, attr string) error {
+	return &os.PathError{Op: "removexattr", Path: name, Err: ErrUnsupported}
+}
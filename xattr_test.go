@@ -0,0 +1,137 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+)
+
+func TestXattrInterface(t *testing.T) {
+	_ = vfs.Xattrer(vfs.OS())
+	_ = vfs.Xattrer(vfs.ReadOnly(vfs.OS()))
+	_ = vfs.Xattrer(vfs.Logged(vfs.OS(), nil))
+	_ = vfs.Xattrer(vfs.Chroot(vfs.OS(), "/"))
+	_ = vfs.Xattrer(vfs.CaseInsensitive(vfs.OS()))
+	_ = vfs.Xattrer(vfs.EnforcePermissions(vfs.OS(), 0, 0))
+	_ = vfs.Xattrer(vfs.Throttle(vfs.OS(), 0, 0))
+	_ = vfs.Xattrer(vfs.Quota(vfs.OS(), 0, 0))
+}
+
+func TestXattrUnsupported(t *testing.T) {
+	fs := vfs.Dummy(errors.New("Not implemented"))
+	if _, err := vfs.GetXattr(fs, "/file", "user.a"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := vfs.SetXattr(fs, "/file", "user.a", nil); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if _, err := vfs.ListXattr(fs, "/file"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+	if err := vfs.RemoveXattr(fs, "/file", "user.a"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestXattrReadOnly(t *testing.T) {
+	mfs := memfs.Create()
+	vfs.WriteFile(mfs, "/file", nil, 0644)
+	mfs.SetXattr("/file", "user.a", []byte("1"))
+	fs := vfs.ReadOnly(mfs)
+	if v, err := vfs.GetXattr(fs, "/file", "user.a"); err != nil || string(v) != "1" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+	if err := vfs.SetXattr(fs, "/file", "user.a", nil); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := vfs.RemoveXattr(fs, "/file", "user.a"); err != vfs.ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestXattrOS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs-xattr")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	fs := vfs.OS()
+	if err := fs.SetXattr(name, "user.vfs", []byte("value")); errors.Is(err, vfs.ErrUnsupported) {
+		t.Skip("Extended attributes not supported")
+	} else if err != nil {
+		t.Fatalf("SetXattr: %s", err)
+	}
+	if v, err := fs.GetXattr(name, "user.vfs"); err != nil || string(v) != "value" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+	if attrs, err := fs.ListXattr(name); err != nil || !reflect.DeepEqual(attrs, []string{"user.vfs"}) {
+		t.Errorf("Unexpected attributes: %q %v", attrs, err)
+	}
+	if err := fs.RemoveXattr(name, "user.vfs"); err != nil {
+		t.Fatalf("RemoveXattr: %s", err)
+	}
+	if _, err := fs.GetXattr(name, "user.vfs"); !errors.Is(err, vfs.ErrNoXattr) {
+		t.Errorf("Expected ErrNoXattr, got %v", err)
+	}
+}
+
+func TestCopyXattrs(t *testing.T) {
+	src := memfs.Create()
+	dst := memfs.Create()
+	vfs.WriteFile(src, "/a", nil, 0644)
+	vfs.WriteFile(dst, "/b", nil, 0644)
+	src.SetXattr("/a", "user.x", []byte("1"))
+	src.SetXattr("/a", "user.y", []byte("2"))
+	dst.SetXattr("/b", "user.z", []byte("3"))
+
+	if err := vfs.CopyXattrs(dst, src, "/b", "/a"); err != nil {
+		t.Fatalf("CopyXattrs: %s", err)
+	}
+	if attrs, _ := dst.ListXattr("/b"); !reflect.DeepEqual(attrs, []string{"user.x", "user.y", "user.z"}) {
+		t.Errorf("Unexpected attributes: %q", attrs)
+	}
+	if v, _ := dst.GetXattr("/b", "user.y"); string(v) != "2" {
+		t.Errorf("Unexpected value: %q", v)
+	}
+	if err := vfs.CopyXattrs(vfs.Dummy(nil), src, "/b", "/a"); !errors.Is(err, vfs.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestCopyTreePreserveXattrs(t *testing.T) {
+	src := memfs.Create()
+	vfs.MkdirAll(src, "/src/sub", 0755)
+	vfs.WriteFile(src, "/src/sub/file", nil, 0644)
+	src.SetXattr("/src/sub", "user.dir", []byte("d"))
+	src.SetXattr("/src/sub/file", "user.file", []byte("f"))
+
+	dst := memfs.Create()
+	if err := vfs.CopyTree(dst, src, "/dst", "/src", &vfs.CopyOptions{PreserveXattrs: true}); err != nil {
+		t.Fatalf("CopyTree: %s", err)
+	}
+	if v, err := dst.GetXattr("/dst/sub", "user.dir"); err != nil || string(v) != "d" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+	if v, err := dst.GetXattr("/dst/sub/file", "user.file"); err != nil || string(v) != "f" {
+		t.Errorf("Unexpected value: %q %v", v, err)
+	}
+
+	plain := memfs.Create()
+	if err := vfs.CopyTree(plain, src, "/dst", "/src", nil); err != nil {
+		t.Fatalf("CopyTree: %s", err)
+	}
+	if attrs, _ := plain.ListXattr("/dst/sub/file"); len(attrs) != 0 {
+		t.Errorf("Unexpected attributes: %q", attrs)
+	}
+}
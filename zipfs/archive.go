@@ -0,0 +1,308 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	filepath "path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+// maxLinkDepth limits the number of symbolic links followed resolving a path.
+const maxLinkDepth = 40
+
+// archive is a read-only filesystem of the entries of a zip archive.
+type archive struct {
+	nodes map[string]*node
+}
+
+// node is a file, directory or symbolic link of the archive.
+// Directories without an entry of their own have no file.
+type node struct {
+	path   string
+	file   *zip.File
+	childs []string
+}
+
+func newArchive(r *zip.Reader) *archive {
+	a := &archive{
+		nodes: map[string]*node{
+			"/": {path: "/"},
+		},
+	}
+	for _, f := range r.File {
+		name := clean(f.Name)
+		if name == "/" {
+			continue
+		}
+		if n, ok := a.nodes[name]; ok {
+			n.file = f
+			continue
+		}
+		p := a.parent(name)
+		p.childs = append(p.childs, filepath.Base(name))
+		a.nodes[name] = &node{path: name, file: f}
+	}
+	for _, n := range a.nodes {
+		sort.Strings(n.childs)
+	}
+	return a
+}
+
+func clean(name string) string {
+	return filepath.Clean("/" + name)
+}
+
+// parent returns the parent directory of name, creating missing directories.
+func (a *archive) parent(name string) *node {
+	dir := filepath.Dir(name)
+	if n, ok := a.nodes[dir]; ok {
+		return n
+	}
+	n := &node{path: dir}
+	p := a.parent(dir)
+	p.childs = append(p.childs, filepath.Base(dir))
+	a.nodes[dir] = n
+	return n
+}
+
+func (n *node) isDir() bool {
+	return n.file == nil || n.file.Mode().IsDir()
+}
+
+func (n *node) isLink() bool {
+	return n.file != nil && n.file.Mode()&os.ModeSymlink != 0
+}
+
+func (n *node) info() os.FileInfo {
+	if n.file == nil {
+		return dirInfo(filepath.Base(n.path))
+	}
+	return fileInfo{n.file.FileInfo(), filepath.Base(n.path)}
+}
+
+// readlink returns the target of a symbolic link, which is stored as its content.
+func (n *node) readlink() (string, error) {
+	rc, err := n.file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	return string(b), err
+}
+
+// lookup returns the node of name, following a symbolic link if follow is set.
+// Symbolic links of parent directories are always followed.
+func (a *archive) lookup(name string, follow bool) (*node, error) {
+	return a.resolve(clean(name), follow, 0)
+}
+
+func (a *archive) resolve(p string, follow bool, depth int) (*node, error) {
+	n, ok := a.nodes[p]
+	if !ok {
+		if p == "/" {
+			return nil, os.ErrNotExist
+		}
+		// The path might contain a symbolic link as parent
+		dir, err := a.resolve(filepath.Dir(p), true, depth)
+		if err != nil {
+			return nil, err
+		}
+		if !dir.isDir() {
+			return nil, vfs.ErrNotDirectory
+		}
+		target := filepath.Join(dir.path, filepath.Base(p))
+		if _, ok := a.nodes[target]; !ok {
+			return nil, os.ErrNotExist
+		}
+		return a.resolve(target, follow, depth+1)
+	}
+	if !follow || !n.isLink() {
+		return n, nil
+	}
+	if depth == maxLinkDepth {
+		return nil, syscall.ELOOP
+	}
+	target, err := n.readlink()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = filepath.Join(filepath.Dir(p), target)
+	}
+	return a.resolve(clean(target), true, depth+1)
+}
+
+func (a *archive) PathSeparator() uint8 {
+	return '/'
+}
+
+func (a *archive) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: vfs.ErrReadOnly}
+	}
+	n, err := a.lookup(name, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if n.isDir() {
+		return vfs.DirFile(a, name)
+	}
+	return openFile(n.file, name, renamed(n.info(), filepath.Base(clean(name))))
+}
+
+// openFile returns a seekable file of the entry.
+// Stored entries are read directly from the archive, compressed entries are
+// decompressed into memory.
+func openFile(f *zip.File, name string, info os.FileInfo) (vfs.File, error) {
+	if f.Method == zip.Store {
+		if rd, err := f.OpenRaw(); err == nil {
+			if ra, ok := rd.(*io.SectionReader); ok {
+				return &file{SectionReader: ra, name: name, info: info}, nil
+			}
+		}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &file{SectionReader: io.NewSectionReader(bytes.NewReader(b), 0, int64(len(b))), name: name, info: info}, nil
+}
+
+func (a *archive) Remove(name string) error {
+	return &os.PathError{Op: "remove", Path: name, Err: vfs.ErrReadOnly}
+}
+
+func (a *archive) Rename(oldpath, newpath string) error {
+	return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: vfs.ErrReadOnly}
+}
+
+func (a *archive) Mkdir(name string, perm os.FileMode) error {
+	return &os.PathError{Op: "mkdir", Path: name, Err: vfs.ErrReadOnly}
+}
+
+func (a *archive) Symlink(oldname, newname string) error {
+	return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: vfs.ErrReadOnly}
+}
+
+func (a *archive) Readlink(name string) (string, error) {
+	n, err := a.lookup(name, false)
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	if !n.isLink() {
+		return "", &os.PathError{Op: "readlink", Path: name, Err: os.ErrInvalid}
+	}
+	return n.readlink()
+}
+
+func (a *archive) Stat(name string) (os.FileInfo, error) {
+	n, err := a.lookup(name, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return renamed(n.info(), filepath.Base(clean(name))), nil
+}
+
+func (a *archive) Lstat(name string) (os.FileInfo, error) {
+	n, err := a.lookup(name, false)
+	if err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	}
+	return n.info(), nil
+}
+
+func (a *archive) ReadDir(path string) ([]os.FileInfo, error) {
+	n, err := a.lookup(path, true)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: err}
+	}
+	if !n.isDir() {
+		return nil, &os.PathError{Op: "readdir", Path: path, Err: vfs.ErrNotDirectory}
+	}
+	fis := make([]os.FileInfo, 0, len(n.childs))
+	for _, name := range n.childs {
+		fis = append(fis, a.nodes[filepath.Join(n.path, name)].info())
+	}
+	return fis, nil
+}
+
+// fileInfo overrides the name of an entry's FileInfo.
+// Sys() returns the *zip.FileHeader of the entry.
+type fileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi fileInfo) Name() string {
+	return fi.name
+}
+
+func renamed(fi os.FileInfo, name string) os.FileInfo {
+	if f, ok := fi.(fileInfo); ok {
+		f.name = name
+		return f
+	}
+	if _, ok := fi.(dirInfo); ok {
+		return dirInfo(name)
+	}
+	return fi
+}
+
+// dirInfo describes a directory without an entry of its own.
+type dirInfo string
+
+func (fi dirInfo) Name() string       { return string(fi) }
+func (fi dirInfo) Size() int64        { return 0 }
+func (fi dirInfo) Mode() os.FileMode  { return os.ModeDir | 0755 }
+func (fi dirInfo) ModTime() time.Time { return time.Time{} }
+func (fi dirInfo) IsDir() bool        { return true }
+func (fi dirInfo) Sys() interface{}   { return nil }
+
+// file is an open file of the archive.
+type file struct {
+	*io.SectionReader
+	name string
+	info os.FileInfo
+}
+
+func (f *file) Name() string {
+	return f.name
+}
+
+func (f *file) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Readdir(int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.name, Err: vfs.ErrNotDirectory}
+}
+
+func (f *file) Sync() error {
+	return nil
+}
+
+func (f *file) Truncate(int64) error {
+	return vfs.ErrReadOnly
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	return 0, vfs.ErrReadOnly
+}
+
+func (f *file) Close() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blang/vfs"
+)
+
+// testEntry is a file of a test archive, names ending in a slash are directories.
+type testEntry struct {
+	name    string
+	content string
+	method  uint16
+	mode    os.FileMode
+}
+
+func testZip(t *testing.T, entries ...testEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: e.method}
+		if e.mode != 0 {
+			hdr.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, e.content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testArchive(t *testing.T) *archive {
+	b := testZip(t,
+		testEntry{name: "dir/"},
+		testEntry{name: "dir/stored", content: "stored content", method: zip.Store},
+		testEntry{name: "dir/deflated", content: "deflated content", method: zip.Deflate},
+		testEntry{name: "implicit/sub/file", content: "file", method: zip.Deflate},
+		testEntry{name: "link", content: "dir", mode: os.ModeSymlink | 0777},
+		testEntry{name: "ilink", content: "implicit", mode: os.ModeSymlink | 0777},
+	)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newArchive(zr)
+}
+
+func TestArchiveReadDir(t *testing.T) {
+	a := testArchive(t)
+	tests := map[string][]string{
+		"/":          {"dir", "ilink", "implicit", "link"},
+		"/dir/":      {"deflated", "stored"},
+		"implicit":   {"sub"},
+		"/link":      {"deflated", "stored"},
+		"/ilink/sub": {"file"},
+	}
+	for path, expected := range tests {
+		fis, err := a.ReadDir(path)
+		if err != nil {
+			t.Errorf("ReadDir %s: %s", path, err)
+			continue
+		}
+		var names []string
+		for _, fi := range fis {
+			names = append(names, fi.Name())
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("ReadDir %s: Expected %q, got %q", path, expected, names)
+		}
+	}
+	if _, err := a.ReadDir("/dir/stored"); !errors.Is(err, vfs.ErrNotDirectory) {
+		t.Errorf("Expected ErrNotDirectory, got %v", err)
+	}
+}
+
+func TestArchiveStat(t *testing.T) {
+	a := testArchive(t)
+	fi, err := a.Stat("/dir/deflated")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Name() != "deflated" || fi.Size() != 16 || fi.IsDir() {
+		t.Errorf("Unexpected FileInfo: %s %d %s", fi.Name(), fi.Size(), fi.Mode())
+	}
+	if _, ok := fi.Sys().(*zip.FileHeader); !ok {
+		t.Errorf("Expected *zip.FileHeader, got %T", fi.Sys())
+	}
+	if fi, err := a.Stat("/implicit"); err != nil || !fi.IsDir() || fi.Name() != "implicit" {
+		t.Errorf("Expected implicit directory: %v, %v", fi, err)
+	}
+	if fi, err := a.Stat("/link"); err != nil || !fi.IsDir() || fi.Name() != "link" {
+		t.Errorf("Expected followed link: %v, %v", fi, err)
+	}
+	if fi, err := a.Lstat("/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link: %v, %v", fi, err)
+	}
+	if target, err := a.Readlink("/link"); err != nil || target != "dir" {
+		t.Errorf("Readlink: %q, %v", target, err)
+	}
+	if _, err := a.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+func TestArchiveOpenFile(t *testing.T) {
+	a := testArchive(t)
+	for _, path := range []string{"/dir/stored", "/link/deflated"} {
+		f, err := a.OpenFile(path, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("OpenFile %s: %s", path, err)
+		}
+		if fi, err := f.Stat(); err != nil || fi.IsDir() || !strings.HasSuffix(path, "/"+fi.Name()) {
+			t.Errorf("Stat %s: %v, %v", path, fi, err)
+		}
+		buf := make([]byte, 7)
+		if _, err := f.ReadAt(buf, 2); err != nil {
+			t.Errorf("ReadAt %s: %s", path, err)
+		}
+		if _, err := f.Seek(-7, io.SeekEnd); err != nil {
+			t.Errorf("Seek %s: %s", path, err)
+		}
+		if b, err := ioutil.ReadAll(f); err != nil || string(b) != "content" {
+			t.Errorf("ReadAll %s: %q, %v", path, b, err)
+		}
+		if _, err := f.Write([]byte("x")); err != vfs.ErrReadOnly {
+			t.Errorf("Expected ErrReadOnly, got %v", err)
+		}
+		f.Close()
+	}
+	d, err := a.OpenFile("/dir", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile /dir: %s", err)
+	}
+	if _, err := d.Read(make([]byte, 1)); !errors.Is(err, vfs.ErrIsDirectory) {
+		t.Errorf("Expected ErrIsDirectory, got %v", err)
+	}
+	if fis, err := d.Readdir(-1); err != nil || len(fis) == 0 {
+		t.Errorf("Readdir: %v, %v", fis, err)
+	}
+	if _, err := a.OpenFile("/dir/stored", os.O_RDWR, 0); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if err := a.Remove("/dir/stored"); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestArchiveSymlinkLoop(t *testing.T) {
+	b := testZip(t,
+		testEntry{name: "a", content: "b", mode: os.ModeSymlink | 0777},
+		testEntry{name: "b", content: "a", mode: os.ModeSymlink | 0777},
+	)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newArchive(zr).Stat("/a"); err == nil {
+		t.Errorf("Expected error following link loop")
+	}
+}
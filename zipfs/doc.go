@@ -0,0 +1,6 @@
+// Package zipfs defines a filesystem backed by a zip archive.
+//
+// Archives are read-only by default. A writable filesystem stages all
+// modifications in memory on top of the archive, which stays untouched,
+// and writes a new archive on Flush.
+package zipfs
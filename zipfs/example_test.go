@@ -0,0 +1,35 @@
+package zipfs_test
+
+import (
+	"os"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/zipfs"
+)
+
+func ExampleFS() {
+	f, err := os.Open("document.odt")
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return
+	}
+
+	// Open the archive, modifications are staged in memory
+	fs, err := zipfs.CreateWritable(f, fi.Size())
+	if err != nil {
+		return
+	}
+	vfs.WriteFile(fs, "/content.xml", []byte("<office:document-content/>"), 0644)
+
+	// Write the modified archive
+	out, err := os.Create("modified.odt")
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	fs.Flush(out)
+}
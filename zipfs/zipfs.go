@@ -0,0 +1,121 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blang/vfs"
+	"github.com/blang/vfs/memfs"
+	"github.com/blang/vfs/unionfs"
+)
+
+// FS represents a filesystem backed by a zip archive.
+type FS struct {
+	vfs.Filesystem
+}
+
+// Create opens the zip archive of the given size read from r as a read-only filesystem.
+// Entries are decompressed when they are opened.
+func Create(r io.ReaderAt, size int64) (*FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{Filesystem: newArchive(zr)}, nil
+}
+
+// CreateWritable opens the zip archive of the given size read from r as a writable filesystem.
+// Modifications are staged in memory, r is never written. Use Flush to write a new archive.
+func CreateWritable(r io.ReaderAt, size int64) (*FS, error) {
+	fs, err := Create(r, size)
+	if err != nil {
+		return nil, err
+	}
+	fs.Filesystem = unionfs.Create(memfs.Create(), fs.Filesystem)
+	return fs, nil
+}
+
+// New returns an empty writable filesystem to build a new archive.
+func New() *FS {
+	return &FS{Filesystem: memfs.Create()}
+}
+
+// Symlink creates a symbolic link if the filesystem is writable.
+func (fs *FS) Symlink(oldname, newname string) error {
+	return vfs.Symlink(fs.Filesystem, oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link.
+func (fs *FS) Readlink(name string) (string, error) {
+	return vfs.Readlink(fs.Filesystem, name)
+}
+
+// Chmod changes the mode of the named file if the filesystem is writable.
+func (fs *FS) Chmod(name string, mode os.FileMode) error {
+	return vfs.Chmod(fs.Filesystem, name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file if the filesystem is writable.
+// Owners are not stored in the archive.
+func (fs *FS) Chown(name string, uid, gid int) error {
+	return vfs.Chown(fs.Filesystem, name, uid, gid)
+}
+
+// Chtimes changes the access and modification times of the named file if the filesystem is writable.
+func (fs *FS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return vfs.Chtimes(fs.Filesystem, name, atime, mtime)
+}
+
+// Flush writes the current content of the filesystem as a new zip archive to w.
+// Files are compressed using Deflate, symbolic links are stored with their target as content.
+func (fs *FS) Flush(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := vfs.Walk(fs, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil || path == "/" {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(path, "/")
+		switch {
+		case fi.IsDir():
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+			_, err = zw.CreateHeader(hdr)
+			return err
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := vfs.Readlink(fs, path)
+			if err != nil {
+				return err
+			}
+			hdr.Method = zip.Store
+			ew, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(ew, target)
+			return err
+		}
+		hdr.Method = zip.Deflate
+		ew, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := vfs.Open(fs, path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(ew, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,149 @@
+package zipfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/blang/vfs"
+)
+
+func open(t *testing.T, b []byte, writable bool) *FS {
+	create := Create
+	if writable {
+		create = CreateWritable
+	}
+	fs, err := create(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Create: %s", err)
+	}
+	return fs
+}
+
+func entries(t *testing.T, b []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("NewReader: %s", err)
+	}
+	m := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %s", f.Name, err)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(rc)
+		rc.Close()
+		m[f.Name] = buf.String()
+	}
+	return m
+}
+
+func TestInterface(t *testing.T) {
+	_ = vfs.Filesystem(New())
+}
+
+func TestReadOnly(t *testing.T) {
+	fs := open(t, testZip(t, testEntry{name: "file", content: "content"}), false)
+	if err := vfs.WriteFile(fs, "/file", []byte("changed"), 0644); !errors.Is(err, vfs.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if b, err := vfs.ReadFile(fs, "/file"); err != nil || string(b) != "content" {
+		t.Errorf("ReadFile: %q, %v", b, err)
+	}
+}
+
+func TestWritableFlush(t *testing.T) {
+	b := testZip(t,
+		testEntry{name: "dir/a", content: "a"},
+		testEntry{name: "dir/b", content: "b"},
+		testEntry{name: "keep", content: "keep", method: zip.Deflate},
+	)
+	fs := open(t, b, true)
+	if err := vfs.WriteFile(fs, "/dir/a", []byte("changed"), 0644); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	if err := fs.Remove("/dir/b"); err != nil {
+		t.Fatalf("Remove: %s", err)
+	}
+	if err := fs.Mkdir("/new", 0755); err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+	if err := fs.Symlink("/keep", "/new/link"); err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fs.Flush(&buf); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+	expected := map[string]string{
+		"dir/":     "",
+		"dir/a":    "changed",
+		"keep":     "keep",
+		"new/":     "",
+		"new/link": "/keep",
+	}
+	if m := entries(t, buf.Bytes()); !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected entries: %q", m)
+	}
+	if m := entries(t, b); m["dir/a"] != "a" || m["dir/b"] != "b" {
+		t.Errorf("Source archive modified: %q", m)
+	}
+
+	// The new archive can be read again
+	fs = open(t, buf.Bytes(), false)
+	if b, err := vfs.ReadFile(fs, "/new/link"); err != nil || string(b) != "keep" {
+		t.Errorf("ReadFile: %q, %v", b, err)
+	}
+	if fi, err := fs.Lstat("/new/link"); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected link: %v, %v", fi, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	fs := New()
+	if err := vfs.WriteFile(fs, "/file", []byte("content"), 0600); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := fs.Flush(&buf); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+	if m := entries(t, buf.Bytes()); !reflect.DeepEqual(m, map[string]string{"file": "content"}) {
+		t.Errorf("Unexpected entries: %q", m)
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	if _, err := Create(bytes.NewReader([]byte("no zip")), 6); err == nil {
+		t.Errorf("Expected error on corrupt archive")
+	}
+}
+
+func TestFlushAttributes(t *testing.T) {
+	fs := New()
+	vfs.WriteFile(fs, "/file", nil, 0644)
+	mtime := time.Date(2020, 1, 2, 3, 4, 6, 0, time.UTC)
+	if err := fs.Chmod("/file", 0600); err != nil {
+		t.Fatalf("Chmod: %s", err)
+	}
+	if err := fs.Chtimes("/file", mtime, mtime); err != nil {
+		t.Fatalf("Chtimes: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := fs.Flush(&buf); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+	fi, err := open(t, buf.Bytes(), false).Stat("/file")
+	if err != nil {
+		t.Fatalf("Stat: %s", err)
+	}
+	if fi.Mode() != 0600 || !fi.ModTime().Equal(mtime) {
+		t.Errorf("Unexpected attributes: %s %s", fi.Mode(), fi.ModTime())
+	}
+}